@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
+)
+
+// dynamoDBTableResourceID returns the resource ID of the table in Application Auto Scaling.
+func dynamoDBTableResourceID(tableName string) string {
+	return "table/" + tableName
+}
+
+type AutoScalingDescribeScalableTargetsAPI interface {
+	DescribeScalableTargets(ctx context.Context,
+		params *applicationautoscaling.DescribeScalableTargetsInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DescribeScalableTargetsOutput, error)
+}
+
+// describeDynamoDBScalableTarget returns the scalable target of the dimension, or nil if it isn't registered.
+func describeDynamoDBScalableTarget(c context.Context, api AutoScalingDescribeScalableTargetsAPI, tableName string, dimension types.ScalableDimension) (*types.ScalableTarget, error) {
+	in := &applicationautoscaling.DescribeScalableTargetsInput{
+		ServiceNamespace:  types.ServiceNamespaceDynamodb,
+		ResourceIds:       []string{dynamoDBTableResourceID(tableName)},
+		ScalableDimension: dimension,
+	}
+	out, err := api.DescribeScalableTargets(c, in)
+	if err != nil {
+		return nil, err
+	}
+	if len(out.ScalableTargets) == 0 {
+		return nil, nil
+	}
+	return &out.ScalableTargets[0], nil
+}
+
+type AutoScalingRegisterScalableTargetAPI interface {
+	RegisterScalableTarget(ctx context.Context,
+		params *applicationautoscaling.RegisterScalableTargetInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.RegisterScalableTargetOutput, error)
+}
+
+// registerDynamoDBScalableTarget registers the dimension of the table, or updates the bounds if already registered.
+func registerDynamoDBScalableTarget(c context.Context, api AutoScalingRegisterScalableTargetAPI, tableName string, dimension types.ScalableDimension, minCapacity int32, maxCapacity int32) (*applicationautoscaling.RegisterScalableTargetOutput, error) {
+	in := &applicationautoscaling.RegisterScalableTargetInput{
+		ServiceNamespace:  types.ServiceNamespaceDynamodb,
+		ResourceId:        aws.String(dynamoDBTableResourceID(tableName)),
+		ScalableDimension: dimension,
+		MinCapacity:       aws.Int32(minCapacity),
+		MaxCapacity:       aws.Int32(maxCapacity),
+	}
+	return api.RegisterScalableTarget(c, in)
+}
+
+type AutoScalingDeregisterScalableTargetAPI interface {
+	DeregisterScalableTarget(ctx context.Context,
+		params *applicationautoscaling.DeregisterScalableTargetInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DeregisterScalableTargetOutput, error)
+}
+
+// deregisterDynamoDBScalableTarget deregisters the dimension of the table. Its scaling policies are deleted together.
+func deregisterDynamoDBScalableTarget(c context.Context, api AutoScalingDeregisterScalableTargetAPI, tableName string, dimension types.ScalableDimension) (*applicationautoscaling.DeregisterScalableTargetOutput, error) {
+	in := &applicationautoscaling.DeregisterScalableTargetInput{
+		ServiceNamespace:  types.ServiceNamespaceDynamodb,
+		ResourceId:        aws.String(dynamoDBTableResourceID(tableName)),
+		ScalableDimension: dimension,
+	}
+	return api.DeregisterScalableTarget(c, in)
+}
+
+type AutoScalingDescribeScalingPoliciesAPI interface {
+	DescribeScalingPolicies(ctx context.Context,
+		params *applicationautoscaling.DescribeScalingPoliciesInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DescribeScalingPoliciesOutput, error)
+}
+
+// describeDynamoDBScalingPolicy returns the scaling policy, or nil if it doesn't exist.
+func describeDynamoDBScalingPolicy(c context.Context, api AutoScalingDescribeScalingPoliciesAPI, tableName string, dimension types.ScalableDimension, policyName string) (*types.ScalingPolicy, error) {
+	in := &applicationautoscaling.DescribeScalingPoliciesInput{
+		ServiceNamespace:  types.ServiceNamespaceDynamodb,
+		ResourceId:        aws.String(dynamoDBTableResourceID(tableName)),
+		ScalableDimension: dimension,
+		PolicyNames:       []string{policyName},
+	}
+	out, err := api.DescribeScalingPolicies(c, in)
+	if err != nil {
+		return nil, err
+	}
+	if len(out.ScalingPolicies) == 0 {
+		return nil, nil
+	}
+	return &out.ScalingPolicies[0], nil
+}
+
+type AutoScalingPutScalingPolicyAPI interface {
+	PutScalingPolicy(ctx context.Context,
+		params *applicationautoscaling.PutScalingPolicyInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.PutScalingPolicyOutput, error)
+}
+
+// putDynamoDBTargetTrackingPolicy creates or updates target tracking policy which keeps the utilization of the dimension around targetValue.
+func putDynamoDBTargetTrackingPolicy(c context.Context, api AutoScalingPutScalingPolicyAPI, tableName string, dimension types.ScalableDimension, policyName string, targetValue float64) (*applicationautoscaling.PutScalingPolicyOutput, error) {
+	var metric types.MetricType
+	switch dimension {
+	case types.ScalableDimensionDynamoDBTableReadCapacityUnits:
+		metric = types.MetricTypeDynamoDBReadCapacityUtilization
+	case types.ScalableDimensionDynamoDBTableWriteCapacityUnits:
+		metric = types.MetricTypeDynamoDBWriteCapacityUtilization
+	default:
+		return nil, fmt.Errorf("unsupported scalable dimension: %v", dimension)
+	}
+
+	in := &applicationautoscaling.PutScalingPolicyInput{
+		ServiceNamespace:  types.ServiceNamespaceDynamodb,
+		ResourceId:        aws.String(dynamoDBTableResourceID(tableName)),
+		ScalableDimension: dimension,
+		PolicyName:        aws.String(policyName),
+		PolicyType:        types.PolicyTypeTargetTrackingScaling,
+		TargetTrackingScalingPolicyConfiguration: &types.TargetTrackingScalingPolicyConfiguration{
+			TargetValue: aws.Float64(targetValue),
+			PredefinedMetricSpecification: &types.PredefinedMetricSpecification{
+				PredefinedMetricType: metric,
+			},
+		},
+	}
+	return api.PutScalingPolicy(c, in)
+}
+
+type AutoScalingDeleteScalingPolicyAPI interface {
+	DeleteScalingPolicy(ctx context.Context,
+		params *applicationautoscaling.DeleteScalingPolicyInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DeleteScalingPolicyOutput, error)
+}
+
+func deleteDynamoDBScalingPolicy(c context.Context, api AutoScalingDeleteScalingPolicyAPI, tableName string, dimension types.ScalableDimension, policyName string) (*applicationautoscaling.DeleteScalingPolicyOutput, error) {
+	in := &applicationautoscaling.DeleteScalingPolicyInput{
+		ServiceNamespace:  types.ServiceNamespaceDynamodb,
+		ResourceId:        aws.String(dynamoDBTableResourceID(tableName)),
+		ScalableDimension: dimension,
+		PolicyName:        aws.String(policyName),
+	}
+	return api.DeleteScalingPolicy(c, in)
+}
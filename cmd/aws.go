@@ -2,26 +2,55 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
-	"unicode"
+	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
 var (
-	bucketName  string
-	tableName   string
-	billingMode string
+	bucketName          string
+	tableName           string
+	billingMode         string
+	kmsKeyID            string
+	newKMSKey           bool
+	kmsKeyAlias         string
+	dryRun              bool
+	pointInTimeRecovery bool
+	deletionProtection  bool
+	tableKMSKeyID       string
+	readCapacity        int64
+	writeCapacity       int64
+	autoScaling         bool
+	autoScalingMin      int32
+	autoScalingMax      int32
+	autoScalingTarget   float64
+	outputFormat        string
 )
 
 type S3Clientable interface {
+	HeadBucket(ctx context.Context,
+		params *s3.HeadBucketInput,
+		optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+
 	CreateBucket(ctx context.Context,
 		params *s3.CreateBucketInput,
 		optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
@@ -53,6 +82,110 @@ type S3Clientable interface {
 	GetBucketVersioning(ctx context.Context,
 		params *s3.GetBucketVersioningInput,
 		optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error)
+
+	DeleteBucket(ctx context.Context,
+		params *s3.DeleteBucketInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error)
+
+	DeletePublicAccessBlock(ctx context.Context,
+		params *s3.DeletePublicAccessBlockInput,
+		optFns ...func(*s3.Options)) (*s3.DeletePublicAccessBlockOutput, error)
+
+	DeleteBucketEncryption(ctx context.Context,
+		params *s3.DeleteBucketEncryptionInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketEncryptionOutput, error)
+
+	ListObjectsV2(ctx context.Context,
+		params *s3.ListObjectsV2Input,
+		optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+
+	ListObjectVersions(ctx context.Context,
+		params *s3.ListObjectVersionsInput,
+		optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+
+	DeleteObjects(ctx context.Context,
+		params *s3.DeleteObjectsInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+
+	GetBucketPolicy(ctx context.Context,
+		params *s3.GetBucketPolicyInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error)
+
+	PutBucketPolicy(ctx context.Context,
+		params *s3.PutBucketPolicyInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error)
+
+	GetBucketLogging(ctx context.Context,
+		params *s3.GetBucketLoggingInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketLoggingOutput, error)
+
+	PutBucketLogging(ctx context.Context,
+		params *s3.PutBucketLoggingInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketLoggingOutput, error)
+
+	GetBucketLifecycleConfiguration(ctx context.Context,
+		params *s3.GetBucketLifecycleConfigurationInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error)
+
+	PutBucketLifecycleConfiguration(ctx context.Context,
+		params *s3.PutBucketLifecycleConfigurationInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error)
+
+	DeleteBucketLifecycle(ctx context.Context,
+		params *s3.DeleteBucketLifecycleInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error)
+
+	DeleteBucketPolicy(ctx context.Context,
+		params *s3.DeleteBucketPolicyInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketPolicyOutput, error)
+
+	GetBucketReplication(ctx context.Context,
+		params *s3.GetBucketReplicationInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketReplicationOutput, error)
+
+	PutBucketReplication(ctx context.Context,
+		params *s3.PutBucketReplicationInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketReplicationOutput, error)
+
+	DeleteBucketReplication(ctx context.Context,
+		params *s3.DeleteBucketReplicationInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketReplicationOutput, error)
+
+	GetBucketTagging(ctx context.Context,
+		params *s3.GetBucketTaggingInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error)
+
+	PutBucketTagging(ctx context.Context,
+		params *s3.PutBucketTaggingInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error)
+
+	DeleteBucketTagging(ctx context.Context,
+		params *s3.DeleteBucketTaggingInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketTaggingOutput, error)
+
+	PutObject(ctx context.Context,
+		params *s3.PutObjectInput,
+		optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+
+	GetObjectLockConfiguration(ctx context.Context,
+		params *s3.GetObjectLockConfigurationInput,
+		optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error)
+
+	PutObjectLockConfiguration(ctx context.Context,
+		params *s3.PutObjectLockConfigurationInput,
+		optFns ...func(*s3.Options)) (*s3.PutObjectLockConfigurationOutput, error)
+
+	GetBucketOwnershipControls(ctx context.Context,
+		params *s3.GetBucketOwnershipControlsInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error)
+
+	PutBucketOwnershipControls(ctx context.Context,
+		params *s3.PutBucketOwnershipControlsInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketOwnershipControlsOutput, error)
+
+	DeleteBucketOwnershipControls(ctx context.Context,
+		params *s3.DeleteBucketOwnershipControlsInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketOwnershipControlsOutput, error)
 }
 
 type DynamoDBClientable interface {
@@ -62,21 +195,245 @@ type DynamoDBClientable interface {
 	DescribeTable(ctx context.Context,
 		params *dynamodb.DescribeTableInput,
 		optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	UpdateTable(ctx context.Context,
+		params *dynamodb.UpdateTableInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error)
+	DeleteTable(ctx context.Context,
+		params *dynamodb.DeleteTableInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error)
+	Scan(ctx context.Context,
+		params *dynamodb.ScanInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	DescribeContinuousBackups(ctx context.Context,
+		params *dynamodb.DescribeContinuousBackupsInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error)
+	UpdateContinuousBackups(ctx context.Context,
+		params *dynamodb.UpdateContinuousBackupsInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error)
+	ListTagsOfResource(ctx context.Context,
+		params *dynamodb.ListTagsOfResourceInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error)
+	TagResource(ctx context.Context,
+		params *dynamodb.TagResourceInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error)
+	UntagResource(ctx context.Context,
+		params *dynamodb.UntagResourceInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.UntagResourceOutput, error)
+}
+
+type KMSClientable interface {
+	CreateKey(ctx context.Context,
+		params *kms.CreateKeyInput,
+		optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error)
+
+	EnableKeyRotation(ctx context.Context,
+		params *kms.EnableKeyRotationInput,
+		optFns ...func(*kms.Options)) (*kms.EnableKeyRotationOutput, error)
+
+	CreateAlias(ctx context.Context,
+		params *kms.CreateAliasInput,
+		optFns ...func(*kms.Options)) (*kms.CreateAliasOutput, error)
+
+	DescribeKey(ctx context.Context,
+		params *kms.DescribeKeyInput,
+		optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error)
+
+	ScheduleKeyDeletion(ctx context.Context,
+		params *kms.ScheduleKeyDeletionInput,
+		optFns ...func(*kms.Options)) (*kms.ScheduleKeyDeletionOutput, error)
+
+	DeleteAlias(ctx context.Context,
+		params *kms.DeleteAliasInput,
+		optFns ...func(*kms.Options)) (*kms.DeleteAliasOutput, error)
+}
+
+type CloudTrailClientable interface {
+	GetTrail(ctx context.Context,
+		params *cloudtrail.GetTrailInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetTrailOutput, error)
+
+	CreateTrail(ctx context.Context,
+		params *cloudtrail.CreateTrailInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.CreateTrailOutput, error)
+
+	StartLogging(ctx context.Context,
+		params *cloudtrail.StartLoggingInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.StartLoggingOutput, error)
+
+	DeleteTrail(ctx context.Context,
+		params *cloudtrail.DeleteTrailInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.DeleteTrailOutput, error)
+
+	GetEventSelectors(ctx context.Context,
+		params *cloudtrail.GetEventSelectorsInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetEventSelectorsOutput, error)
+
+	PutEventSelectors(ctx context.Context,
+		params *cloudtrail.PutEventSelectorsInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.PutEventSelectorsOutput, error)
+}
+
+type IAMClientable interface {
+	GetRole(ctx context.Context,
+		params *iam.GetRoleInput,
+		optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+
+	CreateRole(ctx context.Context,
+		params *iam.CreateRoleInput,
+		optFns ...func(*iam.Options)) (*iam.CreateRoleOutput, error)
+
+	DeleteRole(ctx context.Context,
+		params *iam.DeleteRoleInput,
+		optFns ...func(*iam.Options)) (*iam.DeleteRoleOutput, error)
+
+	GetRolePolicy(ctx context.Context,
+		params *iam.GetRolePolicyInput,
+		optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error)
+
+	PutRolePolicy(ctx context.Context,
+		params *iam.PutRolePolicyInput,
+		optFns ...func(*iam.Options)) (*iam.PutRolePolicyOutput, error)
+
+	DeleteRolePolicy(ctx context.Context,
+		params *iam.DeleteRolePolicyInput,
+		optFns ...func(*iam.Options)) (*iam.DeleteRolePolicyOutput, error)
+}
+
+type AutoScalingClientable interface {
+	DescribeScalableTargets(ctx context.Context,
+		params *applicationautoscaling.DescribeScalableTargetsInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DescribeScalableTargetsOutput, error)
+
+	RegisterScalableTarget(ctx context.Context,
+		params *applicationautoscaling.RegisterScalableTargetInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.RegisterScalableTargetOutput, error)
+
+	DeregisterScalableTarget(ctx context.Context,
+		params *applicationautoscaling.DeregisterScalableTargetInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DeregisterScalableTargetOutput, error)
+
+	DescribeScalingPolicies(ctx context.Context,
+		params *applicationautoscaling.DescribeScalingPoliciesInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DescribeScalingPoliciesOutput, error)
+
+	PutScalingPolicy(ctx context.Context,
+		params *applicationautoscaling.PutScalingPolicyInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.PutScalingPolicyOutput, error)
+
+	DeleteScalingPolicy(ctx context.Context,
+		params *applicationautoscaling.DeleteScalingPolicyInput,
+		optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DeleteScalingPolicyOutput, error)
+}
+
+// initS3Option holds optional settings of the terraform backend bucket.
+type initS3Option struct {
+	// KMSKeyID is the ARN of the KMS key used for default encryption. If empty, SSE-S3 is used.
+	KMSKeyID string
+	// S3Compatible skips AWS-only steps such as block public access, for S3-compatible object stores.
+	S3Compatible bool
+	// Tags are added to the bucket. Tags of the adopted bucket which aren't in Tags are kept.
+	Tags map[string]string
+	// BucketPolicy attaches the bucket policy if not nil.
+	BucketPolicy *bucketPolicyOption
+	// Lifecycle configures the lifecycle rule for old state versions if any action is specified.
+	Lifecycle lifecycleOption
+	// AccessLogging enables server access logging of the bucket if not nil.
+	AccessLogging *s3types.LoggingEnabled
+	// Replication replicates objects of the bucket into the replica bucket if not nil.
+	Replication *replicationOption
+	// Lockfile checks that the bucket supports conditional writes, which terraform needs to lock with .tflock object.
+	Lockfile bool
+	// ObjectLock creates the bucket with Object Lock and applies the default retention if not nil.
+	ObjectLock *objectLockOption
+}
+
+// stepStatus represents what a step did to the resource.
+type stepStatus string
+
+const (
+	stepStatusCreated   stepStatus = "CREATED"
+	stepStatusUnchanged stepStatus = "UNCHANGED"
+	stepStatusUpdated   stepStatus = "UPDATED"
+	stepStatusSuccess   stepStatus = "SUCCESS"
+	stepStatusFailure   stepStatus = "FAILURE"
+	// stepStatusSkipped means the step is not supported by the S3-compatible object store.
+	stepStatusSkipped stepStatus = "SKIPPED"
+	// stepStatusDeleted and stepStatusAlreadyDeleted are used by destroy.
+	stepStatusDeleted        stepStatus = "DELETED"
+	stepStatusAlreadyDeleted stepStatus = "ALREADY DELETED"
+)
+
+// appliedStatus returns the status of a step which has just applied settings.
+func appliedStatus(adopted bool) stepStatus {
+	if adopted {
+		return stepStatusUpdated
+	}
+	return stepStatusCreated
 }
 
 type initS3Result struct {
-	BucketName        string
-	Region            string
-	BlockPublicAccess string
-	Encryption        string
-	Versioning        string
+	BucketName        string            `json:"bucket_name" yaml:"bucket_name"`
+	BucketArn         string            `json:"bucket_arn" yaml:"bucket_arn"`
+	Region            string            `json:"region" yaml:"region"`
+	BlockPublicAccess string            `json:"block_public_access" yaml:"block_public_access"`
+	ObjectOwnership   string            `json:"object_ownership" yaml:"object_ownership"`
+	Encryption        string            `json:"encryption" yaml:"encryption"`
+	KMSKeyID          string            `json:"kms_key_id,omitempty" yaml:"kms_key_id,omitempty"`
+	BucketKey         string            `json:"bucket_key,omitempty" yaml:"bucket_key,omitempty"`
+	Versioning        string            `json:"versioning" yaml:"versioning"`
+	ObjectLock        string            `json:"object_lock,omitempty" yaml:"object_lock,omitempty"`
+	Lifecycle         string            `json:"lifecycle,omitempty" yaml:"lifecycle,omitempty"`
+	BucketPolicy      string            `json:"bucket_policy,omitempty" yaml:"bucket_policy,omitempty"`
+	AccessLogging     string            `json:"access_logging,omitempty" yaml:"access_logging,omitempty"`
+	Replication       string            `json:"replication,omitempty" yaml:"replication,omitempty"`
+	Lockfile          string            `json:"lockfile,omitempty" yaml:"lockfile,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+type initKMSResult struct {
+	KeyID       string `json:"key_id" yaml:"key_id"`
+	KeyArn      string `json:"key_arn" yaml:"key_arn"`
+	Alias       string `json:"alias" yaml:"alias"`
+	KeyRotation string `json:"key_rotation" yaml:"key_rotation"`
+}
+
+// initDynamoDBOption holds optional settings of the terraform lock table.
+type initDynamoDBOption struct {
+	PointInTimeRecovery bool
+	DeletionProtection  bool
+	// KMSKeyID is the ARN of the customer managed KMS key for the table. If empty, AWS owned key is used.
+	KMSKeyID string
+	// Capacity is used for PROVISIONED table. If zero, defaultTableCapacity is used.
+	Capacity tableCapacity
+	// AutoScaling registers the table to Application Auto Scaling if not nil.
+	AutoScaling *autoScalingOption
+	// Tags are added to the table. Tags of the existing table which aren't in Tags are kept.
+	Tags map[string]string
+}
+
+// autoScalingOption holds bounds and target of auto scaling, which are applied to both read and write capacity.
+type autoScalingOption struct {
+	MinCapacity int32
+	MaxCapacity int32
+	// TargetUtilization is the percentage of consumed capacity to provisioned capacity.
+	TargetUtilization float64
 }
 
 type initDynamoDBResult struct {
-	TableName     string
-	BillingMode   string
-	WriteCapacity string
-	ReadCapacity  string
+	TableName           string `json:"table_name" yaml:"table_name"`
+	TableArn            string `json:"table_arn" yaml:"table_arn"`
+	BillingMode         string `json:"billing_mode" yaml:"billing_mode"`
+	WriteCapacity       string `json:"write_capacity,omitempty" yaml:"write_capacity,omitempty"`
+	ReadCapacity        string `json:"read_capacity,omitempty" yaml:"read_capacity,omitempty"`
+	PointInTimeRecovery string `json:"point_in_time_recovery,omitempty" yaml:"point_in_time_recovery,omitempty"`
+	DeletionProtection  string `json:"deletion_protection,omitempty" yaml:"deletion_protection,omitempty"`
+	Encryption          string `json:"encryption,omitempty" yaml:"encryption,omitempty"`
+	KMSKeyID            string `json:"kms_key_id,omitempty" yaml:"kms_key_id,omitempty"`
+	// AutoScaling* are set only when the table is registered to Application Auto Scaling.
+	AutoScalingMinCapacity       string            `json:"autoscaling_min_capacity,omitempty" yaml:"autoscaling_min_capacity,omitempty"`
+	AutoScalingMaxCapacity       string            `json:"autoscaling_max_capacity,omitempty" yaml:"autoscaling_max_capacity,omitempty"`
+	AutoScalingTargetUtilization string            `json:"autoscaling_target_utilization,omitempty" yaml:"autoscaling_target_utilization,omitempty"`
+	Tags                         map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
 }
 
 func NewCmdAws() *cobra.Command {
@@ -89,10 +446,50 @@ func NewCmdAws() *cobra.Command {
 By default, the bucket configuration is below.
 - Enabled versioning
 - Enabled block public access
+- Disabled ACLs (Object Ownership: BucketOwnerEnforced)
 - Enabled default encryption: SSE-S3(AES-256)
+  (SSE-KMS with S3 Bucket Keys when --kms-key-id or --create-kms-key is specified)
+- Object Lock with the default retention, if --object-lock is specified
+  Object Lock can be enabled only when the bucket is created, and can't be used with --replica-region.
+- Lifecycle rule for noncurrent versions, if --noncurrent-version-expiration-days,
+  --noncurrent-version-transition-days, --expire-delete-markers or
+  --abort-incomplete-upload-days is specified
+- Server access logging into the hardened log bucket, if --log-bucket is specified
+  (CloudTrail data events of state files are also logged by --cloudtrail-trail)
+- Cross-region replication into the replica bucket, if --replica-region is specified
+  The replica bucket has the same hardening, and the IAM role for replication is
+  created. Delete markers are replicated, and SSE-KMS objects are re-encrypted
+  with --replica-kms-key-id.
+- Bucket policy which denies requests without TLS (disabled by --no-bucket-policy)
+  Uploads without encryption or with other KMS key, and requests from principals
+  other than --allowed-principal or outside --principal-org-id can also be denied.
 
 By default, the table configuration is below.
-- Billing mode: PROVISIONED
+- Billing mode: PROVISIONED (5 RCU / 5 WCU, changed by --read-capacity and --write-capacity)
+- Encryption: AWS owned key
+  (customer managed KMS key when --dynamodb-kms-key-id is specified)
+Point-in-time recovery and deletion protection are enabled with
+--point-in-time-recovery and --deletion-protection.
+With --autoscaling, read and write capacity of PROVISIONED table are registered to
+Application Auto Scaling with target tracking policies.
+
+With --use-lockfile, no table is created. Terraform 1.10 or later locks state with
+a .tflock object in the bucket itself (use_lockfile = true), and tfbackend checks
+that the bucket supports conditional writes which the lock relies on.
+
+Every resource is tagged with ManagedBy=tfbackend and TfbackendVersion, merged with
+--tag key=value flags and 'tags' of the config file (a list of key=value).
+
+If the bucket or the table already exists and is owned by you, it is adopted
+and each setting is converged to the configuration above.
+
+If any step fails, completed steps are rolled back in reverse order: newly created
+resources are deleted and previous settings of adopted resources are restored.
+Use --no-rollback to keep them for debugging.
+
+S3-compatible object stores (MinIO, Ceph RGW, Wasabi, LocalStack) are supported
+with --endpoint-url and --force-path-style. With --s3-compatible, steps which the
+store doesn't implement, e.g. block public access, are reported as SKIPPED.
 `,
 		SilenceUsage: true,
 		RunE:         runCmdAws,
@@ -103,10 +500,64 @@ By default, the table configuration is below.
 	})
 
 	// flag
-	cmd.Flags().StringVarP(&bucketName, "s3", "", "", "Name of S3 bucket to create.")
-	cmd.MarkFlagRequired("s3")
-	cmd.Flags().StringVarP(&tableName, "dynamodb", "", "", "Name of DynamoDB table to create.")
-	cmd.Flags().StringVarP(&billingMode, "billing-mode", "", "", "DynamoDB billing mode. Only 'PAY_PER_REQUEST' or 'PROVISIONED' can be accepted. Default is PROVISIONED.")
+	cmd.PersistentFlags().StringVarP(&bucketName, "s3", "", "", "Name of S3 bucket to create.")
+	cmd.MarkPersistentFlagRequired("s3")
+	cmd.PersistentFlags().StringVarP(&tableName, "dynamodb", "", "", "Name of DynamoDB table to create.")
+	cmd.PersistentFlags().StringVarP(&billingMode, "billing-mode", "", "", "DynamoDB billing mode. Only 'PAY_PER_REQUEST' or 'PROVISIONED' can be accepted. Default is PROVISIONED.")
+	cmd.PersistentFlags().Int64VarP(&readCapacity, "read-capacity", "", 5, "Read capacity units of PROVISIONED DynamoDB table.")
+	cmd.PersistentFlags().Int64VarP(&writeCapacity, "write-capacity", "", 5, "Write capacity units of PROVISIONED DynamoDB table.")
+	cmd.PersistentFlags().BoolVarP(&autoScaling, "autoscaling", "", false, "Register read and write capacity of PROVISIONED DynamoDB table to Application Auto Scaling.")
+	cmd.PersistentFlags().Int32VarP(&autoScalingMin, "autoscaling-min-capacity", "", 5, "Minimum capacity units of auto scaling, applied to both read and write.")
+	cmd.PersistentFlags().Int32VarP(&autoScalingMax, "autoscaling-max-capacity", "", 100, "Maximum capacity units of auto scaling, applied to both read and write.")
+	cmd.PersistentFlags().Float64VarP(&autoScalingTarget, "autoscaling-target-utilization", "", 70, "Target utilization (%) of target tracking policies. Between 20 and 90.")
+	cmd.PersistentFlags().BoolVarP(&pointInTimeRecovery, "point-in-time-recovery", "", false, "Enable point-in-time recovery of DynamoDB table.")
+	cmd.PersistentFlags().BoolVarP(&deletionProtection, "deletion-protection", "", false, "Enable deletion protection of DynamoDB table.")
+	cmd.PersistentFlags().StringVarP(&tableKMSKeyID, "dynamodb-kms-key-id", "", "", "ID, ARN or alias of the customer managed KMS key for encryption of DynamoDB table. Default is AWS owned key.")
+	cmd.PersistentFlags().Int32VarP(&noncurrentVersionExpirationDays, "noncurrent-version-expiration-days", "", 0, "Expire noncurrent versions of state files after the days. 0 means never.")
+	cmd.PersistentFlags().Int32VarP(&noncurrentVersionsToKeep, "noncurrent-versions-to-keep", "", 0, "Number of the newest noncurrent versions kept from expiration. Up to 100.")
+	cmd.PersistentFlags().Int32VarP(&noncurrentVersionTransitionDays, "noncurrent-version-transition-days", "", 0, "Move noncurrent versions to --noncurrent-version-storage-class after the days. 0 means never.")
+	cmd.PersistentFlags().StringVarP(&noncurrentVersionStorageClass, "noncurrent-version-storage-class", "", "STANDARD_IA", "Storage class which noncurrent versions move to. 'STANDARD_IA' or 'GLACIER'.")
+	cmd.PersistentFlags().BoolVarP(&expireDeleteMarkers, "expire-delete-markers", "", false, "Remove delete markers whose noncurrent versions are all expired.")
+	cmd.PersistentFlags().Int32VarP(&abortIncompleteUploadDays, "abort-incomplete-upload-days", "", 0, "Abort incomplete multipart uploads after the days. 0 means never.")
+	cmd.PersistentFlags().StringVarP(&logBucketName, "log-bucket", "", "", "Name of the log bucket created or adopted for logs of the state bucket. If specified, server access logging of the state bucket is enabled.")
+	cmd.PersistentFlags().StringVarP(&accessLogPrefix, "access-log-prefix", "", "", "Key prefix of server access logs in the log bucket. Default is 's3-access-logs/BUCKET_NAME/'.")
+	cmd.PersistentFlags().StringVarP(&cloudTrailName, "cloudtrail-trail", "", "", "Name of CloudTrail trail which logs data events of state files. The trail is created with --log-bucket if it doesn't exist.")
+	cmd.PersistentFlags().StringVarP(&replicaRegion, "replica-region", "", "", "Region of the replica bucket. If specified, the state bucket is replicated into the replica bucket for disaster recovery.")
+	cmd.PersistentFlags().StringVarP(&replicaBucketName, "replica-bucket", "", "", "Name of the replica bucket created or adopted in --replica-region. Default is 'BUCKET_NAME-replica'.")
+	cmd.PersistentFlags().StringVarP(&replicaKMSKeyID, "replica-kms-key-id", "", "", "ID, ARN or alias of the KMS key in --replica-region which re-encrypts replicas. Needed for the SSE-KMS state bucket.")
+	cmd.PersistentFlags().StringVarP(&replicationRoleName, "replication-role-name", "", "", "Name of the IAM role which S3 assumes for replication. Default is 'tfbackend-replication-BUCKET_NAME'.")
+	cmd.PersistentFlags().BoolVarP(&objectLock, "object-lock", "", false, "Create S3 bucket with Object Lock, which keeps state versions immutable for the retention. Can't be enabled on an existing bucket, nor used with --replica-region.")
+	cmd.PersistentFlags().StringVarP(&objectLockMode, "object-lock-mode", "", "GOVERNANCE", "Mode of the default retention of Object Lock. 'GOVERNANCE' or 'COMPLIANCE'.")
+	cmd.PersistentFlags().Int32VarP(&objectLockRetentionDays, "object-lock-retention-days", "", 0, "Days of the default retention of Object Lock. Needed with --object-lock.")
+	cmd.PersistentFlags().BoolVarP(&useLockfile, "use-lockfile", "", false, "Lock state with .tflock object in S3 bucket instead of DynamoDB table. Needs Terraform 1.10 or later.")
+	cmd.PersistentFlags().BoolVarP(&noBucketPolicy, "no-bucket-policy", "", false, "Don't attach the bucket policy which denies requests without TLS.")
+	cmd.PersistentFlags().BoolVarP(&denyUnencryptedUploads, "deny-unencrypted-uploads", "", false, "Deny PutObject without the server-side encryption header of the default encryption.")
+	cmd.PersistentFlags().BoolVarP(&denyIncorrectKMSKey, "deny-incorrect-kms-key", "", false, "Deny PutObject with other KMS key than the one of default encryption. Needs --kms-key-id or --create-kms-key.")
+	cmd.PersistentFlags().StringArrayVarP(&allowedPrincipals, "allowed-principal", "", nil, "ARN of IAM principal allowed to access the bucket. Requests from the others are denied. Can be specified multiple times. The caller is always allowed.")
+	cmd.PersistentFlags().StringVarP(&principalOrgID, "principal-org-id", "", "", "Deny requests from principals outside the AWS Organizations organization, e.g. o-xxxxxxxxxx.")
+	cmd.PersistentFlags().StringArrayVarP(&tagFlags, "tag", "", nil, "Tag in key=value form added to every resource. Can be specified multiple times.")
+	cmd.PersistentFlags().StringVarP(&kmsKeyID, "kms-key-id", "", "", "ID, ARN or alias of the customer managed KMS key for default encryption of S3 bucket. If specified, SSE-KMS is used instead of SSE-S3.")
+	cmd.PersistentFlags().BoolVarP(&newKMSKey, "create-kms-key", "", false, "Create a new customer managed KMS key dedicated to S3 bucket and use it for default encryption.")
+	cmd.PersistentFlags().StringVarP(&kmsKeyAlias, "kms-key-alias", "", "", "Alias of the KMS key created by --create-kms-key. Default is 'alias/tfbackend/<BUCKET_NAME>'.")
+	cmd.Flags().BoolVarP(&noRollback, "no-rollback", "", false, "Don't rollback resources created or updated before a failure. Useful for debugging.")
+	cmd.PersistentFlags().StringVarP(&backendKey, "backend-key", "", "terraform.tfstate", "Template of the state key in generated backend configuration. {{.Env}}, {{.Component}} and {{.Bucket}} are available.")
+	cmd.PersistentFlags().StringVarP(&backendEnv, "env", "", "", "Value of {{.Env}} in --backend-key template.")
+	cmd.PersistentFlags().StringVarP(&backendComponent, "component", "", "", "Value of {{.Component}} in --backend-key template.")
+	cmd.Flags().StringVarP(&emitBackend, "emit-backend", "", "", "Write terraform backend \"s3\" block of the created backend to the file, e.g. backend.tf. '.hcl' extension writes partial configuration.")
+	cmd.Flags().BoolVarP(&overwriteBackend, "overwrite", "", false, "Overwrite the file given by --emit-backend if it exists.")
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "Output format of the result. One of 'table', 'json' or 'yaml'. With 'json' or 'yaml', step progress is written to stderr.")
+	cmd.PersistentFlags().StringVarP(&endpointURL, "endpoint-url", "", "", "Send all API requests to the endpoint instead of AWS, e.g. http://localhost:9000 for MinIO.")
+	cmd.PersistentFlags().BoolVarP(&forcePathStyle, "force-path-style", "", false, "Use path-style addressing (http://host/bucket) for S3. Most S3-compatible object stores need it.")
+	cmd.PersistentFlags().BoolVarP(&s3Compatible, "s3-compatible", "", false, "Skip AWS-only steps such as block public access, for S3-compatible object stores like MinIO or Ceph RGW.")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "", false, "Show the changes to apply without calling any API which creates or updates resources. Same as 'tfbackend aws plan'.")
+
+	// subcommand
+	cmd.AddCommand(NewCmdAwsPlan())
+	cmd.AddCommand(NewCmdAwsDestroy())
+	cmd.AddCommand(NewCmdAwsBackendConfig())
+	cmd.AddCommand(NewCmdAwsVerify())
+	cmd.AddCommand(NewCmdAwsMigrateLockfile())
+	cmd.AddCommand(NewCmdAwsValidateName())
 
 	return cmd
 }
@@ -118,240 +569,1848 @@ func initBuildInDefault() {
 }
 
 func runCmdAws(cmd *cobra.Command, args []string) error {
+	if dryRun {
+		return runCmdAwsPlan(cmd, args)
+	}
+
 	// Validation
-	if !validateBucketName(bucketName) {
-		return fmt.Errorf("bucket name contains capital letter: %v", bucketName)
+	if err := validateAwsFlags(); err != nil {
+		return err
+	}
+	if !validateOutputFormat(outputFormat) {
+		return fmt.Errorf("output format must be 'table', 'json' or 'yaml': %v", outputFormat)
+	}
+	tags, err := newAwsTags()
+	if err != nil {
+		return err
+	}
+	if emitBackend != "" {
+		// Fail before touching AWS if the key template is broken.
+		if _, err := renderBackendKey(backendKey, backendKeyVars{Env: backendEnv, Component: backendComponent, Bucket: bucketName}); err != nil {
+			return err
+		}
+		if err := checkBackendConfigWritable(emitBackend, overwriteBackend); err != nil {
+			return err
+		}
 	}
 
 	// Load config
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	cfg, err := loadAwsConfig()
 	if err != nil {
 		return fmt.Errorf("configuration error: %w", err)
 	}
+	if replicaRegion != "" && replicaRegion == cfg.Region {
+		return fmt.Errorf("--replica-region must be different from the region of the state bucket: %v", replicaRegion)
+	}
+
+	// Keep stdout clean for the machine-readable document.
+	if outputFormat != "table" {
+		progressOut = os.Stderr
+	}
+	report := &awsReport{}
+
+	// Every completed step records its undo action, so that a failure can rollback the backend.
+	tx := &transaction{}
+
+	// Prepare KMS key and bucket policy.
+	s3Opt := initS3Option{S3Compatible: s3Compatible, Tags: tags, Lifecycle: newLifecycleOption(), Lockfile: useLockfile, ObjectLock: newObjectLockOption()}
+	if kmsKeyID != "" {
+		arn, err := resolveKMSKeyArn(kms.NewFromConfig(cfg), kmsKeyID)
+		if err != nil {
+			return fmt.Errorf("failed to describe kms key: %w", err)
+		}
+		s3Opt.KMSKeyID = arn
+	}
+	if s3Opt.BucketPolicy, err = newBucketPolicyOption(sts.NewFromConfig(cfg)); err != nil {
+		return err
+	}
+	if newKMSKey {
+		alias := kmsKeyAlias
+		if alias == "" {
+			alias = defaultKMSKeyAlias(bucketName)
+		}
+
+		kmsClient := kms.NewFromConfig(cfg)
+		stsClient := sts.NewFromConfig(cfg)
+		kmsRes, err := initKMS(kmsClient, stsClient, alias, cfg.Region, tags, tx)
+		if err != nil {
+			return abortTransaction(tx, fmt.Errorf("failed to initialize kms key: %w", err))
+		}
+		s3Opt.KMSKeyID = kmsRes.KeyArn
+		report.KMS = kmsRes
+
+		printCyan(fmt.Sprintf("Successfully create kms key for terraform backend: %v\n", alias))
+		if outputFormat == "table" {
+			fmt.Fprintf(progressOut, "Detail ... \n\n")
+			kTable := tablewriter.NewWriter(os.Stdout)
+			h, b := kmsRes.createTableInput()
+			kTable.SetHeader(h)
+			for _, v := range b {
+				kTable.Append(v)
+			}
+			kTable.SetAlignment(tablewriter.ALIGN_LEFT)
+			kTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			kTable.SetCenterSeparator("|")
+			kTable.Render()
+		}
+	}
+
+	// Initialize log bucket.
+	s3 := newS3Client(cfg)
+	if logBucketName != "" {
+		identity, err := getCallerIdentity(context.TODO(), sts.NewFromConfig(cfg))
+		if err != nil {
+			return abortTransaction(tx, fmt.Errorf("failed to get caller identity: %w", err))
+		}
+		logRes, err := initLogBucket(s3, logBucketName, cfg.Region, aws.ToString(identity.Account), tags, tx)
+		if err != nil {
+			return abortTransaction(tx, fmt.Errorf("failed to initialize log bucket: %w", err))
+		}
+		s3Opt.AccessLogging = newAccessLogging(logBucketName, accessLogPrefix, bucketName)
+		report.LogBucket = logRes
+
+		printCyan(fmt.Sprintf("Successfully create log bucket: %v\n", logBucketName))
+		if outputFormat == "table" {
+			fmt.Fprintf(progressOut, "Detail ... \n\n")
+			lTable := tablewriter.NewWriter(os.Stdout)
+			h, b := logRes.createTableInput()
+			lTable.SetHeader(h)
+			for _, v := range b {
+				lTable.Append(v)
+			}
+			lTable.SetAlignment(tablewriter.ALIGN_LEFT)
+			lTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			lTable.SetCenterSeparator("|")
+			lTable.Render()
+		}
+	}
+
+	// Initialize replication role and replica bucket.
+	if replicaRegion != "" {
+		replicaCfg := cfg.Copy()
+		replicaCfg.Region = replicaRegion
+		replication := replicationOption{ReplicaBucket: newReplicaBucketName(), ReplicaRegion: replicaRegion}
+		if replicaKMSKeyID != "" {
+			arn, err := resolveKMSKeyArn(kms.NewFromConfig(replicaCfg), replicaKMSKeyID)
+			if err != nil {
+				return abortTransaction(tx, fmt.Errorf("failed to describe kms key: %w", err))
+			}
+			replication.ReplicaKMSKeyID = arn
+		}
+
+		roleName := newReplicationRoleName()
+		rolePolicy, err := renderReplicationRolePolicy(bucketName, replication.ReplicaBucket, cfg.Region, s3Opt.KMSKeyID, replication.ReplicaKMSKeyID)
+		if err != nil {
+			return abortTransaction(tx, err)
+		}
+		roleRes, err := initReplicationRole(iam.NewFromConfig(cfg), roleName, rolePolicy, tags, tx)
+		if err != nil {
+			return abortTransaction(tx, fmt.Errorf("failed to initialize iam role for replication: %w", err))
+		}
+		replication.RoleArn = roleRes.RoleArn
+		allowReplicationRole(s3Opt.BucketPolicy, roleRes.RoleArn)
+		report.ReplicationRole = roleRes
+
+		printCyan(fmt.Sprintf("Successfully create iam role for replication: %v\n", roleName))
+		if outputFormat == "table" {
+			fmt.Fprintf(progressOut, "Detail ... \n\n")
+			rTable := tablewriter.NewWriter(os.Stdout)
+			h, b := roleRes.createTableInput()
+			rTable.SetHeader(h)
+			for _, v := range b {
+				rTable.Append(v)
+			}
+			rTable.SetAlignment(tablewriter.ALIGN_LEFT)
+			rTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			rTable.SetCenterSeparator("|")
+			rTable.Render()
+		}
+
+		replicaOpt := replicaBucketOption(replication.ReplicaKMSKeyID, tags, s3Opt.BucketPolicy, s3Opt.Lifecycle)
+		replicaRes, err := initReplicaBucket(newS3Client(replicaCfg), replication.ReplicaBucket, replicaRegion, replicaOpt, tx)
+		if err != nil {
+			return abortTransaction(tx, fmt.Errorf("failed to initialize replica bucket: %w", err))
+		}
+		s3Opt.Replication = &replication
+		report.Replica = replicaRes
+
+		printCyan(fmt.Sprintf("Successfully create replica bucket: %v\n", replication.ReplicaBucket))
+		if outputFormat == "table" {
+			fmt.Fprintf(progressOut, "Detail ... \n\n")
+			pTable := tablewriter.NewWriter(os.Stdout)
+			h, b := replicaRes.createTableInput()
+			pTable.SetHeader(h)
+			for _, v := range b {
+				pTable.Append(v)
+			}
+			pTable.SetAlignment(tablewriter.ALIGN_LEFT)
+			pTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			pTable.SetCenterSeparator("|")
+			pTable.Render()
+		}
+	}
 
 	// Initialize S3 bucket.
-	s3 := s3.NewFromConfig(cfg)
-	s3Res, err := initS3(s3, bucketName, cfg.Region)
+	s3Res, err := initS3(s3, bucketName, cfg.Region, s3Opt, tx)
 	if err != nil {
-		return fmt.Errorf("failed to initialize s3 bucket: %w", err)
+		return abortTransaction(tx, fmt.Errorf("failed to initialize s3 bucket: %w", err))
 	}
+	report.S3 = s3Res
 	printCyan(fmt.Sprintf("Successfully create terraform backend - s3 bucket: %v\n", bucketName))
-	fmt.Printf("Detail ... \n\n")
-	sTable := tablewriter.NewWriter(os.Stdout)
-	h, b := s3Res.createTableInput()
-	sTable.SetHeader(h)
-	for _, v := range b {
-		sTable.Append(v)
-	}
-	sTable.SetAlignment(tablewriter.ALIGN_LEFT)
-	sTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-	sTable.SetCenterSeparator("|")
-	sTable.Render()
+	if outputFormat == "table" {
+		fmt.Fprintf(progressOut, "Detail ... \n\n")
+		sTable := tablewriter.NewWriter(os.Stdout)
+		h, b := s3Res.createTableInput()
+		sTable.SetHeader(h)
+		for _, v := range b {
+			sTable.Append(v)
+		}
+		sTable.SetAlignment(tablewriter.ALIGN_LEFT)
+		sTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+		sTable.SetCenterSeparator("|")
+		sTable.Render()
+	}
+
+	// Configure CloudTrail trail.
+	if cloudTrailName != "" {
+		trailRes, err := initCloudTrail(cloudtrail.NewFromConfig(cfg), cloudTrailName, bucketName, cfg.Region, logBucketName, tags, tx)
+		if err != nil {
+			return abortTransaction(tx, fmt.Errorf("failed to configure cloudtrail trail: %w", err))
+		}
+		report.CloudTrail = trailRes
+
+		printCyan(fmt.Sprintf("Successfully configure cloudtrail trail: %v\n", cloudTrailName))
+		if outputFormat == "table" {
+			fmt.Fprintf(progressOut, "Detail ... \n\n")
+			cTable := tablewriter.NewWriter(os.Stdout)
+			h, b := trailRes.createTableInput()
+			cTable.SetHeader(h)
+			for _, v := range b {
+				cTable.Append(v)
+			}
+			cTable.SetAlignment(tablewriter.ALIGN_LEFT)
+			cTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			cTable.SetCenterSeparator("|")
+			cTable.Render()
+		}
+	}
 
 	// Initialize DynamoDB table.
+	var dynamoRes *initDynamoDBResult
 	if tableName != "" {
+		dynamoOpt := newInitDynamoDBOption()
+		dynamoOpt.Tags = tags
+		if tableKMSKeyID != "" {
+			arn, err := resolveKMSKeyArn(kms.NewFromConfig(cfg), tableKMSKeyID)
+			if err != nil {
+				return abortTransaction(tx, fmt.Errorf("failed to describe kms key: %w", err))
+			}
+			dynamoOpt.KMSKeyID = arn
+		}
+
 		dynamodb := dynamodb.NewFromConfig(cfg)
-		dynamoRes, err := initDynamoDB(dynamodb, tableName, billingMode)
+		dynamoRes, err = initDynamoDB(dynamodb, tableName, billingMode, dynamoOpt, tx)
 		if err != nil {
-			return fmt.Errorf("failed to initialize dynamodb table: %w", err)
+			return abortTransaction(tx, fmt.Errorf("failed to initialize dynamodb table: %w", err))
+		}
+		if dynamoOpt.AutoScaling != nil {
+			autoScalingClient := applicationautoscaling.NewFromConfig(cfg)
+			if err := initDynamoDBAutoScaling(autoScalingClient, tableName, *dynamoOpt.AutoScaling, dynamoRes, tx); err != nil {
+				return abortTransaction(tx, fmt.Errorf("failed to initialize auto scaling of dynamodb table: %w", err))
+			}
 		}
+		report.DynamoDB = dynamoRes
 
 		printCyan(fmt.Sprintf("Successfully create terraform lock table - dynamodb table: %v\n", tableName))
-		fmt.Printf("Detail ... \n\n")
-		dTable := tablewriter.NewWriter(os.Stdout)
-		h, b := dynamoRes.createTableInput()
-		dTable.SetHeader(h)
-		for _, v := range b {
-			dTable.Append(v)
+		if outputFormat == "table" {
+			fmt.Fprintf(progressOut, "Detail ... \n\n")
+			dTable := tablewriter.NewWriter(os.Stdout)
+			h, b := dynamoRes.createTableInput()
+			dTable.SetHeader(h)
+			for _, v := range b {
+				dTable.Append(v)
+			}
+			dTable.SetAlignment(tablewriter.ALIGN_LEFT)
+			dTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+			dTable.SetCenterSeparator("|")
+			dTable.Render()
+		}
+	}
+
+	// Generate backend configuration.
+	if emitBackend != "" {
+		backend, err := newBackendConfig(s3Res, dynamoRes, backendKey, backendKeyVars{Env: backendEnv, Component: backendComponent, Bucket: bucketName})
+		if err != nil {
+			return err
+		}
+		backend.Endpoint, backend.ForcePathStyle = endpointURL, forcePathStyle
+		backend.UseLockfile = useLockfile
+		if err := writeBackendConfig(emitBackend, backend, overwriteBackend); err != nil {
+			return err
+		}
+		printCyan(fmt.Sprintf("Successfully write terraform backend configuration: %v\n", emitBackend))
+	} else if useLockfile {
+		printCyan(lockfileGuidance)
+	}
+
+	// Write machine-readable document.
+	if outputFormat != "table" {
+		report.Steps = progress.steps
+		if err := writeReport(os.Stdout, report, outputFormat); err != nil {
+			return fmt.Errorf("failed to write %v output: %w", outputFormat, err)
 		}
-		dTable.SetAlignment(tablewriter.ALIGN_LEFT)
-		dTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-		dTable.SetCenterSeparator("|")
-		dTable.Render()
 	}
 
 	return nil
 }
 
-// initS3 setup terraform backend with messages.
-func initS3(c S3Clientable, bucketName string, region string) (*initS3Result, error) {
-	fmt.Printf("\n")
-	fmt.Printf("---------------------------------------------------------\n")
-	fmt.Printf("🚀 Start to create terraform backend: s3 bucket ... \n")
-	fmt.Printf("---------------------------------------------------------\n")
-	fmt.Printf("\n")
-
-	// Create bucket
-	fmt.Printf("Step1: Creating bucket ... ")
-	if _, err := createS3Bucket(context.TODO(), c, bucketName, region); err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("failed to create s3 bucket: %w", err)
+// validateAwsFlags validates flags shared by aws command and its subcommands.
+func validateAwsFlags() error {
+	if err := validateAwsNames(); err != nil {
+		return err
 	}
-	fmt.Printf("SUCCESS\n")
 
-	// Activate block all public access
-	fmt.Printf("Step2: Activate block public access ... ")
-	if _, err := enableAllPublicAccessBlock(context.TODO(), c, bucketName); err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("failed to activate block public access of s3 bucket: %w", err)
+	if kmsKeyID != "" && newKMSKey {
+		return fmt.Errorf("--kms-key-id and --create-kms-key cannot be specified at the same time")
 	}
-	fmt.Printf("SUCCESS\n")
-
-	// Activate default encryption
-	fmt.Printf("Step3: Activate default encryption (AES256) ... ")
-	if _, err := enableBucketEncryptionAES256(context.TODO(), c, bucketName); err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("failed to activate default encryption of s3 bucket: %w", err)
+	if tableName == "" && (pointInTimeRecovery || deletionProtection || tableKMSKeyID != "") {
+		return fmt.Errorf("--point-in-time-recovery, --deletion-protection and --dynamodb-kms-key-id need --dynamodb")
+	}
+	if readCapacity < 1 || writeCapacity < 1 {
+		return fmt.Errorf("--read-capacity and --write-capacity must be 1 or more")
+	}
+	if autoScaling {
+		if tableName == "" || billingMode != string(types.BillingModeProvisioned) {
+			return fmt.Errorf("--autoscaling needs --dynamodb with PROVISIONED billing mode")
+		}
+		if autoScalingMin < 1 || autoScalingMax < autoScalingMin {
+			return fmt.Errorf("auto scaling capacity must satisfy 1 <= min <= max: min=%v, max=%v", autoScalingMin, autoScalingMax)
+		}
+		if autoScalingTarget < 20 || autoScalingTarget > 90 {
+			return fmt.Errorf("--autoscaling-target-utilization must be between 20 and 90: %v", autoScalingTarget)
+		}
+	}
+	if s3Compatible && (kmsKeyID != "" || newKMSKey) {
+		return fmt.Errorf("--kms-key-id and --create-kms-key cannot be specified with --s3-compatible")
+	}
+	if err := validateLifecycleFlags(); err != nil {
+		return err
+	}
+	if err := validateLoggingFlags(); err != nil {
+		return err
+	}
+	if err := validateReplicationFlags(); err != nil {
+		return err
+	}
+	if err := validateObjectLockFlags(); err != nil {
+		return err
 	}
-	fmt.Printf("SUCCESS\n")
+	if useLockfile && tableName != "" {
+		return fmt.Errorf("--use-lockfile and --dynamodb cannot be specified at the same time. Use 'tfbackend aws migrate-lockfile' to switch the lock table over")
+	}
+	return validateBucketPolicyFlags()
+}
 
-	// Activate versioning
-	fmt.Printf("Step4: Activate bucket versioning ... ")
-	if _, err := enableBucketVersioning(context.TODO(), c, bucketName); err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("failed to activate versioning: %w", err)
+// newBucketPolicyOption builds the bucket policy from the flags, or returns nil if --no-bucket-policy is specified.
+// If principals are restricted, the caller is added to them so that tfbackend doesn't lock itself out.
+func newBucketPolicyOption(s STSGetCallerIdentityAPI) (*bucketPolicyOption, error) {
+	if noBucketPolicy {
+		return nil, nil
+	}
+	opt := &bucketPolicyOption{
+		DenyUnencryptedUploads: denyUnencryptedUploads,
+		DenyIncorrectKMSKey:    denyIncorrectKMSKey,
+		PrincipalOrgID:         principalOrgID,
+	}
+	if len(allowedPrincipals) > 0 {
+		identity, err := getCallerIdentity(context.TODO(), s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get caller identity: %w", err)
+		}
+		caller, err := principalArnPattern(*identity.Arn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse caller arn: %w", err)
+		}
+		opt.AllowedPrincipals = append([]string{caller}, allowedPrincipals...)
 	}
-	fmt.Printf("SUCCESS\n")
+	return opt, nil
+}
 
-	// Describe bucket
-	res := initS3Result{
-		BucketName: bucketName,
+// newInitDynamoDBOption builds the table settings from the flags, except the KMS key which needs to be resolved.
+func newInitDynamoDBOption() initDynamoDBOption {
+	opt := initDynamoDBOption{
+		PointInTimeRecovery: pointInTimeRecovery,
+		DeletionProtection:  deletionProtection,
+		Capacity:            tableCapacity{Read: readCapacity, Write: writeCapacity},
+	}
+	if autoScaling {
+		opt.AutoScaling = &autoScalingOption{
+			MinCapacity:       autoScalingMin,
+			MaxCapacity:       autoScalingMax,
+			TargetUtilization: autoScalingTarget,
+		}
 	}
+	return opt
+}
 
-	fmt.Printf("Step5: Confirmation - Get bucket location ... ")
-	locationRes, err := getBucketLocation(context.TODO(), c, bucketName)
+// initKMS creates customer managed key dedicated to terraform backend with messages.
+func initKMS(c KMSClientable, s STSGetCallerIdentityAPI, aliasName string, region string, tags map[string]string, tx *transaction) (*initKMSResult, error) {
+	progress.section("kms_key", "🚀 Start to create kms key for terraform backend ...")
+
+	// Get account
+	progress.begin("Get caller identity")
+	identity, err := getCallerIdentity(context.TODO(), s)
 	if err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+		progress.fail()
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
 	}
-	res.Region = string(locationRes.LocationConstraint)
-	fmt.Printf("SUCCESS\n")
+	callerArn, err := arn.Parse(*identity.Arn)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to parse caller arn: %w", err)
+	}
+	progress.end(stepStatusSuccess)
 
-	fmt.Printf("Step6: Confirmation - Get block public access status ... ")
-	blockRes, err := getPublicAccessBlock(context.TODO(), c, bucketName)
+	// Create key
+	progress.begin("Creating key")
+	policy, err := buildKMSKeyPolicy(callerArn.Partition, *identity.Account, region)
 	if err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+		progress.fail()
+		return nil, fmt.Errorf("failed to build key policy: %w", err)
 	}
-	if blockRes.PublicAccessBlockConfiguration.BlockPublicAcls &&
-		blockRes.PublicAccessBlockConfiguration.BlockPublicPolicy &&
-		blockRes.PublicAccessBlockConfiguration.IgnorePublicAcls &&
-		blockRes.PublicAccessBlockConfiguration.RestrictPublicBuckets {
-		res.BlockPublicAccess = "Enabled"
-	} else if !blockRes.PublicAccessBlockConfiguration.BlockPublicAcls &&
-		!blockRes.PublicAccessBlockConfiguration.BlockPublicPolicy &&
-		!blockRes.PublicAccessBlockConfiguration.IgnorePublicAcls &&
-		!blockRes.PublicAccessBlockConfiguration.RestrictPublicBuckets {
-		res.BlockPublicAccess = "Not fully enabled"
-	}
-	fmt.Printf("SUCCESS\n")
-
-	fmt.Printf("Step7: Confirmation - Get bucket encryption status ... ")
-	encryptionRes, err := getBucketEncryption(context.TODO(), c, bucketName)
+	keyRes, err := createKMSKey(context.TODO(), c, "Encryption key for terraform backend", policy, tags)
 	if err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+		progress.fail()
+		return nil, fmt.Errorf("failed to create kms key: %w", err)
 	}
-	if encryptionRes.ServerSideEncryptionConfiguration.Rules[0].ApplyServerSideEncryptionByDefault != nil {
-		res.Encryption = string(encryptionRes.ServerSideEncryptionConfiguration.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+	progress.end(stepStatusSuccess)
+
+	res := initKMSResult{
+		KeyID:  *keyRes.KeyMetadata.KeyId,
+		KeyArn: *keyRes.KeyMetadata.Arn,
 	}
-	fmt.Printf("SUCCESS\n")
+	tx.record(fmt.Sprintf("Schedule deletion of kms key %v", res.KeyID), func() error {
+		_, err := scheduleKMSKeyDeletion(context.TODO(), c, res.KeyID)
+		return err
+	})
 
-	fmt.Printf("Step8: Confirmation - Get bucket versioning status ... ")
-	versioningRes, err := getBucketVersioning(context.TODO(), c, bucketName)
-	if err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+	// Activate key rotation
+	progress.begin("Activate automatic key rotation")
+	if _, err := enableKMSKeyRotation(context.TODO(), c, res.KeyID); err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to activate key rotation: %w", err)
 	}
-	res.Versioning = string(versioningRes.Status)
-	fmt.Printf("SUCCESS\n")
+	res.KeyRotation = "Enabled"
+	progress.end(stepStatusSuccess)
+
+	// Create alias
+	progress.begin("Creating alias")
+	if _, err := createKMSAlias(context.TODO(), c, aliasName, res.KeyID); err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to create alias of kms key: %w", err)
+	}
+	tx.record(fmt.Sprintf("Delete kms alias %v", aliasName), func() error {
+		_, err := deleteKMSAlias(context.TODO(), c, aliasName)
+		return err
+	})
+	res.Alias = aliasName
+	progress.end(stepStatusSuccess)
 
 	return &res, nil
 }
 
-// initDynamoDB setup terraform lock table with messages.
-func initDynamoDB(c DynamoDBClientable, tableName string, billingMode string) (*initDynamoDBResult, error) {
-	fmt.Printf("\n")
-	fmt.Printf("---------------------------------------------------------\n")
-	fmt.Printf("🚀 Start to create terraform lock table: DynamoDB ... \n")
-	fmt.Printf("---------------------------------------------------------\n")
-	fmt.Printf("\n")
+// initS3 setup terraform backend with messages.
+func initS3(c S3Clientable, bucketName string, region string, opt initS3Option, tx *transaction) (*initS3Result, error) {
+	progress.section("s3_bucket", "🚀 Start to create terraform backend: s3 bucket ...")
+	return initBucket(c, bucketName, region, opt, tx)
+}
 
-	// Create table
-	fmt.Printf("Step1: Creating table ... ")
-	if _, err := createDynamoDBTable(context.TODO(), c, tableName, billingMode); err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("failed to create dynamodb table: %w", err)
+// initBucket creates or adopts the bucket and converges its settings, then confirms them.
+// The progress section is started by the caller.
+func initBucket(c S3Clientable, bucketName string, region string, opt initS3Option, tx *transaction) (*initS3Result, error) {
+	// Create bucket. If the bucket already exists and is owned by us, adopt it.
+	progress.begin("Creating bucket")
+	exists, err := s3BucketExists(context.TODO(), c, bucketName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of s3 bucket: %w", err)
+	}
+	if exists {
+		progress.end(stepStatusUnchanged)
+	} else {
+		create := createS3Bucket
+		if opt.S3Compatible {
+			create = createS3CompatibleBucket
+		}
+		if _, err := create(context.TODO(), c, bucketName, region, opt.ObjectLock != nil); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create s3 bucket: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete s3 bucket %v", bucketName), func() error {
+			_, err := deleteS3Bucket(context.TODO(), c, bucketName)
+			return err
+		})
+		progress.end(stepStatusCreated)
 	}
-	fmt.Printf("SUCCESS\n")
 
-	// Describe table
-	fmt.Printf("Step2: Confirmation - Describe table ... ")
-	desc, err := describeDynamoDBTable(context.TODO(), c, tableName)
-	if err != nil {
-		printRed("FAILURE\n\n")
-		return nil, fmt.Errorf("successfully created dynamodb table, but failed to describe dynamodb table: %w", err)
+	// Activate block all public access
+	// Block public access is AWS-only, so it is never called on S3-compatible object stores.
+	progress.begin("Activate block public access")
+	status := stepStatusSkipped
+	if !opt.S3Compatible {
+		status, err = ensurePublicAccessBlock(c, bucketName, exists, tx)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to activate block public access of s3 bucket: %w", err)
+		}
 	}
-	fmt.Printf("SUCCESS\n")
+	progress.end(status)
 
-	res := initDynamoDBResult{}
-	if desc.Table.TableName != nil {
-		res.TableName = *desc.Table.TableName
+	// Disable ACLs
+	// Ownership controls are AWS-only too, so they are never called on S3-compatible object stores.
+	progress.begin("Disable ACLs (bucket owner enforced)")
+	status = stepStatusSkipped
+	if !opt.S3Compatible {
+		status, err = ensureBucketOwnershipControls(c, bucketName, exists, tx)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to disable acls of s3 bucket: %w", err)
+		}
 	}
+	progress.end(status)
 
-	if desc.Table.BillingModeSummary != nil {
-		res.BillingMode = string(desc.Table.BillingModeSummary.BillingMode)
-	} else if desc.Table.ProvisionedThroughput != nil {
-		res.BillingMode = "PROVISIONED"
+	// Activate default encryption
+	if opt.KMSKeyID != "" {
+		progress.begin("Activate default encryption (SSE-KMS)")
+	} else {
+		progress.begin("Activate default encryption (AES256)")
+	}
+	status, err = ensureBucketEncryption(c, bucketName, opt.KMSKeyID, exists, tx)
+	if err != nil && opt.S3Compatible && isNotImplemented(err) {
+		status, err = stepStatusSkipped, nil
+	}
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to activate default encryption of s3 bucket: %w", err)
 	}
+	progress.end(status)
+	encryptionStatus := status
 
-	if desc.Table.ProvisionedThroughput != nil {
-		res.WriteCapacity = strconv.FormatInt(*desc.Table.ProvisionedThroughput.WriteCapacityUnits, 10)
-		res.ReadCapacity = strconv.FormatInt(*desc.Table.ProvisionedThroughput.ReadCapacityUnits, 10)
+	// Activate versioning
+	progress.begin("Activate bucket versioning")
+	status, err = ensureBucketVersioning(c, bucketName, exists, tx)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to activate versioning: %w", err)
 	}
+	progress.end(status)
 
-	return &res, nil
-}
+	// Configure object lock retention
+	if opt.ObjectLock != nil {
+		progress.begin("Configure object lock retention")
+		status, err := ensureObjectLockRetention(c, bucketName, *opt.ObjectLock, exists, tx)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to configure object lock retention: %w", err)
+		}
+		progress.end(status)
+	}
 
-func (i *initS3Result) createTableInput() (header []string, body [][]string) {
-	h := []string{"PARAMETER", "VALUE"}
-	b := [][]string{
-		{"Bucket name", i.BucketName},
-		{"Region", i.Region},
-		{"Block Public Access", i.BlockPublicAccess},
-		{"Encryption", i.Encryption},
-		{"Versioning", i.Versioning},
+	// Configure lifecycle
+	var lifecycleStatus stepStatus
+	if opt.Lifecycle.enabled() {
+		progress.begin("Configure lifecycle rule")
+		lifecycleStatus, err = ensureBucketLifecycle(c, bucketName, buildLifecycleRule(opt.Lifecycle), exists, tx)
+		if err != nil && opt.S3Compatible && isNotImplemented(err) {
+			lifecycleStatus, err = stepStatusSkipped, nil
+		}
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to configure lifecycle rule: %w", err)
+		}
+		progress.end(lifecycleStatus)
 	}
-	return h, b
-}
 
-func (i *initDynamoDBResult) createTableInput() (header []string, body [][]string) {
-	h := []string{"PARAMETER", "VALUE"}
-	if i.BillingMode == "PAY_PER_REQUEST" {
-		b := [][]string{
-			{"Table name", i.TableName},
-			{"Billing mode", i.BillingMode},
+	// Configure replication
+	if opt.Replication != nil {
+		progress.begin("Configure replication")
+		status, err := ensureBucketReplication(c, bucketName, *opt.Replication, exists, tx)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to configure replication: %w", err)
 		}
-		return h, b
-	} else {
-		b := [][]string{
-			{"Table name", i.TableName},
-			{"Billing mode", i.BillingMode},
-			{"Write capacity", i.WriteCapacity},
-			{"Read capacity", i.ReadCapacity},
+		progress.end(status)
+	}
+
+	// Attach bucket policy
+	var policy string
+	var policyStatus stepStatus
+	if opt.BucketPolicy != nil {
+		progress.begin("Attach bucket policy")
+		policy, err = renderBucketPolicy(s3BucketArn(bucketName, region), opt.KMSKeyID, *opt.BucketPolicy)
+		if err != nil {
+			progress.fail()
+			return nil, err
+		}
+		policyStatus, err = ensureBucketPolicy(c, bucketName, policy, exists, tx)
+		if err != nil && opt.S3Compatible && isNotImplemented(err) {
+			policyStatus, err = stepStatusSkipped, nil
 		}
-		return h, b
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to attach bucket policy: %w", err)
+		}
+		progress.end(policyStatus)
 	}
-}
 
-// validateBucketName checks if bucket name contains capitals.
-func validateBucketName(b string) bool {
-	for _, r := range b {
-		if !unicode.IsLower(r) && unicode.IsLetter(r) {
-			return false
+	// Enable server access logging
+	if opt.AccessLogging != nil {
+		progress.begin("Enable server access logging")
+		status, err := ensureBucketLogging(c, bucketName, opt.AccessLogging, exists, tx)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to enable server access logging: %w", err)
 		}
+		progress.end(status)
 	}
-	return true
+
+	// Apply tags
+	var tagsStatus stepStatus
+	if len(opt.Tags) > 0 {
+		progress.begin("Apply tags")
+		tagsStatus, err = ensureBucketTags(c, bucketName, opt.Tags, exists, tx)
+		if err != nil && opt.S3Compatible && isNotImplemented(err) {
+			tagsStatus, err = stepStatusSkipped, nil
+		}
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to apply tags of s3 bucket: %w", err)
+		}
+		progress.end(tagsStatus)
+	}
+
+	// Describe bucket
+	res := initS3Result{
+		BucketName: bucketName,
+	}
+
+	progress.begin("Confirmation - Get bucket location")
+	locationRes, err := getBucketLocation(context.TODO(), c, bucketName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+	}
+	res.Region = string(locationRes.LocationConstraint)
+	res.BucketArn = s3BucketArn(bucketName, bucketRegion(res.Region))
+	progress.end(stepStatusSuccess)
+
+	progress.begin("Confirmation - Get block public access status")
+	if opt.S3Compatible {
+		res.BlockPublicAccess = "Skipped"
+		progress.end(stepStatusSkipped)
+	} else {
+		blockRes, err := getPublicAccessBlock(context.TODO(), c, bucketName)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+		}
+		res.BlockPublicAccess = publicAccessBlockStatus(blockRes.PublicAccessBlockConfiguration)
+		progress.end(stepStatusSuccess)
+	}
+
+	progress.begin("Confirmation - Get ownership controls")
+	if opt.S3Compatible {
+		res.ObjectOwnership = "Skipped"
+		progress.end(stepStatusSkipped)
+	} else {
+		ownership, err := getBucketOwnershipControls(context.TODO(), c, bucketName)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+		}
+		if !isBucketOwnerEnforced(ownership) {
+			progress.fail()
+			return nil, fmt.Errorf("acls of s3 bucket are not disabled: %v", objectOwnershipStatus(ownership))
+		}
+		res.ObjectOwnership = objectOwnershipStatus(ownership)
+		progress.end(stepStatusSuccess)
+	}
+
+	progress.begin("Confirmation - Get bucket encryption status")
+	if encryptionStatus == stepStatusSkipped {
+		res.Encryption = "Skipped"
+		progress.end(stepStatusSkipped)
+	} else {
+		encryptionRes, err := getBucketEncryption(context.TODO(), c, bucketName)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+		}
+		if rules := encryptionRes.ServerSideEncryptionConfiguration.Rules; len(rules) > 0 && rules[0].ApplyServerSideEncryptionByDefault != nil {
+			rule := rules[0]
+			res.Encryption = string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+			if rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm == s3types.ServerSideEncryptionAwsKms {
+				if rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID != nil {
+					res.KMSKeyID = *rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID
+				}
+				if rule.BucketKeyEnabled {
+					res.BucketKey = "Enabled"
+				} else {
+					res.BucketKey = "Disabled"
+				}
+			}
+		}
+		progress.end(stepStatusSuccess)
+	}
+
+	progress.begin("Confirmation - Get bucket versioning status")
+	versioningRes, err := getBucketVersioning(context.TODO(), c, bucketName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+	}
+	res.Versioning = string(versioningRes.Status)
+	progress.end(stepStatusSuccess)
+
+	if opt.ObjectLock != nil {
+		progress.begin("Confirmation - Get object lock configuration")
+		lockCfg, err := getObjectLockConfiguration(context.TODO(), c, bucketName)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+		}
+		if objectLockSummary(lockCfg) != objectLockSummary(buildObjectLockConfiguration(*opt.ObjectLock)) {
+			progress.fail()
+			return nil, fmt.Errorf("object lock retention of s3 bucket is not applied: %v", objectLockSummary(lockCfg))
+		}
+		res.ObjectLock = objectLockSummary(lockCfg)
+		progress.end(stepStatusSuccess)
+	}
+
+	if opt.Lifecycle.enabled() {
+		progress.begin("Confirmation - Get bucket lifecycle configuration")
+		if lifecycleStatus == stepStatusSkipped {
+			res.Lifecycle = "Skipped"
+			progress.end(stepStatusSkipped)
+		} else {
+			rules, err := getBucketLifecycleRules(context.TODO(), c, bucketName)
+			if err != nil {
+				progress.fail()
+				return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+			}
+			rule := findLifecycleRule(rules)
+			if rule == nil || lifecycleSummary(*rule) != lifecycleSummary(buildLifecycleRule(opt.Lifecycle)) {
+				progress.fail()
+				return nil, fmt.Errorf("lifecycle rule of s3 bucket is not applied: %v", lifecycleRuleID)
+			}
+			res.Lifecycle = lifecycleSummary(*rule)
+			progress.end(stepStatusSuccess)
+		}
+	}
+
+	if opt.Replication != nil {
+		progress.begin("Confirmation - Get bucket replication")
+		replication, err := getBucketReplication(context.TODO(), c, bucketName)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+		}
+		if !isReplicationApplied(replication, *opt.Replication) {
+			progress.fail()
+			return nil, fmt.Errorf("replication of s3 bucket is not configured: %v", replicationRuleID)
+		}
+		res.Replication = replicationSummary(*findReplicationRule(replication))
+		progress.end(stepStatusSuccess)
+	}
+
+	if opt.BucketPolicy != nil {
+		progress.begin("Confirmation - Get bucket policy")
+		if policyStatus == stepStatusSkipped {
+			res.BucketPolicy = "Skipped"
+			progress.end(stepStatusSkipped)
+		} else {
+			policyRes, err := getBucketPolicy(context.TODO(), c, bucketName)
+			if err != nil {
+				progress.fail()
+				return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+			}
+			current := aws.ToString(policyRes.Policy)
+			applied, err := hasManagedBucketPolicy(current, policy)
+			if err != nil {
+				progress.fail()
+				return nil, fmt.Errorf("successfully put bucket policy, but failed to parse it: %w", err)
+			}
+			if !applied {
+				progress.fail()
+				return nil, fmt.Errorf("bucket policy of s3 bucket is not applied. The statements managed by tfbackend are not found")
+			}
+			if !isTLSOnlyBucketPolicy(current) {
+				progress.fail()
+				return nil, fmt.Errorf("bucket policy of s3 bucket is not applied. The statement denying requests without TLS is not found")
+			}
+			if res.BucketPolicy, err = bucketPolicyStatementNames(current); err != nil {
+				progress.fail()
+				return nil, err
+			}
+			progress.end(stepStatusSuccess)
+		}
+	}
+
+	if opt.AccessLogging != nil {
+		progress.begin("Confirmation - Get bucket logging")
+		loggingRes, err := getBucketLogging(context.TODO(), c, bucketName)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+		}
+		if !sameLoggingTarget(loggingRes.LoggingEnabled, opt.AccessLogging) {
+			progress.fail()
+			return nil, fmt.Errorf("server access logging of s3 bucket is not enabled: %v", formatLoggingTarget(opt.AccessLogging))
+		}
+		res.AccessLogging = formatLoggingTarget(loggingRes.LoggingEnabled)
+		progress.end(stepStatusSuccess)
+	}
+
+	if len(opt.Tags) > 0 {
+		progress.begin("Confirmation - Get bucket tagging")
+		if tagsStatus == stepStatusSkipped {
+			progress.end(stepStatusSkipped)
+		} else {
+			tags, err := getBucketTags(context.TODO(), c, bucketName)
+			if err != nil {
+				progress.fail()
+				return nil, fmt.Errorf("successfully created s3 bucket, but failed to describe s3 bucket: %w", err)
+			}
+			if missing := missingTags(tags, opt.Tags); len(missing) > 0 {
+				progress.fail()
+				return nil, fmt.Errorf("tags of s3 bucket are not applied: %v", formatTags(missing))
+			}
+			res.Tags = tags
+			progress.end(stepStatusSuccess)
+		}
+	}
+
+	if opt.Lockfile {
+		progress.begin("Confirmation - Check conditional writes")
+		encryption, keyID := objectEncryption(&res)
+		if err := checkConditionalWrites(c, bucketName, encryption, keyID, opt.ObjectLock != nil); err != nil {
+			progress.fail()
+			return nil, err
+		}
+		res.Lockfile = "Supported"
+		progress.end(stepStatusSuccess)
+	}
+
+	return &res, nil
+}
+
+// initDynamoDB setup terraform lock table with messages.
+func initDynamoDB(c DynamoDBClientable, tableName string, billingMode string, opt initDynamoDBOption, tx *transaction) (*initDynamoDBResult, error) {
+	progress.section("dynamodb_table", "🚀 Start to create terraform lock table: DynamoDB ...")
+
+	capacity := opt.Capacity
+	if capacity == (tableCapacity{}) {
+		capacity = defaultTableCapacity
+	}
+
+	// Create table. If the table already exists, adopt it.
+	progress.begin("Creating table")
+	current, err := describeDynamoDBTable(context.TODO(), c, tableName)
+	exists := err == nil
+	if err != nil && !isAPIErrorCode(err, "ResourceNotFoundException") {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of dynamodb table: %w", err)
+	}
+	if exists {
+		progress.end(stepStatusUnchanged)
+	} else {
+		if _, err := createDynamoDBTable(context.TODO(), c, tableName, billingMode, capacity, opt.Tags); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create dynamodb table: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete dynamodb table %v", tableName), func() error {
+			// A table can't be deleted while it is being created.
+			if err := waitDynamoDBTableActive(context.TODO(), c, tableName, 5*time.Minute); err != nil {
+				return err
+			}
+			_, err := deleteDynamoDBTable(context.TODO(), c, tableName)
+			return err
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Check key schema. Key schema of the existing table cannot be changed, so only checks it.
+	progress.begin("Check key schema (LockID)")
+	if exists {
+		if !hasLockIDKeySchema(current.Table) {
+			progress.fail()
+			return nil, fmt.Errorf("existing dynamodb table %v must have only 'LockID' (string) as hash key", tableName)
+		}
+		progress.end(stepStatusUnchanged)
+	} else {
+		progress.end(stepStatusCreated)
+	}
+
+	// Apply billing mode. Capacity of the table with auto scaling is managed by Application Auto Scaling, so it isn't converged.
+	progress.begin("Apply billing mode (%v)", billingMode)
+	if exists {
+		previous, previousCapacity := tableBillingMode(current.Table), provisionedCapacity(current.Table)
+		if previous == billingMode && (billingMode != string(types.BillingModeProvisioned) || opt.AutoScaling != nil || previousCapacity == capacity) {
+			progress.end(stepStatusUnchanged)
+		} else {
+			if _, err := updateDynamoDBTableBillingMode(context.TODO(), c, tableName, billingMode, capacity); err != nil {
+				progress.fail()
+				return nil, fmt.Errorf("failed to update billing mode of dynamodb table: %w", err)
+			}
+			tx.record(fmt.Sprintf("Restore billing mode of dynamodb table %v to %v", tableName, previous), func() error {
+				if err := waitDynamoDBTableActive(context.TODO(), c, tableName, 5*time.Minute); err != nil {
+					return err
+				}
+				_, err := updateDynamoDBTableBillingMode(context.TODO(), c, tableName, previous, previousCapacity)
+				return err
+			})
+			progress.end(stepStatusUpdated)
+		}
+	} else {
+		progress.end(stepStatusCreated)
+	}
+
+	// The existing table is nil if the table has just been created.
+	var existing *types.TableDescription
+	if exists {
+		existing = current.Table
+	}
+
+	// Activate point-in-time recovery
+	if opt.PointInTimeRecovery {
+		progress.begin("Activate point-in-time recovery")
+		status, err := ensureDynamoDBPointInTimeRecovery(c, tableName, exists, tx)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to activate point-in-time recovery of dynamodb table: %w", err)
+		}
+		progress.end(status)
+	}
+
+	// Activate encryption with customer managed key
+	if opt.KMSKeyID != "" {
+		progress.begin("Activate encryption (SSE-KMS)")
+		status, err := ensureDynamoDBTableEncryption(c, tableName, opt.KMSKeyID, existing, tx)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to activate encryption of dynamodb table: %w", err)
+		}
+		progress.end(status)
+	}
+
+	// Apply tags. Tags of a new table are applied at creation.
+	if len(opt.Tags) > 0 {
+		progress.begin("Apply tags")
+		if exists {
+			status, err := ensureDynamoDBTableTags(c, current.Table, opt.Tags, tx)
+			if err != nil {
+				progress.fail()
+				return nil, fmt.Errorf("failed to apply tags of dynamodb table: %w", err)
+			}
+			progress.end(status)
+		} else {
+			progress.end(stepStatusCreated)
+		}
+	}
+
+	// Activate deletion protection. This is the last step, so that rollback disables it before deleting the table.
+	if opt.DeletionProtection {
+		progress.begin("Activate deletion protection")
+		status, err := ensureDynamoDBDeletionProtection(c, tableName, existing, tx)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to activate deletion protection of dynamodb table: %w", err)
+		}
+		progress.end(status)
+	}
+
+	// Describe table
+	progress.begin("Confirmation - Describe table")
+	desc, err := describeDynamoDBTable(context.TODO(), c, tableName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created dynamodb table, but failed to describe dynamodb table: %w", err)
+	}
+	progress.end(stepStatusSuccess)
+
+	progress.begin("Confirmation - Describe continuous backups")
+	backupsRes, err := describeDynamoDBContinuousBackups(context.TODO(), c, tableName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created dynamodb table, but failed to describe continuous backups: %w", err)
+	}
+	progress.end(stepStatusSuccess)
+
+	res := initDynamoDBResult{}
+	if len(opt.Tags) > 0 {
+		progress.begin("Confirmation - List tags of table")
+		if desc.Table.TableArn == nil {
+			progress.fail()
+			return nil, fmt.Errorf("successfully created dynamodb table, but its ARN is unknown")
+		}
+		tags, err := listDynamoDBTableTags(context.TODO(), c, *desc.Table.TableArn)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("successfully created dynamodb table, but failed to list tags: %w", err)
+		}
+		if missing := missingTags(tags, opt.Tags); len(missing) > 0 {
+			progress.fail()
+			return nil, fmt.Errorf("tags of dynamodb table are not applied: %v", formatTags(missing))
+		}
+		res.Tags = tags
+		progress.end(stepStatusSuccess)
+	}
+
+	if desc.Table.TableName != nil {
+		res.TableName = *desc.Table.TableName
+	}
+	if desc.Table.TableArn != nil {
+		res.TableArn = *desc.Table.TableArn
+	}
+
+	res.BillingMode = tableBillingMode(desc.Table)
+
+	if desc.Table.ProvisionedThroughput != nil {
+		res.WriteCapacity = strconv.FormatInt(*desc.Table.ProvisionedThroughput.WriteCapacityUnits, 10)
+		res.ReadCapacity = strconv.FormatInt(*desc.Table.ProvisionedThroughput.ReadCapacityUnits, 10)
+	}
+
+	res.PointInTimeRecovery = enabledOrDisabled(isPointInTimeRecoveryEnabled(backupsRes.ContinuousBackupsDescription))
+	res.DeletionProtection = enabledOrDisabled(desc.Table.DeletionProtectionEnabled != nil && *desc.Table.DeletionProtectionEnabled)
+	res.Encryption = "AWS owned key"
+	if res.KMSKeyID = tableKMSKeyArn(desc.Table); res.KMSKeyID != "" {
+		res.Encryption = string(types.SSETypeKms)
+	}
+
+	return &res, nil
+}
+
+// initDynamoDBAutoScaling registers read and write capacity of the table to Application Auto Scaling with messages.
+// The steps continue the section of the table, and the confirmed bounds are set to res.
+func initDynamoDBAutoScaling(c AutoScalingClientable, tableName string, opt autoScalingOption, res *initDynamoDBResult, tx *transaction) error {
+	dimensions := []struct {
+		name      string
+		dimension autoscalingtypes.ScalableDimension
+	}{
+		{name: "read", dimension: autoscalingtypes.ScalableDimensionDynamoDBTableReadCapacityUnits},
+		{name: "write", dimension: autoscalingtypes.ScalableDimensionDynamoDBTableWriteCapacityUnits},
+	}
+
+	for _, d := range dimensions {
+		progress.begin("Register scalable target (%v capacity, %v-%v)", d.name, opt.MinCapacity, opt.MaxCapacity)
+		status, err := ensureDynamoDBScalableTarget(c, tableName, d.dimension, opt, tx)
+		if err != nil {
+			progress.fail()
+			return fmt.Errorf("failed to register %v capacity to auto scaling: %w", d.name, err)
+		}
+		progress.end(status)
+
+		progress.begin("Put target tracking policy (%v capacity, %v%%)", d.name, opt.TargetUtilization)
+		status, err = ensureDynamoDBTargetTrackingPolicy(c, tableName, d.dimension, dynamoDBScalingPolicyName(tableName, d.name), opt.TargetUtilization, tx)
+		if err != nil {
+			progress.fail()
+			return fmt.Errorf("failed to put scaling policy of %v capacity: %w", d.name, err)
+		}
+		progress.end(status)
+	}
+
+	progress.begin("Confirmation - Describe scalable targets")
+	for _, d := range dimensions {
+		target, err := describeDynamoDBScalableTarget(context.TODO(), c, tableName, d.dimension)
+		if err != nil {
+			progress.fail()
+			return fmt.Errorf("successfully registered auto scaling, but failed to describe scalable target of %v capacity: %w", d.name, err)
+		}
+		if target == nil || target.MinCapacity == nil || target.MaxCapacity == nil {
+			progress.fail()
+			return fmt.Errorf("successfully registered auto scaling, but scalable target of %v capacity is not found", d.name)
+		}
+		res.AutoScalingMinCapacity = strconv.Itoa(int(*target.MinCapacity))
+		res.AutoScalingMaxCapacity = strconv.Itoa(int(*target.MaxCapacity))
+	}
+	res.AutoScalingTargetUtilization = formatTargetUtilization(opt.TargetUtilization)
+	progress.end(stepStatusSuccess)
+
+	return nil
+}
+
+// ensureDynamoDBScalableTarget registers the dimension with the bounds.
+// For an already registered dimension, the previous bounds are restored on rollback.
+func ensureDynamoDBScalableTarget(c AutoScalingClientable, tableName string, dimension autoscalingtypes.ScalableDimension, opt autoScalingOption, tx *transaction) (stepStatus, error) {
+	current, err := describeDynamoDBScalableTarget(context.TODO(), c, tableName, dimension)
+	if err != nil {
+		return "", err
+	}
+	if current != nil && current.MinCapacity != nil && current.MaxCapacity != nil &&
+		*current.MinCapacity == opt.MinCapacity && *current.MaxCapacity == opt.MaxCapacity {
+		return stepStatusUnchanged, nil
+	}
+
+	if _, err := registerDynamoDBScalableTarget(context.TODO(), c, tableName, dimension, opt.MinCapacity, opt.MaxCapacity); err != nil {
+		return "", err
+	}
+	if current == nil {
+		tx.record(fmt.Sprintf("Deregister scalable target %v of dynamodb table %v", dimension, tableName), func() error {
+			_, err := deregisterDynamoDBScalableTarget(context.TODO(), c, tableName, dimension)
+			return err
+		})
+	} else if current.MinCapacity != nil && current.MaxCapacity != nil {
+		previousMin, previousMax := *current.MinCapacity, *current.MaxCapacity
+		tx.record(fmt.Sprintf("Restore bounds of scalable target %v of dynamodb table %v", dimension, tableName), func() error {
+			_, err := registerDynamoDBScalableTarget(context.TODO(), c, tableName, dimension, previousMin, previousMax)
+			return err
+		})
+	}
+	return appliedStatus(current != nil), nil
+}
+
+// ensureDynamoDBTargetTrackingPolicy puts target tracking policy of the dimension.
+// For an existing policy, the previous target value is restored on rollback.
+func ensureDynamoDBTargetTrackingPolicy(c AutoScalingClientable, tableName string, dimension autoscalingtypes.ScalableDimension, policyName string, targetValue float64, tx *transaction) (stepStatus, error) {
+	current, err := describeDynamoDBScalingPolicy(context.TODO(), c, tableName, dimension, policyName)
+	if err != nil {
+		return "", err
+	}
+	var previous *float64
+	if current != nil && current.TargetTrackingScalingPolicyConfiguration != nil {
+		previous = current.TargetTrackingScalingPolicyConfiguration.TargetValue
+	}
+	if previous != nil && *previous == targetValue {
+		return stepStatusUnchanged, nil
+	}
+
+	if _, err := putDynamoDBTargetTrackingPolicy(context.TODO(), c, tableName, dimension, policyName, targetValue); err != nil {
+		return "", err
+	}
+	if current == nil {
+		tx.record(fmt.Sprintf("Delete scaling policy %v", policyName), func() error {
+			_, err := deleteDynamoDBScalingPolicy(context.TODO(), c, tableName, dimension, policyName)
+			return err
+		})
+	} else if previous != nil {
+		previousValue := *previous
+		tx.record(fmt.Sprintf("Restore target value of scaling policy %v", policyName), func() error {
+			_, err := putDynamoDBTargetTrackingPolicy(context.TODO(), c, tableName, dimension, policyName, previousValue)
+			return err
+		})
+	}
+	return appliedStatus(current != nil), nil
+}
+
+// formatTargetUtilization formats the target value of target tracking policy, e.g. 70%.
+func formatTargetUtilization(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64) + "%"
+}
+
+// dynamoDBScalingPolicyName returns the name of target tracking policy, e.g. tfbackend-my-table-read.
+func dynamoDBScalingPolicyName(tableName string, dimension string) string {
+	return fmt.Sprintf("tfbackend-%v-%v", tableName, dimension)
+}
+
+// ensureDynamoDBPointInTimeRecovery enables point-in-time recovery of the table.
+// For an adopted table, it is applied only if disabled, and disabled again on rollback.
+func ensureDynamoDBPointInTimeRecovery(c DynamoDBClientable, tableName string, adopted bool, tx *transaction) (stepStatus, error) {
+	if adopted {
+		cur, err := describeDynamoDBContinuousBackups(context.TODO(), c, tableName)
+		if err != nil {
+			return "", err
+		}
+		if isPointInTimeRecoveryEnabled(cur.ContinuousBackupsDescription) {
+			return stepStatusUnchanged, nil
+		}
+	}
+
+	if err := waitDynamoDBTableActive(context.TODO(), c, tableName, 5*time.Minute); err != nil {
+		return "", err
+	}
+	// Continuous backups of a new table become available a little after the table becomes ACTIVE.
+	for i := 0; ; i++ {
+		_, err := updateDynamoDBPointInTimeRecovery(context.TODO(), c, tableName, true)
+		if err == nil {
+			break
+		}
+		if !isAPIErrorCode(err, "ContinuousBackupsUnavailableException") || i >= 12 {
+			return "", err
+		}
+		time.Sleep(5 * time.Second)
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Disable point-in-time recovery of dynamodb table %v", tableName), func() error {
+			_, err := updateDynamoDBPointInTimeRecovery(context.TODO(), c, tableName, false)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensureDynamoDBTableEncryption encrypts the table with the customer managed key.
+// existing is nil for a new table. For an existing table, the previous key is restored on rollback.
+func ensureDynamoDBTableEncryption(c DynamoDBClientable, tableName string, kmsKeyID string, existing *types.TableDescription, tx *transaction) (stepStatus, error) {
+	previous := tableKMSKeyArn(existing)
+	if existing != nil && previous == kmsKeyID {
+		return stepStatusUnchanged, nil
+	}
+
+	if err := waitDynamoDBTableActive(context.TODO(), c, tableName, 5*time.Minute); err != nil {
+		return "", err
+	}
+	if _, err := updateDynamoDBTableEncryption(context.TODO(), c, tableName, kmsKeyID); err != nil {
+		return "", err
+	}
+	if existing != nil {
+		tx.record(fmt.Sprintf("Restore encryption of dynamodb table %v", tableName), func() error {
+			if err := waitDynamoDBTableActive(context.TODO(), c, tableName, 5*time.Minute); err != nil {
+				return err
+			}
+			_, err := updateDynamoDBTableEncryption(context.TODO(), c, tableName, previous)
+			return err
+		})
+	}
+	return appliedStatus(existing != nil), nil
+}
+
+// ensureDynamoDBDeletionProtection enables deletion protection of the table. existing is nil for a new table.
+// The rollback is recorded even for a new table, because the table can't be deleted while it is protected.
+func ensureDynamoDBDeletionProtection(c DynamoDBClientable, tableName string, existing *types.TableDescription, tx *transaction) (stepStatus, error) {
+	if existing != nil && existing.DeletionProtectionEnabled != nil && *existing.DeletionProtectionEnabled {
+		return stepStatusUnchanged, nil
+	}
+
+	if err := waitDynamoDBTableActive(context.TODO(), c, tableName, 5*time.Minute); err != nil {
+		return "", err
+	}
+	if _, err := updateDynamoDBTableDeletionProtection(context.TODO(), c, tableName, true); err != nil {
+		return "", err
+	}
+	tx.record(fmt.Sprintf("Disable deletion protection of dynamodb table %v", tableName), func() error {
+		if err := waitDynamoDBTableActive(context.TODO(), c, tableName, 5*time.Minute); err != nil {
+			return err
+		}
+		_, err := updateDynamoDBTableDeletionProtection(context.TODO(), c, tableName, false)
+		return err
+	})
+	return appliedStatus(existing != nil), nil
+}
+
+// ensureDynamoDBTableTags adds the tags which the existing table doesn't have.
+// On rollback, the added tags are removed and overwritten ones are restored.
+func ensureDynamoDBTableTags(c DynamoDBClientable, existing *types.TableDescription, tags map[string]string, tx *transaction) (stepStatus, error) {
+	if existing.TableArn == nil {
+		return "", fmt.Errorf("ARN of dynamodb table is unknown")
+	}
+	tableArn := *existing.TableArn
+
+	previous, err := listDynamoDBTableTags(context.TODO(), c, tableArn)
+	if err != nil {
+		return "", err
+	}
+	missing := missingTags(previous, tags)
+	if len(missing) == 0 {
+		return stepStatusUnchanged, nil
+	}
+
+	if _, err := tagDynamoDBTable(context.TODO(), c, tableArn, missing); err != nil {
+		return "", err
+	}
+	added, overwritten := []string{}, map[string]string{}
+	for k := range missing {
+		if v, ok := previous[k]; ok {
+			overwritten[k] = v
+		} else {
+			added = append(added, k)
+		}
+	}
+	tx.record(fmt.Sprintf("Restore tags of dynamodb table %v", tableArn), func() error {
+		if len(added) > 0 {
+			if _, err := untagDynamoDBTable(context.TODO(), c, tableArn, added); err != nil {
+				return err
+			}
+		}
+		if len(overwritten) > 0 {
+			if _, err := tagDynamoDBTable(context.TODO(), c, tableArn, overwritten); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return stepStatusUpdated, nil
+}
+
+// ensureBucketLifecycle puts the lifecycle rule managed by tfbackend. PutBucketLifecycleConfiguration replaces
+// the whole configuration, so the other rules of an adopted bucket are kept, and the rules are restored on rollback.
+func ensureBucketLifecycle(c S3Clientable, bucketName string, rule s3types.LifecycleRule, adopted bool, tx *transaction) (stepStatus, error) {
+	var previous []s3types.LifecycleRule
+	if adopted {
+		cur, err := getBucketLifecycleRules(context.TODO(), c, bucketName)
+		if err != nil {
+			return "", err
+		}
+		if r := findLifecycleRule(cur); r != nil && lifecycleSummary(*r) == lifecycleSummary(rule) {
+			return stepStatusUnchanged, nil
+		}
+		previous = cur
+	}
+
+	if _, err := putBucketLifecycleRules(context.TODO(), c, bucketName, mergeLifecycleRules(previous, rule)); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore lifecycle rules of s3 bucket %v", bucketName), func() error {
+			if len(previous) == 0 {
+				_, err := deleteBucketLifecycle(context.TODO(), c, bucketName)
+				return err
+			}
+			_, err := putBucketLifecycleRules(context.TODO(), c, bucketName, previous)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensureBucketReplication puts the replication rule managed by tfbackend. PutBucketReplication replaces
+// the whole configuration, so the other rules of an adopted bucket are kept, and the configuration is restored on rollback.
+func ensureBucketReplication(c S3Clientable, bucketName string, opt replicationOption, adopted bool, tx *transaction) (stepStatus, error) {
+	var previous *s3types.ReplicationConfiguration
+	if adopted {
+		cur, err := getBucketReplication(context.TODO(), c, bucketName)
+		if err != nil {
+			return "", err
+		}
+		if isReplicationApplied(cur, opt) {
+			return stepStatusUnchanged, nil
+		}
+		previous = cur
+	}
+
+	merged, err := mergeReplicationRules(previous, opt)
+	if err != nil {
+		return "", err
+	}
+	if _, err := putBucketReplication(context.TODO(), c, bucketName, merged); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore replication of s3 bucket %v", bucketName), func() error {
+			if previous == nil {
+				_, err := deleteBucketReplication(context.TODO(), c, bucketName)
+				return err
+			}
+			_, err := putBucketReplication(context.TODO(), c, bucketName, previous)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensureBucketLogging enables server access logging of the bucket into the target.
+// For an adopted bucket, the previous logging status is restored on rollback.
+func ensureBucketLogging(c S3Clientable, bucketName string, target *s3types.LoggingEnabled, adopted bool, tx *transaction) (stepStatus, error) {
+	var previous *s3types.LoggingEnabled
+	if adopted {
+		cur, err := getBucketLogging(context.TODO(), c, bucketName)
+		if err != nil {
+			return "", err
+		}
+		if sameLoggingTarget(cur.LoggingEnabled, target) {
+			return stepStatusUnchanged, nil
+		}
+		previous = cur.LoggingEnabled
+	}
+
+	if _, err := putBucketLogging(context.TODO(), c, bucketName, target); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore server access logging of s3 bucket %v", bucketName), func() error {
+			_, err := putBucketLogging(context.TODO(), c, bucketName, previous)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensureBucketPolicy attaches the managed statements of the policy to the bucket.
+// For an adopted bucket, the other statements of the current policy are kept, and the current policy is restored on rollback.
+func ensureBucketPolicy(c S3Clientable, bucketName string, policy string, adopted bool, tx *transaction) (stepStatus, error) {
+	previous := ""
+	if adopted {
+		cur, err := getBucketPolicy(context.TODO(), c, bucketName)
+		if err != nil && !isAPIErrorCode(err, "NoSuchBucketPolicy") {
+			return "", err
+		}
+		if err == nil {
+			previous = aws.ToString(cur.Policy)
+		}
+		applied, err := hasManagedBucketPolicy(previous, policy)
+		if err != nil {
+			return "", err
+		}
+		if applied {
+			return stepStatusUnchanged, nil
+		}
+	}
+
+	merged, err := mergeBucketPolicy(previous, policy)
+	if err != nil {
+		return "", err
+	}
+	if _, err := putBucketPolicy(context.TODO(), c, bucketName, merged); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore bucket policy of s3 bucket %v", bucketName), func() error {
+			if previous == "" {
+				_, err := deleteBucketPolicy(context.TODO(), c, bucketName)
+				return err
+			}
+			_, err := putBucketPolicy(context.TODO(), c, bucketName, previous)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensureBucketTags adds the tags to the bucket. PutBucketTagging replaces the whole tag set,
+// so the tags of an adopted bucket are kept, and restored on rollback.
+func ensureBucketTags(c S3Clientable, bucketName string, tags map[string]string, adopted bool, tx *transaction) (stepStatus, error) {
+	previous := map[string]string{}
+	if adopted {
+		cur, err := getBucketTags(context.TODO(), c, bucketName)
+		if err != nil {
+			return "", err
+		}
+		if len(missingTags(cur, tags)) == 0 {
+			return stepStatusUnchanged, nil
+		}
+		previous = cur
+	}
+
+	if _, err := putBucketTags(context.TODO(), c, bucketName, mergeTags(previous, tags)); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore tags of s3 bucket %v", bucketName), func() error {
+			if len(previous) == 0 {
+				_, err := deleteBucketTags(context.TODO(), c, bucketName)
+				return err
+			}
+			_, err := putBucketTags(context.TODO(), c, bucketName, previous)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensurePublicAccessBlock blocks all public access of the bucket.
+// For an adopted bucket, the setting is applied only if the current one differs,
+// and the previous setting is recorded to tx so that it can be restored.
+func ensurePublicAccessBlock(c S3Clientable, bucketName string, adopted bool, tx *transaction) (stepStatus, error) {
+	var previous *s3types.PublicAccessBlockConfiguration
+	if adopted {
+		cur, err := getPublicAccessBlock(context.TODO(), c, bucketName)
+		if err != nil && !isAPIErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+			return "", err
+		}
+		if err == nil {
+			if isAllPublicAccessBlocked(cur.PublicAccessBlockConfiguration) {
+				return stepStatusUnchanged, nil
+			}
+			previous = cur.PublicAccessBlockConfiguration
+		}
+	}
+
+	if _, err := enableAllPublicAccessBlock(context.TODO(), c, bucketName); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore block public access of s3 bucket %v", bucketName), func() error {
+			if previous == nil {
+				_, err := deletePublicAccessBlock(context.TODO(), c, bucketName)
+				return err
+			}
+			_, err := putPublicAccessBlock(context.TODO(), c, bucketName, previous)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensureBucketOwnershipControls sets BucketOwnerEnforced, which disables ACLs. The new bucket is created with it,
+// but it is put again for the bucket created in the account whose default still allows ACLs.
+func ensureBucketOwnershipControls(c S3Clientable, bucketName string, adopted bool, tx *transaction) (stepStatus, error) {
+	var previous *s3types.OwnershipControls
+	if adopted {
+		cur, err := getBucketOwnershipControls(context.TODO(), c, bucketName)
+		if err != nil {
+			return "", err
+		}
+		if isBucketOwnerEnforced(cur) {
+			return stepStatusUnchanged, nil
+		}
+		previous = cur
+	}
+
+	if _, err := putBucketOwnershipControls(context.TODO(), c, bucketName, s3types.ObjectOwnershipBucketOwnerEnforced); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore ownership controls of s3 bucket %v", bucketName), func() error {
+			if previous == nil || len(previous.Rules) == 0 {
+				_, err := deleteBucketOwnershipControls(context.TODO(), c, bucketName)
+				return err
+			}
+			_, err := putBucketOwnershipControls(context.TODO(), c, bucketName, previous.Rules[0].ObjectOwnership)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensureBucketEncryption activates default encryption of the bucket. SSE-KMS is used if kmsKeyID is specified.
+// For an adopted bucket, the setting is applied only if the current one differs.
+func ensureBucketEncryption(c S3Clientable, bucketName string, kmsKeyID string, adopted bool, tx *transaction) (stepStatus, error) {
+	var previous *s3types.ServerSideEncryptionConfiguration
+	if adopted {
+		cur, err := getBucketEncryption(context.TODO(), c, bucketName)
+		if err != nil && !isAPIErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+			return "", err
+		}
+		if err == nil {
+			if isDesiredBucketEncryption(cur.ServerSideEncryptionConfiguration, kmsKeyID) {
+				return stepStatusUnchanged, nil
+			}
+			previous = cur.ServerSideEncryptionConfiguration
+		}
+	}
+
+	if kmsKeyID != "" {
+		if _, err := enableBucketEncryptionKMS(context.TODO(), c, bucketName, kmsKeyID); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := enableBucketEncryptionAES256(context.TODO(), c, bucketName); err != nil {
+			return "", err
+		}
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore default encryption of s3 bucket %v", bucketName), func() error {
+			if previous == nil {
+				_, err := deleteBucketEncryption(context.TODO(), c, bucketName)
+				return err
+			}
+			_, err := putBucketEncryption(context.TODO(), c, bucketName, previous)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensureBucketVersioning activates versioning of the bucket.
+// For an adopted bucket, the setting is applied only if the current one differs.
+// Versioning can't be turned off once enabled, so it is restored to Suspended if it has never been enabled.
+func ensureBucketVersioning(c S3Clientable, bucketName string, adopted bool, tx *transaction) (stepStatus, error) {
+	previous := s3types.BucketVersioningStatusSuspended
+	if adopted {
+		cur, err := getBucketVersioning(context.TODO(), c, bucketName)
+		if err != nil {
+			return "", err
+		}
+		if cur.Status == s3types.BucketVersioningStatusEnabled {
+			return stepStatusUnchanged, nil
+		}
+		if cur.Status != "" {
+			previous = cur.Status
+		}
+	}
+
+	if _, err := enableBucketVersioning(context.TODO(), c, bucketName); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore versioning of s3 bucket %v", bucketName), func() error {
+			_, err := putBucketVersioning(context.TODO(), c, bucketName, previous)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// ensureObjectLockRetention applies the default retention. Object Lock itself can be enabled only when the bucket
+// is created, so the adopted bucket without Object Lock is an error.
+func ensureObjectLockRetention(c S3Clientable, bucketName string, opt objectLockOption, adopted bool, tx *transaction) (stepStatus, error) {
+	current, err := getObjectLockConfiguration(context.TODO(), c, bucketName)
+	if err != nil {
+		return "", err
+	}
+	if !isObjectLockConfigurationEnabled(current) {
+		return "", fmt.Errorf("object lock of s3 bucket %v is not enabled. Object Lock can be enabled only when the bucket is created", bucketName)
+	}
+
+	desired := buildObjectLockConfiguration(opt)
+	if objectLockSummary(current) == objectLockSummary(desired) {
+		return stepStatusUnchanged, nil
+	}
+	if _, err := putObjectLockConfiguration(context.TODO(), c, bucketName, desired); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore object lock retention of s3 bucket %v", bucketName), func() error {
+			_, err := putObjectLockConfiguration(context.TODO(), c, bucketName, current)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// isAllPublicAccessBlocked checks if all of four block public access settings are enabled.
+func isAllPublicAccessBlocked(cfg *s3types.PublicAccessBlockConfiguration) bool {
+	return cfg != nil &&
+		cfg.BlockPublicAcls &&
+		cfg.BlockPublicPolicy &&
+		cfg.IgnorePublicAcls &&
+		cfg.RestrictPublicBuckets
+}
+
+// isDesiredBucketEncryption checks if the default encryption is SSE-KMS with kmsKeyID and S3 Bucket Keys,
+// or SSE-S3 when kmsKeyID is empty.
+func isDesiredBucketEncryption(cfg *s3types.ServerSideEncryptionConfiguration, kmsKeyID string) bool {
+	if cfg == nil || len(cfg.Rules) == 0 || cfg.Rules[0].ApplyServerSideEncryptionByDefault == nil {
+		return false
+	}
+
+	rule := cfg.Rules[0]
+	if kmsKeyID == "" {
+		return rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm == s3types.ServerSideEncryptionAes256
+	}
+	return rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm == s3types.ServerSideEncryptionAwsKms &&
+		rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID != nil &&
+		*rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID == kmsKeyID &&
+		rule.BucketKeyEnabled
+}
+
+// hasLockIDKeySchema checks if the table has only 'LockID' (string) as hash key, which terraform requires.
+func hasLockIDKeySchema(t *types.TableDescription) bool {
+	if t == nil || len(t.KeySchema) != 1 {
+		return false
+	}
+	if t.KeySchema[0].AttributeName == nil || *t.KeySchema[0].AttributeName != "LockID" || t.KeySchema[0].KeyType != types.KeyTypeHash {
+		return false
+	}
+	for _, a := range t.AttributeDefinitions {
+		if a.AttributeName != nil && *a.AttributeName == "LockID" {
+			return a.AttributeType == types.ScalarAttributeTypeS
+		}
+	}
+	return false
+}
+
+// publicAccessBlockStatus summarizes the four flags of block public access.
+// If only some of them are enabled, the disabled ones are listed.
+func publicAccessBlockStatus(cfg *s3types.PublicAccessBlockConfiguration) string {
+	if cfg == nil {
+		return "Not configured"
+	}
+
+	var disabled []string
+	if !cfg.BlockPublicAcls {
+		disabled = append(disabled, "BlockPublicAcls")
+	}
+	if !cfg.IgnorePublicAcls {
+		disabled = append(disabled, "IgnorePublicAcls")
+	}
+	if !cfg.BlockPublicPolicy {
+		disabled = append(disabled, "BlockPublicPolicy")
+	}
+	if !cfg.RestrictPublicBuckets {
+		disabled = append(disabled, "RestrictPublicBuckets")
+	}
+
+	switch len(disabled) {
+	case 0:
+		return "Enabled"
+	case 4:
+		return "Disabled"
+	}
+	return fmt.Sprintf("Partially enabled (disabled: %v)", strings.Join(disabled, ", "))
+}
+
+// isBucketOwnerEnforced checks if ACLs of the bucket are disabled.
+func isBucketOwnerEnforced(cfg *s3types.OwnershipControls) bool {
+	return cfg != nil && len(cfg.Rules) > 0 && cfg.Rules[0].ObjectOwnership == s3types.ObjectOwnershipBucketOwnerEnforced
+}
+
+// objectOwnershipStatus returns "ACLs disabled" for BucketOwnerEnforced, otherwise the object ownership.
+func objectOwnershipStatus(cfg *s3types.OwnershipControls) string {
+	if isBucketOwnerEnforced(cfg) {
+		return "ACLs disabled"
+	}
+	if cfg == nil || len(cfg.Rules) == 0 {
+		return "Not configured"
+	}
+	return string(cfg.Rules[0].ObjectOwnership)
+}
+
+// isPointInTimeRecoveryEnabled checks if point-in-time recovery of the table is enabled.
+func isPointInTimeRecoveryEnabled(d *types.ContinuousBackupsDescription) bool {
+	return d != nil && d.PointInTimeRecoveryDescription != nil &&
+		d.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus == types.PointInTimeRecoveryStatusEnabled
+}
+
+// tableKMSKeyArn returns the ARN of KMS key which encrypts the table, or empty if AWS owned key is used.
+func tableKMSKeyArn(t *types.TableDescription) string {
+	if t == nil || t.SSEDescription == nil || t.SSEDescription.SSEType != types.SSETypeKms || t.SSEDescription.KMSMasterKeyArn == nil {
+		return ""
+	}
+	if t.SSEDescription.Status != types.SSEStatusEnabled && t.SSEDescription.Status != types.SSEStatusEnabling && t.SSEDescription.Status != types.SSEStatusUpdating {
+		return ""
+	}
+	return *t.SSEDescription.KMSMasterKeyArn
+}
+
+// provisionedCapacity returns the capacity of PROVISIONED table, or zero value for PAY_PER_REQUEST table.
+func provisionedCapacity(t *types.TableDescription) tableCapacity {
+	if t == nil || t.ProvisionedThroughput == nil || t.ProvisionedThroughput.ReadCapacityUnits == nil || t.ProvisionedThroughput.WriteCapacityUnits == nil {
+		return tableCapacity{}
+	}
+	return tableCapacity{Read: *t.ProvisionedThroughput.ReadCapacityUnits, Write: *t.ProvisionedThroughput.WriteCapacityUnits}
+}
+
+// tableBillingMode returns billing mode of the table.
+// AWS doesn't always return BillingModeSummary for PROVISIONED table.
+func tableBillingMode(t *types.TableDescription) string {
+	if t == nil {
+		return ""
+	}
+	if t.BillingModeSummary != nil {
+		return string(t.BillingModeSummary.BillingMode)
+	}
+	if t.ProvisionedThroughput != nil {
+		return string(types.BillingModeProvisioned)
+	}
+	return ""
+}
+
+func (i *initS3Result) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Bucket name", i.BucketName},
+		{"Region", i.Region},
+		{"Block Public Access", i.BlockPublicAccess},
+		{"Object Ownership", i.ObjectOwnership},
+		{"Encryption", i.Encryption},
+	}
+	if i.Encryption == string(s3types.ServerSideEncryptionAwsKms) {
+		b = append(b, []string{"KMS key", i.KMSKeyID}, []string{"Bucket key", i.BucketKey})
+	}
+	b = append(b, []string{"Versioning", i.Versioning})
+	if i.ObjectLock != "" {
+		b = append(b, []string{"Object Lock", i.ObjectLock})
+	}
+	if i.Lifecycle != "" {
+		b = append(b, []string{"Lifecycle", i.Lifecycle})
+	}
+	if i.Replication != "" {
+		b = append(b, []string{"Replication", i.Replication})
+	}
+	if i.BucketPolicy != "" {
+		b = append(b, []string{"Bucket policy", i.BucketPolicy})
+	}
+	if i.AccessLogging != "" {
+		b = append(b, []string{"Access logging", i.AccessLogging})
+	}
+	if len(i.Tags) > 0 {
+		b = append(b, []string{"Tags", formatTags(i.Tags)})
+	}
+	if i.Lockfile != "" {
+		b = append(b, []string{"Lockfile", i.Lockfile})
+	}
+	return h, b
+}
+
+func (i *initKMSResult) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Key ID", i.KeyID},
+		{"Key ARN", i.KeyArn},
+		{"Alias", i.Alias},
+		{"Key rotation", i.KeyRotation},
+	}
+	return h, b
+}
+
+func (i *initDynamoDBResult) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Table name", i.TableName},
+		{"Billing mode", i.BillingMode},
+	}
+	if i.BillingMode != "PAY_PER_REQUEST" {
+		b = append(b, []string{"Write capacity", i.WriteCapacity}, []string{"Read capacity", i.ReadCapacity})
+	}
+	if i.PointInTimeRecovery != "" {
+		b = append(b, []string{"Point-in-time recovery", i.PointInTimeRecovery})
+	}
+	if i.DeletionProtection != "" {
+		b = append(b, []string{"Deletion protection", i.DeletionProtection})
+	}
+	if i.Encryption != "" {
+		b = append(b, []string{"Encryption", i.Encryption})
+	}
+	if i.KMSKeyID != "" {
+		b = append(b, []string{"KMS key", i.KMSKeyID})
+	}
+	if i.AutoScalingMinCapacity != "" {
+		b = append(b,
+			[]string{"Auto scaling min capacity", i.AutoScalingMinCapacity},
+			[]string{"Auto scaling max capacity", i.AutoScalingMaxCapacity},
+			[]string{"Auto scaling target utilization", i.AutoScalingTargetUtilization},
+		)
+	}
+	if len(i.Tags) > 0 {
+		b = append(b, []string{"Tags", formatTags(i.Tags)})
+	}
+	return h, b
+}
+
+// isAPIErrorCode checks if err is an AWS API error which has one of codes.
+func isAPIErrorCode(err error, codes ...string) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, c := range codes {
+		if apiErr.ErrorCode() == c {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultKMSKeyAlias returns alias name of the kms key dedicated to the bucket.
+// Dots are not allowed in alias names, so they are replaced with hyphens.
+func defaultKMSKeyAlias(bucketName string) string {
+	return "alias/tfbackend/" + strings.ReplaceAll(bucketName, ".", "-")
+}
+
+// regionPartition returns the partition of the region.
+func regionPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	}
+	return "aws"
+}
+
+// s3BucketArn returns ARN of the bucket. S3 bucket ARN has neither region nor account.
+func s3BucketArn(bucketName string, region string) string {
+	return "arn:" + regionPartition(region) + ":s3:::" + bucketName
 }
 
 func validateBillingMode(m string) bool {
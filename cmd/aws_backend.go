@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	emitBackend      string
+	backendOut       string
+	backendKey       string
+	backendEnv       string
+	backendComponent string
+	overwriteBackend bool
+)
+
+// backendKeyVars holds the values which can be used in the key template.
+type backendKeyVars struct {
+	Env       string
+	Component string
+	Bucket    string
+}
+
+// backendConfig holds the arguments of terraform backend "s3" block.
+type backendConfig struct {
+	Bucket        string
+	Key           string
+	Region        string
+	DynamoDBTable string
+	// UseLockfile locks state with .tflock object in the bucket. It needs Terraform 1.10 or later.
+	UseLockfile bool
+	Encrypt     bool
+	KMSKeyID    string
+	// Endpoint is set for S3-compatible object stores. Validations which call AWS STS are skipped.
+	Endpoint       string
+	ForcePathStyle bool
+}
+
+// backendAttribute is a line of backend block. Name is padded to align "=" like terraform fmt.
+type backendAttribute struct {
+	Name  string
+	Value string
+}
+
+const backendTemplate = `terraform {
+  backend "{{.Type}}" {
+{{- range .Attributes}}
+    {{.Name}} = {{.Value}}
+{{- end}}
+  }
+}
+`
+
+const partialBackendTemplate = `{{range .Attributes}}{{.Name}} = {{.Value}}
+{{end}}`
+
+func NewCmdAwsBackendConfig() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backend-config",
+		Short: "Generate terraform backend \"s3\" block of existing S3 bucket and DynamoDB table.",
+		Long: `Generate terraform backend "s3" block of existing S3 bucket and DynamoDB table.
+
+Only read-only APIs are called. If --out ends with .hcl, a partial configuration
+for 'terraform init -backend-config=FILE' is written instead of backend.tf.
+If --out is not specified, the block is printed to stdout.
+
+The key is rendered from --backend-key template, in which {{.Env}}, {{.Component}}
+and {{.Bucket}} are available. e.g. '{{.Env}}/{{.Component}}/terraform.tfstate'
+`,
+		SilenceUsage: true,
+		RunE:         runCmdAwsBackendConfig,
+	}
+
+	// flag
+	cmd.Flags().StringVarP(&backendOut, "out", "", "", "Path of the file to write. '.hcl' extension writes partial configuration. Default is stdout.")
+	cmd.Flags().BoolVarP(&overwriteBackend, "overwrite", "", false, "Overwrite the file given by --out if it exists.")
+
+	return cmd
+}
+
+func runCmdAwsBackendConfig(cmd *cobra.Command, args []string) error {
+	// Validation
+	if err := validateAwsNames(); err != nil {
+		return err
+	}
+
+	if backendOut != "" {
+		if err := checkBackendConfigWritable(backendOut, overwriteBackend); err != nil {
+			return err
+		}
+	}
+
+	// Load config
+	cfg, err := loadAwsConfig()
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	s3Res, err := describeS3Backend(newS3Client(cfg), bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to describe s3 bucket: %w", err)
+	}
+
+	var dynamoRes *initDynamoDBResult
+	if tableName != "" {
+		dynamoRes, err = describeDynamoDBBackend(dynamodb.NewFromConfig(cfg), tableName)
+		if err != nil {
+			return fmt.Errorf("failed to describe dynamodb table: %w", err)
+		}
+	}
+
+	backend, err := newBackendConfig(s3Res, dynamoRes, backendKey, backendKeyVars{Env: backendEnv, Component: backendComponent, Bucket: bucketName})
+	if err != nil {
+		return err
+	}
+	backend.Endpoint, backend.ForcePathStyle = endpointURL, forcePathStyle
+	backend.UseLockfile = useLockfile
+
+	if backendOut == "" {
+		return renderBackendConfig(cmd.OutOrStdout(), backend, false)
+	}
+	if err := writeBackendConfig(backendOut, backend, overwriteBackend); err != nil {
+		return err
+	}
+	printCyan(fmt.Sprintf("Successfully write terraform backend configuration: %v\n", backendOut))
+	return nil
+}
+
+// describeS3Backend reads the region and default encryption of the existing bucket.
+func describeS3Backend(c S3Clientable, bucketName string) (*initS3Result, error) {
+	res := initS3Result{
+		BucketName: bucketName,
+	}
+
+	locationRes, err := getBucketLocation(context.TODO(), c, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket location: %w", err)
+	}
+	res.Region = string(locationRes.LocationConstraint)
+
+	encryptionRes, err := getBucketEncryption(context.TODO(), c, bucketName)
+	if err != nil && !isAPIErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") && !isNotImplemented(err) {
+		return nil, fmt.Errorf("failed to get bucket encryption status: %w", err)
+	}
+	if err == nil && len(encryptionRes.ServerSideEncryptionConfiguration.Rules) > 0 {
+		if rule := encryptionRes.ServerSideEncryptionConfiguration.Rules[0]; rule.ApplyServerSideEncryptionByDefault != nil {
+			res.Encryption = string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+			if rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID != nil {
+				res.KMSKeyID = *rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID
+			}
+		}
+	}
+
+	return &res, nil
+}
+
+// describeDynamoDBBackend checks that the existing table can be used as terraform lock table.
+func describeDynamoDBBackend(c DynamoDBClientable, tableName string) (*initDynamoDBResult, error) {
+	desc, err := describeDynamoDBTable(context.TODO(), c, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if !hasLockIDKeySchema(desc.Table) {
+		return nil, fmt.Errorf("existing dynamodb table %v must have only 'LockID' (string) as hash key", tableName)
+	}
+	return &initDynamoDBResult{
+		TableName:   tableName,
+		BillingMode: tableBillingMode(desc.Table),
+	}, nil
+}
+
+// newBackendConfig builds backend configuration from the applied settings.
+// dynamoRes can be nil when no lock table is used.
+func newBackendConfig(s3Res *initS3Result, dynamoRes *initDynamoDBResult, keyTemplate string, vars backendKeyVars) (*backendConfig, error) {
+	key, err := renderBackendKey(keyTemplate, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := backendConfig{
+		Bucket:  s3Res.BucketName,
+		Key:     key,
+		Region:  bucketRegion(s3Res.Region),
+		Encrypt: s3Res.Encryption != "" && s3Res.Encryption != "Skipped",
+	}
+	if s3Res.Encryption == string(s3types.ServerSideEncryptionAwsKms) {
+		cfg.KMSKeyID = s3Res.KMSKeyID
+	}
+	if dynamoRes != nil {
+		cfg.DynamoDBTable = dynamoRes.TableName
+	}
+	return &cfg, nil
+}
+
+// renderBackendKey renders the key template. Empty path segments are rejected,
+// because they usually mean that --env or --component is missing.
+func renderBackendKey(keyTemplate string, vars backendKeyVars) (string, error) {
+	t, err := template.New("key").Parse(keyTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid backend key template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render backend key template: %w", err)
+	}
+
+	key := buf.String()
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" {
+			return "", fmt.Errorf("backend key %q contains empty path segment. Check --env and --component", key)
+		}
+	}
+	return key, nil
+}
+
+// bucketRegion converts LocationConstraint to region name.
+// Buckets in us-east-1 have empty LocationConstraint, and legacy buckets in eu-west-1 have "EU".
+func bucketRegion(locationConstraint string) string {
+	switch locationConstraint {
+	case "":
+		return "us-east-1"
+	case string(s3types.BucketLocationConstraintEu):
+		return "eu-west-1"
+	}
+	return locationConstraint
+}
+
+// attributes returns the lines of backend block in the order terraform documents them.
+func (b *backendConfig) attributes() []backendAttribute {
+	attrs := []backendAttribute{
+		{Name: "bucket", Value: strconv.Quote(b.Bucket)},
+		{Name: "key", Value: strconv.Quote(b.Key)},
+		{Name: "region", Value: strconv.Quote(b.Region)},
+	}
+	if b.DynamoDBTable != "" {
+		attrs = append(attrs, backendAttribute{Name: "dynamodb_table", Value: strconv.Quote(b.DynamoDBTable)})
+	}
+	if b.UseLockfile {
+		attrs = append(attrs, backendAttribute{Name: "use_lockfile", Value: "true"})
+	}
+	attrs = append(attrs, backendAttribute{Name: "encrypt", Value: strconv.FormatBool(b.Encrypt)})
+	if b.KMSKeyID != "" {
+		attrs = append(attrs, backendAttribute{Name: "kms_key_id", Value: strconv.Quote(b.KMSKeyID)})
+	}
+	// endpoints and use_path_style replace endpoint and force_path_style deprecated in terraform 1.6.
+	if b.Endpoint != "" {
+		attrs = append(attrs,
+			backendAttribute{Name: "endpoints", Value: fmt.Sprintf("{ s3 = %v }", strconv.Quote(b.Endpoint))},
+			backendAttribute{Name: "skip_credentials_validation", Value: "true"},
+			backendAttribute{Name: "skip_region_validation", Value: "true"},
+			backendAttribute{Name: "skip_requesting_account_id", Value: "true"},
+		)
+	}
+	if b.ForcePathStyle {
+		attrs = append(attrs, backendAttribute{Name: "use_path_style", Value: "true"})
+	}
+	return alignBackendAttributes(attrs)
+}
+
+// alignBackendAttributes pads the names to align "=" like terraform fmt.
+func alignBackendAttributes(attrs []backendAttribute) []backendAttribute {
+	width := 0
+	for _, a := range attrs {
+		if len(a.Name) > width {
+			width = len(a.Name)
+		}
+	}
+	for i := range attrs {
+		attrs[i].Name = fmt.Sprintf("%-*s", width, attrs[i].Name)
+	}
+	return attrs
+}
+
+// renderBackendConfig writes backend "s3" block, or partial configuration if partial is true.
+func renderBackendConfig(w io.Writer, b *backendConfig, partial bool) error {
+	return renderBackendBlock(w, "s3", b.attributes(), partial)
+}
+
+// renderBackendBlock writes backend block of the type, or partial configuration if partial is true.
+func renderBackendBlock(w io.Writer, backendType string, attrs []backendAttribute, partial bool) error {
+	text := backendTemplate
+	if partial {
+		text = partialBackendTemplate
+	}
+	t := template.Must(template.New("backend").Parse(text))
+	return t.Execute(w, struct {
+		Type       string
+		Attributes []backendAttribute
+	}{Type: backendType, Attributes: attrs})
+}
+
+// writeBackendConfig writes the configuration to the file. Partial configuration is written if the extension is .hcl.
+// The existing file is overwritten only if overwrite is true.
+func writeBackendConfig(path string, b *backendConfig, overwrite bool) error {
+	var buf bytes.Buffer
+	if err := renderBackendConfig(&buf, b, filepath.Ext(path) == ".hcl"); err != nil {
+		return fmt.Errorf("failed to render backend configuration: %w", err)
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%v already exists. Specify --overwrite to overwrite it", path)
+		}
+		return fmt.Errorf("failed to write backend configuration: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write backend configuration: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write backend configuration: %w", err)
+	}
+	return nil
+}
+
+// checkBackendConfigWritable fails before touching any backend if the file exists and overwrite is false.
+func checkBackendConfigWritable(path string, overwrite bool) error {
+	if overwrite {
+		return nil
+	}
+	_, err := os.Stat(path)
+	if err == nil {
+		return fmt.Errorf("%v already exists. Specify --overwrite to overwrite it", path)
+	}
+	if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to check %v: %w", path, err)
+	}
+	return nil
+}
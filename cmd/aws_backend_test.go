@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_renderBackendKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		keyTemplate string
+		vars        backendKeyVars
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "S01: Fixed key",
+			keyTemplate: "terraform.tfstate",
+			vars:        backendKeyVars{},
+			want:        "terraform.tfstate",
+			wantErr:     false,
+		},
+		{
+			name:        "S02: Env and component",
+			keyTemplate: "{{.Env}}/{{.Component}}/terraform.tfstate",
+			vars:        backendKeyVars{Env: "prod", Component: "network"},
+			want:        "prod/network/terraform.tfstate",
+			wantErr:     false,
+		},
+		{
+			name:        "F01: Missing env",
+			keyTemplate: "{{.Env}}/{{.Component}}/terraform.tfstate",
+			vars:        backendKeyVars{Component: "network"},
+			want:        "",
+			wantErr:     true,
+		},
+		{
+			name:        "F02: Unknown field",
+			keyTemplate: "{{.Stage}}/terraform.tfstate",
+			vars:        backendKeyVars{},
+			want:        "",
+			wantErr:     true,
+		},
+		{
+			name:        "F03: Broken template",
+			keyTemplate: "{{.Env}/terraform.tfstate",
+			vars:        backendKeyVars{},
+			want:        "",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderBackendKey(tt.keyTemplate, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("renderBackendKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("renderBackendKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newBackendConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		s3Res     *initS3Result
+		dynamoRes *initDynamoDBResult
+		want      *backendConfig
+	}{
+		{
+			name: "S01: SSE-S3 with lock table",
+			s3Res: &initS3Result{
+				BucketName: "test-bucket",
+				Region:     "ap-northeast-1",
+				Encryption: "AES256",
+			},
+			dynamoRes: &initDynamoDBResult{TableName: "test-table"},
+			want: &backendConfig{
+				Bucket:        "test-bucket",
+				Key:           "terraform.tfstate",
+				Region:        "ap-northeast-1",
+				DynamoDBTable: "test-table",
+				Encrypt:       true,
+			},
+		},
+		{
+			name: "S02: SSE-KMS in us-east-1 without lock table",
+			s3Res: &initS3Result{
+				BucketName: "test-bucket",
+				Region:     "",
+				Encryption: "aws:kms",
+				KMSKeyID:   "arn:aws:kms:us-east-1:123456789012:key/test",
+			},
+			dynamoRes: nil,
+			want: &backendConfig{
+				Bucket:   "test-bucket",
+				Key:      "terraform.tfstate",
+				Region:   "us-east-1",
+				Encrypt:  true,
+				KMSKeyID: "arn:aws:kms:us-east-1:123456789012:key/test",
+			},
+		},
+		{
+			name: "S03: Encryption skipped on S3-compatible object store",
+			s3Res: &initS3Result{
+				BucketName: "test-bucket",
+				Region:     "",
+				Encryption: "Skipped",
+			},
+			dynamoRes: nil,
+			want: &backendConfig{
+				Bucket:  "test-bucket",
+				Key:     "terraform.tfstate",
+				Region:  "us-east-1",
+				Encrypt: false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newBackendConfig(tt.s3Res, tt.dynamoRes, "terraform.tfstate", backendKeyVars{})
+			if err != nil {
+				t.Errorf("newBackendConfig() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newBackendConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_renderBackendConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend *backendConfig
+		partial bool
+		want    string
+	}{
+		{
+			name: "S01: backend.tf",
+			backend: &backendConfig{
+				Bucket:        "test-bucket",
+				Key:           "prod/network/terraform.tfstate",
+				Region:        "ap-northeast-1",
+				DynamoDBTable: "test-table",
+				Encrypt:       true,
+				KMSKeyID:      "arn:aws:kms:ap-northeast-1:123456789012:key/test",
+			},
+			partial: false,
+			want: `terraform {
+  backend "s3" {
+    bucket         = "test-bucket"
+    key            = "prod/network/terraform.tfstate"
+    region         = "ap-northeast-1"
+    dynamodb_table = "test-table"
+    encrypt        = true
+    kms_key_id     = "arn:aws:kms:ap-northeast-1:123456789012:key/test"
+  }
+}
+`,
+		},
+		{
+			name: "S02: Partial configuration without lock table",
+			backend: &backendConfig{
+				Bucket:  "test-bucket",
+				Key:     "terraform.tfstate",
+				Region:  "ap-northeast-1",
+				Encrypt: true,
+			},
+			partial: true,
+			want: `bucket  = "test-bucket"
+key     = "terraform.tfstate"
+region  = "ap-northeast-1"
+encrypt = true
+`,
+		},
+		{
+			name: "S03: S3-compatible object store",
+			backend: &backendConfig{
+				Bucket:         "test-bucket",
+				Key:            "terraform.tfstate",
+				Region:         "us-east-1",
+				Endpoint:       "http://localhost:9000",
+				ForcePathStyle: true,
+			},
+			partial: false,
+			want: `terraform {
+  backend "s3" {
+    bucket                      = "test-bucket"
+    key                         = "terraform.tfstate"
+    region                      = "us-east-1"
+    encrypt                     = false
+    endpoints                   = { s3 = "http://localhost:9000" }
+    skip_credentials_validation = true
+    skip_region_validation      = true
+    skip_requesting_account_id  = true
+    use_path_style              = true
+  }
+}
+`,
+		},
+		{
+			name: "S04: S3 lockfile",
+			backend: &backendConfig{
+				Bucket:      "test-bucket",
+				Key:         "terraform.tfstate",
+				Region:      "ap-northeast-1",
+				UseLockfile: true,
+				Encrypt:     true,
+			},
+			partial: false,
+			want: `terraform {
+  backend "s3" {
+    bucket       = "test-bucket"
+    key          = "terraform.tfstate"
+    region       = "ap-northeast-1"
+    use_lockfile = true
+    encrypt      = true
+  }
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := renderBackendConfig(&buf, tt.backend, tt.partial); err != nil {
+				t.Errorf("renderBackendConfig() error = %v", err)
+				return
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("renderBackendConfig() = \n%v\nwant \n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeBackendConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  bool
+		overwrite bool
+		wantErr   bool
+	}{
+		{
+			name:      "S01: New file",
+			existing:  false,
+			overwrite: false,
+			wantErr:   false,
+		},
+		{
+			name:      "S02: Existing file with overwrite",
+			existing:  true,
+			overwrite: true,
+			wantErr:   false,
+		},
+		{
+			name:      "F01: Existing file without overwrite",
+			existing:  true,
+			overwrite: false,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "backend.tf")
+			if tt.existing {
+				if err := ioutil.WriteFile(path, []byte("existing"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := writeBackendConfig(path, &backendConfig{Bucket: "test-bucket", Key: "terraform.tfstate", Region: "ap-northeast-1"}, tt.overwrite)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("writeBackendConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := checkBackendConfigWritable(path, tt.overwrite); (err != nil) != !tt.overwrite {
+				t.Errorf("checkBackendConfigWritable() error = %v", err)
+			}
+
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wantKept := tt.wantErr; (string(got) == "existing") != wantKept {
+				t.Errorf("writeBackendConfig() file = %q", got)
+			}
+		})
+	}
+}
+
+func Test_describeS3Backend(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  S3Clientable
+		want    *initS3Result
+		wantErr bool
+	}{
+		{
+			name:   "S01: SSE-KMS bucket",
+			client: mockS3ClientAllSuccessKMS{},
+			want: &initS3Result{
+				BucketName: "test-bucket",
+				Region:     "ap-northeast-1",
+				Encryption: "aws:kms",
+				KMSKeyID:   "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "F01: GetBucketLocation failure",
+			client:  mockS3ClientGetBucketLocationFailure{},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "F02: GetBucketEncryption failure",
+			client:  mockS3ClientGetBucketEncryptionFailure{},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := describeS3Backend(tt.client, "test-bucket")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("describeS3Backend() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("describeS3Backend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	force             bool
+	deleteStateFiles  bool
+	ignoreActiveLocks bool
+)
+
+func NewCmdAwsDestroy() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "destroy",
+		Short: "Delete S3 bucket and DynamoDB table of terraform backend.",
+		Long: `Delete S3 bucket and DynamoDB table of terraform backend.
+
+All object versions and delete markers in the bucket are deleted before the bucket itself.
+You need to type the bucket name to confirm, unless --force is specified.
+
+For safety, tfbackend refuses to destroy the backend when
+- the bucket has Object Lock enabled (no override)
+- the bucket holds .tfstate objects (override with --delete-state-files)
+- the lock table has deletion protection enabled (no override)
+- the lock table holds active locks (override with --ignore-active-locks)
+`,
+		SilenceUsage: true,
+		RunE:         runCmdAwsDestroy,
+	}
+
+	// flag
+	cmd.Flags().BoolVarP(&force, "force", "", false, "Don't ask to type the bucket name to confirm.")
+	cmd.Flags().BoolVarP(&deleteStateFiles, "delete-state-files", "", false, "Destroy the bucket even if it holds .tfstate objects.")
+	cmd.Flags().BoolVarP(&ignoreActiveLocks, "ignore-active-locks", "", false, "Destroy the table even if it holds active locks.")
+
+	return cmd
+}
+
+func runCmdAwsDestroy(cmd *cobra.Command, args []string) error {
+	// Validation
+	if err := validateAwsNames(); err != nil {
+		return err
+	}
+
+	// Confirmation
+	if !force {
+		if err := confirmDestroy(cmd.InOrStdin(), cmd.OutOrStdout(), bucketName); err != nil {
+			return err
+		}
+	}
+
+	// Load config
+	cfg, err := loadAwsConfig()
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	s3Client := newS3Client(cfg)
+	dynamodbClient := dynamodb.NewFromConfig(cfg)
+
+	// Safety checks before deleting anything.
+	if err := checkS3Destroyable(s3Client, bucketName, deleteStateFiles); err != nil {
+		return err
+	}
+	if tableName != "" {
+		if err := checkDynamoDBDestroyable(dynamodbClient, tableName, ignoreActiveLocks); err != nil {
+			return err
+		}
+	}
+
+	// Destroy
+	if err := destroyS3(s3Client, bucketName); err != nil {
+		return fmt.Errorf("failed to destroy s3 bucket: %w", err)
+	}
+	printCyan(fmt.Sprintf("Successfully destroy terraform backend - s3 bucket: %v\n", bucketName))
+
+	if tableName != "" {
+		if err := destroyDynamoDB(dynamodbClient, tableName); err != nil {
+			return fmt.Errorf("failed to destroy dynamodb table: %w", err)
+		}
+		printCyan(fmt.Sprintf("Successfully destroy terraform lock table - dynamodb table: %v\n", tableName))
+	}
+
+	return nil
+}
+
+// confirmDestroy asks the user to type the bucket name.
+func confirmDestroy(in io.Reader, out io.Writer, bucketName string) error {
+	fmt.Fprintf(out, "Do you really want to destroy terraform backend? All state files and their history will be lost.\n")
+	fmt.Fprintf(out, "Enter the bucket name (%v) to confirm: ", bucketName)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	if strings.TrimSpace(answer) != bucketName {
+		return fmt.Errorf("destroy is cancelled: the entered name doesn't match the bucket name")
+	}
+	return nil
+}
+
+// checkS3Destroyable refuses to destroy the bucket with Object Lock, and the bucket which holds .tfstate objects unless allowed.
+// Versions under retention can't be deleted, so emptying the bucket with Object Lock would fail halfway.
+func checkS3Destroyable(c S3Clientable, bucketName string, allowStateFiles bool) error {
+	locked, err := isObjectLockEnabled(c, bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to get object lock configuration of s3 bucket: %w", err)
+	}
+	if locked {
+		return fmt.Errorf("s3 bucket %v has Object Lock enabled. Its versions can't be deleted until the retention expires, so destroy is refused", bucketName)
+	}
+
+	if allowStateFiles {
+		return nil
+	}
+
+	states, err := findS3StateFiles(c, bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to list objects of s3 bucket: %w", err)
+	}
+	if len(states) > 0 {
+		return fmt.Errorf("s3 bucket %v holds %v .tfstate object(s) such as %v. Specify --delete-state-files to destroy anyway", bucketName, len(states), states[0])
+	}
+	return nil
+}
+
+// checkDynamoDBDestroyable refuses to destroy the table with deletion protection, and the table which holds active locks unless allowed.
+// DeleteTable of the protected table fails, so destroy would stop after the bucket is gone.
+func checkDynamoDBDestroyable(c DynamoDBClientable, tableName string, allowActiveLocks bool) error {
+	desc, err := describeDynamoDBTable(context.TODO(), c, tableName)
+	if err != nil {
+		if isAPIErrorCode(err, "ResourceNotFoundException") {
+			return nil
+		}
+		return fmt.Errorf("failed to describe dynamodb table: %w", err)
+	}
+	if desc.Table != nil && desc.Table.DeletionProtectionEnabled != nil && *desc.Table.DeletionProtectionEnabled {
+		return fmt.Errorf("dynamodb table %v has deletion protection enabled. Disable it before destroy, e.g. 'aws dynamodb update-table --table-name %v --no-deletion-protection-enabled'", tableName, tableName)
+	}
+
+	if allowActiveLocks {
+		return nil
+	}
+
+	locks, err := scanDynamoDBLocks(context.TODO(), c, tableName)
+	if err != nil {
+		if isAPIErrorCode(err, "ResourceNotFoundException") {
+			return nil
+		}
+		return fmt.Errorf("failed to scan dynamodb table: %w", err)
+	}
+	if len(locks) > 0 {
+		return fmt.Errorf("dynamodb table %v holds %v active lock(s) such as %v. Specify --ignore-active-locks to destroy anyway", tableName, len(locks), locks[0])
+	}
+	return nil
+}
+
+// findS3StateFiles returns keys of the current objects whose name ends with .tfstate.
+func findS3StateFiles(c S3Clientable, bucketName string) ([]string, error) {
+	var states []string
+	var token *string
+	for {
+		out, err := listS3Objects(context.TODO(), c, bucketName, token)
+		if err != nil {
+			if isAPIErrorCode(err, "NoSuchBucket") {
+				return nil, nil
+			}
+			return nil, err
+		}
+		for _, o := range out.Contents {
+			if o.Key != nil && strings.HasSuffix(*o.Key, ".tfstate") {
+				states = append(states, *o.Key)
+			}
+		}
+		if !out.IsTruncated {
+			return states, nil
+		}
+		token = out.NextContinuationToken
+	}
+}
+
+// emptyS3Bucket deletes all object versions and delete markers in the bucket, and returns the number of them.
+func emptyS3Bucket(c S3Clientable, bucketName string) (int, error) {
+	deleted := 0
+	var keyMarker, versionIDMarker *string
+	for {
+		out, err := listS3ObjectVersions(context.TODO(), c, bucketName, keyMarker, versionIDMarker)
+		if err != nil {
+			return deleted, err
+		}
+
+		var objects []s3types.ObjectIdentifier
+		for _, v := range out.Versions {
+			objects = append(objects, s3types.ObjectIdentifier{Key: v.Key, VersionId: v.VersionId})
+		}
+		for _, m := range out.DeleteMarkers {
+			objects = append(objects, s3types.ObjectIdentifier{Key: m.Key, VersionId: m.VersionId})
+		}
+
+		// Versions and delete markers in a page are up to 1,000 in total, which DeleteObjects accepts at once.
+		if len(objects) > 0 {
+			if _, err := deleteS3Objects(context.TODO(), c, bucketName, objects); err != nil {
+				return deleted, err
+			}
+			deleted += len(objects)
+		}
+
+		if !out.IsTruncated {
+			return deleted, nil
+		}
+		keyMarker, versionIDMarker = out.NextKeyMarker, out.NextVersionIdMarker
+	}
+}
+
+// destroyS3 empties and deletes the bucket with messages.
+func destroyS3(c S3Clientable, bucketName string) error {
+	progress.section("s3_bucket", "💣 Start to destroy terraform backend: s3 bucket ...")
+
+	progress.begin("Check existence of bucket")
+	exists, err := s3BucketExists(context.TODO(), c, bucketName)
+	if err != nil {
+		progress.fail()
+		return fmt.Errorf("failed to check existence of s3 bucket: %w", err)
+	}
+	if !exists {
+		progress.end(stepStatusAlreadyDeleted)
+		return nil
+	}
+	progress.end(stepStatusSuccess)
+
+	progress.begin("Delete all object versions and delete markers")
+	if _, err := emptyS3Bucket(c, bucketName); err != nil {
+		progress.fail()
+		return fmt.Errorf("failed to empty s3 bucket: %w", err)
+	}
+	progress.end(stepStatusDeleted)
+
+	progress.begin("Delete bucket")
+	if _, err := deleteS3Bucket(context.TODO(), c, bucketName); err != nil {
+		progress.fail()
+		return fmt.Errorf("failed to delete s3 bucket: %w", err)
+	}
+	progress.end(stepStatusDeleted)
+
+	return nil
+}
+
+// destroyDynamoDB deletes the table with messages.
+func destroyDynamoDB(c DynamoDBClientable, tableName string) error {
+	progress.section("dynamodb_table", "💣 Start to destroy terraform lock table: DynamoDB ...")
+
+	progress.begin("Delete table")
+	if _, err := deleteDynamoDBTable(context.TODO(), c, tableName); err != nil {
+		if isAPIErrorCode(err, "ResourceNotFoundException") {
+			progress.end(stepStatusAlreadyDeleted)
+			return nil
+		}
+		progress.fail()
+		return fmt.Errorf("failed to delete dynamodb table: %w", err)
+	}
+	progress.end(stepStatusDeleted)
+
+	return nil
+}
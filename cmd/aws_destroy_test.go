@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func Test_confirmDestroy(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:    "S01: Bucket name is entered",
+			input:   "test-bucket\n",
+			wantErr: false,
+		},
+		{
+			name:    "S02: Bucket name is entered without newline",
+			input:   "test-bucket",
+			wantErr: false,
+		},
+		{
+			name:    "F01: Different name is entered",
+			input:   "other-bucket\n",
+			wantErr: true,
+		},
+		{
+			name:    "F02: Nothing is entered",
+			input:   "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := confirmDestroy(strings.NewReader(tt.input), &bytes.Buffer{}, "test-bucket")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("confirmDestroy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_checkS3Destroyable(t *testing.T) {
+	tests := []struct {
+		name            string
+		client          S3Clientable
+		allowStateFiles bool
+		wantErr         bool
+	}{
+		{
+			name:            "S01: Empty bucket",
+			client:          &mockS3ClientBucketObjects{},
+			allowStateFiles: false,
+			wantErr:         false,
+		},
+		{
+			name: "S02: No .tfstate object",
+			client: &mockS3ClientBucketObjects{
+				objectPages: []s3.ListObjectsV2Output{
+					{Contents: []s3types.Object{{Key: aws.String("README.md")}}},
+				},
+			},
+			allowStateFiles: false,
+			wantErr:         false,
+		},
+		{
+			name: "S03: State files are allowed to be deleted",
+			client: &mockS3ClientBucketObjects{
+				objectPages: []s3.ListObjectsV2Output{
+					{Contents: []s3types.Object{{Key: aws.String("env/terraform.tfstate")}}},
+				},
+			},
+			allowStateFiles: true,
+			wantErr:         false,
+		},
+		{
+			name: "F01: Bucket holds .tfstate object in the second page",
+			client: &mockS3ClientBucketObjects{
+				objectPages: []s3.ListObjectsV2Output{
+					{Contents: []s3types.Object{{Key: aws.String("README.md")}}},
+					{Contents: []s3types.Object{{Key: aws.String("env/terraform.tfstate")}}},
+				},
+			},
+			allowStateFiles: false,
+			wantErr:         true,
+		},
+		{
+			name: "F02: Bucket has Object Lock enabled",
+			client: &mockS3ClientExistingBucket{
+				objectLock: &s3types.ObjectLockConfiguration{ObjectLockEnabled: s3types.ObjectLockEnabledEnabled},
+			},
+			allowStateFiles: true,
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkS3Destroyable(tt.client, "test-bucket", tt.allowStateFiles)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkS3Destroyable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_checkDynamoDBDestroyable(t *testing.T) {
+	tests := []struct {
+		name             string
+		client           *mockDynamoDBClient
+		allowActiveLocks bool
+		wantErr          bool
+	}{
+		{
+			name:             "S01: No active lock",
+			client:           &mockDynamoDBClient{exists: true},
+			allowActiveLocks: false,
+			wantErr:          false,
+		},
+		{
+			name:             "S02: Active locks are allowed to be ignored",
+			client:           &mockDynamoDBClient{exists: true, locks: []string{"test-bucket/terraform.tfstate"}},
+			allowActiveLocks: true,
+			wantErr:          false,
+		},
+		{
+			name:             "F01: Table holds active lock",
+			client:           &mockDynamoDBClient{exists: true, locks: []string{"test-bucket/terraform.tfstate"}},
+			allowActiveLocks: false,
+			wantErr:          true,
+		},
+		{
+			name:             "F02: Table has deletion protection enabled",
+			client:           &mockDynamoDBClient{exists: true, table: mockTableProtected()},
+			allowActiveLocks: true,
+			wantErr:          true,
+		},
+		{
+			name:             "F03: Scan failure",
+			client:           &mockDynamoDBClient{exists: true, scanErr: errors.New("some error")},
+			allowActiveLocks: false,
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDynamoDBDestroyable(tt.client, "test-table", tt.allowActiveLocks)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkDynamoDBDestroyable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_destroyS3(t *testing.T) {
+	tests := []struct {
+		name              string
+		client            *mockS3ClientBucketObjects
+		wantBatches       int
+		wantDeleted       int
+		wantBucketDeleted bool
+		wantErr           bool
+	}{
+		{
+			name:              "S01: Empty bucket",
+			client:            &mockS3ClientBucketObjects{},
+			wantBatches:       0,
+			wantDeleted:       0,
+			wantBucketDeleted: true,
+			wantErr:           false,
+		},
+		{
+			name: "S02: Versions and delete markers across pages",
+			client: &mockS3ClientBucketObjects{
+				versionPages: []s3.ListObjectVersionsOutput{
+					{
+						Versions: []s3types.ObjectVersion{
+							{Key: aws.String("terraform.tfstate"), VersionId: aws.String("v1")},
+							{Key: aws.String("terraform.tfstate"), VersionId: aws.String("v2")},
+						},
+						DeleteMarkers: []s3types.DeleteMarkerEntry{
+							{Key: aws.String("terraform.tfstate"), VersionId: aws.String("v3")},
+						},
+					},
+					{
+						Versions: []s3types.ObjectVersion{
+							{Key: aws.String("other.tfstate"), VersionId: aws.String("v1")},
+						},
+					},
+				},
+			},
+			wantBatches:       2,
+			wantDeleted:       4,
+			wantBucketDeleted: true,
+			wantErr:           false,
+		},
+		{
+			name: "F01: DeleteObjects reports errors",
+			client: &mockS3ClientBucketObjects{
+				versionPages: []s3.ListObjectVersionsOutput{
+					{
+						Versions: []s3types.ObjectVersion{
+							{Key: aws.String("terraform.tfstate"), VersionId: aws.String("v1")},
+						},
+					},
+				},
+				deleteObjectErr: []s3types.Error{
+					{Key: aws.String("terraform.tfstate"), Message: aws.String("Access Denied")},
+				},
+			},
+			wantBatches:       1,
+			wantDeleted:       1,
+			wantBucketDeleted: false,
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := destroyS3(tt.client, "test-bucket")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("destroyS3() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(tt.client.deletedBatches) != tt.wantBatches {
+				t.Errorf("destroyS3() DeleteObjects calls = %v, want %v", len(tt.client.deletedBatches), tt.wantBatches)
+			}
+			deleted := 0
+			for _, b := range tt.client.deletedBatches {
+				deleted += len(b)
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("destroyS3() deleted objects = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if tt.client.bucketDeleted != tt.wantBucketDeleted {
+				t.Errorf("destroyS3() bucket deleted = %v, want %v", tt.client.bucketDeleted, tt.wantBucketDeleted)
+			}
+		})
+	}
+}
+
+func Test_destroyDynamoDB(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  *mockDynamoDBClient
+		wantErr bool
+	}{
+		{
+			name:    "S01: Delete table",
+			client:  &mockDynamoDBClient{exists: true, table: mockTablePayPerRequest()},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := destroyDynamoDB(tt.client, "test-table")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("destroyDynamoDB() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.client.deleted {
+				t.Errorf("destroyDynamoDB() table is not deleted")
+			}
+		})
+	}
+}
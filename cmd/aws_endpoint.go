@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var (
+	endpointURL    string
+	forcePathStyle bool
+	s3Compatible   bool
+)
+
+// loadAwsConfig loads the default config. If --endpoint-url is specified, all services are sent to the endpoint.
+// S3-compatible object stores often have no region, so us-east-1 is used if the region is not configured.
+func loadAwsConfig() (aws.Config, error) {
+	var optFns []func(*config.LoadOptions) error
+	if endpointURL != "" {
+		optFns = append(optFns, config.WithEndpointResolver(aws.EndpointResolverFunc(
+			func(service, region string) (aws.Endpoint, error) {
+				return aws.Endpoint{
+					URL:               endpointURL,
+					SigningRegion:     region,
+					HostnameImmutable: forcePathStyle,
+				}, nil
+			})))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), optFns...)
+	if err != nil {
+		return cfg, err
+	}
+	if endpointURL != "" && cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return cfg, nil
+}
+
+// newS3Client returns S3 client which uses path-style addressing if --force-path-style is specified.
+func newS3Client(cfg aws.Config) *s3.Client {
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = forcePathStyle
+	})
+}
+
+// isNotImplemented checks if err means that the S3-compatible object store doesn't support the API.
+func isNotImplemented(err error) bool {
+	return isAPIErrorCode(err, "NotImplemented", "NotImplementedException", "XNotImplemented", "MethodNotAllowed")
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var (
+	noncurrentVersionExpirationDays int32
+	noncurrentVersionsToKeep        int32
+	noncurrentVersionTransitionDays int32
+	noncurrentVersionStorageClass   string
+	expireDeleteMarkers             bool
+	abortIncompleteUploadDays       int32
+)
+
+// lifecycleRuleID is the ID of the lifecycle rule managed by tfbackend.
+// Rules of an adopted bucket with the other IDs are kept as they are.
+const lifecycleRuleID = "tfbackend-state-versions"
+
+// lifecycleOption holds the lifecycle rule for old state versions. Zero value means no lifecycle configuration.
+type lifecycleOption struct {
+	// NoncurrentVersionExpirationDays expires noncurrent versions after the days.
+	NoncurrentVersionExpirationDays int32
+	// NoncurrentVersionsToKeep keeps the newest noncurrent versions from expiration.
+	NoncurrentVersionsToKeep int32
+	// NoncurrentVersionTransitionDays transitions noncurrent versions to StorageClass after the days.
+	NoncurrentVersionTransitionDays int32
+	// StorageClass is the storage class of the transition, STANDARD_IA or GLACIER.
+	StorageClass string
+	// ExpireDeleteMarkers removes delete markers without noncurrent versions.
+	ExpireDeleteMarkers bool
+	// AbortIncompleteUploadDays aborts incomplete multipart uploads after the days.
+	AbortIncompleteUploadDays int32
+}
+
+// enabled checks if any action of the lifecycle rule is specified.
+func (o lifecycleOption) enabled() bool {
+	return o.NoncurrentVersionExpirationDays > 0 || o.NoncurrentVersionTransitionDays > 0 || o.ExpireDeleteMarkers || o.AbortIncompleteUploadDays > 0
+}
+
+// newLifecycleOption builds the lifecycle option from the flags.
+func newLifecycleOption() lifecycleOption {
+	return lifecycleOption{
+		NoncurrentVersionExpirationDays: noncurrentVersionExpirationDays,
+		NoncurrentVersionsToKeep:        noncurrentVersionsToKeep,
+		NoncurrentVersionTransitionDays: noncurrentVersionTransitionDays,
+		StorageClass:                    noncurrentVersionStorageClass,
+		ExpireDeleteMarkers:             expireDeleteMarkers,
+		AbortIncompleteUploadDays:       abortIncompleteUploadDays,
+	}
+}
+
+// validateLifecycleFlags validates flags of the lifecycle rule.
+func validateLifecycleFlags() error {
+	opt := newLifecycleOption()
+	if opt.NoncurrentVersionExpirationDays < 0 || opt.NoncurrentVersionsToKeep < 0 || opt.NoncurrentVersionTransitionDays < 0 || opt.AbortIncompleteUploadDays < 0 {
+		return fmt.Errorf("days and number of versions of lifecycle rule must not be negative")
+	}
+	if opt.NoncurrentVersionsToKeep > 100 {
+		return fmt.Errorf("--noncurrent-versions-to-keep must be 100 or less: %v", opt.NoncurrentVersionsToKeep)
+	}
+	if opt.NoncurrentVersionsToKeep > 0 && opt.NoncurrentVersionExpirationDays == 0 {
+		return fmt.Errorf("--noncurrent-versions-to-keep needs --noncurrent-version-expiration-days")
+	}
+	if opt.NoncurrentVersionTransitionDays > 0 {
+		if !validateTransitionStorageClass(opt.StorageClass) {
+			return fmt.Errorf("--noncurrent-version-storage-class must be 'STANDARD_IA' or 'GLACIER': %v", opt.StorageClass)
+		}
+		if opt.StorageClass == string(s3types.TransitionStorageClassStandardIa) && opt.NoncurrentVersionTransitionDays < 30 {
+			return fmt.Errorf("transition to STANDARD_IA needs 30 days or more: %v", opt.NoncurrentVersionTransitionDays)
+		}
+		if opt.NoncurrentVersionExpirationDays > 0 && opt.NoncurrentVersionExpirationDays <= opt.NoncurrentVersionTransitionDays {
+			return fmt.Errorf("--noncurrent-version-expiration-days must be greater than --noncurrent-version-transition-days")
+		}
+	}
+	if s3Compatible && opt.NoncurrentVersionTransitionDays > 0 {
+		return fmt.Errorf("--noncurrent-version-transition-days cannot be specified with --s3-compatible")
+	}
+	return nil
+}
+
+func validateTransitionStorageClass(storageClass string) bool {
+	return storageClass == string(s3types.TransitionStorageClassStandardIa) || storageClass == string(s3types.TransitionStorageClassGlacier)
+}
+
+// buildLifecycleRule builds the lifecycle rule which applies to all objects of the bucket.
+func buildLifecycleRule(opt lifecycleOption) s3types.LifecycleRule {
+	rule := s3types.LifecycleRule{
+		ID:     aws.String(lifecycleRuleID),
+		Status: s3types.ExpirationStatusEnabled,
+		Filter: &s3types.LifecycleRuleFilterMemberPrefix{Value: ""},
+	}
+	if opt.NoncurrentVersionExpirationDays > 0 {
+		rule.NoncurrentVersionExpiration = &s3types.NoncurrentVersionExpiration{
+			NoncurrentDays:          opt.NoncurrentVersionExpirationDays,
+			NewerNoncurrentVersions: opt.NoncurrentVersionsToKeep,
+		}
+	}
+	if opt.NoncurrentVersionTransitionDays > 0 {
+		rule.NoncurrentVersionTransitions = []s3types.NoncurrentVersionTransition{
+			{
+				NoncurrentDays: opt.NoncurrentVersionTransitionDays,
+				StorageClass:   s3types.TransitionStorageClass(opt.StorageClass),
+			},
+		}
+	}
+	if opt.ExpireDeleteMarkers {
+		rule.Expiration = &s3types.LifecycleExpiration{ExpiredObjectDeleteMarker: true}
+	}
+	if opt.AbortIncompleteUploadDays > 0 {
+		rule.AbortIncompleteMultipartUpload = &s3types.AbortIncompleteMultipartUpload{DaysAfterInitiation: opt.AbortIncompleteUploadDays}
+	}
+	return rule
+}
+
+// lifecycleSummary describes the actions of the lifecycle rule, e.g. "Noncurrent versions expire after 90 days (newest 3 kept)".
+// S3 may return the rule in a different shape from the one put, so rules are compared by the summary.
+func lifecycleSummary(rule s3types.LifecycleRule) string {
+	if rule.Status != s3types.ExpirationStatusEnabled {
+		return "Disabled"
+	}
+
+	var actions []string
+	if e := rule.NoncurrentVersionExpiration; e != nil && e.NoncurrentDays > 0 {
+		action := fmt.Sprintf("Noncurrent versions expire after %v days", e.NoncurrentDays)
+		if e.NewerNoncurrentVersions > 0 {
+			action += fmt.Sprintf(" (newest %v kept)", e.NewerNoncurrentVersions)
+		}
+		actions = append(actions, action)
+	}
+	for _, t := range rule.NoncurrentVersionTransitions {
+		actions = append(actions, fmt.Sprintf("Noncurrent versions move to %v after %v days", t.StorageClass, t.NoncurrentDays))
+	}
+	if rule.Expiration != nil && rule.Expiration.ExpiredObjectDeleteMarker {
+		actions = append(actions, "Expired delete markers are removed")
+	}
+	if a := rule.AbortIncompleteMultipartUpload; a != nil && a.DaysAfterInitiation > 0 {
+		actions = append(actions, fmt.Sprintf("Incomplete uploads abort after %v days", a.DaysAfterInitiation))
+	}
+	return strings.Join(actions, ", ")
+}
+
+// findLifecycleRule returns the rule managed by tfbackend, or nil if not found.
+func findLifecycleRule(rules []s3types.LifecycleRule) *s3types.LifecycleRule {
+	for i := range rules {
+		if aws.ToString(rules[i].ID) == lifecycleRuleID {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// mergeLifecycleRules replaces the rule managed by tfbackend in the current rules with the desired one.
+func mergeLifecycleRules(current []s3types.LifecycleRule, desired s3types.LifecycleRule) []s3types.LifecycleRule {
+	merged := []s3types.LifecycleRule{}
+	for _, r := range current {
+		if aws.ToString(r.ID) != lifecycleRuleID {
+			merged = append(merged, r)
+		}
+	}
+	return append(merged, desired)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func Test_lifecycleSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		rule s3types.LifecycleRule
+		want string
+	}{
+		{
+			name: "S01: All actions",
+			rule: buildLifecycleRule(lifecycleOption{
+				NoncurrentVersionExpirationDays: 365,
+				NoncurrentVersionsToKeep:        10,
+				NoncurrentVersionTransitionDays: 30,
+				StorageClass:                    "STANDARD_IA",
+				ExpireDeleteMarkers:             true,
+				AbortIncompleteUploadDays:       3,
+			}),
+			want: "Noncurrent versions expire after 365 days (newest 10 kept), Noncurrent versions move to STANDARD_IA after 30 days, Expired delete markers are removed, Incomplete uploads abort after 3 days",
+		},
+		{
+			name: "S02: Disabled rule",
+			rule: s3types.LifecycleRule{
+				ID:                          aws.String(lifecycleRuleID),
+				Status:                      s3types.ExpirationStatusDisabled,
+				NoncurrentVersionExpiration: &s3types.NoncurrentVersionExpiration{NoncurrentDays: 90},
+			},
+			want: "Disabled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lifecycleSummary(tt.rule); got != tt.want {
+				t.Errorf("lifecycleSummary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mergeLifecycleRules(t *testing.T) {
+	other := s3types.LifecycleRule{ID: aws.String("expire-logs"), Status: s3types.ExpirationStatusEnabled}
+	current := []s3types.LifecycleRule{
+		{ID: aws.String(lifecycleRuleID), Status: s3types.ExpirationStatusDisabled},
+		other,
+	}
+	desired := buildLifecycleRule(lifecycleOption{ExpireDeleteMarkers: true})
+
+	want := []s3types.LifecycleRule{other, desired}
+	if got := mergeLifecycleRules(current, desired); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeLifecycleRules() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// Test_LocalStack runs the whole aws flow against LocalStack.
+// It is skipped unless TFBACKEND_LOCALSTACK_ENDPOINT is set, e.g.
+//
+//	docker run -d -p 4566:4566 localstack/localstack
+//	TFBACKEND_LOCALSTACK_ENDPOINT=http://localhost:4566 go test ./cmd -run LocalStack
+func Test_LocalStack(t *testing.T) {
+	endpoint := os.Getenv("TFBACKEND_LOCALSTACK_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TFBACKEND_LOCALSTACK_ENDPOINT is not set")
+	}
+
+	// LocalStack accepts any credentials.
+	for k, v := range map[string]string{
+		"AWS_ACCESS_KEY_ID":     "test",
+		"AWS_SECRET_ACCESS_KEY": "test",
+		"AWS_REGION":            "us-east-1",
+	} {
+		if os.Getenv(k) == "" {
+			os.Setenv(k, v)
+			defer os.Unsetenv(k)
+		}
+	}
+	endpointURL, forcePathStyle = endpoint, true
+	defer func() { endpointURL, forcePathStyle = "", false }()
+
+	cfg, err := loadAwsConfig()
+	if err != nil {
+		t.Fatalf("loadAwsConfig() error = %v", err)
+	}
+
+	suffix := time.Now().UnixNano()
+	bucket := fmt.Sprintf("tfbackend-localstack-%v", suffix)
+	table := fmt.Sprintf("tfbackend-localstack-%v", suffix)
+
+	tests := []struct {
+		name string
+		opt  initS3Option
+	}{
+		{name: "S01: AWS profile", opt: initS3Option{}},
+		{name: "S02: S3-compatible profile", opt: initS3Option{S3Compatible: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every created resource is deleted by rollback at the end.
+			tx := &transaction{}
+			defer func() {
+				if err := tx.rollback(); err != nil {
+					t.Errorf("rollback() error = %v", err)
+				}
+			}()
+
+			s3Res, err := initS3(newS3Client(cfg), bucket, cfg.Region, tt.opt, tx)
+			if err != nil {
+				t.Fatalf("initS3() error = %v", err)
+			}
+			if s3Res.Versioning != "Enabled" {
+				t.Errorf("initS3() Versioning = %v, want Enabled", s3Res.Versioning)
+			}
+
+			dynamoRes, err := initDynamoDB(dynamodb.NewFromConfig(cfg), table, "PAY_PER_REQUEST", initDynamoDBOption{}, tx)
+			if err != nil {
+				t.Fatalf("initDynamoDB() error = %v", err)
+			}
+			if dynamoRes.TableName != table {
+				t.Errorf("initDynamoDB() TableName = %v, want %v", dynamoRes.TableName, table)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/spf13/cobra"
+)
+
+var (
+	useLockfile  bool
+	keepDynamoDB bool
+	migrateOut   string
+)
+
+// lockfileProbeKey is the object written to check conditional writes. All of its versions are deleted after the check.
+const lockfileProbeKey = ".tfbackend/conditional-write-check.tflock"
+
+// lockfileGuidance is shown when --use-lockfile is specified without --emit-backend.
+const lockfileGuidance = `State is locked with .tflock object in the bucket. Set 'use_lockfile = true' in backend "s3" block
+instead of 'dynamodb_table'. Terraform 1.10 or later is needed.
+`
+
+func NewCmdAwsMigrateLockfile() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate-lockfile",
+		Short: "Check that the existing backend can switch from DynamoDB lock table to S3 lockfile.",
+		Long: `Check that the existing backend can switch from DynamoDB lock table to S3 lockfile.
+
+Terraform 1.10 or later can lock state with a .tflock object in the bucket itself
+(use_lockfile = true). Before switching, tfbackend checks that
+- the lock table given by --dynamodb holds no active lock
+- the bucket supports conditional writes which the lockfile relies on
+and then generates backend "s3" block with use_lockfile = true.
+
+With --keep-dynamodb, dynamodb_table is kept in the block, so that terraform acquires
+both locks while some configurations still use the table only. Remove it when all
+of them are migrated. No resource is deleted by this command.
+
+The block is printed to stdout, or written to --out ('.hcl' writes partial configuration).
+Run 'terraform init -reconfigure' with the new configuration to apply it.
+`,
+		SilenceUsage: true,
+		RunE:         runCmdAwsMigrateLockfile,
+	}
+
+	// flag
+	cmd.Flags().BoolVarP(&keepDynamoDB, "keep-dynamodb", "", false, "Keep dynamodb_table in the generated block to acquire both locks during the transition.")
+	cmd.Flags().StringVarP(&migrateOut, "out", "", "", "Path of the file to write. '.hcl' extension writes partial configuration. Default is stdout.")
+	cmd.Flags().BoolVarP(&overwriteBackend, "overwrite", "", false, "Overwrite the file given by --out if it exists.")
+
+	return cmd
+}
+
+func runCmdAwsMigrateLockfile(cmd *cobra.Command, args []string) error {
+	// Validation
+	if err := validateAwsNames(); err != nil {
+		return err
+	}
+	if tableName == "" {
+		return fmt.Errorf("--dynamodb is needed to check the lock table in use")
+	}
+	if migrateOut != "" {
+		if err := checkBackendConfigWritable(migrateOut, overwriteBackend); err != nil {
+			return err
+		}
+	}
+
+	// Load config
+	cfg, err := loadAwsConfig()
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	s3Client := newS3Client(cfg)
+
+	// Safety checks before switching.
+	if err := checkNoActiveLocks(dynamodb.NewFromConfig(cfg), tableName); err != nil {
+		return err
+	}
+	s3Res, err := describeS3Backend(s3Client, bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to describe s3 bucket: %w", err)
+	}
+	locked, err := isObjectLockEnabled(s3Client, bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to get object lock configuration: %w", err)
+	}
+	encryption, keyID := objectEncryption(s3Res)
+	if err := checkConditionalWrites(s3Client, bucketName, encryption, keyID, locked); err != nil {
+		return err
+	}
+
+	var dynamoRes *initDynamoDBResult
+	if keepDynamoDB {
+		dynamoRes = &initDynamoDBResult{TableName: tableName}
+	}
+	backend, err := newBackendConfig(s3Res, dynamoRes, backendKey, backendKeyVars{Env: backendEnv, Component: backendComponent, Bucket: bucketName})
+	if err != nil {
+		return err
+	}
+	backend.Endpoint, backend.ForcePathStyle = endpointURL, forcePathStyle
+	backend.UseLockfile = true
+
+	if migrateOut == "" {
+		if err := renderBackendConfig(cmd.OutOrStdout(), backend, false); err != nil {
+			return err
+		}
+	} else {
+		if err := writeBackendConfig(migrateOut, backend, overwriteBackend); err != nil {
+			return err
+		}
+		printCyan(fmt.Sprintf("Successfully write terraform backend configuration: %v\n", migrateOut))
+	}
+
+	if keepDynamoDB {
+		printCyan(fmt.Sprintf("Run 'terraform init -reconfigure'. Remove dynamodb_table after every configuration using %v is migrated.\n", tableName))
+	} else {
+		printCyan(fmt.Sprintf("Run 'terraform init -reconfigure'. dynamodb table %v is no longer used by this configuration.\n", tableName))
+	}
+	return nil
+}
+
+// checkNoActiveLocks refuses to migrate while terraform holds locks in the table,
+// because the runs holding them wouldn't see the lockfile.
+func checkNoActiveLocks(c DynamoDBScanAPI, tableName string) error {
+	locks, err := scanDynamoDBLocks(context.TODO(), c, tableName)
+	if err != nil {
+		return fmt.Errorf("failed to scan dynamodb table: %w", err)
+	}
+	if len(locks) > 0 {
+		return fmt.Errorf("dynamodb table %v holds %v active lock(s) such as %v. Wait until they are released before switching to S3 lockfile", tableName, len(locks), locks[0])
+	}
+	return nil
+}
+
+// checkConditionalWrites puts the probe object twice, the second time with If-None-Match.
+// The bucket supports conditional writes only if the second write is rejected with PreconditionFailed.
+// Stores which ignore the header accept it, and then terraform couldn't detect the lock held by others.
+// With objectLock, versions of the probe can't be deleted during the retention, so only a delete marker is put.
+func checkConditionalWrites(c S3Clientable, bucketName string, encryption s3types.ServerSideEncryption, kmsKeyID string, objectLock bool) (err error) {
+	var written []s3types.ObjectIdentifier
+	defer func() {
+		if len(written) == 0 {
+			return
+		}
+		if _, deleteErr := deleteS3Objects(context.TODO(), c, bucketName, written); deleteErr != nil && err == nil {
+			err = fmt.Errorf("failed to delete probe object %v: %w", lockfileProbeKey, deleteErr)
+		}
+	}()
+
+	body := []byte("tfbackend conditional write check\n")
+	out, err := putS3Object(context.TODO(), c, bucketName, lockfileProbeKey, body, encryption, kmsKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to put probe object %v: %w", lockfileProbeKey, err)
+	}
+	written = append(written, s3types.ObjectIdentifier{Key: aws.String(lockfileProbeKey), VersionId: out.VersionId})
+	if objectLock {
+		written[0].VersionId = nil
+	}
+
+	out, err = putS3Object(context.TODO(), c, bucketName, lockfileProbeKey, body, encryption, kmsKeyID, withIfNoneMatch)
+	switch {
+	case err == nil:
+		// Unversioned stores overwrite the same object, which is deleted by the first identifier.
+		if out.VersionId != nil && !objectLock {
+			written = append(written, s3types.ObjectIdentifier{Key: aws.String(lockfileProbeKey), VersionId: out.VersionId})
+		}
+		return fmt.Errorf("s3 bucket %v doesn't support conditional writes: the write with If-None-Match overwrote the existing object", bucketName)
+	case isAPIErrorCode(err, "PreconditionFailed"):
+		return nil
+	case isNotImplemented(err):
+		return fmt.Errorf("s3 bucket %v doesn't support conditional writes: %w", bucketName, err)
+	}
+	return fmt.Errorf("failed to put probe object %v with If-None-Match: %w", lockfileProbeKey, err)
+}
+
+// objectEncryption returns the server-side encryption header which uploads to the bucket should carry,
+// so that the bucket policy denying unencrypted uploads or other KMS keys accepts them.
+func objectEncryption(res *initS3Result) (s3types.ServerSideEncryption, string) {
+	switch res.Encryption {
+	case string(s3types.ServerSideEncryptionAes256):
+		return s3types.ServerSideEncryptionAes256, ""
+	case string(s3types.ServerSideEncryptionAwsKms):
+		return s3types.ServerSideEncryptionAwsKms, res.KMSKeyID
+	}
+	return "", ""
+}
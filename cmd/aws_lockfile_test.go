@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+func Test_checkConditionalWrites(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      *mockS3ClientConditionalWrites
+		encryption  s3types.ServerSideEncryption
+		kmsKeyID    string
+		objectLock  bool
+		wantDeleted []string
+		wantErr     bool
+	}{
+		{
+			name:        "S01: Conditional write is rejected, and the probe is deleted",
+			client:      &mockS3ClientConditionalWrites{},
+			encryption:  s3types.ServerSideEncryptionAes256,
+			wantDeleted: []string{"v1"},
+			wantErr:     false,
+		},
+		{
+			name:        "S02: SSE-KMS bucket",
+			client:      &mockS3ClientConditionalWrites{},
+			encryption:  s3types.ServerSideEncryptionAwsKms,
+			kmsKeyID:    "arn:aws:kms:ap-northeast-1:123456789012:key/test",
+			wantDeleted: []string{"v1"},
+			wantErr:     false,
+		},
+		{
+			name:        "S03: Object Lock bucket gets delete marker instead of deleting versions",
+			client:      &mockS3ClientConditionalWrites{},
+			objectLock:  true,
+			wantDeleted: []string{""},
+			wantErr:     false,
+		},
+		{
+			name:        "F01: Conditional write overwrites the probe, and both versions are deleted",
+			client:      &mockS3ClientConditionalWrites{ignoreIfNoneMatch: true},
+			wantDeleted: []string{"v1", "v2"},
+			wantErr:     true,
+		},
+		{
+			name:        "F02: Unversioned store overwrites the probe",
+			client:      &mockS3ClientConditionalWrites{ignoreIfNoneMatch: true, unversioned: true},
+			wantDeleted: []string{""},
+			wantErr:     true,
+		},
+		{
+			name:        "F03: If-None-Match is not implemented",
+			client:      &mockS3ClientConditionalWrites{conditionalErr: &smithy.GenericAPIError{Code: "NotImplemented"}},
+			wantDeleted: []string{"v1"},
+			wantErr:     true,
+		},
+		{
+			name:        "F04: Probe can't be written",
+			client:      &mockS3ClientConditionalWrites{putErr: errors.New("some error")},
+			wantDeleted: nil,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkConditionalWrites(tt.client, "test-bucket", tt.encryption, tt.kmsKeyID, tt.objectLock)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkConditionalWrites() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var deleted []string
+			for _, o := range tt.client.deleted {
+				if aws.ToString(o.Key) != lockfileProbeKey {
+					t.Errorf("checkConditionalWrites() deleted %v, want %v", aws.ToString(o.Key), lockfileProbeKey)
+				}
+				deleted = append(deleted, aws.ToString(o.VersionId))
+			}
+			if len(deleted) != len(tt.wantDeleted) {
+				t.Fatalf("checkConditionalWrites() deleted versions = %v, want %v", deleted, tt.wantDeleted)
+			}
+			for i := range deleted {
+				if deleted[i] != tt.wantDeleted[i] {
+					t.Errorf("checkConditionalWrites() deleted versions = %v, want %v", deleted, tt.wantDeleted)
+				}
+			}
+
+			for _, in := range tt.client.puts {
+				if in.ServerSideEncryption != tt.encryption || aws.ToString(in.SSEKMSKeyId) != tt.kmsKeyID {
+					t.Errorf("checkConditionalWrites() put with encryption %v %v, want %v %v", in.ServerSideEncryption, aws.ToString(in.SSEKMSKeyId), tt.encryption, tt.kmsKeyID)
+				}
+			}
+		})
+	}
+}
+
+func Test_checkNoActiveLocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  *mockDynamoDBClient
+		wantErr bool
+	}{
+		{
+			name:    "S01: No active lock",
+			client:  &mockDynamoDBClient{exists: true},
+			wantErr: false,
+		},
+		{
+			name:    "F01: Table holds active lock",
+			client:  &mockDynamoDBClient{exists: true, locks: []string{"test-bucket/terraform.tfstate"}},
+			wantErr: true,
+		},
+		{
+			name:    "F02: Scan failure",
+			client:  &mockDynamoDBClient{exists: true, scanErr: errors.New("some error")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNoActiveLocks(tt.client, "test-table")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkNoActiveLocks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_objectEncryption(t *testing.T) {
+	tests := []struct {
+		name           string
+		res            *initS3Result
+		wantEncryption s3types.ServerSideEncryption
+		wantKMSKeyID   string
+	}{
+		{
+			name:           "S01: SSE-S3",
+			res:            &initS3Result{Encryption: "AES256"},
+			wantEncryption: s3types.ServerSideEncryptionAes256,
+		},
+		{
+			name:           "S02: SSE-KMS",
+			res:            &initS3Result{Encryption: "aws:kms", KMSKeyID: "arn:aws:kms:ap-northeast-1:123456789012:key/test"},
+			wantEncryption: s3types.ServerSideEncryptionAwsKms,
+			wantKMSKeyID:   "arn:aws:kms:ap-northeast-1:123456789012:key/test",
+		},
+		{
+			name: "S03: Encryption is skipped on S3-compatible object store",
+			res:  &initS3Result{Encryption: "Skipped"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryption, kmsKeyID := objectEncryption(tt.res)
+			if encryption != tt.wantEncryption || kmsKeyID != tt.wantKMSKeyID {
+				t.Errorf("objectEncryption() = %v, %v, want %v, %v", encryption, kmsKeyID, tt.wantEncryption, tt.wantKMSKeyID)
+			}
+		})
+	}
+}
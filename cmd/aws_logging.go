@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cloudtrailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var (
+	logBucketName   string
+	accessLogPrefix string
+	cloudTrailName  string
+)
+
+// cloudTrailKeyPrefix is the key prefix of the log bucket under which a trail created by tfbackend delivers logs.
+const cloudTrailKeyPrefix = "cloudtrail"
+
+// cloudTrailS3ObjectType is the resource type of data events for S3 objects.
+const cloudTrailS3ObjectType = "AWS::S3::Object"
+
+var trailNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{2,127}$`)
+
+type initCloudTrailResult struct {
+	TrailName  string `json:"trail_name" yaml:"trail_name"`
+	TrailArn   string `json:"trail_arn" yaml:"trail_arn"`
+	LogBucket  string `json:"log_bucket" yaml:"log_bucket"`
+	DataEvents string `json:"data_events" yaml:"data_events"`
+}
+
+func (i *initCloudTrailResult) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Trail name", i.TrailName},
+		{"Trail ARN", i.TrailArn},
+		{"Log bucket", i.LogBucket},
+		{"Data events", i.DataEvents},
+	}
+	return h, b
+}
+
+// validateLoggingFlags validates flags of server access logging and CloudTrail.
+func validateLoggingFlags() error {
+	if logBucketName != "" {
+		if err := validateS3BucketName(logBucketName); err != nil {
+			return fmt.Errorf("invalid log bucket name %v: %w", logBucketName, err)
+		}
+		if logBucketName == bucketName {
+			return fmt.Errorf("--log-bucket must be different from the state bucket: %v", logBucketName)
+		}
+	}
+	if accessLogPrefix != "" && logBucketName == "" {
+		return fmt.Errorf("--access-log-prefix needs --log-bucket")
+	}
+	if cloudTrailName != "" && !trailNamePattern.MatchString(cloudTrailName) {
+		return fmt.Errorf("trail name must be 3 to 128 letters, numbers, periods, underscores and dashes: %v", cloudTrailName)
+	}
+	if s3Compatible && (logBucketName != "" || cloudTrailName != "") {
+		return fmt.Errorf("--log-bucket and --cloudtrail-trail cannot be specified with --s3-compatible")
+	}
+	return nil
+}
+
+// newAccessLogging returns the target of server access logging of the state bucket.
+// The default prefix separates logs of each state bucket sharing the log bucket.
+func newAccessLogging(logBucket string, prefix string, bucketName string) *s3types.LoggingEnabled {
+	if prefix == "" {
+		prefix = "s3-access-logs/" + bucketName + "/"
+	}
+	return &s3types.LoggingEnabled{
+		TargetBucket: aws.String(logBucket),
+		TargetPrefix: aws.String(prefix),
+	}
+}
+
+// sameLoggingTarget checks if server access logging is delivered to the same bucket and prefix.
+func sameLoggingTarget(current *s3types.LoggingEnabled, desired *s3types.LoggingEnabled) bool {
+	if current == nil || desired == nil {
+		return current == desired
+	}
+	return aws.ToString(current.TargetBucket) == aws.ToString(desired.TargetBucket) &&
+		aws.ToString(current.TargetPrefix) == aws.ToString(desired.TargetPrefix)
+}
+
+// formatLoggingTarget returns the target as s3://bucket/prefix, or "Disabled" if nil.
+func formatLoggingTarget(target *s3types.LoggingEnabled) string {
+	if target == nil {
+		return "Disabled"
+	}
+	return "s3://" + aws.ToString(target.TargetBucket) + "/" + aws.ToString(target.TargetPrefix)
+}
+
+// logBucketOption returns the settings of the log bucket. Logs are encrypted with SSE-S3,
+// because server access logging can't deliver to the bucket with SSE-KMS default encryption.
+func logBucketOption(accountID string, tags map[string]string) initS3Option {
+	return initS3Option{
+		Tags:         tags,
+		BucketPolicy: &bucketPolicyOption{LogDeliveryAccountID: accountID},
+	}
+}
+
+// initLogBucket creates or adopts the hardened bucket which receives server access logs and CloudTrail logs.
+func initLogBucket(c S3Clientable, logBucket string, region string, accountID string, tags map[string]string, tx *transaction) (*initS3Result, error) {
+	progress.section("log_bucket", "🚀 Start to create log bucket for terraform backend ...")
+	return initBucket(c, logBucket, region, logBucketOption(accountID, tags), tx)
+}
+
+// stateDataResource returns the data resource value which matches all objects of the state bucket.
+func stateDataResource(bucketName string, region string) string {
+	return s3BucketArn(bucketName, region) + "/"
+}
+
+// initCloudTrail creates the trail if it doesn't exist, and adds data event selectors for objects of the state bucket.
+// A new trail delivers logs to logBucket, so logBucket is needed only when the trail doesn't exist.
+func initCloudTrail(c CloudTrailClientable, trailName string, bucketName string, region string, logBucket string, tags map[string]string, tx *transaction) (*initCloudTrailResult, error) {
+	progress.section("cloudtrail_trail", "🚀 Start to configure cloudtrail trail for terraform backend ...")
+
+	res := initCloudTrailResult{TrailName: trailName}
+
+	// Create trail. If the trail already exists, adopt it.
+	progress.begin("Creating trail")
+	trailRes, err := getTrail(context.TODO(), c, trailName)
+	if err != nil && !isAPIErrorCode(err, "TrailNotFoundException") {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of cloudtrail trail: %w", err)
+	}
+	exists := err == nil
+	if exists {
+		res.TrailArn = aws.ToString(trailRes.Trail.TrailARN)
+		res.LogBucket = aws.ToString(trailRes.Trail.S3BucketName)
+		progress.end(stepStatusUnchanged)
+	} else {
+		if logBucket == "" {
+			progress.fail()
+			return nil, fmt.Errorf("cloudtrail trail %v is not found, and --log-bucket is needed to create it", trailName)
+		}
+		createRes, err := createTrail(context.TODO(), c, trailName, logBucket, cloudTrailKeyPrefix, tags)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create cloudtrail trail: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete cloudtrail trail %v", trailName), func() error {
+			_, err := deleteTrail(context.TODO(), c, trailName)
+			return err
+		})
+		res.TrailArn = aws.ToString(createRes.TrailARN)
+		res.LogBucket = logBucket
+		progress.end(stepStatusCreated)
+
+		progress.begin("Start logging")
+		if _, err := startTrailLogging(context.TODO(), c, trailName); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to start logging of cloudtrail trail: %w", err)
+		}
+		progress.end(stepStatusSuccess)
+	}
+
+	// Add data event selectors
+	dataResource := stateDataResource(bucketName, region)
+	progress.begin("Add data event selectors")
+	status, err := ensureTrailDataEvents(c, trailName, dataResource, exists, tx)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to add data event selectors: %w", err)
+	}
+	progress.end(status)
+
+	// Confirmation
+	progress.begin("Confirmation - Get event selectors")
+	selectorsRes, err := getEventSelectors(context.TODO(), c, trailName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully configured cloudtrail trail, but failed to describe cloudtrail trail: %w", err)
+	}
+	if !hasTrailDataEvents(selectorsRes.EventSelectors, selectorsRes.AdvancedEventSelectors, dataResource) {
+		progress.fail()
+		return nil, fmt.Errorf("data events of cloudtrail trail are not logged: %v", dataResource)
+	}
+	res.DataEvents = dataResource
+	progress.end(stepStatusSuccess)
+
+	return &res, nil
+}
+
+// ensureTrailDataEvents adds the selector which logs read and write data events of objects under dataResource.
+// The trail keeps using advanced event selectors if it already does. For an adopted trail, the previous selectors are restored on rollback.
+func ensureTrailDataEvents(c CloudTrailClientable, trailName string, dataResource string, adopted bool, tx *transaction) (stepStatus, error) {
+	cur, err := getEventSelectors(context.TODO(), c, trailName)
+	if err != nil {
+		return "", err
+	}
+	if hasTrailDataEvents(cur.EventSelectors, cur.AdvancedEventSelectors, dataResource) {
+		return stepStatusUnchanged, nil
+	}
+
+	selectors, advanced := cur.EventSelectors, cur.AdvancedEventSelectors
+	if len(advanced) > 0 {
+		advanced = append(append([]cloudtrailtypes.AdvancedEventSelector{}, advanced...), cloudtrailtypes.AdvancedEventSelector{
+			Name: aws.String("tfbackend state access"),
+			FieldSelectors: []cloudtrailtypes.AdvancedFieldSelector{
+				{Field: aws.String("eventCategory"), Equals: []string{"Data"}},
+				{Field: aws.String("resources.type"), Equals: []string{cloudTrailS3ObjectType}},
+				{Field: aws.String("resources.ARN"), StartsWith: []string{dataResource}},
+			},
+		})
+		selectors = nil
+	} else {
+		selectors = append(append([]cloudtrailtypes.EventSelector{}, selectors...), cloudtrailtypes.EventSelector{
+			ReadWriteType:           cloudtrailtypes.ReadWriteTypeAll,
+			IncludeManagementEvents: aws.Bool(false),
+			DataResources: []cloudtrailtypes.DataResource{
+				{Type: aws.String(cloudTrailS3ObjectType), Values: []string{dataResource}},
+			},
+		})
+	}
+
+	if _, err := putEventSelectors(context.TODO(), c, trailName, selectors, advanced); err != nil {
+		return "", err
+	}
+	if adopted {
+		tx.record(fmt.Sprintf("Restore event selectors of cloudtrail trail %v", trailName), func() error {
+			_, err := putEventSelectors(context.TODO(), c, trailName, cur.EventSelectors, cur.AdvancedEventSelectors)
+			return err
+		})
+	}
+	return appliedStatus(adopted), nil
+}
+
+// hasTrailDataEvents checks if any selector logs both read and write data events of objects under dataResource.
+func hasTrailDataEvents(selectors []cloudtrailtypes.EventSelector, advanced []cloudtrailtypes.AdvancedEventSelector, dataResource string) bool {
+	for _, s := range selectors {
+		if s.ReadWriteType != "" && s.ReadWriteType != cloudtrailtypes.ReadWriteTypeAll {
+			continue
+		}
+		for _, r := range s.DataResources {
+			if aws.ToString(r.Type) != cloudTrailS3ObjectType {
+				continue
+			}
+			for _, v := range r.Values {
+				if strings.HasPrefix(dataResource, v) {
+					return true
+				}
+			}
+		}
+	}
+
+	for _, s := range advanced {
+		if advancedSelectorMatches(s, dataResource) {
+			return true
+		}
+	}
+	return false
+}
+
+// advancedSelectorMatches checks if the advanced selector logs all data events of objects under dataResource.
+// Selectors with fields other than event category, resource type and ARN prefix are not regarded as matching.
+func advancedSelectorMatches(s cloudtrailtypes.AdvancedEventSelector, dataResource string) bool {
+	var category, resourceType bool
+	for _, f := range s.FieldSelectors {
+		switch aws.ToString(f.Field) {
+		case "eventCategory":
+			category = containsString(f.Equals, "Data")
+		case "resources.type":
+			resourceType = containsString(f.Equals, cloudTrailS3ObjectType)
+		case "resources.ARN":
+			matched := false
+			for _, v := range f.StartsWith {
+				if strings.HasPrefix(dataResource, v) {
+					matched = true
+				}
+			}
+			if !matched {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return category && resourceType
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
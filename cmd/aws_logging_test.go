@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cloudtrailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+func Test_initLogBucket(t *testing.T) {
+	c := &mockS3ClientNewBucket{}
+	got, err := initLogBucket(c, "happy-log-bucket", "ap-northeast-1", "123456789012", nil, nil)
+	if err != nil {
+		t.Fatalf("initLogBucket() error = %v", err)
+	}
+
+	want := &initS3Result{
+		BucketName:        "happy-log-bucket",
+		BucketArn:         "arn:aws:s3:::happy-log-bucket",
+		Region:            "ap-northeast-1",
+		BlockPublicAccess: "Enabled",
+		ObjectOwnership:   "ACLs disabled",
+		Encryption:        "AES256",
+		Versioning:        "Enabled",
+		BucketPolicy:      "DenyInsecureTransport, AllowServerAccessLogs, AllowCloudTrailAclCheck, AllowCloudTrailWrite",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("initLogBucket() = %v, want %v", got, want)
+	}
+}
+
+func Test_initCloudTrail(t *testing.T) {
+	dataResource := "arn:aws:s3:::happy-bucket/"
+	existingTrail := &cloudtrailtypes.Trail{
+		Name:         aws.String("happy-trail"),
+		TrailARN:     aws.String("arn:aws:cloudtrail:ap-northeast-1:123456789012:trail/happy-trail"),
+		S3BucketName: aws.String("org-trail-bucket"),
+	}
+	tests := []struct {
+		name         string
+		c            *mockCloudTrailClient
+		logBucket    string
+		want         *initCloudTrailResult
+		wantCalls    []string
+		wantAdvanced bool
+		wantErr      bool
+	}{
+		{
+			name:      "S01: New trail",
+			c:         &mockCloudTrailClient{},
+			logBucket: "happy-log-bucket",
+			want: &initCloudTrailResult{
+				TrailName:  "happy-trail",
+				TrailArn:   "arn:aws:cloudtrail:ap-northeast-1:123456789012:trail/happy-trail",
+				LogBucket:  "happy-log-bucket",
+				DataEvents: dataResource,
+			},
+			wantCalls: []string{"CreateTrail", "StartLogging", "PutEventSelectors"},
+			wantErr:   false,
+		},
+		{
+			name: "S02: Existing trail with basic event selectors",
+			c: &mockCloudTrailClient{
+				trail:     existingTrail,
+				selectors: []cloudtrailtypes.EventSelector{{ReadWriteType: cloudtrailtypes.ReadWriteTypeAll, IncludeManagementEvents: aws.Bool(true)}},
+			},
+			want: &initCloudTrailResult{
+				TrailName:  "happy-trail",
+				TrailArn:   "arn:aws:cloudtrail:ap-northeast-1:123456789012:trail/happy-trail",
+				LogBucket:  "org-trail-bucket",
+				DataEvents: dataResource,
+			},
+			wantCalls: []string{"PutEventSelectors"},
+			wantErr:   false,
+		},
+		{
+			name: "S03: Existing trail with advanced event selectors",
+			c: &mockCloudTrailClient{
+				trail: existingTrail,
+				advanced: []cloudtrailtypes.AdvancedEventSelector{
+					{
+						Name:           aws.String("Management events"),
+						FieldSelectors: []cloudtrailtypes.AdvancedFieldSelector{{Field: aws.String("eventCategory"), Equals: []string{"Management"}}},
+					},
+				},
+			},
+			want: &initCloudTrailResult{
+				TrailName:  "happy-trail",
+				TrailArn:   "arn:aws:cloudtrail:ap-northeast-1:123456789012:trail/happy-trail",
+				LogBucket:  "org-trail-bucket",
+				DataEvents: dataResource,
+			},
+			wantCalls:    []string{"PutEventSelectors"},
+			wantAdvanced: true,
+			wantErr:      false,
+		},
+		{
+			name: "S04: Existing trail already logs data events of all buckets",
+			c: &mockCloudTrailClient{
+				trail: existingTrail,
+				selectors: []cloudtrailtypes.EventSelector{
+					{
+						ReadWriteType: cloudtrailtypes.ReadWriteTypeAll,
+						DataResources: []cloudtrailtypes.DataResource{{Type: aws.String("AWS::S3::Object"), Values: []string{"arn:aws:s3"}}},
+					},
+				},
+			},
+			want: &initCloudTrailResult{
+				TrailName:  "happy-trail",
+				TrailArn:   "arn:aws:cloudtrail:ap-northeast-1:123456789012:trail/happy-trail",
+				LogBucket:  "org-trail-bucket",
+				DataEvents: dataResource,
+			},
+			wantCalls: nil,
+			wantErr:   false,
+		},
+		{
+			name:    "F01: New trail without log bucket",
+			c:       &mockCloudTrailClient{},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := initCloudTrail(tt.c, "happy-trail", "happy-bucket", "ap-northeast-1", tt.logBucket, nil, &transaction{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initCloudTrail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initCloudTrail() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.c.calls, tt.wantCalls) {
+				t.Errorf("initCloudTrail() calls = %v, want %v", tt.c.calls, tt.wantCalls)
+			}
+			if !tt.wantErr && (len(tt.c.advanced) > 0) != tt.wantAdvanced {
+				t.Errorf("initCloudTrail() advanced selectors = %v, want advanced %v", tt.c.advanced, tt.wantAdvanced)
+			}
+		})
+	}
+}
+
+func Test_initCloudTrail_Rollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		c         *mockCloudTrailClient
+		wantCalls []string
+	}{
+		{
+			name:      "S01: New trail is deleted",
+			c:         &mockCloudTrailClient{},
+			wantCalls: []string{"CreateTrail", "StartLogging", "PutEventSelectors", "DeleteTrail"},
+		},
+		{
+			name: "S02: Event selectors of existing trail are restored",
+			c: &mockCloudTrailClient{
+				trail:     &cloudtrailtypes.Trail{Name: aws.String("happy-trail"), S3BucketName: aws.String("org-trail-bucket")},
+				selectors: []cloudtrailtypes.EventSelector{{ReadWriteType: cloudtrailtypes.ReadWriteTypeAll, IncludeManagementEvents: aws.Bool(true)}},
+			},
+			wantCalls: []string{"PutEventSelectors", "PutEventSelectors"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := tt.c.selectors
+			tx := &transaction{}
+			if _, err := initCloudTrail(tt.c, "happy-trail", "happy-bucket", "ap-northeast-1", "happy-log-bucket", nil, tx); err != nil {
+				t.Fatalf("initCloudTrail() error = %v", err)
+			}
+
+			if err := tx.rollback(); err != nil {
+				t.Errorf("transaction.rollback() error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.c.calls, tt.wantCalls) {
+				t.Errorf("transaction.rollback() calls = %v, want %v", tt.c.calls, tt.wantCalls)
+			}
+			if tt.c.trail != nil && !reflect.DeepEqual(tt.c.selectors, previous) {
+				t.Errorf("transaction.rollback() selectors = %v, want %v", tt.c.selectors, previous)
+			}
+		})
+	}
+}
+
+func Test_initCloudTrail_PutEventSelectorsFailure(t *testing.T) {
+	c := &mockCloudTrailClient{putErr: errors.New("some error")}
+	tx := &transaction{}
+	if _, err := initCloudTrail(c, "happy-trail", "happy-bucket", "ap-northeast-1", "happy-log-bucket", nil, tx); err == nil {
+		t.Fatalf("initCloudTrail() error = nil, want error")
+	}
+
+	if err := tx.rollback(); err != nil {
+		t.Errorf("transaction.rollback() error = %v", err)
+	}
+	if c.trail != nil {
+		t.Errorf("transaction.rollback() trail = %v, want deleted", c.trail)
+	}
+}
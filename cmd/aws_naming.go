@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// s3BucketNameReservedPrefixes and s3BucketNameReservedSuffixes are reserved by S3 for access point aliases,
+// directory buckets, table buckets and so on. Bucket names with them are rejected by CreateBucket.
+var (
+	s3BucketNameReservedPrefixes = []string{"xn--", "sthree-", "amzn-s3-demo-"}
+	s3BucketNameReservedSuffixes = []string{"-s3alias", "--ol-s3", ".mrap", "--x-s3", "--table-s3"}
+)
+
+var ipAddressPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+$`)
+
+func NewCmdAwsValidateName() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate-name",
+		Short: "Validate S3 bucket and DynamoDB table names without calling AWS.",
+		Long: `Validate S3 bucket and DynamoDB table names without calling AWS.
+
+The names given by --s3, --log-bucket, --replica-bucket and --dynamodb are checked
+against the naming rules of S3 general purpose buckets and DynamoDB tables.
+Unlike the other commands, --s3 is optional, so that a table name can be checked alone.
+Each name results in VALID or INVALID with the rule it breaks, and the command
+exits with non-zero status if any name is invalid, so that naming pipelines
+can check generated names before running tfbackend.
+
+S3 bucket
+- 3 to 63 characters long
+- Only lowercase letters, numbers, dots (.) and hyphens (-)
+- Begins and ends with a letter or number
+- No two adjacent dots, and not formatted as an IP address
+- Not prefixed with 'xn--', 'sthree-' or 'amzn-s3-demo-'
+- Not suffixed with '-s3alias', '--ol-s3', '.mrap', '--x-s3' or '--table-s3'
+
+DynamoDB table
+- 3 to 255 characters long
+- Only letters, numbers, underscores (_), hyphens (-) and dots (.)
+`,
+		SilenceUsage: true,
+		RunE:         runCmdAwsValidateName,
+	}
+
+	// Local flags shadow the persistent ones of the parent, which makes --s3 optional.
+	cmd.Flags().StringVarP(&bucketName, "s3", "", "", "Name of S3 bucket to validate.")
+	cmd.Flags().StringVarP(&tableName, "dynamodb", "", "", "Name of DynamoDB table to validate.")
+
+	return cmd
+}
+
+func runCmdAwsValidateName(cmd *cobra.Command, args []string) error {
+	type target struct {
+		kind     string
+		name     string
+		validate func(string) error
+	}
+	var targets []target
+	if bucketName != "" {
+		targets = append(targets, target{"s3 bucket", bucketName, validateS3BucketName})
+	}
+	if logBucketName != "" {
+		targets = append(targets, target{"log bucket", logBucketName, validateS3BucketName})
+	}
+	if replicaBucketName != "" {
+		targets = append(targets, target{"replica bucket", replicaBucketName, validateS3BucketName})
+	}
+	if tableName != "" {
+		targets = append(targets, target{"dynamodb table", tableName, validateDynamoDBTableName})
+	}
+
+	if len(targets) == 0 {
+		return fmt.Errorf("no name to validate. Specify at least one of --s3, --log-bucket, --replica-bucket and --dynamodb")
+	}
+
+	invalid := 0
+	for _, t := range targets {
+		if err := t.validate(t.name); err != nil {
+			invalid++
+			fmt.Fprintf(cmd.OutOrStdout(), "INVALID %v %v: %v\n", t.kind, t.name, err)
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "VALID   %v %v\n", t.kind, t.name)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%v of %v name(s) are invalid", invalid, len(targets))
+	}
+	return nil
+}
+
+// validateAwsNames validates --s3 and --dynamodb before any API call.
+func validateAwsNames() error {
+	if err := validateS3BucketName(bucketName); err != nil {
+		return fmt.Errorf("invalid bucket name %v: %w", bucketName, err)
+	}
+	if tableName != "" {
+		if err := validateDynamoDBTableName(tableName); err != nil {
+			return fmt.Errorf("invalid dynamodb table name %v: %w", tableName, err)
+		}
+	}
+	return nil
+}
+
+// validateS3BucketName checks the naming rules of S3 general purpose buckets, and returns the rule which the name breaks.
+func validateS3BucketName(name string) error {
+	if len(name) < 3 || len(name) > 63 {
+		return fmt.Errorf("must be between 3 and 63 characters long, but %v characters", len(name))
+	}
+	for i, r := range name {
+		if !isLowerAlphanumeric(r) && r != '.' && r != '-' {
+			return fmt.Errorf("must consist of lowercase letters, numbers, dots and hyphens, but contains %q at position %v", r, i+1)
+		}
+	}
+	if !isLowerAlphanumeric(rune(name[0])) {
+		return fmt.Errorf("must begin with a lowercase letter or number")
+	}
+	if !isLowerAlphanumeric(rune(name[len(name)-1])) {
+		return fmt.Errorf("must end with a lowercase letter or number")
+	}
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("must not contain two adjacent dots")
+	}
+	if ipAddressPattern.MatchString(name) {
+		return fmt.Errorf("must not be formatted as an IP address")
+	}
+	for _, p := range s3BucketNameReservedPrefixes {
+		if strings.HasPrefix(name, p) {
+			return fmt.Errorf("must not start with the reserved prefix %q", p)
+		}
+	}
+	for _, s := range s3BucketNameReservedSuffixes {
+		if strings.HasSuffix(name, s) {
+			return fmt.Errorf("must not end with the reserved suffix %q", s)
+		}
+	}
+	return nil
+}
+
+// validateDynamoDBTableName checks the naming rules of DynamoDB tables, and returns the rule which the name breaks.
+func validateDynamoDBTableName(name string) error {
+	if len(name) < 3 || len(name) > 255 {
+		return fmt.Errorf("must be between 3 and 255 characters long, but %v characters", len(name))
+	}
+	for i, r := range name {
+		if !isLowerAlphanumeric(r) && !('A' <= r && r <= 'Z') && r != '_' && r != '-' && r != '.' {
+			return fmt.Errorf("must consist of letters, numbers, underscores, hyphens and dots, but contains %q at position %v", r, i+1)
+		}
+	}
+	return nil
+}
+
+func isLowerAlphanumeric(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('0' <= r && r <= '9')
+}
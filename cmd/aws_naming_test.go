@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_validateS3BucketName(t *testing.T) {
+	tests := []struct {
+		name       string
+		bucketName string
+		wantErr    string
+	}{
+		{
+			name:       "S01: Happy path",
+			bucketName: "success-bucket",
+		},
+		{
+			name:       "S02: Dots and numbers",
+			bucketName: "123.success.bucket",
+		},
+		{
+			name:       "S03: 3 characters",
+			bucketName: "abc",
+		},
+		{
+			name:       "S04: 63 characters",
+			bucketName: strings.Repeat("a", 63),
+		},
+		{
+			name:       "F01: Too short",
+			bucketName: "ab",
+			wantErr:    "between 3 and 63 characters",
+		},
+		{
+			name:       "F02: Too long",
+			bucketName: strings.Repeat("a", 64),
+			wantErr:    "between 3 and 63 characters",
+		},
+		{
+			name:       "F03: Contains capitals",
+			bucketName: "FAILURE-BUCKET",
+			wantErr:    "contains 'F' at position 1",
+		},
+		{
+			name:       "F04: Contains underscore",
+			bucketName: "failure_bucket",
+			wantErr:    "contains '_' at position 8",
+		},
+		{
+			name:       "F05: Leading dot",
+			bucketName: ".failure-bucket",
+			wantErr:    "must begin with",
+		},
+		{
+			name:       "F06: Trailing hyphen",
+			bucketName: "failure-bucket-",
+			wantErr:    "must end with",
+		},
+		{
+			name:       "F07: Consecutive dots",
+			bucketName: "failure..bucket",
+			wantErr:    "two adjacent dots",
+		},
+		{
+			name:       "F08: IP address",
+			bucketName: "192.168.5.4",
+			wantErr:    "IP address",
+		},
+		{
+			name:       "F09: xn-- prefix",
+			bucketName: "xn--failure-bucket",
+			wantErr:    `reserved prefix "xn--"`,
+		},
+		{
+			name:       "F10: -s3alias suffix",
+			bucketName: "failure-bucket-s3alias",
+			wantErr:    `reserved suffix "-s3alias"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateS3BucketName(tt.bucketName)
+			if (err != nil) != (tt.wantErr != "") {
+				t.Fatalf("validateS3BucketName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateS3BucketName() error = %v, want it to contain %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_validateDynamoDBTableName(t *testing.T) {
+	tests := []struct {
+		name      string
+		tableName string
+		wantErr   string
+	}{
+		{
+			name:      "S01: Happy path",
+			tableName: "Terraform_Lock-Table.v1",
+		},
+		{
+			name:      "S02: 255 characters",
+			tableName: strings.Repeat("a", 255),
+		},
+		{
+			name:      "F01: Too short",
+			tableName: "tf",
+			wantErr:   "between 3 and 255 characters",
+		},
+		{
+			name:      "F02: Too long",
+			tableName: strings.Repeat("a", 256),
+			wantErr:   "between 3 and 255 characters",
+		},
+		{
+			name:      "F03: Contains slash",
+			tableName: "terraform/lock",
+			wantErr:   "contains '/' at position 10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDynamoDBTableName(tt.tableName)
+			if (err != nil) != (tt.wantErr != "") {
+				t.Fatalf("validateDynamoDBTableName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateDynamoDBTableName() error = %v, want it to contain %v", err, tt.wantErr)
+			}
+		})
+	}
+}
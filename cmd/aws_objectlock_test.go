@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func Test_objectLockSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *s3types.ObjectLockConfiguration
+		want string
+	}{
+		{
+			name: "S01: Not enabled",
+			cfg:  nil,
+			want: "Disabled",
+		},
+		{
+			name: "S02: No default retention",
+			cfg:  &s3types.ObjectLockConfiguration{ObjectLockEnabled: s3types.ObjectLockEnabledEnabled},
+			want: "Enabled (no default retention)",
+		},
+		{
+			name: "S03: Retention in days",
+			cfg:  buildObjectLockConfiguration(objectLockOption{Mode: s3types.ObjectLockRetentionModeGovernance, Days: 30}),
+			want: "GOVERNANCE 30 days",
+		},
+		{
+			name: "S04: Retention in years set outside tfbackend",
+			cfg: &s3types.ObjectLockConfiguration{
+				ObjectLockEnabled: s3types.ObjectLockEnabledEnabled,
+				Rule: &s3types.ObjectLockRule{
+					DefaultRetention: &s3types.DefaultRetention{Mode: s3types.ObjectLockRetentionModeCompliance, Years: 7},
+				},
+			},
+			want: "COMPLIANCE 7 years",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := objectLockSummary(tt.cfg); got != tt.want {
+				t.Errorf("objectLockSummary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,768 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// knownAfterApply is shown as the desired value which is decided only when resources are created.
+const knownAfterApply = "(known after apply)"
+
+// checkedOnApply is shown for the lockfile support, because conditional writes can be checked only by writing an object.
+const checkedOnApply = "(checked on apply)"
+
+// planAction represents what tfbackend will do to the resource.
+type planAction string
+
+const (
+	planActionCreate planAction = "create"
+	planActionUpdate planAction = "update"
+	planActionNoop   planAction = "no-op"
+)
+
+type planAttribute struct {
+	Name    string
+	Current string
+	Desired string
+}
+
+func (a planAttribute) changed() bool {
+	return a.Current != a.Desired
+}
+
+type planResource struct {
+	Type       string
+	Name       string
+	Exists     bool
+	Attributes []planAttribute
+}
+
+// action returns planActionCreate if the resource doesn't exist,
+// otherwise planActionUpdate if any attribute differs from the desired one.
+func (p *planResource) action() planAction {
+	if !p.Exists {
+		return planActionCreate
+	}
+	for _, a := range p.Attributes {
+		if a.changed() {
+			return planActionUpdate
+		}
+	}
+	return planActionNoop
+}
+
+func NewCmdAwsPlan() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the changes to S3 bucket and DynamoDB table without touching them.",
+		Long: `Show the changes to S3 bucket and DynamoDB table without touching them.
+
+Only read-only APIs are called. The output shows the difference between
+the current configuration and the configuration which 'tfbackend aws' applies.
+`,
+		SilenceUsage: true,
+		RunE:         runCmdAwsPlan,
+	}
+
+	return cmd
+}
+
+func runCmdAwsPlan(cmd *cobra.Command, args []string) error {
+	// Validation
+	if err := validateAwsFlags(); err != nil {
+		return err
+	}
+	tags, err := newAwsTags()
+	if err != nil {
+		return err
+	}
+
+	// Load config
+	cfg, err := loadAwsConfig()
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	if replicaRegion != "" && replicaRegion == cfg.Region {
+		return fmt.Errorf("--replica-region must be different from the region of the state bucket: %v", replicaRegion)
+	}
+
+	var resources []*planResource
+
+	// Plan KMS key.
+	s3Opt := initS3Option{S3Compatible: s3Compatible, Tags: tags, Lifecycle: newLifecycleOption(), Lockfile: useLockfile, ObjectLock: newObjectLockOption()}
+	if kmsKeyID != "" {
+		arn, err := resolveKMSKeyArn(kms.NewFromConfig(cfg), kmsKeyID)
+		if err != nil {
+			return fmt.Errorf("failed to describe kms key: %w", err)
+		}
+		s3Opt.KMSKeyID = arn
+	}
+	if s3Opt.BucketPolicy, err = newBucketPolicyOption(sts.NewFromConfig(cfg)); err != nil {
+		return err
+	}
+	if newKMSKey {
+		alias := kmsKeyAlias
+		if alias == "" {
+			alias = defaultKMSKeyAlias(bucketName)
+		}
+		resources = append(resources, planKMS(alias))
+		s3Opt.KMSKeyID = knownAfterApply
+	}
+
+	// Plan log bucket.
+	if logBucketName != "" {
+		identity, err := getCallerIdentity(context.TODO(), sts.NewFromConfig(cfg))
+		if err != nil {
+			return fmt.Errorf("failed to get caller identity: %w", err)
+		}
+		logPlan, err := planS3(newS3Client(cfg), logBucketName, cfg.Region, logBucketOption(aws.ToString(identity.Account), tags))
+		if err != nil {
+			return fmt.Errorf("failed to plan log bucket: %w", err)
+		}
+		resources = append(resources, logPlan)
+		s3Opt.AccessLogging = newAccessLogging(logBucketName, accessLogPrefix, bucketName)
+	}
+
+	// Plan replication role and replica bucket.
+	if replicaRegion != "" {
+		replicaCfg := cfg.Copy()
+		replicaCfg.Region = replicaRegion
+		replication := replicationOption{ReplicaBucket: newReplicaBucketName(), ReplicaRegion: replicaRegion}
+		if replicaKMSKeyID != "" {
+			arn, err := resolveKMSKeyArn(kms.NewFromConfig(replicaCfg), replicaKMSKeyID)
+			if err != nil {
+				return fmt.Errorf("failed to describe kms key: %w", err)
+			}
+			replication.ReplicaKMSKeyID = arn
+		}
+		identity, err := getCallerIdentity(context.TODO(), sts.NewFromConfig(cfg))
+		if err != nil {
+			return fmt.Errorf("failed to get caller identity: %w", err)
+		}
+
+		roleName := newReplicationRoleName()
+		rolePolicy, err := renderReplicationRolePolicy(bucketName, replication.ReplicaBucket, cfg.Region, s3Opt.KMSKeyID, replication.ReplicaKMSKeyID)
+		if err != nil {
+			return err
+		}
+		rolePlan, roleArn, err := planReplicationRole(iam.NewFromConfig(cfg), roleName, rolePolicy, replicationRoleArn(cfg.Region, aws.ToString(identity.Account), roleName))
+		if err != nil {
+			return fmt.Errorf("failed to plan iam role for replication: %w", err)
+		}
+		resources = append(resources, rolePlan)
+		replication.RoleArn = roleArn
+		allowReplicationRole(s3Opt.BucketPolicy, roleArn)
+
+		replicaOpt := replicaBucketOption(replication.ReplicaKMSKeyID, tags, s3Opt.BucketPolicy, s3Opt.Lifecycle)
+		replicaPlan, err := planS3(newS3Client(replicaCfg), replication.ReplicaBucket, replicaRegion, replicaOpt)
+		if err != nil {
+			return fmt.Errorf("failed to plan replica bucket: %w", err)
+		}
+		resources = append(resources, replicaPlan)
+		s3Opt.Replication = &replication
+	}
+
+	// Plan S3 bucket.
+	s3Plan, err := planS3(newS3Client(cfg), bucketName, cfg.Region, s3Opt)
+	if err != nil {
+		return fmt.Errorf("failed to plan s3 bucket: %w", err)
+	}
+	resources = append(resources, s3Plan)
+
+	// Plan CloudTrail trail.
+	if cloudTrailName != "" {
+		trailPlan, err := planCloudTrail(cloudtrail.NewFromConfig(cfg), cloudTrailName, stateDataResource(bucketName, cfg.Region), logBucketName)
+		if err != nil {
+			return fmt.Errorf("failed to plan cloudtrail trail: %w", err)
+		}
+		resources = append(resources, trailPlan)
+	}
+
+	// Plan DynamoDB table.
+	if tableName != "" {
+		dynamoOpt := newInitDynamoDBOption()
+		dynamoOpt.Tags = tags
+		if tableKMSKeyID != "" {
+			arn, err := resolveKMSKeyArn(kms.NewFromConfig(cfg), tableKMSKeyID)
+			if err != nil {
+				return fmt.Errorf("failed to describe kms key: %w", err)
+			}
+			dynamoOpt.KMSKeyID = arn
+		}
+		dynamoPlan, err := planDynamoDB(dynamodb.NewFromConfig(cfg), tableName, billingMode, dynamoOpt)
+		if err != nil {
+			return fmt.Errorf("failed to plan dynamodb table: %w", err)
+		}
+		if dynamoOpt.AutoScaling != nil {
+			attrs, err := planDynamoDBAutoScaling(applicationautoscaling.NewFromConfig(cfg), tableName, *dynamoOpt.AutoScaling)
+			if err != nil {
+				return fmt.Errorf("failed to plan auto scaling of dynamodb table: %w", err)
+			}
+			dynamoPlan.Attributes = append(dynamoPlan.Attributes, attrs...)
+		}
+		resources = append(resources, dynamoPlan)
+	}
+
+	printPlan(os.Stdout, resources)
+
+	if s3Opt.BucketPolicy != nil {
+		policy, err := renderBucketPolicy(s3BucketArn(bucketName, cfg.Region), s3Opt.KMSKeyID, *s3Opt.BucketPolicy)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "\nBucket policy statements managed by tfbackend:\n%v", policy)
+	}
+	return nil
+}
+
+// planKMS returns the plan of the kms key which is always newly created.
+func planKMS(aliasName string) *planResource {
+	return &planResource{
+		Type:   "kms_key",
+		Name:   aliasName,
+		Exists: false,
+		Attributes: []planAttribute{
+			{Name: "alias", Desired: aliasName},
+			{Name: "key_rotation", Desired: "Enabled"},
+		},
+	}
+}
+
+// planS3 compares the current configuration of the bucket with the desired one.
+func planS3(c S3Clientable, bucketName string, region string, opt initS3Option) (*planResource, error) {
+	res := planResource{
+		Type: "s3_bucket",
+		Name: bucketName,
+	}
+
+	exists, err := s3BucketExists(context.TODO(), c, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existence of s3 bucket: %w", err)
+	}
+	res.Exists = exists
+
+	desiredEncryption := string(s3types.ServerSideEncryptionAes256)
+	if opt.KMSKeyID != "" {
+		desiredEncryption = string(s3types.ServerSideEncryptionAwsKms)
+	}
+
+	desiredBlockPublicAccess, desiredObjectOwnership := "Enabled", "ACLs disabled"
+	if opt.S3Compatible {
+		desiredBlockPublicAccess, desiredObjectOwnership = "Skipped", "Skipped"
+	}
+
+	var desiredPolicy string
+	if opt.BucketPolicy != nil {
+		desiredPolicy, err = renderBucketPolicy(s3BucketArn(bucketName, region), opt.KMSKeyID, *opt.BucketPolicy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !exists {
+		res.Attributes = []planAttribute{
+			{Name: "region", Desired: region},
+			{Name: "block_public_access", Desired: desiredBlockPublicAccess},
+			{Name: "object_ownership", Desired: desiredObjectOwnership},
+			{Name: "encryption", Desired: desiredEncryption},
+		}
+		if opt.KMSKeyID != "" {
+			res.Attributes = append(res.Attributes,
+				planAttribute{Name: "kms_key_id", Desired: opt.KMSKeyID},
+				planAttribute{Name: "bucket_key", Desired: "Enabled"},
+			)
+		}
+		res.Attributes = append(res.Attributes, planAttribute{Name: "versioning", Desired: string(s3types.BucketVersioningStatusEnabled)})
+		if opt.ObjectLock != nil {
+			res.Attributes = append(res.Attributes, planAttribute{Name: "object_lock", Desired: objectLockSummary(buildObjectLockConfiguration(*opt.ObjectLock))})
+		}
+		if opt.Lifecycle.enabled() {
+			res.Attributes = append(res.Attributes, planAttribute{Name: "lifecycle", Desired: lifecycleSummary(buildLifecycleRule(opt.Lifecycle))})
+		}
+		if opt.Replication != nil {
+			res.Attributes = append(res.Attributes, planAttribute{Name: "replication", Desired: replicationSummary(buildReplicationRule(*opt.Replication, 0))})
+		}
+		if opt.AccessLogging != nil {
+			res.Attributes = append(res.Attributes, planAttribute{Name: "access_logging", Desired: formatLoggingTarget(opt.AccessLogging)})
+		}
+		if desiredPolicy != "" {
+			names, err := bucketPolicyStatementNames(desiredPolicy)
+			if err != nil {
+				return nil, err
+			}
+			res.Attributes = append(res.Attributes, planAttribute{Name: "bucket_policy", Desired: names})
+		}
+		if len(opt.Tags) > 0 {
+			res.Attributes = append(res.Attributes, planAttribute{Name: "tags", Desired: formatTags(opt.Tags)})
+		}
+		if opt.Lockfile {
+			res.Attributes = append(res.Attributes, planAttribute{Name: "lockfile", Desired: checkedOnApply})
+		}
+		return &res, nil
+	}
+
+	locationRes, err := getBucketLocation(context.TODO(), c, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket location: %w", err)
+	}
+	res.Attributes = append(res.Attributes, planAttribute{
+		Name:    "region",
+		Current: string(locationRes.LocationConstraint),
+		Desired: string(locationRes.LocationConstraint),
+	})
+
+	blockPublicAccess := "Skipped"
+	if !opt.S3Compatible {
+		blockPublicAccess = "Not configured"
+		blockRes, err := getPublicAccessBlock(context.TODO(), c, bucketName)
+		if err != nil && !isAPIErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+			return nil, fmt.Errorf("failed to get block public access status: %w", err)
+		}
+		if err == nil {
+			blockPublicAccess = publicAccessBlockStatus(blockRes.PublicAccessBlockConfiguration)
+		}
+	}
+	res.Attributes = append(res.Attributes, planAttribute{Name: "block_public_access", Current: blockPublicAccess, Desired: desiredBlockPublicAccess})
+
+	objectOwnership := "Skipped"
+	if !opt.S3Compatible {
+		ownership, err := getBucketOwnershipControls(context.TODO(), c, bucketName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ownership controls: %w", err)
+		}
+		objectOwnership = objectOwnershipStatus(ownership)
+	}
+	res.Attributes = append(res.Attributes, planAttribute{Name: "object_ownership", Current: objectOwnership, Desired: desiredObjectOwnership})
+
+	encryption, currentKMSKeyID, bucketKey := "Not configured", "", ""
+	encryptionRes, err := getBucketEncryption(context.TODO(), c, bucketName)
+	if err != nil && !isAPIErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") && !(opt.S3Compatible && isNotImplemented(err)) {
+		return nil, fmt.Errorf("failed to get bucket encryption status: %w", err)
+	}
+	if err == nil && len(encryptionRes.ServerSideEncryptionConfiguration.Rules) > 0 {
+		if rule := encryptionRes.ServerSideEncryptionConfiguration.Rules[0]; rule.ApplyServerSideEncryptionByDefault != nil {
+			encryption = string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+			if rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID != nil {
+				currentKMSKeyID = *rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID
+			}
+			if rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm == s3types.ServerSideEncryptionAwsKms {
+				bucketKey = "Disabled"
+				if rule.BucketKeyEnabled {
+					bucketKey = "Enabled"
+				}
+			}
+		}
+	}
+	res.Attributes = append(res.Attributes, planAttribute{Name: "encryption", Current: encryption, Desired: desiredEncryption})
+	if opt.KMSKeyID != "" || currentKMSKeyID != "" {
+		desiredBucketKey := ""
+		if opt.KMSKeyID != "" {
+			desiredBucketKey = "Enabled"
+		}
+		res.Attributes = append(res.Attributes,
+			planAttribute{Name: "kms_key_id", Current: currentKMSKeyID, Desired: opt.KMSKeyID},
+			planAttribute{Name: "bucket_key", Current: bucketKey, Desired: desiredBucketKey},
+		)
+	}
+
+	versioningRes, err := getBucketVersioning(context.TODO(), c, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket versioning status: %w", err)
+	}
+	versioning := string(versioningRes.Status)
+	if versioning == "" {
+		versioning = "Not configured"
+	}
+	res.Attributes = append(res.Attributes, planAttribute{Name: "versioning", Current: versioning, Desired: string(s3types.BucketVersioningStatusEnabled)})
+
+	// Object Lock can't be enabled on the existing bucket, so the plan fails like apply does.
+	if opt.ObjectLock != nil {
+		lockCfg, err := getObjectLockConfiguration(context.TODO(), c, bucketName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get object lock configuration: %w", err)
+		}
+		if !isObjectLockConfigurationEnabled(lockCfg) {
+			return nil, fmt.Errorf("object lock of s3 bucket %v is not enabled. Object Lock can be enabled only when the bucket is created", bucketName)
+		}
+		res.Attributes = append(res.Attributes, planAttribute{Name: "object_lock", Current: objectLockSummary(lockCfg), Desired: objectLockSummary(buildObjectLockConfiguration(*opt.ObjectLock))})
+	}
+
+	// Lifecycle rules of the bucket which aren't managed by tfbackend are kept.
+	if opt.Lifecycle.enabled() {
+		lifecycle := "Not configured"
+		rules, err := getBucketLifecycleRules(context.TODO(), c, bucketName)
+		if err != nil && !(opt.S3Compatible && isNotImplemented(err)) {
+			return nil, fmt.Errorf("failed to get bucket lifecycle configuration: %w", err)
+		}
+		if rule := findLifecycleRule(rules); rule != nil {
+			lifecycle = lifecycleSummary(*rule)
+		}
+		res.Attributes = append(res.Attributes, planAttribute{Name: "lifecycle", Current: lifecycle, Desired: lifecycleSummary(buildLifecycleRule(opt.Lifecycle))})
+	}
+
+	if opt.Replication != nil {
+		attr, err := planBucketReplication(c, bucketName, *opt.Replication)
+		if err != nil {
+			return nil, err
+		}
+		res.Attributes = append(res.Attributes, attr)
+	}
+
+	if opt.AccessLogging != nil {
+		loggingRes, err := getBucketLogging(context.TODO(), c, bucketName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get bucket logging: %w", err)
+		}
+		res.Attributes = append(res.Attributes, planAttribute{Name: "access_logging", Current: formatLoggingTarget(loggingRes.LoggingEnabled), Desired: formatLoggingTarget(opt.AccessLogging)})
+	}
+
+	if desiredPolicy != "" {
+		attr, err := planBucketPolicy(c, bucketName, desiredPolicy, opt.S3Compatible)
+		if err != nil {
+			return nil, err
+		}
+		res.Attributes = append(res.Attributes, attr)
+	}
+
+	// Tags of the bucket which aren't specified are kept.
+	if len(opt.Tags) > 0 {
+		tags, err := getBucketTags(context.TODO(), c, bucketName)
+		if err != nil && !(opt.S3Compatible && isNotImplemented(err)) {
+			return nil, fmt.Errorf("failed to get bucket tagging: %w", err)
+		}
+		res.Attributes = append(res.Attributes, planAttribute{Name: "tags", Current: formatTags(tags), Desired: formatTags(mergeTags(tags, opt.Tags))})
+	}
+
+	if opt.Lockfile {
+		res.Attributes = append(res.Attributes, planAttribute{Name: "lockfile", Current: checkedOnApply, Desired: checkedOnApply})
+	}
+
+	return &res, nil
+}
+
+// planBucketPolicy compares the statements managed by tfbackend in the current bucket policy with the desired ones.
+// Statements are shown by their names, and "(outdated)" is added if the statements of the same names differ.
+func planBucketPolicy(c S3Clientable, bucketName string, desired string, s3Compatible bool) (planAttribute, error) {
+	attr := planAttribute{Name: "bucket_policy", Current: "Not configured"}
+
+	names, err := bucketPolicyStatementNames(desired)
+	if err != nil {
+		return attr, err
+	}
+	attr.Desired = names
+
+	policyRes, err := getBucketPolicy(context.TODO(), c, bucketName)
+	if err != nil && !isAPIErrorCode(err, "NoSuchBucketPolicy") && !(s3Compatible && isNotImplemented(err)) {
+		return attr, fmt.Errorf("failed to get bucket policy: %w", err)
+	}
+	if err != nil {
+		return attr, nil
+	}
+
+	current := aws.ToString(policyRes.Policy)
+	currentNames, err := bucketPolicyStatementNames(current)
+	if err != nil {
+		return attr, err
+	}
+	if currentNames != "" {
+		attr.Current = currentNames
+	}
+	applied, err := hasManagedBucketPolicy(current, desired)
+	if err != nil {
+		return attr, err
+	}
+	if !applied && currentNames == names {
+		attr.Current += " (outdated)"
+	}
+	return attr, nil
+}
+
+// planBucketReplication compares the replication rule managed by tfbackend with the desired one.
+// "(outdated)" is added if the rule is the same but S3 assumes the other role.
+func planBucketReplication(c S3Clientable, bucketName string, opt replicationOption) (planAttribute, error) {
+	attr := planAttribute{Name: "replication", Current: "Not configured", Desired: replicationSummary(buildReplicationRule(opt, 0))}
+
+	replication, err := getBucketReplication(context.TODO(), c, bucketName)
+	if err != nil {
+		return attr, fmt.Errorf("failed to get bucket replication: %w", err)
+	}
+	// The other rules assuming another role can't be kept, so the plan fails like apply does.
+	if _, err := mergeReplicationRules(replication, opt); err != nil {
+		return attr, err
+	}
+	if rule := findReplicationRule(replication); rule != nil {
+		attr.Current = replicationSummary(*rule)
+		if attr.Current == attr.Desired && !isReplicationApplied(replication, opt) {
+			attr.Current += " (outdated)"
+		}
+	}
+	return attr, nil
+}
+
+// planReplicationRole compares the inline policy of the replication role with the desired one, and returns
+// the ARN of the role. roleArn is returned for a new role, because the role created by tfbackend has no path.
+func planReplicationRole(c IAMClientable, roleName string, policy string, roleArn string) (*planResource, string, error) {
+	res := planResource{
+		Type: "iam_role",
+		Name: roleName,
+	}
+
+	roleRes, err := getRole(context.TODO(), c, roleName)
+	if err != nil && !isAPIErrorCode(err, "NoSuchEntity") {
+		return nil, "", fmt.Errorf("failed to check existence of iam role: %w", err)
+	}
+	res.Exists = err == nil
+
+	if !res.Exists {
+		res.Attributes = []planAttribute{
+			{Name: "assume_role_principal", Desired: "s3.amazonaws.com"},
+			{Name: "inline_policy", Desired: replicationPolicyName},
+		}
+		return &res, roleArn, nil
+	}
+
+	trustPolicy, err := decodePolicyDocument(aws.ToString(roleRes.Role.AssumeRolePolicyDocument))
+	if err != nil || !allowsS3AssumeRole(trustPolicy) {
+		return nil, "", fmt.Errorf("iam role %v exists, but s3.amazonaws.com is not allowed to assume it", roleName)
+	}
+
+	inlinePolicy := "Not configured"
+	current, err := getRolePolicyDocument(context.TODO(), c, roleName, replicationPolicyName)
+	if err != nil && !isAPIErrorCode(err, "NoSuchEntity") {
+		return nil, "", fmt.Errorf("failed to get inline policy of iam role: %w", err)
+	}
+	if err == nil {
+		inlinePolicy = replicationPolicyName
+		if !samePolicyDocument(current, policy) {
+			inlinePolicy += " (outdated)"
+		}
+	}
+	res.Attributes = []planAttribute{
+		{Name: "assume_role_principal", Current: "s3.amazonaws.com", Desired: "s3.amazonaws.com"},
+		{Name: "inline_policy", Current: inlinePolicy, Desired: replicationPolicyName},
+	}
+	return &res, aws.ToString(roleRes.Role.Arn), nil
+}
+
+// planCloudTrail compares the data events logged by the trail with the desired ones.
+// A new trail is created with logBucket, so an error is returned if the trail doesn't exist without logBucket.
+func planCloudTrail(c CloudTrailClientable, trailName string, dataResource string, logBucket string) (*planResource, error) {
+	res := planResource{
+		Type: "cloudtrail_trail",
+		Name: trailName,
+	}
+
+	trailRes, err := getTrail(context.TODO(), c, trailName)
+	if err != nil && !isAPIErrorCode(err, "TrailNotFoundException") {
+		return nil, fmt.Errorf("failed to check existence of cloudtrail trail: %w", err)
+	}
+	res.Exists = err == nil
+
+	if !res.Exists {
+		if logBucket == "" {
+			return nil, fmt.Errorf("cloudtrail trail %v is not found, and --log-bucket is needed to create it", trailName)
+		}
+		res.Attributes = []planAttribute{
+			{Name: "s3_bucket_name", Desired: logBucket},
+			{Name: "log_file_validation", Desired: "Enabled"},
+			{Name: "data_events", Desired: dataResource},
+		}
+		return &res, nil
+	}
+
+	selectorsRes, err := getEventSelectors(context.TODO(), c, trailName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event selectors: %w", err)
+	}
+	dataEvents := "Not configured"
+	if hasTrailDataEvents(selectorsRes.EventSelectors, selectorsRes.AdvancedEventSelectors, dataResource) {
+		dataEvents = dataResource
+	}
+	s3BucketName := aws.ToString(trailRes.Trail.S3BucketName)
+	res.Attributes = []planAttribute{
+		{Name: "s3_bucket_name", Current: s3BucketName, Desired: s3BucketName},
+		{Name: "data_events", Current: dataEvents, Desired: dataResource},
+	}
+	return &res, nil
+}
+
+// planDynamoDB compares the current configuration of the table with the desired one.
+func planDynamoDB(c DynamoDBClientable, tableName string, billingMode string, opt initDynamoDBOption) (*planResource, error) {
+	res := planResource{
+		Type: "dynamodb_table",
+		Name: tableName,
+	}
+
+	capacity := opt.Capacity
+	if capacity == (tableCapacity{}) {
+		capacity = defaultTableCapacity
+	}
+
+	desired := []planAttribute{
+		{Name: "hash_key", Desired: "LockID (S)"},
+		{Name: "billing_mode", Desired: billingMode},
+	}
+	if billingMode == "PROVISIONED" {
+		desired = append(desired,
+			planAttribute{Name: "write_capacity", Desired: strconv.FormatInt(capacity.Write, 10)},
+			planAttribute{Name: "read_capacity", Desired: strconv.FormatInt(capacity.Read, 10)},
+		)
+	}
+	if opt.PointInTimeRecovery {
+		desired = append(desired, planAttribute{Name: "point_in_time_recovery", Desired: "Enabled"})
+	}
+	if opt.KMSKeyID != "" {
+		desired = append(desired, planAttribute{Name: "kms_key_id", Desired: opt.KMSKeyID})
+	}
+	if len(opt.Tags) > 0 {
+		desired = append(desired, planAttribute{Name: "tags", Desired: formatTags(opt.Tags)})
+	}
+	if opt.DeletionProtection {
+		desired = append(desired, planAttribute{Name: "deletion_protection", Desired: "Enabled"})
+	}
+
+	desc, err := describeDynamoDBTable(context.TODO(), c, tableName)
+	if err != nil {
+		if isAPIErrorCode(err, "ResourceNotFoundException") {
+			res.Attributes = desired
+			return &res, nil
+		}
+		return nil, fmt.Errorf("failed to describe dynamodb table: %w", err)
+	}
+	res.Exists = true
+
+	if !hasLockIDKeySchema(desc.Table) {
+		return nil, fmt.Errorf("existing dynamodb table %v must have only 'LockID' (string) as hash key", tableName)
+	}
+
+	current := map[string]string{
+		"hash_key":     "LockID (S)",
+		"billing_mode": tableBillingMode(desc.Table),
+	}
+	if current["billing_mode"] == "PROVISIONED" && desc.Table.ProvisionedThroughput != nil {
+		currentCapacity := provisionedCapacity(desc.Table)
+		current["write_capacity"] = strconv.FormatInt(currentCapacity.Write, 10)
+		current["read_capacity"] = strconv.FormatInt(currentCapacity.Read, 10)
+	}
+	if opt.PointInTimeRecovery {
+		backupsRes, err := describeDynamoDBContinuousBackups(context.TODO(), c, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe continuous backups: %w", err)
+		}
+		current["point_in_time_recovery"] = enabledOrDisabled(isPointInTimeRecoveryEnabled(backupsRes.ContinuousBackupsDescription))
+	}
+	current["kms_key_id"] = tableKMSKeyArn(desc.Table)
+	current["deletion_protection"] = enabledOrDisabled(desc.Table.DeletionProtectionEnabled != nil && *desc.Table.DeletionProtectionEnabled)
+	var currentTags map[string]string
+	if len(opt.Tags) > 0 && desc.Table.TableArn != nil {
+		currentTags, err = listDynamoDBTableTags(context.TODO(), c, *desc.Table.TableArn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of dynamodb table: %w", err)
+		}
+		current["tags"] = formatTags(currentTags)
+	}
+	for _, a := range desired {
+		a.Current = current[a.Name]
+		// Capacity of the existing table with auto scaling is managed by Application Auto Scaling.
+		if opt.AutoScaling != nil && tableBillingMode(desc.Table) == billingMode && (a.Name == "write_capacity" || a.Name == "read_capacity") {
+			a.Desired = a.Current
+		}
+		// Tags of the table which aren't specified are kept.
+		if a.Name == "tags" {
+			a.Desired = formatTags(mergeTags(currentTags, opt.Tags))
+		}
+		res.Attributes = append(res.Attributes, a)
+	}
+
+	return &res, nil
+}
+
+// planDynamoDBAutoScaling compares the scalable targets and target tracking policies of read and write capacity with the desired ones.
+func planDynamoDBAutoScaling(c AutoScalingClientable, tableName string, opt autoScalingOption) ([]planAttribute, error) {
+	dimensions := []struct {
+		name      string
+		dimension autoscalingtypes.ScalableDimension
+	}{
+		{name: "read", dimension: autoscalingtypes.ScalableDimensionDynamoDBTableReadCapacityUnits},
+		{name: "write", dimension: autoscalingtypes.ScalableDimensionDynamoDBTableWriteCapacityUnits},
+	}
+
+	var attrs []planAttribute
+	for _, d := range dimensions {
+		capacity := planAttribute{Name: fmt.Sprintf("autoscaling_%v_capacity", d.name), Current: "Not configured", Desired: fmt.Sprintf("%v-%v", opt.MinCapacity, opt.MaxCapacity)}
+		target, err := describeDynamoDBScalableTarget(context.TODO(), c, tableName, d.dimension)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe scalable target of %v capacity: %w", d.name, err)
+		}
+		if target != nil && target.MinCapacity != nil && target.MaxCapacity != nil {
+			capacity.Current = fmt.Sprintf("%v-%v", *target.MinCapacity, *target.MaxCapacity)
+		}
+
+		utilization := planAttribute{Name: fmt.Sprintf("autoscaling_%v_target_utilization", d.name), Current: "Not configured", Desired: formatTargetUtilization(opt.TargetUtilization)}
+		policy, err := describeDynamoDBScalingPolicy(context.TODO(), c, tableName, d.dimension, dynamoDBScalingPolicyName(tableName, d.name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe scaling policy of %v capacity: %w", d.name, err)
+		}
+		if policy != nil && policy.TargetTrackingScalingPolicyConfiguration != nil && policy.TargetTrackingScalingPolicyConfiguration.TargetValue != nil {
+			utilization.Current = formatTargetUtilization(*policy.TargetTrackingScalingPolicyConfiguration.TargetValue)
+		}
+		attrs = append(attrs, capacity, utilization)
+	}
+	return attrs, nil
+}
+
+// printPlan prints the plan like terraform plan.
+func printPlan(w io.Writer, resources []*planResource) {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	var toCreate, toUpdate, unchanged int
+	fmt.Fprintf(w, "\ntfbackend will perform the following actions:\n")
+	for _, r := range resources {
+		fmt.Fprintf(w, "\n")
+		switch r.action() {
+		case planActionCreate:
+			toCreate++
+			fmt.Fprintf(w, "  # %v %q will be created\n", r.Type, r.Name)
+			fmt.Fprintf(w, "  %v %v %q {\n", green("+"), r.Type, r.Name)
+			for _, a := range r.Attributes {
+				fmt.Fprintf(w, "      %v %v = %q\n", green("+"), a.Name, a.Desired)
+			}
+		case planActionUpdate:
+			toUpdate++
+			fmt.Fprintf(w, "  # %v %q will be updated in-place\n", r.Type, r.Name)
+			fmt.Fprintf(w, "  %v %v %q {\n", yellow("~"), r.Type, r.Name)
+			for _, a := range r.Attributes {
+				if a.changed() {
+					fmt.Fprintf(w, "      %v %v = %q -> %q\n", yellow("~"), a.Name, a.Current, a.Desired)
+				} else {
+					fmt.Fprintf(w, "        %v = %q\n", a.Name, a.Current)
+				}
+			}
+		default:
+			unchanged++
+			fmt.Fprintf(w, "  # %v %q is up-to-date\n", r.Type, r.Name)
+			fmt.Fprintf(w, "    %v %q {\n", r.Type, r.Name)
+			for _, a := range r.Attributes {
+				fmt.Fprintf(w, "        %v = %q\n", a.Name, a.Current)
+			}
+		}
+		fmt.Fprintf(w, "    }\n")
+	}
+	fmt.Fprintf(w, "\nPlan: %v to create, %v to update, %v unchanged.\n", toCreate, toUpdate, unchanged)
+}
@@ -0,0 +1,866 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cloudtrailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func Test_planS3(t *testing.T) {
+	type args struct {
+		c          S3Clientable
+		bucketName string
+		region     string
+		opt        initS3Option
+	}
+	tests := []struct {
+		name       string
+		args       args
+		want       *planResource
+		wantAction planAction
+		wantErr    bool
+	}{
+		{
+			name: "S01: New bucket",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: false,
+				Attributes: []planAttribute{
+					{Name: "region", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Desired: "Enabled"},
+					{Name: "object_ownership", Desired: "ACLs disabled"},
+					{Name: "encryption", Desired: "AES256"},
+					{Name: "versioning", Desired: "Enabled"},
+				},
+			},
+			wantAction: planActionCreate,
+			wantErr:    false,
+		},
+		{
+			name: "S02: Existing bucket, nothing configured, SSE-KMS",
+			args: args{
+				c:          &mockS3ClientExistingBucket{},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					KMSKeyID: "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				},
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "region", Current: "ap-northeast-1", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Current: "Not configured", Desired: "Enabled"},
+					{Name: "object_ownership", Current: "Not configured", Desired: "ACLs disabled"},
+					{Name: "encryption", Current: "Not configured", Desired: "aws:kms"},
+					{Name: "kms_key_id", Current: "", Desired: "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key"},
+					{Name: "bucket_key", Current: "", Desired: "Enabled"},
+					{Name: "versioning", Current: "Not configured", Desired: "Enabled"},
+				},
+			},
+			wantAction: planActionUpdate,
+			wantErr:    false,
+		},
+		{
+			name: "S03: Existing bucket, already converged",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					publicAccessBlock: &s3types.PublicAccessBlockConfiguration{
+						BlockPublicAcls:       true,
+						BlockPublicPolicy:     true,
+						IgnorePublicAcls:      true,
+						RestrictPublicBuckets: true,
+					},
+					encryption: &s3types.ServerSideEncryptionConfiguration{
+						Rules: []s3types.ServerSideEncryptionRule{
+							{
+								ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{
+									SSEAlgorithm: s3types.ServerSideEncryptionAes256,
+								},
+							},
+						},
+					},
+					versioning: s3types.BucketVersioningStatusEnabled,
+					ownership:  &s3types.OwnershipControls{Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: s3types.ObjectOwnershipBucketOwnerEnforced}}},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "region", Current: "ap-northeast-1", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Current: "Enabled", Desired: "Enabled"},
+					{Name: "object_ownership", Current: "ACLs disabled", Desired: "ACLs disabled"},
+					{Name: "encryption", Current: "AES256", Desired: "AES256"},
+					{Name: "versioning", Current: "Enabled", Desired: "Enabled"},
+				},
+			},
+			wantAction: planActionNoop,
+			wantErr:    false,
+		},
+		{
+			name: "S04: Existing bucket, tags are merged with the existing ones",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					versioning: s3types.BucketVersioningStatusEnabled,
+					tags:       map[string]string{"Team": "infra"},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Tags: map[string]string{"ManagedBy": "tfbackend"},
+				},
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "region", Current: "ap-northeast-1", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Current: "Not configured", Desired: "Enabled"},
+					{Name: "object_ownership", Current: "Not configured", Desired: "ACLs disabled"},
+					{Name: "encryption", Current: "Not configured", Desired: "AES256"},
+					{Name: "versioning", Current: "Enabled", Desired: "Enabled"},
+					{Name: "tags", Current: "Team=infra", Desired: "ManagedBy=tfbackend, Team=infra"},
+				},
+			},
+			wantAction: planActionUpdate,
+			wantErr:    false,
+		},
+		{
+			name: "S05: New bucket, bucket policy",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					BucketPolicy: &bucketPolicyOption{PrincipalOrgID: "o-abcdefghij"},
+				},
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: false,
+				Attributes: []planAttribute{
+					{Name: "region", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Desired: "Enabled"},
+					{Name: "object_ownership", Desired: "ACLs disabled"},
+					{Name: "encryption", Desired: "AES256"},
+					{Name: "versioning", Desired: "Enabled"},
+					{Name: "bucket_policy", Desired: "DenyInsecureTransport, DenyOtherOrganizations"},
+				},
+			},
+			wantAction: planActionCreate,
+			wantErr:    false,
+		},
+		{
+			name: "S06: Existing bucket, bucket policy is outdated",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					versioning: s3types.BucketVersioningStatusEnabled,
+					policy:     aws.String(`{"Version":"2012-10-17","Statement":[{"Sid":"TfbackendDenyInsecureTransport","Effect":"Deny","Principal":"*","Action":"s3:*","Resource":"*","Condition":{"Bool":{"aws:SecureTransport":"false"}}}]}`),
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					BucketPolicy: &bucketPolicyOption{},
+				},
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "region", Current: "ap-northeast-1", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Current: "Not configured", Desired: "Enabled"},
+					{Name: "object_ownership", Current: "Not configured", Desired: "ACLs disabled"},
+					{Name: "encryption", Current: "Not configured", Desired: "AES256"},
+					{Name: "versioning", Current: "Enabled", Desired: "Enabled"},
+					{Name: "bucket_policy", Current: "DenyInsecureTransport (outdated)", Desired: "DenyInsecureTransport"},
+				},
+			},
+			wantAction: planActionUpdate,
+			wantErr:    false,
+		},
+		{
+			name: "S07: Existing bucket, lifecycle rule is changed",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					versioning: s3types.BucketVersioningStatusEnabled,
+					lifecycleRules: []s3types.LifecycleRule{
+						{
+							ID:                          aws.String(lifecycleRuleID),
+							Status:                      s3types.ExpirationStatusEnabled,
+							NoncurrentVersionExpiration: &s3types.NoncurrentVersionExpiration{NoncurrentDays: 30},
+						},
+					},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Lifecycle: lifecycleOption{NoncurrentVersionExpirationDays: 90},
+				},
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "region", Current: "ap-northeast-1", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Current: "Not configured", Desired: "Enabled"},
+					{Name: "object_ownership", Current: "Not configured", Desired: "ACLs disabled"},
+					{Name: "encryption", Current: "Not configured", Desired: "AES256"},
+					{Name: "versioning", Current: "Enabled", Desired: "Enabled"},
+					{Name: "lifecycle", Current: "Noncurrent versions expire after 30 days", Desired: "Noncurrent versions expire after 90 days"},
+				},
+			},
+			wantAction: planActionUpdate,
+			wantErr:    false,
+		},
+		{
+			name: "S08: Existing bucket, replication role is outdated",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					versioning: s3types.BucketVersioningStatusEnabled,
+					replication: &s3types.ReplicationConfiguration{
+						Role: aws.String("arn:aws:iam::123456789012:role/old-role"),
+						Rules: []s3types.ReplicationRule{buildReplicationRule(replicationOption{
+							ReplicaBucket: "happy-bucket-replica",
+							ReplicaRegion: "us-west-2",
+						}, 1)},
+					},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Replication: &replicationOption{
+						RoleArn:       "arn:aws:iam::123456789012:role/happy-role",
+						ReplicaBucket: "happy-bucket-replica",
+						ReplicaRegion: "us-west-2",
+					},
+				},
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "region", Current: "ap-northeast-1", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Current: "Not configured", Desired: "Enabled"},
+					{Name: "object_ownership", Current: "Not configured", Desired: "ACLs disabled"},
+					{Name: "encryption", Current: "Not configured", Desired: "AES256"},
+					{Name: "versioning", Current: "Enabled", Desired: "Enabled"},
+					{Name: "replication", Current: "arn:aws:s3:::happy-bucket-replica (delete markers) (outdated)", Desired: "arn:aws:s3:::happy-bucket-replica (delete markers)"},
+				},
+			},
+			wantAction: planActionUpdate,
+			wantErr:    false,
+		},
+		{
+			name: "S09: New bucket, Object Lock",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt:        initS3Option{ObjectLock: &objectLockOption{Mode: s3types.ObjectLockRetentionModeGovernance, Days: 30}},
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: false,
+				Attributes: []planAttribute{
+					{Name: "region", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Desired: "Enabled"},
+					{Name: "object_ownership", Desired: "ACLs disabled"},
+					{Name: "encryption", Desired: "AES256"},
+					{Name: "versioning", Desired: "Enabled"},
+					{Name: "object_lock", Desired: "GOVERNANCE 30 days"},
+				},
+			},
+			wantAction: planActionCreate,
+			wantErr:    false,
+		},
+		{
+			name: "S10: New bucket, lockfile",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt:        initS3Option{Lockfile: true},
+			},
+			want: &planResource{
+				Type:   "s3_bucket",
+				Name:   "happy-bucket",
+				Exists: false,
+				Attributes: []planAttribute{
+					{Name: "region", Desired: "ap-northeast-1"},
+					{Name: "block_public_access", Desired: "Enabled"},
+					{Name: "object_ownership", Desired: "ACLs disabled"},
+					{Name: "encryption", Desired: "AES256"},
+					{Name: "versioning", Desired: "Enabled"},
+					{Name: "lockfile", Desired: "(checked on apply)"},
+				},
+			},
+			wantAction: planActionCreate,
+			wantErr:    false,
+		},
+		{
+			name: "F01: HeadBucket fails",
+			args: args{
+				c:          mockS3ClientHeadBucketFailure{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F02: Existing bucket without Object Lock",
+			args: args{
+				c:          &mockS3ClientExistingBucket{versioning: s3types.BucketVersioningStatusEnabled},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt:        initS3Option{ObjectLock: &objectLockOption{Mode: s3types.ObjectLockRetentionModeGovernance, Days: 30}},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F03: Existing replication rule assumes another role",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					versioning: s3types.BucketVersioningStatusEnabled,
+					replication: &s3types.ReplicationConfiguration{
+						Role:  aws.String("arn:aws:iam::123456789012:role/other-role"),
+						Rules: []s3types.ReplicationRule{{ID: aws.String("other-rule"), Priority: 1, Status: s3types.ReplicationRuleStatusEnabled}},
+					},
+				},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Replication: &replicationOption{
+						RoleArn:       "arn:aws:iam::123456789012:role/happy-role",
+						ReplicaBucket: "error-bucket-replica",
+						ReplicaRegion: "us-west-2",
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planS3(tt.args.c, tt.args.bucketName, tt.args.region, tt.args.opt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("planS3() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planS3() = %v, want %v", got, tt.want)
+			}
+			if got != nil && got.action() != tt.wantAction {
+				t.Errorf("planS3().action() = %v, want %v", got.action(), tt.wantAction)
+			}
+			if m, ok := tt.args.c.(*mockS3ClientExistingBucket); ok && m.putCalls != 0 {
+				t.Errorf("planS3() put calls = %v, want 0", m.putCalls)
+			}
+		})
+	}
+}
+
+func Test_planCloudTrail(t *testing.T) {
+	dataResource := "arn:aws:s3:::happy-bucket/"
+	tests := []struct {
+		name       string
+		c          *mockCloudTrailClient
+		logBucket  string
+		want       *planResource
+		wantAction planAction
+		wantErr    bool
+	}{
+		{
+			name:      "S01: New trail",
+			c:         &mockCloudTrailClient{},
+			logBucket: "happy-log-bucket",
+			want: &planResource{
+				Type:   "cloudtrail_trail",
+				Name:   "happy-trail",
+				Exists: false,
+				Attributes: []planAttribute{
+					{Name: "s3_bucket_name", Desired: "happy-log-bucket"},
+					{Name: "log_file_validation", Desired: "Enabled"},
+					{Name: "data_events", Desired: dataResource},
+				},
+			},
+			wantAction: planActionCreate,
+			wantErr:    false,
+		},
+		{
+			name: "S02: Existing trail without data events",
+			c: &mockCloudTrailClient{
+				trail: &cloudtrailtypes.Trail{Name: aws.String("happy-trail"), S3BucketName: aws.String("org-trail-bucket")},
+			},
+			want: &planResource{
+				Type:   "cloudtrail_trail",
+				Name:   "happy-trail",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "s3_bucket_name", Current: "org-trail-bucket", Desired: "org-trail-bucket"},
+					{Name: "data_events", Current: "Not configured", Desired: dataResource},
+				},
+			},
+			wantAction: planActionUpdate,
+			wantErr:    false,
+		},
+		{
+			name:    "F01: New trail without log bucket",
+			c:       &mockCloudTrailClient{},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planCloudTrail(tt.c, "happy-trail", dataResource, tt.logBucket)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("planCloudTrail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planCloudTrail() = %v, want %v", got, tt.want)
+			}
+			if got != nil && got.action() != tt.wantAction {
+				t.Errorf("planCloudTrail().action() = %v, want %v", got.action(), tt.wantAction)
+			}
+			if len(tt.c.calls) != 0 {
+				t.Errorf("planCloudTrail() calls = %v, want none", tt.c.calls)
+			}
+		})
+	}
+}
+
+func Test_planDynamoDB(t *testing.T) {
+	type args struct {
+		c           *mockDynamoDBClient
+		tableName   string
+		billingMode string
+		opt         initDynamoDBOption
+	}
+	tests := []struct {
+		name       string
+		args       args
+		want       *planResource
+		wantAction planAction
+		wantErr    bool
+	}{
+		{
+			name: "S01: New table",
+			args: args{
+				c:           &mockDynamoDBClient{table: mockTablePayPerRequest()},
+				tableName:   "happy-table",
+				billingMode: "PROVISIONED",
+			},
+			want: &planResource{
+				Type:   "dynamodb_table",
+				Name:   "happy-table",
+				Exists: false,
+				Attributes: []planAttribute{
+					{Name: "hash_key", Desired: "LockID (S)"},
+					{Name: "billing_mode", Desired: "PROVISIONED"},
+					{Name: "write_capacity", Desired: "5"},
+					{Name: "read_capacity", Desired: "5"},
+				},
+			},
+			wantAction: planActionCreate,
+			wantErr:    false,
+		},
+		{
+			name: "S02: Existing table, billing mode differs",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTablePayPerRequest()},
+				tableName:   "happy-table",
+				billingMode: "PROVISIONED",
+			},
+			want: &planResource{
+				Type:   "dynamodb_table",
+				Name:   "happy-table",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "hash_key", Current: "LockID (S)", Desired: "LockID (S)"},
+					{Name: "billing_mode", Current: "PAY_PER_REQUEST", Desired: "PROVISIONED"},
+					{Name: "write_capacity", Current: "", Desired: "5"},
+					{Name: "read_capacity", Current: "", Desired: "5"},
+				},
+			},
+			wantAction: planActionUpdate,
+			wantErr:    false,
+		},
+		{
+			name: "S03: Existing table, already converged",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableProvisionedWrite5Read5()},
+				tableName:   "happy-table",
+				billingMode: "PROVISIONED",
+			},
+			want: &planResource{
+				Type:   "dynamodb_table",
+				Name:   "happy-table",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "hash_key", Current: "LockID (S)", Desired: "LockID (S)"},
+					{Name: "billing_mode", Current: "PROVISIONED", Desired: "PROVISIONED"},
+					{Name: "write_capacity", Current: "5", Desired: "5"},
+					{Name: "read_capacity", Current: "5", Desired: "5"},
+				},
+			},
+			wantAction: planActionNoop,
+			wantErr:    false,
+		},
+		{
+			name: "S04: Existing table, PITR, SSE-KMS and deletion protection are planned",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTablePayPerRequest()},
+				tableName:   "happy-table",
+				billingMode: "PAY_PER_REQUEST",
+				opt: initDynamoDBOption{
+					PointInTimeRecovery: true,
+					DeletionProtection:  true,
+					KMSKeyID:            "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				},
+			},
+			want: &planResource{
+				Type:   "dynamodb_table",
+				Name:   "happy-table",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "hash_key", Current: "LockID (S)", Desired: "LockID (S)"},
+					{Name: "billing_mode", Current: "PAY_PER_REQUEST", Desired: "PAY_PER_REQUEST"},
+					{Name: "point_in_time_recovery", Current: "Disabled", Desired: "Enabled"},
+					{Name: "kms_key_id", Current: "", Desired: "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key"},
+					{Name: "deletion_protection", Current: "Disabled", Desired: "Enabled"},
+				},
+			},
+			wantAction: planActionUpdate,
+			wantErr:    false,
+		},
+		{
+			name: "S05: New table, custom capacity",
+			args: args{
+				c:           &mockDynamoDBClient{table: mockTablePayPerRequest()},
+				tableName:   "happy-table",
+				billingMode: "PROVISIONED",
+				opt:         initDynamoDBOption{Capacity: tableCapacity{Read: 20, Write: 50}},
+			},
+			want: &planResource{
+				Type:   "dynamodb_table",
+				Name:   "happy-table",
+				Exists: false,
+				Attributes: []planAttribute{
+					{Name: "hash_key", Desired: "LockID (S)"},
+					{Name: "billing_mode", Desired: "PROVISIONED"},
+					{Name: "write_capacity", Desired: "50"},
+					{Name: "read_capacity", Desired: "20"},
+				},
+			},
+			wantAction: planActionCreate,
+			wantErr:    false,
+		},
+		{
+			name: "S06: Existing table with auto scaling, capacity is left to auto scaling",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableProvisionedWrite5Read5()},
+				tableName:   "happy-table",
+				billingMode: "PROVISIONED",
+				opt: initDynamoDBOption{
+					Capacity:    tableCapacity{Read: 20, Write: 50},
+					AutoScaling: &autoScalingOption{MinCapacity: 5, MaxCapacity: 200, TargetUtilization: 70},
+				},
+			},
+			want: &planResource{
+				Type:   "dynamodb_table",
+				Name:   "happy-table",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "hash_key", Current: "LockID (S)", Desired: "LockID (S)"},
+					{Name: "billing_mode", Current: "PROVISIONED", Desired: "PROVISIONED"},
+					{Name: "write_capacity", Current: "5", Desired: "5"},
+					{Name: "read_capacity", Current: "5", Desired: "5"},
+				},
+			},
+			wantAction: planActionNoop,
+			wantErr:    false,
+		},
+		{
+			name: "S07: Existing table, tags already applied",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableWithArn(), tags: map[string]string{"Team": "infra", "ManagedBy": "tfbackend"}},
+				tableName:   "happy-table",
+				billingMode: "PAY_PER_REQUEST",
+				opt:         initDynamoDBOption{Tags: map[string]string{"ManagedBy": "tfbackend"}},
+			},
+			want: &planResource{
+				Type:   "dynamodb_table",
+				Name:   "happy-table",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "hash_key", Current: "LockID (S)", Desired: "LockID (S)"},
+					{Name: "billing_mode", Current: "PAY_PER_REQUEST", Desired: "PAY_PER_REQUEST"},
+					{Name: "tags", Current: "ManagedBy=tfbackend, Team=infra", Desired: "ManagedBy=tfbackend, Team=infra"},
+				},
+			},
+			wantAction: planActionNoop,
+			wantErr:    false,
+		},
+		{
+			name: "S08: Existing PROVISIONED table without provisioned throughput",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableProvisionedWithoutThroughput()},
+				tableName:   "happy-table",
+				billingMode: "PROVISIONED",
+			},
+			want: &planResource{
+				Type:   "dynamodb_table",
+				Name:   "happy-table",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "hash_key", Current: "LockID (S)", Desired: "LockID (S)"},
+					{Name: "billing_mode", Current: "PROVISIONED", Desired: "PROVISIONED"},
+					{Name: "write_capacity", Current: "", Desired: "5"},
+					{Name: "read_capacity", Current: "", Desired: "5"},
+				},
+			},
+			wantAction: planActionUpdate,
+			wantErr:    false,
+		},
+		{
+			name: "F01: Existing table has invalid key schema",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableInvalidKeySchema()},
+				tableName:   "failure-table",
+				billingMode: "PROVISIONED",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F02: DescribeTable fails",
+			args: args{
+				c:           &mockDynamoDBClient{describeErr: errors.New("some error")},
+				tableName:   "failure-table",
+				billingMode: "PROVISIONED",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planDynamoDB(tt.args.c, tt.args.tableName, tt.args.billingMode, tt.args.opt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("planDynamoDB() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planDynamoDB() = %v, want %v", got, tt.want)
+			}
+			if got != nil && got.action() != tt.wantAction {
+				t.Errorf("planDynamoDB().action() = %v, want %v", got.action(), tt.wantAction)
+			}
+			if tt.args.c.updated {
+				t.Errorf("planDynamoDB() must not update the table")
+			}
+		})
+	}
+}
+
+func Test_planDynamoDBAutoScaling(t *testing.T) {
+	opt := autoScalingOption{MinCapacity: 5, MaxCapacity: 100, TargetUtilization: 70}
+	tests := []struct {
+		name string
+		c    *mockAutoScalingClient
+		want []planAttribute
+	}{
+		{
+			name: "S01: Not registered",
+			c:    newMockAutoScalingClient(),
+			want: []planAttribute{
+				{Name: "autoscaling_read_capacity", Current: "Not configured", Desired: "5-100"},
+				{Name: "autoscaling_read_target_utilization", Current: "Not configured", Desired: "70%"},
+				{Name: "autoscaling_write_capacity", Current: "Not configured", Desired: "5-100"},
+				{Name: "autoscaling_write_target_utilization", Current: "Not configured", Desired: "70%"},
+			},
+		},
+		{
+			name: "S02: Registered with the same bounds and target",
+			c:    mockAutoScalingClientRegistered(5, 100, 70),
+			want: []planAttribute{
+				{Name: "autoscaling_read_capacity", Current: "5-100", Desired: "5-100"},
+				{Name: "autoscaling_read_target_utilization", Current: "70%", Desired: "70%"},
+				{Name: "autoscaling_write_capacity", Current: "5-100", Desired: "5-100"},
+				{Name: "autoscaling_write_target_utilization", Current: "70%", Desired: "70%"},
+			},
+		},
+		{
+			name: "S03: Registered with different bounds and target",
+			c:    mockAutoScalingClientRegistered(1, 10, 50),
+			want: []planAttribute{
+				{Name: "autoscaling_read_capacity", Current: "1-10", Desired: "5-100"},
+				{Name: "autoscaling_read_target_utilization", Current: "50%", Desired: "70%"},
+				{Name: "autoscaling_write_capacity", Current: "1-10", Desired: "5-100"},
+				{Name: "autoscaling_write_target_utilization", Current: "50%", Desired: "70%"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planDynamoDBAutoScaling(tt.c, "happy-table", opt)
+			if err != nil {
+				t.Fatalf("planDynamoDBAutoScaling() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planDynamoDBAutoScaling() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_printPlan(t *testing.T) {
+	resources := []*planResource{
+		{
+			Type:   "s3_bucket",
+			Name:   "happy-bucket",
+			Exists: true,
+			Attributes: []planAttribute{
+				{Name: "encryption", Current: "AES256", Desired: "aws:kms"},
+				{Name: "versioning", Current: "Enabled", Desired: "Enabled"},
+			},
+		},
+		{
+			Type:   "dynamodb_table",
+			Name:   "happy-table",
+			Exists: false,
+			Attributes: []planAttribute{
+				{Name: "billing_mode", Desired: "PROVISIONED"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	printPlan(&buf, resources)
+	got := buf.String()
+
+	for _, want := range []string{
+		`# s3_bucket "happy-bucket" will be updated in-place`,
+		`encryption = "AES256" -> "aws:kms"`,
+		`# dynamodb_table "happy-table" will be created`,
+		`billing_mode = "PROVISIONED"`,
+		`Plan: 1 to create, 1 to update, 0 unchanged.`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("printPlan() output doesn't contain %q\n%v", want, got)
+		}
+	}
+}
+
+func Test_planReplicationRole(t *testing.T) {
+	policy, err := renderReplicationRolePolicy("happy-bucket", "happy-bucket-replica", "ap-northeast-1", "", "")
+	if err != nil {
+		t.Fatalf("renderReplicationRolePolicy() error = %v", err)
+	}
+	existingRole := &iamtypes.Role{
+		RoleName:                 aws.String("happy-role"),
+		Arn:                      aws.String("arn:aws:iam::123456789012:role/service-role/happy-role"),
+		AssumeRolePolicyDocument: aws.String(url.QueryEscape(replicationAssumeRolePolicy)),
+	}
+	tests := []struct {
+		name        string
+		c           *mockIAMClient
+		want        *planResource
+		wantRoleArn string
+		wantAction  planAction
+		wantErr     bool
+	}{
+		{
+			name: "S01: New role",
+			c:    &mockIAMClient{},
+			want: &planResource{
+				Type:   "iam_role",
+				Name:   "happy-role",
+				Exists: false,
+				Attributes: []planAttribute{
+					{Name: "assume_role_principal", Desired: "s3.amazonaws.com"},
+					{Name: "inline_policy", Desired: "tfbackend-replication"},
+				},
+			},
+			wantRoleArn: "arn:aws:iam::123456789012:role/happy-role",
+			wantAction:  planActionCreate,
+			wantErr:     false,
+		},
+		{
+			name: "S02: Existing role, inline policy is outdated",
+			c:    &mockIAMClient{role: existingRole, policies: map[string]string{"tfbackend-replication": `{"Version":"2012-10-17","Statement":[]}`}},
+			want: &planResource{
+				Type:   "iam_role",
+				Name:   "happy-role",
+				Exists: true,
+				Attributes: []planAttribute{
+					{Name: "assume_role_principal", Current: "s3.amazonaws.com", Desired: "s3.amazonaws.com"},
+					{Name: "inline_policy", Current: "tfbackend-replication (outdated)", Desired: "tfbackend-replication"},
+				},
+			},
+			wantRoleArn: "arn:aws:iam::123456789012:role/service-role/happy-role",
+			wantAction:  planActionUpdate,
+			wantErr:     false,
+		},
+		{
+			name: "F01: Existing role which S3 can't assume",
+			c: &mockIAMClient{role: &iamtypes.Role{
+				RoleName:                 aws.String("happy-role"),
+				AssumeRolePolicyDocument: aws.String(url.QueryEscape(`{"Version":"2012-10-17","Statement":[]}`)),
+			}},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, roleArn, err := planReplicationRole(tt.c, "happy-role", policy, "arn:aws:iam::123456789012:role/happy-role")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("planReplicationRole() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planReplicationRole() = %v, want %v", got, tt.want)
+			}
+			if roleArn != tt.wantRoleArn {
+				t.Errorf("planReplicationRole() role arn = %v, want %v", roleArn, tt.wantRoleArn)
+			}
+			if got != nil && got.action() != tt.wantAction {
+				t.Errorf("planReplicationRole().action() = %v, want %v", got.action(), tt.wantAction)
+			}
+			if len(tt.c.calls) != 0 {
+				t.Errorf("planReplicationRole() calls = %v, want none", tt.c.calls)
+			}
+		})
+	}
+}
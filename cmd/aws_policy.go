@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var (
+	noBucketPolicy         bool
+	denyUnencryptedUploads bool
+	denyIncorrectKMSKey    bool
+	allowedPrincipals      []string
+	principalOrgID         string
+)
+
+// bucketPolicyOption holds optional statements of the bucket policy.
+// The statement which denies requests without TLS is always included.
+type bucketPolicyOption struct {
+	// DenyUnencryptedUploads denies PutObject without the server-side encryption header of the default encryption.
+	DenyUnencryptedUploads bool
+	// DenyIncorrectKMSKey denies PutObject with other KMS key than the one of default encryption.
+	DenyIncorrectKMSKey bool
+	// AllowedPrincipals are ARN patterns of IAM principals. Requests from the other principals are denied.
+	AllowedPrincipals []string
+	// PrincipalOrgID denies requests from principals outside the organization.
+	PrincipalOrgID string
+	// LogDeliveryAccountID allows S3 server access logging and CloudTrail to deliver logs of the account.
+	// It is used only for the log bucket.
+	LogDeliveryAccountID string
+}
+
+// bucketPolicySidPrefix marks the statements managed by tfbackend.
+// Statements of an adopted bucket without the prefix are kept as they are.
+const bucketPolicySidPrefix = "Tfbackend"
+
+const bucketPolicyTemplate = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "TfbackendDenyInsecureTransport",
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "s3:*",
+      "Resource": [{{json .BucketArn}}, {{json .ObjectsArn}}],
+      "Condition": {"Bool": {"aws:SecureTransport": "false"}}
+    }{{if .DenyUnencryptedUploads}},
+    {
+      "Sid": "TfbackendDenyUnencryptedObjectUploads",
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "s3:PutObject",
+      "Resource": {{json .ObjectsArn}},
+      "Condition": {"StringNotEquals": {"s3:x-amz-server-side-encryption": {{json .Encryption}}}}
+    }{{end}}{{if .DenyIncorrectKMSKey}},
+    {
+      "Sid": "TfbackendDenyIncorrectKMSKey",
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "s3:PutObject",
+      "Resource": {{json .ObjectsArn}},
+      "Condition": {"StringNotEqualsIfExists": {"s3:x-amz-server-side-encryption-aws-kms-key-id": {{json .KMSKeyID}}}}
+    }{{end}}{{if .AllowedPrincipals}},
+    {
+      "Sid": "TfbackendDenyOtherPrincipals",
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "s3:*",
+      "Resource": [{{json .BucketArn}}, {{json .ObjectsArn}}],
+      "Condition": {"ArnNotLike": {"aws:PrincipalArn": {{json .AllowedPrincipals}}}}
+    }{{end}}{{if .PrincipalOrgID}},
+    {
+      "Sid": "TfbackendDenyOtherOrganizations",
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "s3:*",
+      "Resource": [{{json .BucketArn}}, {{json .ObjectsArn}}],
+      "Condition": {"StringNotEquals": {"aws:PrincipalOrgID": {{json .PrincipalOrgID}}}}
+    }{{end}}{{if .LogDeliveryAccountID}},
+    {
+      "Sid": "TfbackendAllowServerAccessLogs",
+      "Effect": "Allow",
+      "Principal": {"Service": "logging.s3.amazonaws.com"},
+      "Action": "s3:PutObject",
+      "Resource": {{json .ObjectsArn}},
+      "Condition": {"StringEquals": {"aws:SourceAccount": {{json .LogDeliveryAccountID}}}}
+    },
+    {
+      "Sid": "TfbackendAllowCloudTrailAclCheck",
+      "Effect": "Allow",
+      "Principal": {"Service": "cloudtrail.amazonaws.com"},
+      "Action": "s3:GetBucketAcl",
+      "Resource": {{json .BucketArn}},
+      "Condition": {"StringEquals": {"aws:SourceAccount": {{json .LogDeliveryAccountID}}}}
+    },
+    {
+      "Sid": "TfbackendAllowCloudTrailWrite",
+      "Effect": "Allow",
+      "Principal": {"Service": "cloudtrail.amazonaws.com"},
+      "Action": "s3:PutObject",
+      "Resource": {{json .ObjectsArn}},
+      "Condition": {"StringEquals": {"s3:x-amz-acl": "bucket-owner-full-control", "aws:SourceAccount": {{json .LogDeliveryAccountID}}}}
+    }{{end}}
+  ]
+}
+`
+
+// renderBucketPolicy renders the statements of the bucket policy managed by tfbackend.
+// kmsKeyID is the key of default encryption, or empty for SSE-S3.
+func renderBucketPolicy(bucketArn string, kmsKeyID string, opt bucketPolicyOption) (string, error) {
+	if opt.DenyIncorrectKMSKey && kmsKeyID == "" {
+		return "", fmt.Errorf("denying incorrect kms key needs SSE-KMS default encryption")
+	}
+
+	encryption := string(s3types.ServerSideEncryptionAes256)
+	if kmsKeyID != "" {
+		encryption = string(s3types.ServerSideEncryptionAwsKms)
+	}
+
+	var buf bytes.Buffer
+	err := policyTemplate(bucketPolicyTemplate).Execute(&buf, struct {
+		bucketPolicyOption
+		BucketArn  string
+		ObjectsArn string
+		Encryption string
+		KMSKeyID   string
+	}{
+		bucketPolicyOption: opt,
+		BucketArn:          bucketArn,
+		ObjectsArn:         bucketArn + "/*",
+		Encryption:         encryption,
+		KMSKeyID:           kmsKeyID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render bucket policy: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return "", fmt.Errorf("rendered bucket policy is not valid JSON")
+	}
+	return buf.String(), nil
+}
+
+// policyTemplate parses the template of a policy document. {{json .X}} renders X as a JSON value.
+func policyTemplate(text string) *template.Template {
+	return template.Must(template.New("policy").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text))
+}
+
+// parseBucketPolicy parses the policy document. Statement is normalized to a list, because it can be a single object.
+func parseBucketPolicy(policy string) (map[string]interface{}, []interface{}, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid bucket policy: %w", err)
+	}
+
+	switch st := doc["Statement"].(type) {
+	case []interface{}:
+		return doc, st, nil
+	case map[string]interface{}:
+		return doc, []interface{}{st}, nil
+	case nil:
+		return doc, nil, nil
+	}
+	return nil, nil, fmt.Errorf("invalid bucket policy: unexpected Statement")
+}
+
+// isManagedBucketPolicyStatement checks if the statement is managed by tfbackend.
+func isManagedBucketPolicyStatement(statement interface{}) bool {
+	st, _ := statement.(map[string]interface{})
+	sid, _ := st["Sid"].(string)
+	return strings.HasPrefix(sid, bucketPolicySidPrefix)
+}
+
+// managedBucketPolicyStatements returns the statements managed by tfbackend. An empty policy has no statements.
+func managedBucketPolicyStatements(policy string) ([]interface{}, error) {
+	if policy == "" {
+		return nil, nil
+	}
+	_, statements, err := parseBucketPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	var managed []interface{}
+	for _, st := range statements {
+		if isManagedBucketPolicyStatement(st) {
+			managed = append(managed, st)
+		}
+	}
+	return managed, nil
+}
+
+// hasManagedBucketPolicy checks if the current policy has exactly the managed statements of the desired policy.
+func hasManagedBucketPolicy(current string, desired string) (bool, error) {
+	cur, err := managedBucketPolicyStatements(current)
+	if err != nil {
+		return false, err
+	}
+	want, err := managedBucketPolicyStatements(desired)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(cur, want), nil
+}
+
+// mergeBucketPolicy replaces the managed statements of the current policy with the desired ones.
+// The other statements and elements such as Id are kept.
+func mergeBucketPolicy(current string, desired string) (string, error) {
+	desiredDoc, desiredStatements, err := parseBucketPolicy(desired)
+	if err != nil {
+		return "", err
+	}
+	if current == "" {
+		return desired, nil
+	}
+
+	doc, statements, err := parseBucketPolicy(current)
+	if err != nil {
+		return "", err
+	}
+	var merged []interface{}
+	for _, st := range statements {
+		if !isManagedBucketPolicyStatement(st) {
+			merged = append(merged, st)
+		}
+	}
+	doc["Version"] = desiredDoc["Version"]
+	doc["Statement"] = append(merged, desiredStatements...)
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// bucketPolicyStatementNames summarizes the managed statements by their Sid without the prefix, e.g. "DenyInsecureTransport".
+func bucketPolicyStatementNames(policy string) (string, error) {
+	statements, err := managedBucketPolicyStatements(policy)
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, st := range statements {
+		sid, _ := st.(map[string]interface{})["Sid"].(string)
+		names = append(names, strings.TrimPrefix(sid, bucketPolicySidPrefix))
+	}
+	return strings.Join(names, ", "), nil
+}
+
+// principalArnPattern converts the caller ARN returned by STS to the pattern of aws:PrincipalArn.
+// aws:PrincipalArn of an assumed role session is the ARN of the role, which may have a path.
+// Role names are unique in the account, so the path is matched with a wildcard.
+func principalArnPattern(callerArn string) (string, error) {
+	a, err := arn.Parse(callerArn)
+	if err != nil {
+		return "", err
+	}
+	if a.Service == "sts" && strings.HasPrefix(a.Resource, "assumed-role/") {
+		parts := strings.Split(a.Resource, "/")
+		return "arn:" + a.Partition + ":iam::" + a.AccountID + ":role*/" + parts[1], nil
+	}
+	return callerArn, nil
+}
+
+var principalOrgIDPattern = regexp.MustCompile(`^o-[a-z0-9]{10,32}$`)
+
+// validateBucketPolicyFlags validates flags of the bucket policy.
+func validateBucketPolicyFlags() error {
+	if noBucketPolicy && (denyUnencryptedUploads || denyIncorrectKMSKey || len(allowedPrincipals) > 0 || principalOrgID != "") {
+		return fmt.Errorf("--no-bucket-policy cannot be specified with the other bucket policy flags")
+	}
+	if denyIncorrectKMSKey && kmsKeyID == "" && !newKMSKey {
+		return fmt.Errorf("--deny-incorrect-kms-key needs --kms-key-id or --create-kms-key")
+	}
+	for _, p := range allowedPrincipals {
+		if _, err := arn.Parse(p); err != nil {
+			return fmt.Errorf("--allowed-principal must be ARN: %v", p)
+		}
+	}
+	if principalOrgID != "" && !principalOrgIDPattern.MatchString(principalOrgID) {
+		return fmt.Errorf("--principal-org-id must be organization ID like o-xxxxxxxxxx: %v", principalOrgID)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_renderBucketPolicy(t *testing.T) {
+	type args struct {
+		kmsKeyID string
+		opt      bucketPolicyOption
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantNames string
+		wantErr   bool
+	}{
+		{
+			name:      "S01: TLS only",
+			args:      args{},
+			wantNames: "DenyInsecureTransport",
+			wantErr:   false,
+		},
+		{
+			name: "S02: All statements",
+			args: args{
+				kmsKeyID: "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				opt: bucketPolicyOption{
+					DenyUnencryptedUploads: true,
+					DenyIncorrectKMSKey:    true,
+					AllowedPrincipals:      []string{"arn:aws:iam::123456789012:role*/admin", "arn:aws:iam::123456789012:user/ci"},
+					PrincipalOrgID:         "o-abcdefghij",
+				},
+			},
+			wantNames: "DenyInsecureTransport, DenyUnencryptedObjectUploads, DenyIncorrectKMSKey, DenyOtherPrincipals, DenyOtherOrganizations",
+			wantErr:   false,
+		},
+		{
+			name: "F01: Incorrect KMS key is denied without SSE-KMS",
+			args: args{
+				opt: bucketPolicyOption{DenyIncorrectKMSKey: true},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderBucketPolicy("arn:aws:s3:::happy-bucket", tt.args.kmsKeyID, tt.args.opt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("renderBucketPolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !isTLSOnlyBucketPolicy(got) {
+				t.Errorf("renderBucketPolicy() = %v, want TLS-only policy", got)
+			}
+			names, err := bucketPolicyStatementNames(got)
+			if err != nil || names != tt.wantNames {
+				t.Errorf("bucketPolicyStatementNames() = %v, %v, want %v", names, err, tt.wantNames)
+			}
+		})
+	}
+}
+
+func Test_mergeBucketPolicy(t *testing.T) {
+	current := `{"Version":"2008-10-17","Id":"custom","Statement":[` +
+		`{"Sid":"AllowReadOnly","Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*"},` +
+		`{"Sid":"TfbackendDenyOtherOrganizations","Effect":"Deny","Principal":"*","Action":"s3:*","Resource":"*"}]}`
+	desired, err := renderBucketPolicy("arn:aws:s3:::happy-bucket", "", bucketPolicyOption{})
+	if err != nil {
+		t.Fatalf("renderBucketPolicy() error = %v", err)
+	}
+
+	got, err := mergeBucketPolicy(current, desired)
+	if err != nil {
+		t.Fatalf("mergeBucketPolicy() error = %v", err)
+	}
+
+	var doc struct {
+		Version   string
+		Id        string
+		Statement []struct{ Sid string }
+	}
+	if err := json.Unmarshal([]byte(got), &doc); err != nil {
+		t.Fatalf("mergeBucketPolicy() = %v, invalid JSON: %v", got, err)
+	}
+	var sids []string
+	for _, st := range doc.Statement {
+		sids = append(sids, st.Sid)
+	}
+	wantSids := []string{"AllowReadOnly", "TfbackendDenyInsecureTransport"}
+	if doc.Version != "2012-10-17" || doc.Id != "custom" || !reflect.DeepEqual(sids, wantSids) {
+		t.Errorf("mergeBucketPolicy() = %v, want Version 2012-10-17, Id custom and statements %v", got, wantSids)
+	}
+
+	applied, err := hasManagedBucketPolicy(got, desired)
+	if err != nil || !applied {
+		t.Errorf("hasManagedBucketPolicy() = %v, %v, want true", applied, err)
+	}
+	applied, err = hasManagedBucketPolicy(current, desired)
+	if err != nil || applied {
+		t.Errorf("hasManagedBucketPolicy() = %v, %v, want false", applied, err)
+	}
+}
+
+func Test_principalArnPattern(t *testing.T) {
+	tests := []struct {
+		name      string
+		callerArn string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "S01: IAM user",
+			callerArn: "arn:aws:iam::123456789012:user/ci",
+			want:      "arn:aws:iam::123456789012:user/ci",
+			wantErr:   false,
+		},
+		{
+			name:      "S02: Assumed role",
+			callerArn: "arn:aws:sts::123456789012:assumed-role/admin/session",
+			want:      "arn:aws:iam::123456789012:role*/admin",
+			wantErr:   false,
+		},
+		{
+			name:      "F01: Not ARN",
+			callerArn: "admin",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := principalArnPattern(tt.callerArn)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("principalArnPattern() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("principalArnPattern() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var (
+	replicaRegion       string
+	replicaBucketName   string
+	replicaKMSKeyID     string
+	replicationRoleName string
+)
+
+// replicationRuleID is the ID of the replication rule managed by tfbackend.
+// Rules of an adopted bucket with the other IDs are kept as they are.
+const replicationRuleID = "tfbackend-replica"
+
+// replicationPolicyName is the name of the inline policy of the replication role.
+const replicationPolicyName = "tfbackend-replication"
+
+// replicationAssumeRolePolicy allows S3 to assume the replication role.
+const replicationAssumeRolePolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"Service": "s3.amazonaws.com"},
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}
+`
+
+const replicationRolePolicyTemplate = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "ReadReplicationConfiguration",
+      "Effect": "Allow",
+      "Action": ["s3:GetReplicationConfiguration", "s3:ListBucket"],
+      "Resource": {{json .SourceBucketArn}}
+    },
+    {
+      "Sid": "ReadSourceVersions",
+      "Effect": "Allow",
+      "Action": ["s3:GetObjectVersionForReplication", "s3:GetObjectVersionAcl", "s3:GetObjectVersionTagging"],
+      "Resource": {{json .SourceObjectsArn}}
+    },
+    {
+      "Sid": "WriteReplicas",
+      "Effect": "Allow",
+      "Action": ["s3:ReplicateObject", "s3:ReplicateDelete", "s3:ReplicateTags"],
+      "Resource": {{json .ReplicaObjectsArn}}
+    }{{if .SourceKMSKeyID}},
+    {
+      "Sid": "DecryptSourceObjects",
+      "Effect": "Allow",
+      "Action": "kms:Decrypt",
+      "Resource": {{json .SourceKMSKeyID}}
+    },
+    {
+      "Sid": "EncryptReplicas",
+      "Effect": "Allow",
+      "Action": ["kms:Encrypt", "kms:GenerateDataKey"],
+      "Resource": {{json .ReplicaKMSKeyID}}
+    }{{end}}
+  ]
+}
+`
+
+var roleNamePattern = regexp.MustCompile(`^[\w+=,.@-]{1,64}$`)
+
+// replicationOption holds the replication of the bucket into the replica bucket.
+type replicationOption struct {
+	// RoleArn is the ARN of the IAM role which S3 assumes to replicate objects.
+	RoleArn string
+	// ReplicaBucket is the name of the destination bucket.
+	ReplicaBucket string
+	// ReplicaRegion is the region of the destination bucket.
+	ReplicaRegion string
+	// ReplicaKMSKeyID is the ARN of the KMS key in the replica region which re-encrypts replicas of SSE-KMS objects.
+	// If empty, replicas are encrypted by the default encryption of the replica bucket.
+	ReplicaKMSKeyID string
+}
+
+type initReplicationRoleResult struct {
+	RoleName   string `json:"role_name" yaml:"role_name"`
+	RoleArn    string `json:"role_arn" yaml:"role_arn"`
+	PolicyName string `json:"policy_name" yaml:"policy_name"`
+}
+
+func (i *initReplicationRoleResult) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Role name", i.RoleName},
+		{"Role ARN", i.RoleArn},
+		{"Inline policy", i.PolicyName},
+	}
+	return h, b
+}
+
+// newReplicaBucketName returns --replica-bucket, or '<BUCKET_NAME>-replica' by default.
+func newReplicaBucketName() string {
+	if replicaBucketName != "" {
+		return replicaBucketName
+	}
+	return bucketName + "-replica"
+}
+
+// newReplicationRoleName returns --replication-role-name, or 'tfbackend-replication-<BUCKET_NAME>' by default.
+func newReplicationRoleName() string {
+	if replicationRoleName != "" {
+		return replicationRoleName
+	}
+	return "tfbackend-replication-" + bucketName
+}
+
+// validateReplicationFlags validates flags of cross-region replication.
+// The replica region is compared with the region of the state bucket after the config is loaded.
+func validateReplicationFlags() error {
+	if replicaRegion == "" {
+		if replicaBucketName != "" || replicaKMSKeyID != "" || replicationRoleName != "" {
+			return fmt.Errorf("--replica-bucket, --replica-kms-key-id and --replication-role-name need --replica-region")
+		}
+		return nil
+	}
+	if s3Compatible {
+		return fmt.Errorf("--replica-region cannot be specified with --s3-compatible")
+	}
+
+	replica := newReplicaBucketName()
+	if len(replica) > 63 && replicaBucketName == "" {
+		return fmt.Errorf("replica bucket name must be 63 characters or less, specify --replica-bucket: %v", replica)
+	}
+	if err := validateS3BucketName(replica); err != nil {
+		return fmt.Errorf("invalid replica bucket name %v: %w", replica, err)
+	}
+	if replica == bucketName || replica == logBucketName {
+		return fmt.Errorf("--replica-bucket must be different from the state bucket and the log bucket: %v", replica)
+	}
+
+	sseKMS := kmsKeyID != "" || newKMSKey
+	if sseKMS && replicaKMSKeyID == "" {
+		return fmt.Errorf("--replica-kms-key-id is needed to re-encrypt replicas of the SSE-KMS state bucket")
+	}
+	if !sseKMS && replicaKMSKeyID != "" {
+		return fmt.Errorf("--replica-kms-key-id needs --kms-key-id or --create-kms-key")
+	}
+
+	if role := newReplicationRoleName(); !roleNamePattern.MatchString(role) {
+		return fmt.Errorf("replication role name must be 1 to 64 letters, numbers and +=,.@_-, specify --replication-role-name: %v", role)
+	}
+	return nil
+}
+
+// replicationRoleArn returns ARN of the role created by tfbackend, which has no path.
+func replicationRoleArn(region string, accountID string, roleName string) string {
+	return "arn:" + regionPartition(region) + ":iam::" + accountID + ":role/" + roleName
+}
+
+// replicaBucketOption returns the settings of the replica bucket, which has the same hardening as the state bucket.
+// Statements on upload headers are left out of the bucket policy, because replicas are written by S3, not by terraform.
+func replicaBucketOption(kmsKeyArn string, tags map[string]string, policy *bucketPolicyOption, lifecycle lifecycleOption) initS3Option {
+	opt := initS3Option{
+		KMSKeyID:  kmsKeyArn,
+		Tags:      tags,
+		Lifecycle: lifecycle,
+	}
+	if policy != nil {
+		opt.BucketPolicy = &bucketPolicyOption{
+			AllowedPrincipals: policy.AllowedPrincipals,
+			PrincipalOrgID:    policy.PrincipalOrgID,
+		}
+	}
+	return opt
+}
+
+// allowReplicationRole adds the replication role to the allowed principals, if principals are restricted.
+func allowReplicationRole(policy *bucketPolicyOption, roleArn string) {
+	if policy != nil && len(policy.AllowedPrincipals) > 0 {
+		policy.AllowedPrincipals = append(policy.AllowedPrincipals, roleArn)
+	}
+}
+
+// renderReplicationRolePolicy renders the inline policy which allows the role to replicate the source bucket into the replica bucket.
+// KMS permissions are added only when the source bucket uses SSE-KMS.
+func renderReplicationRolePolicy(sourceBucket string, replicaBucket string, region string, sourceKMSKeyID string, replicaKMSKeyID string) (string, error) {
+	sourceArn := s3BucketArn(sourceBucket, region)
+
+	var buf bytes.Buffer
+	err := policyTemplate(replicationRolePolicyTemplate).Execute(&buf, struct {
+		SourceBucketArn   string
+		SourceObjectsArn  string
+		ReplicaObjectsArn string
+		SourceKMSKeyID    string
+		ReplicaKMSKeyID   string
+	}{
+		SourceBucketArn:   sourceArn,
+		SourceObjectsArn:  sourceArn + "/*",
+		ReplicaObjectsArn: s3BucketArn(replicaBucket, region) + "/*",
+		SourceKMSKeyID:    sourceKMSKeyID,
+		ReplicaKMSKeyID:   replicaKMSKeyID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render replication role policy: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return "", fmt.Errorf("rendered replication role policy is not valid JSON")
+	}
+	return buf.String(), nil
+}
+
+// samePolicyDocument checks if both policy documents are the same JSON, ignoring formatting.
+func samePolicyDocument(a string, b string) bool {
+	var docA, docB interface{}
+	if json.Unmarshal([]byte(a), &docA) != nil || json.Unmarshal([]byte(b), &docB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(docA, docB)
+}
+
+// allowsS3AssumeRole checks if the trust policy of the role allows S3 to assume it.
+func allowsS3AssumeRole(trustPolicy string) bool {
+	_, statements, err := parseBucketPolicy(trustPolicy)
+	if err != nil {
+		return false
+	}
+	for _, st := range statements {
+		s, _ := st.(map[string]interface{})
+		if s["Effect"] != "Allow" || !containsString(policyValues(s["Action"]), "sts:AssumeRole") {
+			continue
+		}
+		principal, _ := s["Principal"].(map[string]interface{})
+		if containsString(policyValues(principal["Service"]), "s3.amazonaws.com") {
+			return true
+		}
+	}
+	return false
+}
+
+// policyValues normalizes the element of a policy statement, which can be a single string or a list, to a list.
+func policyValues(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var values []string
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// initReplicationRole creates or adopts the IAM role which S3 assumes to replicate the state bucket, and puts its inline policy.
+func initReplicationRole(c IAMClientable, roleName string, policy string, tags map[string]string, tx *transaction) (*initReplicationRoleResult, error) {
+	progress.section("iam_role", "🚀 Start to create iam role for replication of terraform backend ...")
+
+	res := initReplicationRoleResult{RoleName: roleName, PolicyName: replicationPolicyName}
+
+	// Create role. If the role already exists and S3 can assume it, adopt it.
+	progress.begin("Creating role")
+	roleRes, err := getRole(context.TODO(), c, roleName)
+	if err != nil && !isAPIErrorCode(err, "NoSuchEntity") {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of iam role: %w", err)
+	}
+	exists := err == nil
+	if exists {
+		trustPolicy, err := decodePolicyDocument(aws.ToString(roleRes.Role.AssumeRolePolicyDocument))
+		if err != nil || !allowsS3AssumeRole(trustPolicy) {
+			progress.fail()
+			return nil, fmt.Errorf("iam role %v exists, but s3.amazonaws.com is not allowed to assume it", roleName)
+		}
+		res.RoleArn = aws.ToString(roleRes.Role.Arn)
+		progress.end(stepStatusUnchanged)
+	} else {
+		createRes, err := createRole(context.TODO(), c, roleName, replicationAssumeRolePolicy, "Replication of terraform backend", tags)
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create iam role: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete iam role %v", roleName), func() error {
+			_, err := deleteRole(context.TODO(), c, roleName)
+			return err
+		})
+		res.RoleArn = aws.ToString(createRes.Role.Arn)
+		progress.end(stepStatusCreated)
+	}
+
+	// Put inline policy
+	progress.begin("Put inline policy")
+	status, err := ensureRolePolicy(c, roleName, replicationPolicyName, policy, exists, tx)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to put inline policy of iam role: %w", err)
+	}
+	progress.end(status)
+
+	// Confirmation
+	progress.begin("Confirmation - Get role policy")
+	current, err := getRolePolicyDocument(context.TODO(), c, roleName, replicationPolicyName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created iam role, but failed to describe iam role: %w", err)
+	}
+	if !samePolicyDocument(current, policy) {
+		progress.fail()
+		return nil, fmt.Errorf("inline policy of iam role is not applied: %v", replicationPolicyName)
+	}
+	progress.end(stepStatusSuccess)
+
+	return &res, nil
+}
+
+// ensureRolePolicy puts the inline policy of the role. The policy is deleted or restored on rollback,
+// also for a new role, because IAM can't delete the role which has inline policies.
+func ensureRolePolicy(c IAMClientable, roleName string, policyName string, policy string, adopted bool, tx *transaction) (stepStatus, error) {
+	previous := ""
+	if adopted {
+		cur, err := getRolePolicyDocument(context.TODO(), c, roleName, policyName)
+		if err != nil && !isAPIErrorCode(err, "NoSuchEntity") {
+			return "", err
+		}
+		if err == nil {
+			if samePolicyDocument(cur, policy) {
+				return stepStatusUnchanged, nil
+			}
+			previous = cur
+		}
+	}
+
+	if _, err := putRolePolicy(context.TODO(), c, roleName, policyName, policy); err != nil {
+		return "", err
+	}
+	tx.record(fmt.Sprintf("Restore inline policy %v of iam role %v", policyName, roleName), func() error {
+		if previous == "" {
+			_, err := deleteRolePolicy(context.TODO(), c, roleName, policyName)
+			return err
+		}
+		_, err := putRolePolicy(context.TODO(), c, roleName, policyName, previous)
+		return err
+	})
+	return appliedStatus(adopted), nil
+}
+
+// initReplicaBucket creates or adopts the replica bucket in the replica region with the same hardening as the state bucket.
+func initReplicaBucket(c S3Clientable, replicaBucket string, region string, opt initS3Option, tx *transaction) (*initS3Result, error) {
+	progress.section("replica_bucket", "🚀 Start to create replica bucket for terraform backend ...")
+	res, err := initBucket(c, replicaBucket, region, opt, tx)
+	if err != nil {
+		return nil, err
+	}
+	if actual := bucketRegion(res.Region); actual != region {
+		return nil, fmt.Errorf("replica bucket %v is in %v, not in %v", replicaBucket, actual, region)
+	}
+	return res, nil
+}
+
+// buildReplicationRule builds the rule which replicates all objects and delete markers into the replica bucket.
+// SSE-KMS objects are replicated only if selected explicitly, so they are selected and re-encrypted with the replica key.
+func buildReplicationRule(opt replicationOption, priority int32) s3types.ReplicationRule {
+	rule := s3types.ReplicationRule{
+		ID:       aws.String(replicationRuleID),
+		Priority: priority,
+		Status:   s3types.ReplicationRuleStatusEnabled,
+		Filter:   &s3types.ReplicationRuleFilterMemberPrefix{Value: ""},
+		DeleteMarkerReplication: &s3types.DeleteMarkerReplication{
+			Status: s3types.DeleteMarkerReplicationStatusEnabled,
+		},
+		Destination: &s3types.Destination{
+			Bucket: aws.String(s3BucketArn(opt.ReplicaBucket, opt.ReplicaRegion)),
+		},
+	}
+	if opt.ReplicaKMSKeyID != "" {
+		rule.SourceSelectionCriteria = &s3types.SourceSelectionCriteria{
+			SseKmsEncryptedObjects: &s3types.SseKmsEncryptedObjects{Status: s3types.SseKmsEncryptedObjectsStatusEnabled},
+		}
+		rule.Destination.EncryptionConfiguration = &s3types.EncryptionConfiguration{ReplicaKmsKeyID: aws.String(opt.ReplicaKMSKeyID)}
+	}
+	return rule
+}
+
+// replicationSummary describes the replication rule, e.g. "arn:aws:s3:::replica (delete markers, KMS key ...)".
+// S3 may return the rule in a different shape from the one put, so rules are compared by the summary.
+func replicationSummary(rule s3types.ReplicationRule) string {
+	if rule.Status != s3types.ReplicationRuleStatusEnabled || rule.Destination == nil {
+		return "Disabled"
+	}
+
+	summary := aws.ToString(rule.Destination.Bucket)
+	var details []string
+	if rule.DeleteMarkerReplication != nil && rule.DeleteMarkerReplication.Status == s3types.DeleteMarkerReplicationStatusEnabled {
+		details = append(details, "delete markers")
+	}
+	if e := rule.Destination.EncryptionConfiguration; e != nil && aws.ToString(e.ReplicaKmsKeyID) != "" {
+		details = append(details, "KMS key "+aws.ToString(e.ReplicaKmsKeyID))
+	}
+	if len(details) > 0 {
+		summary += " (" + strings.Join(details, ", ") + ")"
+	}
+	return summary
+}
+
+// findReplicationRule returns the rule managed by tfbackend, or nil if not found.
+func findReplicationRule(cfg *s3types.ReplicationConfiguration) *s3types.ReplicationRule {
+	if cfg == nil {
+		return nil
+	}
+	for i := range cfg.Rules {
+		if aws.ToString(cfg.Rules[i].ID) == replicationRuleID {
+			return &cfg.Rules[i]
+		}
+	}
+	return nil
+}
+
+// isReplicationApplied checks if the configuration has the desired rule and role.
+func isReplicationApplied(cfg *s3types.ReplicationConfiguration, opt replicationOption) bool {
+	rule := findReplicationRule(cfg)
+	return rule != nil && aws.ToString(cfg.Role) == opt.RoleArn && replicationSummary(*rule) == replicationSummary(buildReplicationRule(opt, 0))
+}
+
+// mergeReplicationRules replaces the rule managed by tfbackend in the current configuration with the desired one.
+// A configuration has a single role, and the role of tfbackend can replicate only into the replica bucket,
+// so the other rules assuming another role are an error instead of silently stopping their replication.
+// Rules must have unique priorities, so the desired rule takes the one next to the highest of the other rules.
+func mergeReplicationRules(current *s3types.ReplicationConfiguration, opt replicationOption) (*s3types.ReplicationConfiguration, error) {
+	merged := &s3types.ReplicationConfiguration{Role: aws.String(opt.RoleArn)}
+	var priority int32
+	if current != nil {
+		for _, r := range current.Rules {
+			if aws.ToString(r.ID) == replicationRuleID {
+				continue
+			}
+			if aws.ToString(current.Role) != opt.RoleArn {
+				return nil, fmt.Errorf("replication rule %v assumes role %v, which can't be replaced with the role of tfbackend %v. Remove the rule or replicate with the role", aws.ToString(r.ID), aws.ToString(current.Role), opt.RoleArn)
+			}
+			merged.Rules = append(merged.Rules, r)
+			if r.Priority > priority {
+				priority = r.Priority
+			}
+		}
+	}
+	merged.Rules = append(merged.Rules, buildReplicationRule(opt, priority+1))
+	return merged, nil
+}
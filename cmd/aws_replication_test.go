@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func Test_renderReplicationRolePolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		sourceKMSKeyID  string
+		replicaKMSKeyID string
+		wantSids        []string
+	}{
+		{
+			name:     "S01: SSE-S3",
+			wantSids: []string{"ReadReplicationConfiguration", "ReadSourceVersions", "WriteReplicas"},
+		},
+		{
+			name:            "S02: SSE-KMS",
+			sourceKMSKeyID:  "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+			replicaKMSKeyID: "arn:aws:kms:us-west-2:123456789012:key/replica-key",
+			wantSids:        []string{"ReadReplicationConfiguration", "ReadSourceVersions", "WriteReplicas", "DecryptSourceObjects", "EncryptReplicas"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderReplicationRolePolicy("happy-bucket", "happy-bucket-replica", "ap-northeast-1", tt.sourceKMSKeyID, tt.replicaKMSKeyID)
+			if err != nil {
+				t.Fatalf("renderReplicationRolePolicy() error = %v", err)
+			}
+			_, statements, err := parseBucketPolicy(got)
+			if err != nil {
+				t.Fatalf("parseBucketPolicy() error = %v", err)
+			}
+			var sids []string
+			for _, st := range statements {
+				sids = append(sids, st.(map[string]interface{})["Sid"].(string))
+			}
+			if !reflect.DeepEqual(sids, tt.wantSids) {
+				t.Errorf("renderReplicationRolePolicy() sids = %v, want %v", sids, tt.wantSids)
+			}
+			if !strings.Contains(got, `"arn:aws:s3:::happy-bucket-replica/*"`) {
+				t.Errorf("renderReplicationRolePolicy() = %v, want replica objects as resource", got)
+			}
+		})
+	}
+}
+
+func Test_allowsS3AssumeRole(t *testing.T) {
+	tests := []struct {
+		name        string
+		trustPolicy string
+		want        bool
+	}{
+		{
+			name:        "S01: Policy created by tfbackend",
+			trustPolicy: replicationAssumeRolePolicy,
+			want:        true,
+		},
+		{
+			name:        "S02: Service and action in lists",
+			trustPolicy: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["batchoperations.s3.amazonaws.com","s3.amazonaws.com"]},"Action":["sts:AssumeRole"]}]}`,
+			want:        true,
+		},
+		{
+			name:        "S03: Other service",
+			trustPolicy: `{"Version":"2012-10-17","Statement":{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}}`,
+			want:        false,
+		},
+		{
+			name:        "S04: Denied",
+			trustPolicy: `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Principal":{"Service":"s3.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowsS3AssumeRole(tt.trustPolicy); got != tt.want {
+				t.Errorf("allowsS3AssumeRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mergeReplicationRules(t *testing.T) {
+	opt := replicationOption{
+		RoleArn:       "arn:aws:iam::123456789012:role/happy-role",
+		ReplicaBucket: "happy-bucket-replica",
+		ReplicaRegion: "us-west-2",
+	}
+	otherRule := s3types.ReplicationRule{
+		ID:       aws.String("other-rule"),
+		Priority: 3,
+		Status:   s3types.ReplicationRuleStatusEnabled,
+		Filter:   &s3types.ReplicationRuleFilterMemberPrefix{Value: "logs/"},
+	}
+	tests := []struct {
+		name         string
+		current      *s3types.ReplicationConfiguration
+		wantIDs      []string
+		wantPriority int32
+		wantErr      bool
+	}{
+		{
+			name:         "S01: No replication configuration",
+			current:      nil,
+			wantIDs:      []string{"tfbackend-replica"},
+			wantPriority: 1,
+		},
+		{
+			name: "S02: Other rules are kept, and the managed rule is replaced",
+			current: &s3types.ReplicationConfiguration{
+				Role:  aws.String("arn:aws:iam::123456789012:role/happy-role"),
+				Rules: []s3types.ReplicationRule{otherRule, {ID: aws.String("tfbackend-replica"), Priority: 1}},
+			},
+			wantIDs:      []string{"other-rule", "tfbackend-replica"},
+			wantPriority: 4,
+		},
+		{
+			name: "S03: Only the managed rule with the old role is replaced",
+			current: &s3types.ReplicationConfiguration{
+				Role:  aws.String("arn:aws:iam::123456789012:role/old-role"),
+				Rules: []s3types.ReplicationRule{{ID: aws.String("tfbackend-replica"), Priority: 1}},
+			},
+			wantIDs:      []string{"tfbackend-replica"},
+			wantPriority: 1,
+		},
+		{
+			name: "F01: Other rules assume another role",
+			current: &s3types.ReplicationConfiguration{
+				Role:  aws.String("arn:aws:iam::123456789012:role/other-role"),
+				Rules: []s3types.ReplicationRule{otherRule},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeReplicationRules(tt.current, opt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergeReplicationRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var ids []string
+			for _, r := range got.Rules {
+				ids = append(ids, aws.ToString(r.ID))
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("mergeReplicationRules() rule IDs = %v, want %v", ids, tt.wantIDs)
+			}
+			if p := got.Rules[len(got.Rules)-1].Priority; p != tt.wantPriority {
+				t.Errorf("mergeReplicationRules() priority = %v, want %v", p, tt.wantPriority)
+			}
+			if !isReplicationApplied(got, opt) {
+				t.Errorf("isReplicationApplied() = false, want true")
+			}
+		})
+	}
+}
+
+func Test_replicationSummary(t *testing.T) {
+	opt := replicationOption{ReplicaBucket: "happy-bucket-replica", ReplicaRegion: "us-west-2"}
+	if got, want := replicationSummary(buildReplicationRule(opt, 1)), "arn:aws:s3:::happy-bucket-replica (delete markers)"; got != want {
+		t.Errorf("replicationSummary() = %v, want %v", got, want)
+	}
+
+	opt.ReplicaKMSKeyID = "arn:aws:kms:us-west-2:123456789012:key/replica-key"
+	if got, want := replicationSummary(buildReplicationRule(opt, 1)), "arn:aws:s3:::happy-bucket-replica (delete markers, KMS key arn:aws:kms:us-west-2:123456789012:key/replica-key)"; got != want {
+		t.Errorf("replicationSummary() = %v, want %v", got, want)
+	}
+}
+
+func Test_initReplicationRole(t *testing.T) {
+	policy, err := renderReplicationRolePolicy("happy-bucket", "happy-bucket-replica", "ap-northeast-1", "", "")
+	if err != nil {
+		t.Fatalf("renderReplicationRolePolicy() error = %v", err)
+	}
+	existingRole := &iamtypes.Role{
+		RoleName:                 aws.String("happy-role"),
+		Arn:                      aws.String("arn:aws:iam::123456789012:role/service-role/happy-role"),
+		AssumeRolePolicyDocument: aws.String(url.QueryEscape(replicationAssumeRolePolicy)),
+	}
+	tests := []struct {
+		name      string
+		c         *mockIAMClient
+		want      *initReplicationRoleResult
+		wantCalls []string
+		wantErr   bool
+	}{
+		{
+			name: "S01: New role",
+			c:    &mockIAMClient{},
+			want: &initReplicationRoleResult{
+				RoleName:   "happy-role",
+				RoleArn:    "arn:aws:iam::123456789012:role/happy-role",
+				PolicyName: "tfbackend-replication",
+			},
+			wantCalls: []string{"CreateRole", "PutRolePolicy"},
+			wantErr:   false,
+		},
+		{
+			name: "S02: Existing role, already converged",
+			c:    &mockIAMClient{role: existingRole, policies: map[string]string{"tfbackend-replication": policy}},
+			want: &initReplicationRoleResult{
+				RoleName:   "happy-role",
+				RoleArn:    "arn:aws:iam::123456789012:role/service-role/happy-role",
+				PolicyName: "tfbackend-replication",
+			},
+			wantCalls: nil,
+			wantErr:   false,
+		},
+		{
+			name: "S03: Existing role, inline policy is outdated",
+			c:    &mockIAMClient{role: existingRole, policies: map[string]string{"tfbackend-replication": `{"Version":"2012-10-17","Statement":[]}`}},
+			want: &initReplicationRoleResult{
+				RoleName:   "happy-role",
+				RoleArn:    "arn:aws:iam::123456789012:role/service-role/happy-role",
+				PolicyName: "tfbackend-replication",
+			},
+			wantCalls: []string{"PutRolePolicy"},
+			wantErr:   false,
+		},
+		{
+			name: "F01: Existing role which S3 can't assume",
+			c: &mockIAMClient{role: &iamtypes.Role{
+				RoleName:                 aws.String("happy-role"),
+				Arn:                      aws.String("arn:aws:iam::123456789012:role/happy-role"),
+				AssumeRolePolicyDocument: aws.String(url.QueryEscape(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}`)),
+			}},
+			want:      nil,
+			wantCalls: nil,
+			wantErr:   true,
+		},
+		{
+			name:      "F02: PutRolePolicy fails",
+			c:         &mockIAMClient{putErr: errors.New("some error")},
+			want:      nil,
+			wantCalls: []string{"CreateRole", "PutRolePolicy"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := initReplicationRole(tt.c, "happy-role", policy, nil, &transaction{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initReplicationRole() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initReplicationRole() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.c.calls, tt.wantCalls) {
+				t.Errorf("initReplicationRole() calls = %v, want %v", tt.c.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func Test_initReplicationRole_Rollback(t *testing.T) {
+	policy, err := renderReplicationRolePolicy("happy-bucket", "happy-bucket-replica", "ap-northeast-1", "", "")
+	if err != nil {
+		t.Fatalf("renderReplicationRolePolicy() error = %v", err)
+	}
+	previous := `{"Version":"2012-10-17","Statement":[]}`
+	tests := []struct {
+		name         string
+		c            *mockIAMClient
+		wantCalls    []string
+		wantPolicies map[string]string
+	}{
+		{
+			name:         "S01: Inline policy is deleted before new role",
+			c:            &mockIAMClient{},
+			wantCalls:    []string{"CreateRole", "PutRolePolicy", "DeleteRolePolicy", "DeleteRole"},
+			wantPolicies: map[string]string{},
+		},
+		{
+			name: "S02: Inline policy of existing role is restored",
+			c: &mockIAMClient{
+				role: &iamtypes.Role{
+					RoleName:                 aws.String("happy-role"),
+					Arn:                      aws.String("arn:aws:iam::123456789012:role/happy-role"),
+					AssumeRolePolicyDocument: aws.String(url.QueryEscape(replicationAssumeRolePolicy)),
+				},
+				policies: map[string]string{"tfbackend-replication": previous},
+			},
+			wantCalls:    []string{"PutRolePolicy", "PutRolePolicy"},
+			wantPolicies: map[string]string{"tfbackend-replication": previous},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &transaction{}
+			if _, err := initReplicationRole(tt.c, "happy-role", policy, nil, tx); err != nil {
+				t.Fatalf("initReplicationRole() error = %v", err)
+			}
+
+			if err := tx.rollback(); err != nil {
+				t.Errorf("transaction.rollback() error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.c.calls, tt.wantCalls) {
+				t.Errorf("transaction.rollback() calls = %v, want %v", tt.c.calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(tt.c.policies, tt.wantPolicies) {
+				t.Errorf("transaction.rollback() policies = %v, want %v", tt.c.policies, tt.wantPolicies)
+			}
+		})
+	}
+}
+
+func Test_initReplicaBucket(t *testing.T) {
+	opt := replicaBucketOption("", nil, &bucketPolicyOption{DenyUnencryptedUploads: true, AllowedPrincipals: []string{"arn:aws:iam::123456789012:role/happy-role"}}, lifecycleOption{})
+
+	got, err := initReplicaBucket(&mockS3ClientNewBucket{}, "happy-bucket-replica", "ap-northeast-1", opt, nil)
+	if err != nil {
+		t.Fatalf("initReplicaBucket() error = %v", err)
+	}
+	want := &initS3Result{
+		BucketName:        "happy-bucket-replica",
+		BucketArn:         "arn:aws:s3:::happy-bucket-replica",
+		Region:            "ap-northeast-1",
+		BlockPublicAccess: "Enabled",
+		ObjectOwnership:   "ACLs disabled",
+		Encryption:        "AES256",
+		Versioning:        "Enabled",
+		BucketPolicy:      "DenyInsecureTransport, DenyOtherPrincipals",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("initReplicaBucket() = %v, want %v", got, want)
+	}
+
+	// The bucket is found in the other region than the replica region.
+	if _, err := initReplicaBucket(&mockS3ClientNewBucket{}, "happy-bucket-replica", "us-west-2", opt, nil); err == nil {
+		t.Errorf("initReplicaBucket() error = nil, want error")
+	}
+}
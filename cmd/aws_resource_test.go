@@ -3,18 +3,40 @@ package cmd
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	autoscalingtypes "github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	cloudtrailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
 )
 
 // -----------------------------------
 // For initS3 test
 // -----------------------------------
 
+func mockHeadBucketOK(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, nil
+}
+func mockHeadBucketNotFound(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NotFound"}
+}
+func mockHeadBucketNG(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "Forbidden"}
+}
+
 func mockCreateBucketOK(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
 	return &s3.CreateBucketOutput{}, nil
 }
@@ -94,6 +116,9 @@ func mockGetBucketVersioningNG(ctx context.Context, params *s3.GetBucketVersioni
 
 type mockS3ClientAllSuccess struct{}
 
+func (m mockS3ClientAllSuccess) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return mockHeadBucketNotFound(ctx, params, optFns...)
+}
 func (m mockS3ClientAllSuccess) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
 	return mockCreateBucketOK(ctx, params, optFns...)
 }
@@ -118,324 +143,1122 @@ func (m mockS3ClientAllSuccess) GetBucketEncryption(ctx context.Context, params
 func (m mockS3ClientAllSuccess) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
 	return mockGetBucketVersioningOK(ctx, params, optFns...)
 }
-
-type mockS3ClientCreateBucketFailure struct{}
-
-func (m mockS3ClientCreateBucketFailure) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
-	return mockCreateBucketNG(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) DeleteBucket(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+	return &s3.DeleteBucketOutput{}, nil
 }
-func (m mockS3ClientCreateBucketFailure) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
-	return mockPutPublicAccessBlockOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) DeletePublicAccessBlock(ctx context.Context, params *s3.DeletePublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.DeletePublicAccessBlockOutput, error) {
+	return &s3.DeletePublicAccessBlockOutput{}, nil
 }
-func (m mockS3ClientCreateBucketFailure) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
-	return mockPutBucketEncryptionOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) DeleteBucketEncryption(ctx context.Context, params *s3.DeleteBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketEncryptionOutput, error) {
+	return &s3.DeleteBucketEncryptionOutput{}, nil
 }
-func (m mockS3ClientCreateBucketFailure) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
-	return mockPutBucketVersioningOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return &s3.ListObjectsV2Output{}, nil
 }
-func (m mockS3ClientCreateBucketFailure) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
-	return mockGetBucketLocationOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return &s3.ListObjectVersionsOutput{}, nil
 }
-func (m mockS3ClientCreateBucketFailure) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
-	return mockGetPublicAccessBlockOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NoSuchBucketPolicy", Message: "The bucket policy does not exist"}
 }
-func (m mockS3ClientCreateBucketFailure) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
-	return mockGetBucketEncryptionOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) GetBucketLogging(ctx context.Context, params *s3.GetBucketLoggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketLoggingOutput, error) {
+	return &s3.GetBucketLoggingOutput{}, nil
 }
-func (m mockS3ClientCreateBucketFailure) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
-	return mockGetBucketVersioningOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) PutBucketLogging(ctx context.Context, params *s3.PutBucketLoggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketLoggingOutput, error) {
+	return &s3.PutBucketLoggingOutput{}, nil
 }
-
-type mockS3ClientPutPublicAccessBlockFailure struct{}
-
-func (m mockS3ClientPutPublicAccessBlockFailure) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
-	return mockCreateBucketOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NoSuchLifecycleConfiguration"}
 }
-func (m mockS3ClientPutPublicAccessBlockFailure) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
-	return mockPutPublicAccessBlockNG(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
 }
-func (m mockS3ClientPutPublicAccessBlockFailure) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
-	return mockPutBucketEncryptionOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+	return &s3.DeleteBucketLifecycleOutput{}, nil
 }
-func (m mockS3ClientPutPublicAccessBlockFailure) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
-	return mockPutBucketVersioningOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) PutBucketPolicy(ctx context.Context, params *s3.PutBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error) {
+	return &s3.PutBucketPolicyOutput{}, nil
 }
-func (m mockS3ClientPutPublicAccessBlockFailure) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
-	return mockGetBucketLocationOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) DeleteBucketPolicy(ctx context.Context, params *s3.DeleteBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketPolicyOutput, error) {
+	return &s3.DeleteBucketPolicyOutput{}, nil
 }
-func (m mockS3ClientPutPublicAccessBlockFailure) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
-	return mockGetPublicAccessBlockOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) GetBucketReplication(ctx context.Context, params *s3.GetBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.GetBucketReplicationOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "ReplicationConfigurationNotFoundError"}
 }
-func (m mockS3ClientPutPublicAccessBlockFailure) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
-	return mockGetBucketEncryptionOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) PutBucketReplication(ctx context.Context, params *s3.PutBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.PutBucketReplicationOutput, error) {
+	return &s3.PutBucketReplicationOutput{}, nil
 }
-func (m mockS3ClientPutPublicAccessBlockFailure) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
-	return mockGetBucketVersioningOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) DeleteBucketReplication(ctx context.Context, params *s3.DeleteBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketReplicationOutput, error) {
+	return &s3.DeleteBucketReplicationOutput{}, nil
+}
+func (m mockS3ClientAllSuccess) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return &s3.DeleteObjectsOutput{}, nil
 }
 
-type mockS3ClientPutBucketEncryptionFailure struct{}
+func (m mockS3ClientAllSuccess) GetBucketTagging(ctx context.Context, params *s3.GetBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error) {
+	return &s3.GetBucketTaggingOutput{TagSet: []s3types.Tag{{Key: aws.String("ManagedBy"), Value: aws.String("tfbackend")}}}, nil
+}
+func (m mockS3ClientAllSuccess) PutBucketTagging(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error) {
+	return &s3.PutBucketTaggingOutput{}, nil
+}
+func (m mockS3ClientAllSuccess) DeleteBucketTagging(ctx context.Context, params *s3.DeleteBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketTaggingOutput, error) {
+	return &s3.DeleteBucketTaggingOutput{}, nil
+}
 
-func (m mockS3ClientPutBucketEncryptionFailure) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
-	return mockCreateBucketOK(ctx, params, optFns...)
+// PutObject rejects conditional writes like S3, which is what happens to the probe object written twice.
+func (m mockS3ClientAllSuccess) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if isIfNoneMatch(optFns) {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+	return &s3.PutObjectOutput{VersionId: aws.String("probe-version")}, nil
 }
-func (m mockS3ClientPutBucketEncryptionFailure) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
-	return mockPutPublicAccessBlockOK(ctx, params, optFns...)
+
+func (m mockS3ClientAllSuccess) GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "ObjectLockConfigurationNotFoundError"}
 }
-func (m mockS3ClientPutBucketEncryptionFailure) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
-	return mockPutBucketEncryptionNG(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) PutObjectLockConfiguration(ctx context.Context, params *s3.PutObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutObjectLockConfigurationOutput, error) {
+	return &s3.PutObjectLockConfigurationOutput{}, nil
 }
-func (m mockS3ClientPutBucketEncryptionFailure) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
-	return mockPutBucketVersioningOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error) {
+	return &s3.GetBucketOwnershipControlsOutput{
+		OwnershipControls: &s3types.OwnershipControls{
+			Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: s3types.ObjectOwnershipBucketOwnerEnforced}},
+		},
+	}, nil
 }
-func (m mockS3ClientPutBucketEncryptionFailure) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
-	return mockGetBucketLocationOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) PutBucketOwnershipControls(ctx context.Context, params *s3.PutBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.PutBucketOwnershipControlsOutput, error) {
+	return &s3.PutBucketOwnershipControlsOutput{}, nil
 }
-func (m mockS3ClientPutBucketEncryptionFailure) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
-	return mockGetPublicAccessBlockOK(ctx, params, optFns...)
+func (m mockS3ClientAllSuccess) DeleteBucketOwnershipControls(ctx context.Context, params *s3.DeleteBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOwnershipControlsOutput, error) {
+	return &s3.DeleteBucketOwnershipControlsOutput{}, nil
 }
-func (m mockS3ClientPutBucketEncryptionFailure) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
-	return mockGetBucketEncryptionOK(ctx, params, optFns...)
+
+// isIfNoneMatch reports whether the optFns make PutObject a conditional write.
+// withIfNoneMatch is the only option which adds API options to the stack.
+func isIfNoneMatch(optFns []func(*s3.Options)) bool {
+	var o s3.Options
+	for _, fn := range optFns {
+		fn(&o)
+	}
+	return len(o.APIOptions) > 0
 }
-func (m mockS3ClientPutBucketEncryptionFailure) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
-	return mockGetBucketVersioningOK(ctx, params, optFns...)
+
+type mockS3ClientHeadBucketFailure struct {
+	mockS3ClientAllSuccess
 }
 
-type mockS3ClientPutBucketVersioningFailure struct{}
+func (m mockS3ClientHeadBucketFailure) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return mockHeadBucketNG(ctx, params, optFns...)
+}
 
-func (m mockS3ClientPutBucketVersioningFailure) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
-	return mockCreateBucketOK(ctx, params, optFns...)
+type mockS3ClientCreateBucketFailure struct {
+	mockS3ClientAllSuccess
 }
-func (m mockS3ClientPutBucketVersioningFailure) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
-	return mockPutPublicAccessBlockOK(ctx, params, optFns...)
+
+func (m mockS3ClientCreateBucketFailure) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	return mockCreateBucketNG(ctx, params, optFns...)
 }
-func (m mockS3ClientPutBucketVersioningFailure) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
-	return mockPutBucketEncryptionOK(ctx, params, optFns...)
+
+type mockS3ClientPutPublicAccessBlockFailure struct {
+	mockS3ClientAllSuccess
+}
+
+func (m mockS3ClientPutPublicAccessBlockFailure) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
+	return mockPutPublicAccessBlockNG(ctx, params, optFns...)
 }
+
+type mockS3ClientPutBucketEncryptionFailure struct {
+	mockS3ClientAllSuccess
+}
+
+func (m mockS3ClientPutBucketEncryptionFailure) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+	return mockPutBucketEncryptionNG(ctx, params, optFns...)
+}
+
+type mockS3ClientPutBucketVersioningFailure struct {
+	mockS3ClientAllSuccess
+}
+
 func (m mockS3ClientPutBucketVersioningFailure) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
 	return mockPutBucketVersioningNG(ctx, params, optFns...)
 }
-func (m mockS3ClientPutBucketVersioningFailure) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
-	return mockGetBucketLocationOK(ctx, params, optFns...)
+
+type mockS3ClientGetBucketLocationFailure struct {
+	mockS3ClientAllSuccess
 }
-func (m mockS3ClientPutBucketVersioningFailure) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
-	return mockGetPublicAccessBlockOK(ctx, params, optFns...)
+
+func (m mockS3ClientGetBucketLocationFailure) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+	return mockGetBucketLocationNG(ctx, params, optFns...)
 }
-func (m mockS3ClientPutBucketVersioningFailure) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
-	return mockGetBucketEncryptionOK(ctx, params, optFns...)
+
+type mockS3ClientGetPublicAccessBlockFailure struct {
+	mockS3ClientAllSuccess
 }
-func (m mockS3ClientPutBucketVersioningFailure) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
-	return mockGetBucketVersioningOK(ctx, params, optFns...)
+
+func (m mockS3ClientGetPublicAccessBlockFailure) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
+	return mockGetPublicAccessBlockNG(ctx, params, optFns...)
 }
 
-type mockS3ClientGetBucketLocationFailure struct{}
+type mockS3ClientGetBucketEncryptionFailure struct {
+	mockS3ClientAllSuccess
+}
 
-func (m mockS3ClientGetBucketLocationFailure) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
-	return mockCreateBucketOK(ctx, params, optFns...)
+func (m mockS3ClientGetBucketEncryptionFailure) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	return mockGetBucketEncryptionNG(ctx, params, optFns...)
 }
-func (m mockS3ClientGetBucketLocationFailure) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
-	return mockPutPublicAccessBlockOK(ctx, params, optFns...)
+
+type mockS3ClientGetBucketVersioningFailure struct {
+	mockS3ClientAllSuccess
 }
-func (m mockS3ClientGetBucketLocationFailure) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
-	return mockPutBucketEncryptionOK(ctx, params, optFns...)
+
+func (m mockS3ClientGetBucketVersioningFailure) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	return mockGetBucketVersioningNG(ctx, params, optFns...)
 }
-func (m mockS3ClientGetBucketLocationFailure) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
-	return mockPutBucketVersioningOK(ctx, params, optFns...)
+
+// mockS3ClientOwnershipNotEnforced keeps ACLs enabled even after BucketOwnerEnforced is put.
+type mockS3ClientOwnershipNotEnforced struct {
+	mockS3ClientAllSuccess
 }
-func (m mockS3ClientGetBucketLocationFailure) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
-	return mockGetBucketLocationNG(ctx, params, optFns...)
+
+func (m mockS3ClientOwnershipNotEnforced) GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error) {
+	return &s3.GetBucketOwnershipControlsOutput{
+		OwnershipControls: &s3types.OwnershipControls{
+			Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: s3types.ObjectOwnershipObjectWriter}},
+		},
+	}, nil
 }
-func (m mockS3ClientGetBucketLocationFailure) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
-	return mockGetPublicAccessBlockOK(ctx, params, optFns...)
+
+type mockS3ClientAllSuccessKMS struct {
+	mockS3ClientAllSuccess
 }
-func (m mockS3ClientGetBucketLocationFailure) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
-	return mockGetBucketEncryptionOK(ctx, params, optFns...)
+
+func (m mockS3ClientAllSuccessKMS) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	return &s3.GetBucketEncryptionOutput{
+		ServerSideEncryptionConfiguration: &s3types.ServerSideEncryptionConfiguration{
+			Rules: []s3types.ServerSideEncryptionRule{
+				{
+					ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{
+						SSEAlgorithm:   s3types.ServerSideEncryptionAwsKms,
+						KMSMasterKeyID: aws.String("arn:aws:kms:ap-northeast-1:123456789012:key/happy-key"),
+					},
+					BucketKeyEnabled: true,
+				},
+			},
+		},
+	}, nil
 }
-func (m mockS3ClientGetBucketLocationFailure) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
-	return mockGetBucketVersioningOK(ctx, params, optFns...)
+
+// mockS3ClientS3Compatible behaves like S3-compatible object store, e.g. MinIO without KMS.
+// Block public access is not implemented, and default encryption returns NotImplemented.
+type mockS3ClientS3Compatible struct {
+	mockS3ClientAllSuccess
 }
 
-type mockS3ClientGetPublicAccessBlockFailure struct{}
+func (m mockS3ClientS3Compatible) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NotImplemented"}
+}
+func (m mockS3ClientS3Compatible) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NotImplemented"}
+}
+func (m mockS3ClientS3Compatible) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NotImplemented"}
+}
+func (m mockS3ClientS3Compatible) PutBucketPolicy(ctx context.Context, params *s3.PutBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "NotImplemented"}
+}
+func (m mockS3ClientS3Compatible) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+	return &s3.GetBucketLocationOutput{}, nil
+}
 
-func (m mockS3ClientGetPublicAccessBlockFailure) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
-	return mockCreateBucketOK(ctx, params, optFns...)
+// mockS3ClientExistingBucket behaves like S3 which already has the bucket owned by us.
+// Put* methods overwrite the configuration, and Get* methods return the current one.
+type mockS3ClientExistingBucket struct {
+	mockS3ClientAllSuccess
+	publicAccessBlock *s3types.PublicAccessBlockConfiguration
+	encryption        *s3types.ServerSideEncryptionConfiguration
+	versioning        s3types.BucketVersioningStatus
+	policy            *string
+	lifecycleRules    []s3types.LifecycleRule
+	logging           *s3types.LoggingEnabled
+	replication       *s3types.ReplicationConfiguration
+	objectLock        *s3types.ObjectLockConfiguration
+	ownership         *s3types.OwnershipControls
+	tags              map[string]string
+	putVersioningErr  error
+	putCalls          int
 }
-func (m mockS3ClientGetPublicAccessBlockFailure) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
-	return mockPutPublicAccessBlockOK(ctx, params, optFns...)
+
+func (m *mockS3ClientExistingBucket) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return mockHeadBucketOK(ctx, params, optFns...)
 }
-func (m mockS3ClientGetPublicAccessBlockFailure) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
-	return mockPutBucketEncryptionOK(ctx, params, optFns...)
+func (m *mockS3ClientExistingBucket) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	return nil, &s3types.BucketAlreadyOwnedByYou{}
 }
-func (m mockS3ClientGetPublicAccessBlockFailure) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
-	return mockPutBucketVersioningOK(ctx, params, optFns...)
+func (m *mockS3ClientExistingBucket) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
+	m.putCalls++
+	m.publicAccessBlock = params.PublicAccessBlockConfiguration
+	return &s3.PutPublicAccessBlockOutput{}, nil
 }
-func (m mockS3ClientGetPublicAccessBlockFailure) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
-	return mockGetBucketLocationOK(ctx, params, optFns...)
+func (m *mockS3ClientExistingBucket) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+	m.putCalls++
+	m.encryption = params.ServerSideEncryptionConfiguration
+	return &s3.PutBucketEncryptionOutput{}, nil
 }
-func (m mockS3ClientGetPublicAccessBlockFailure) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
-	return mockGetPublicAccessBlockNG(ctx, params, optFns...)
+func (m *mockS3ClientExistingBucket) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
+	if m.putVersioningErr != nil {
+		return nil, m.putVersioningErr
+	}
+	m.putCalls++
+	m.versioning = params.VersioningConfiguration.Status
+	return &s3.PutBucketVersioningOutput{}, nil
 }
-func (m mockS3ClientGetPublicAccessBlockFailure) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
-	return mockGetBucketEncryptionOK(ctx, params, optFns...)
+func (m *mockS3ClientExistingBucket) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
+	if m.publicAccessBlock == nil {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchPublicAccessBlockConfiguration"}
+	}
+	return &s3.GetPublicAccessBlockOutput{PublicAccessBlockConfiguration: m.publicAccessBlock}, nil
+}
+func (m *mockS3ClientExistingBucket) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
+	if m.encryption == nil {
+		return nil, &smithy.GenericAPIError{Code: "ServerSideEncryptionConfigurationNotFoundError"}
+	}
+	return &s3.GetBucketEncryptionOutput{ServerSideEncryptionConfiguration: m.encryption}, nil
+}
+func (m *mockS3ClientExistingBucket) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
+	return &s3.GetBucketVersioningOutput{Status: m.versioning}, nil
+}
+func (m *mockS3ClientExistingBucket) GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error) {
+	if m.policy == nil {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchBucketPolicy"}
+	}
+	return &s3.GetBucketPolicyOutput{Policy: m.policy}, nil
+}
+func (m *mockS3ClientExistingBucket) GetBucketLogging(ctx context.Context, params *s3.GetBucketLoggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketLoggingOutput, error) {
+	return &s3.GetBucketLoggingOutput{LoggingEnabled: m.logging}, nil
+}
+func (m *mockS3ClientExistingBucket) PutBucketLogging(ctx context.Context, params *s3.PutBucketLoggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketLoggingOutput, error) {
+	m.putCalls++
+	m.logging = params.BucketLoggingStatus.LoggingEnabled
+	return &s3.PutBucketLoggingOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if len(m.lifecycleRules) == 0 {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchLifecycleConfiguration"}
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{Rules: m.lifecycleRules}, nil
+}
+func (m *mockS3ClientExistingBucket) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	m.putCalls++
+	m.lifecycleRules = params.LifecycleConfiguration.Rules
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+	m.lifecycleRules = nil
+	return &s3.DeleteBucketLifecycleOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) GetBucketReplication(ctx context.Context, params *s3.GetBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.GetBucketReplicationOutput, error) {
+	if m.replication == nil {
+		return nil, &smithy.GenericAPIError{Code: "ReplicationConfigurationNotFoundError"}
+	}
+	return &s3.GetBucketReplicationOutput{ReplicationConfiguration: m.replication}, nil
+}
+func (m *mockS3ClientExistingBucket) PutBucketReplication(ctx context.Context, params *s3.PutBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.PutBucketReplicationOutput, error) {
+	m.putCalls++
+	m.replication = params.ReplicationConfiguration
+	return &s3.PutBucketReplicationOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) DeleteBucketReplication(ctx context.Context, params *s3.DeleteBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketReplicationOutput, error) {
+	m.replication = nil
+	return &s3.DeleteBucketReplicationOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	if m.objectLock == nil {
+		return nil, &smithy.GenericAPIError{Code: "ObjectLockConfigurationNotFoundError"}
+	}
+	return &s3.GetObjectLockConfigurationOutput{ObjectLockConfiguration: m.objectLock}, nil
+}
+func (m *mockS3ClientExistingBucket) PutObjectLockConfiguration(ctx context.Context, params *s3.PutObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutObjectLockConfigurationOutput, error) {
+	m.putCalls++
+	m.objectLock = params.ObjectLockConfiguration
+	return &s3.PutObjectLockConfigurationOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error) {
+	if m.ownership == nil {
+		return nil, &smithy.GenericAPIError{Code: "OwnershipControlsNotFoundError"}
+	}
+	return &s3.GetBucketOwnershipControlsOutput{OwnershipControls: m.ownership}, nil
+}
+func (m *mockS3ClientExistingBucket) PutBucketOwnershipControls(ctx context.Context, params *s3.PutBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.PutBucketOwnershipControlsOutput, error) {
+	m.putCalls++
+	m.ownership = params.OwnershipControls
+	return &s3.PutBucketOwnershipControlsOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) DeleteBucketOwnershipControls(ctx context.Context, params *s3.DeleteBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOwnershipControlsOutput, error) {
+	m.ownership = nil
+	return &s3.DeleteBucketOwnershipControlsOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) PutBucketPolicy(ctx context.Context, params *s3.PutBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error) {
+	m.putCalls++
+	m.policy = params.Policy
+	return &s3.PutBucketPolicyOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) DeleteBucketPolicy(ctx context.Context, params *s3.DeleteBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketPolicyOutput, error) {
+	m.policy = nil
+	return &s3.DeleteBucketPolicyOutput{}, nil
 }
-func (m mockS3ClientGetPublicAccessBlockFailure) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
-	return mockGetBucketVersioningOK(ctx, params, optFns...)
+
+func (m *mockS3ClientExistingBucket) GetBucketTagging(ctx context.Context, params *s3.GetBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error) {
+	if len(m.tags) == 0 {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchTagSet"}
+	}
+	out := &s3.GetBucketTaggingOutput{}
+	for k, v := range m.tags {
+		out.TagSet = append(out.TagSet, s3types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return out, nil
+}
+func (m *mockS3ClientExistingBucket) PutBucketTagging(ctx context.Context, params *s3.PutBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error) {
+	m.putCalls++
+	m.tags = map[string]string{}
+	for _, t := range params.Tagging.TagSet {
+		m.tags[*t.Key] = *t.Value
+	}
+	return &s3.PutBucketTaggingOutput{}, nil
+}
+func (m *mockS3ClientExistingBucket) DeleteBucketTagging(ctx context.Context, params *s3.DeleteBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketTaggingOutput, error) {
+	m.tags = nil
+	return &s3.DeleteBucketTaggingOutput{}, nil
 }
 
-type mockS3ClientGetBucketEncryptionFailure struct{}
+// mockS3ClientRollbackRecorder records Delete* calls made by rollback, and delegates the others to S3Clientable.
+type mockS3ClientRollbackRecorder struct {
+	S3Clientable
+	calls []string
+}
 
-func (m mockS3ClientGetBucketEncryptionFailure) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
-	return mockCreateBucketOK(ctx, params, optFns...)
+func (m *mockS3ClientRollbackRecorder) DeleteBucket(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+	m.calls = append(m.calls, "DeleteBucket")
+	return &s3.DeleteBucketOutput{}, nil
 }
-func (m mockS3ClientGetBucketEncryptionFailure) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
-	return mockPutPublicAccessBlockOK(ctx, params, optFns...)
+func (m *mockS3ClientRollbackRecorder) DeletePublicAccessBlock(ctx context.Context, params *s3.DeletePublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.DeletePublicAccessBlockOutput, error) {
+	m.calls = append(m.calls, "DeletePublicAccessBlock")
+	return &s3.DeletePublicAccessBlockOutput{}, nil
 }
-func (m mockS3ClientGetBucketEncryptionFailure) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
-	return mockPutBucketEncryptionOK(ctx, params, optFns...)
+func (m *mockS3ClientRollbackRecorder) DeleteBucketEncryption(ctx context.Context, params *s3.DeleteBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketEncryptionOutput, error) {
+	m.calls = append(m.calls, "DeleteBucketEncryption")
+	return &s3.DeleteBucketEncryptionOutput{}, nil
 }
-func (m mockS3ClientGetBucketEncryptionFailure) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
-	return mockPutBucketVersioningOK(ctx, params, optFns...)
+func (m *mockS3ClientRollbackRecorder) DeleteBucketTagging(ctx context.Context, params *s3.DeleteBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketTaggingOutput, error) {
+	m.calls = append(m.calls, "DeleteBucketTagging")
+	return &s3.DeleteBucketTaggingOutput{}, nil
 }
-func (m mockS3ClientGetBucketEncryptionFailure) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
-	return mockGetBucketLocationOK(ctx, params, optFns...)
+func (m *mockS3ClientRollbackRecorder) DeleteBucketLifecycle(ctx context.Context, params *s3.DeleteBucketLifecycleInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error) {
+	m.calls = append(m.calls, "DeleteBucketLifecycle")
+	return &s3.DeleteBucketLifecycleOutput{}, nil
 }
-func (m mockS3ClientGetBucketEncryptionFailure) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
-	return mockGetPublicAccessBlockOK(ctx, params, optFns...)
+func (m *mockS3ClientRollbackRecorder) DeleteBucketPolicy(ctx context.Context, params *s3.DeleteBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketPolicyOutput, error) {
+	m.calls = append(m.calls, "DeleteBucketPolicy")
+	return &s3.DeleteBucketPolicyOutput{}, nil
 }
-func (m mockS3ClientGetBucketEncryptionFailure) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
-	return mockGetBucketEncryptionNG(ctx, params, optFns...)
+func (m *mockS3ClientRollbackRecorder) DeleteBucketReplication(ctx context.Context, params *s3.DeleteBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketReplicationOutput, error) {
+	m.calls = append(m.calls, "DeleteBucketReplication")
+	return &s3.DeleteBucketReplicationOutput{}, nil
 }
-func (m mockS3ClientGetBucketEncryptionFailure) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
-	return mockGetBucketVersioningOK(ctx, params, optFns...)
+func (m *mockS3ClientRollbackRecorder) DeleteBucketOwnershipControls(ctx context.Context, params *s3.DeleteBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOwnershipControlsOutput, error) {
+	m.calls = append(m.calls, "DeleteBucketOwnershipControls")
+	return &s3.DeleteBucketOwnershipControlsOutput{}, nil
 }
 
-type mockS3ClientGetBucketVersioningFailure struct{}
+// mockS3ClientNewBucket behaves like S3 which creates a new bucket, and returns the bucket policy, lifecycle rules,
+// logging, replication and Object Lock configuration put last.
+type mockS3ClientNewBucket struct {
+	mockS3ClientAllSuccess
+	policy         *string
+	lifecycleRules []s3types.LifecycleRule
+	logging        *s3types.LoggingEnabled
+	replication    *s3types.ReplicationConfiguration
+	objectLock     *s3types.ObjectLockConfiguration
+}
 
-func (m mockS3ClientGetBucketVersioningFailure) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+func (m *mockS3ClientNewBucket) CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	if params.ObjectLockEnabledForBucket {
+		m.objectLock = &s3types.ObjectLockConfiguration{ObjectLockEnabled: s3types.ObjectLockEnabledEnabled}
+	}
 	return mockCreateBucketOK(ctx, params, optFns...)
 }
-func (m mockS3ClientGetBucketVersioningFailure) PutPublicAccessBlock(ctx context.Context, params *s3.PutPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error) {
-	return mockPutPublicAccessBlockOK(ctx, params, optFns...)
+func (m *mockS3ClientNewBucket) PutObjectLockConfiguration(ctx context.Context, params *s3.PutObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutObjectLockConfigurationOutput, error) {
+	m.objectLock = params.ObjectLockConfiguration
+	return &s3.PutObjectLockConfigurationOutput{}, nil
 }
-func (m mockS3ClientGetBucketVersioningFailure) PutBucketEncryption(ctx context.Context, params *s3.PutBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
-	return mockPutBucketEncryptionOK(ctx, params, optFns...)
+func (m *mockS3ClientNewBucket) GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	if m.objectLock == nil {
+		return nil, &smithy.GenericAPIError{Code: "ObjectLockConfigurationNotFoundError"}
+	}
+	return &s3.GetObjectLockConfigurationOutput{ObjectLockConfiguration: m.objectLock}, nil
 }
-func (m mockS3ClientGetBucketVersioningFailure) PutBucketVersioning(ctx context.Context, params *s3.PutBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error) {
-	return mockPutBucketVersioningOK(ctx, params, optFns...)
+
+func (m *mockS3ClientNewBucket) PutBucketReplication(ctx context.Context, params *s3.PutBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.PutBucketReplicationOutput, error) {
+	m.replication = params.ReplicationConfiguration
+	return &s3.PutBucketReplicationOutput{}, nil
 }
-func (m mockS3ClientGetBucketVersioningFailure) GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
-	return mockGetBucketLocationOK(ctx, params, optFns...)
+func (m *mockS3ClientNewBucket) GetBucketReplication(ctx context.Context, params *s3.GetBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.GetBucketReplicationOutput, error) {
+	if m.replication == nil {
+		return nil, &smithy.GenericAPIError{Code: "ReplicationConfigurationNotFoundError"}
+	}
+	return &s3.GetBucketReplicationOutput{ReplicationConfiguration: m.replication}, nil
 }
-func (m mockS3ClientGetBucketVersioningFailure) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
-	return mockGetPublicAccessBlockOK(ctx, params, optFns...)
+
+func (m *mockS3ClientNewBucket) PutBucketLogging(ctx context.Context, params *s3.PutBucketLoggingInput, optFns ...func(*s3.Options)) (*s3.PutBucketLoggingOutput, error) {
+	m.logging = params.BucketLoggingStatus.LoggingEnabled
+	return &s3.PutBucketLoggingOutput{}, nil
 }
-func (m mockS3ClientGetBucketVersioningFailure) GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error) {
-	return mockGetBucketEncryptionOK(ctx, params, optFns...)
+func (m *mockS3ClientNewBucket) GetBucketLogging(ctx context.Context, params *s3.GetBucketLoggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketLoggingOutput, error) {
+	return &s3.GetBucketLoggingOutput{LoggingEnabled: m.logging}, nil
 }
-func (m mockS3ClientGetBucketVersioningFailure) GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error) {
-	return mockGetBucketVersioningNG(ctx, params, optFns...)
+
+func (m *mockS3ClientNewBucket) PutBucketLifecycleConfiguration(ctx context.Context, params *s3.PutBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	m.lifecycleRules = params.LifecycleConfiguration.Rules
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+func (m *mockS3ClientNewBucket) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	if len(m.lifecycleRules) == 0 {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchLifecycleConfiguration"}
+	}
+	return &s3.GetBucketLifecycleConfigurationOutput{Rules: m.lifecycleRules}, nil
+}
+
+func (m *mockS3ClientNewBucket) PutBucketPolicy(ctx context.Context, params *s3.PutBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error) {
+	m.policy = params.Policy
+	return &s3.PutBucketPolicyOutput{}, nil
+}
+func (m *mockS3ClientNewBucket) GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error) {
+	if m.policy == nil {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchBucketPolicy"}
+	}
+	return &s3.GetBucketPolicyOutput{Policy: m.policy}, nil
+}
+
+// -----------------------------------
+// For lockfile test
+// -----------------------------------
+
+// mockS3ClientConditionalWrites behaves like the bucket which holds the probe object only.
+// If ignoreIfNoneMatch is true, conditional writes overwrite the object like stores which don't support them.
+type mockS3ClientConditionalWrites struct {
+	mockS3ClientAllSuccess
+	ignoreIfNoneMatch bool
+	unversioned       bool
+	putErr            error
+	conditionalErr    error
+	puts              []*s3.PutObjectInput
+	deleted           []s3types.ObjectIdentifier
+}
+
+func (m *mockS3ClientConditionalWrites) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if isIfNoneMatch(optFns) {
+		if m.conditionalErr != nil {
+			return nil, m.conditionalErr
+		}
+		if len(m.puts) > 0 && !m.ignoreIfNoneMatch {
+			return nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}
+		}
+	} else if m.putErr != nil {
+		return nil, m.putErr
+	}
+	m.puts = append(m.puts, params)
+	if m.unversioned {
+		return &s3.PutObjectOutput{}, nil
+	}
+	return &s3.PutObjectOutput{VersionId: aws.String(fmt.Sprintf("v%d", len(m.puts)))}, nil
+}
+func (m *mockS3ClientConditionalWrites) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	m.deleted = append(m.deleted, params.Delete.Objects...)
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+// -----------------------------------
+// For destroy test
+// -----------------------------------
+
+// mockS3ClientBucketObjects behaves like the versioned bucket which holds the given pages of objects.
+// ListObjectVersions and ListObjectsV2 return one page per call, and DeleteObjects records deleted batches.
+type mockS3ClientBucketObjects struct {
+	mockS3ClientAllSuccess
+	versionPages    []s3.ListObjectVersionsOutput
+	objectPages     []s3.ListObjectsV2Output
+	deleteObjectErr []s3types.Error
+	deletedBatches  [][]s3types.ObjectIdentifier
+	bucketDeleted   bool
+}
+
+func (m *mockS3ClientBucketObjects) HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return mockHeadBucketOK(ctx, params, optFns...)
+}
+func (m *mockS3ClientBucketObjects) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	page := 0
+	if params.KeyMarker != nil {
+		fmt.Sscanf(*params.KeyMarker, "page%d", &page)
+	}
+	if page >= len(m.versionPages) {
+		return &s3.ListObjectVersionsOutput{}, nil
+	}
+	out := m.versionPages[page]
+	if page+1 < len(m.versionPages) {
+		out.IsTruncated = true
+		out.NextKeyMarker = aws.String(fmt.Sprintf("page%d", page+1))
+		out.NextVersionIdMarker = aws.String("version")
+	}
+	return &out, nil
+}
+func (m *mockS3ClientBucketObjects) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	page := 0
+	if params.ContinuationToken != nil {
+		fmt.Sscanf(*params.ContinuationToken, "page%d", &page)
+	}
+	if page >= len(m.objectPages) {
+		return &s3.ListObjectsV2Output{}, nil
+	}
+	out := m.objectPages[page]
+	if page+1 < len(m.objectPages) {
+		out.IsTruncated = true
+		out.NextContinuationToken = aws.String(fmt.Sprintf("page%d", page+1))
+	}
+	return &out, nil
+}
+func (m *mockS3ClientBucketObjects) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	m.deletedBatches = append(m.deletedBatches, params.Delete.Objects)
+	return &s3.DeleteObjectsOutput{Errors: m.deleteObjectErr}, nil
+}
+func (m *mockS3ClientBucketObjects) DeleteBucket(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+	m.bucketDeleted = true
+	return &s3.DeleteBucketOutput{}, nil
 }
 
 // -----------------------------------
 // For initDynamoDB test
 // -----------------------------------
 
-type mockDynamoDBClientAllSuccessProvisionedWrite5Read5 struct{}
+// mockDynamoDBClient behaves like DynamoDB which has (or doesn't have) the single table.
+// The table is created by CreateTable, and its billing mode, encryption and deletion protection are changed by UpdateTable.
+type mockDynamoDBClient struct {
+	exists      bool
+	table       *types.TableDescription
+	createErr   error
+	describeErr error
+	updateErr   error
+	updated     bool
+	deleted     bool
+	locks       []string
+	scanErr     error
+	pitr        bool
+	pitrErr     error
+	tags        map[string]string
+	tagErr      error
+}
 
-func (m mockDynamoDBClientAllSuccessProvisionedWrite5Read5) CreateTable(ctx context.Context,
+func (m *mockDynamoDBClient) CreateTable(ctx context.Context,
 	params *dynamodb.CreateTableInput,
 	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	if m.createErr != nil {
+		return nil, m.createErr
+	}
+	m.exists = true
+	m.tags = map[string]string{}
+	for _, t := range params.Tags {
+		m.tags[*t.Key] = *t.Value
+	}
 	return &dynamodb.CreateTableOutput{}, nil
 }
 
-func (m mockDynamoDBClientAllSuccessProvisionedWrite5Read5) DescribeTable(ctx context.Context,
+func (m *mockDynamoDBClient) DescribeTable(ctx context.Context,
 	params *dynamodb.DescribeTableInput,
 	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
-
+	if m.describeErr != nil {
+		return nil, m.describeErr
+	}
+	if !m.exists {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("table not found")}
+	}
 	return &dynamodb.DescribeTableOutput{
-		Table: &types.TableDescription{
-			TableName: aws.String("happy-bucket"),
-			BillingModeSummary: &types.BillingModeSummary{
-				BillingMode: types.BillingModeProvisioned,
-			},
-			ProvisionedThroughput: &types.ProvisionedThroughputDescription{
-				ReadCapacityUnits:  aws.Int64(5),
-				WriteCapacityUnits: aws.Int64(5),
+		Table: m.table,
+	}, nil
+}
+
+func (m *mockDynamoDBClient) UpdateTable(ctx context.Context,
+	params *dynamodb.UpdateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	if m.updateErr != nil {
+		return nil, m.updateErr
+	}
+	m.updated = true
+	if params.DeletionProtectionEnabled != nil {
+		m.table.DeletionProtectionEnabled = params.DeletionProtectionEnabled
+	}
+	if params.SSESpecification != nil {
+		m.table.SSEDescription = nil
+		if *params.SSESpecification.Enabled {
+			m.table.SSEDescription = &types.SSEDescription{
+				Status:          types.SSEStatusEnabled,
+				SSEType:         params.SSESpecification.SSEType,
+				KMSMasterKeyArn: params.SSESpecification.KMSMasterKeyId,
+			}
+		}
+	}
+	if params.BillingMode == "" {
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	m.table.BillingModeSummary = &types.BillingModeSummary{
+		BillingMode: params.BillingMode,
+	}
+	if params.ProvisionedThroughput != nil {
+		m.table.ProvisionedThroughput = &types.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  params.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: params.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+	return &dynamodb.UpdateTableOutput{}, nil
+}
+
+func (m *mockDynamoDBClient) DeleteTable(ctx context.Context,
+	params *dynamodb.DeleteTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	if m.table != nil && m.table.DeletionProtectionEnabled != nil && *m.table.DeletionProtectionEnabled {
+		return nil, &smithy.GenericAPIError{Code: "ValidationException", Message: "deletion protection is enabled"}
+	}
+	m.exists = false
+	m.deleted = true
+	return &dynamodb.DeleteTableOutput{}, nil
+}
+
+func (m *mockDynamoDBClient) DescribeContinuousBackups(ctx context.Context,
+	params *dynamodb.DescribeContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	status := types.PointInTimeRecoveryStatusDisabled
+	if m.pitr {
+		status = types.PointInTimeRecoveryStatusEnabled
+	}
+	return &dynamodb.DescribeContinuousBackupsOutput{
+		ContinuousBackupsDescription: &types.ContinuousBackupsDescription{
+			ContinuousBackupsStatus: types.ContinuousBackupsStatusEnabled,
+			PointInTimeRecoveryDescription: &types.PointInTimeRecoveryDescription{
+				PointInTimeRecoveryStatus: status,
 			},
 		},
 	}, nil
 }
 
-type mockDynamoDBClientAllSuccessProvisionedWrite5Read5WithoutBillingModeSummary struct{}
+func (m *mockDynamoDBClient) UpdateContinuousBackups(ctx context.Context,
+	params *dynamodb.UpdateContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	if m.pitrErr != nil {
+		return nil, m.pitrErr
+	}
+	m.pitr = *params.PointInTimeRecoverySpecification.PointInTimeRecoveryEnabled
+	return &dynamodb.UpdateContinuousBackupsOutput{}, nil
+}
 
-func (m mockDynamoDBClientAllSuccessProvisionedWrite5Read5WithoutBillingModeSummary) CreateTable(ctx context.Context,
-	params *dynamodb.CreateTableInput,
-	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
-	return &dynamodb.CreateTableOutput{}, nil
+func (m *mockDynamoDBClient) ListTagsOfResource(ctx context.Context,
+	params *dynamodb.ListTagsOfResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error) {
+	out := &dynamodb.ListTagsOfResourceOutput{}
+	for k, v := range m.tags {
+		out.Tags = append(out.Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return out, nil
 }
 
-func (m mockDynamoDBClientAllSuccessProvisionedWrite5Read5WithoutBillingModeSummary) DescribeTable(ctx context.Context,
-	params *dynamodb.DescribeTableInput,
-	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+func (m *mockDynamoDBClient) TagResource(ctx context.Context,
+	params *dynamodb.TagResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error) {
+	if m.tagErr != nil {
+		return nil, m.tagErr
+	}
+	if m.tags == nil {
+		m.tags = map[string]string{}
+	}
+	for _, t := range params.Tags {
+		m.tags[*t.Key] = *t.Value
+	}
+	return &dynamodb.TagResourceOutput{}, nil
+}
 
-	return &dynamodb.DescribeTableOutput{
-		Table: &types.TableDescription{
-			TableName: aws.String("happy-bucket"),
-			ProvisionedThroughput: &types.ProvisionedThroughputDescription{
-				ReadCapacityUnits:  aws.Int64(5),
-				WriteCapacityUnits: aws.Int64(5),
-			},
+func (m *mockDynamoDBClient) UntagResource(ctx context.Context,
+	params *dynamodb.UntagResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UntagResourceOutput, error) {
+	for _, k := range params.TagKeys {
+		delete(m.tags, k)
+	}
+	return &dynamodb.UntagResourceOutput{}, nil
+}
+
+func (m *mockDynamoDBClient) Scan(ctx context.Context,
+	params *dynamodb.ScanInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if m.scanErr != nil {
+		return nil, m.scanErr
+	}
+	out := &dynamodb.ScanOutput{}
+	for _, l := range m.locks {
+		out.Items = append(out.Items, map[string]types.AttributeValue{
+			"LockID": &types.AttributeValueMemberS{Value: l},
+		})
+	}
+	return out, nil
+}
+
+func mockLockTableKeySchema() ([]types.KeySchemaElement, []types.AttributeDefinition) {
+	return []types.KeySchemaElement{
+		{
+			AttributeName: aws.String("LockID"),
+			KeyType:       types.KeyTypeHash,
 		},
-	}, nil
+	}, []types.AttributeDefinition{
+		{
+			AttributeName: aws.String("LockID"),
+			AttributeType: types.ScalarAttributeTypeS,
+		},
+	}
 }
 
-type mockDynamoDBClientAllSuccessPayPerRequest struct{}
+func mockTableProvisionedWrite5Read5() *types.TableDescription {
+	k, a := mockLockTableKeySchema()
+	return &types.TableDescription{
+		TableName:            aws.String("happy-bucket"),
+		TableStatus:          types.TableStatusActive,
+		KeySchema:            k,
+		AttributeDefinitions: a,
+		BillingModeSummary: &types.BillingModeSummary{
+			BillingMode: types.BillingModeProvisioned,
+		},
+		ProvisionedThroughput: &types.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(5),
+			WriteCapacityUnits: aws.Int64(5),
+		},
+	}
+}
 
-func (m mockDynamoDBClientAllSuccessPayPerRequest) CreateTable(ctx context.Context,
-	params *dynamodb.CreateTableInput,
-	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
-	return &dynamodb.CreateTableOutput{}, nil
+func mockTableProvisionedWrite5Read5WithoutBillingModeSummary() *types.TableDescription {
+	t := mockTableProvisionedWrite5Read5()
+	t.BillingModeSummary = nil
+	return t
 }
 
-func (m mockDynamoDBClientAllSuccessPayPerRequest) DescribeTable(ctx context.Context,
-	params *dynamodb.DescribeTableInput,
-	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+func mockTablePayPerRequest() *types.TableDescription {
+	k, a := mockLockTableKeySchema()
+	return &types.TableDescription{
+		TableName:            aws.String("happy-bucket"),
+		TableStatus:          types.TableStatusActive,
+		KeySchema:            k,
+		AttributeDefinitions: a,
+		BillingModeSummary: &types.BillingModeSummary{
+			BillingMode: types.BillingModePayPerRequest,
+		},
+	}
+}
 
-	return &dynamodb.DescribeTableOutput{
-		Table: &types.TableDescription{
-			TableName: aws.String("happy-bucket"),
-			BillingModeSummary: &types.BillingModeSummary{
-				BillingMode: types.BillingModePayPerRequest,
-			},
+// mockTableProvisionedWithoutThroughput is PROVISIONED table whose ProvisionedThroughput isn't returned.
+func mockTableProvisionedWithoutThroughput() *types.TableDescription {
+	t := mockTablePayPerRequest()
+	t.BillingModeSummary.BillingMode = types.BillingModeProvisioned
+	return t
+}
+
+func mockTableProtected() *types.TableDescription {
+	t := mockTablePayPerRequest()
+	t.DeletionProtectionEnabled = aws.Bool(true)
+	return t
+}
+
+func mockTableWithArn() *types.TableDescription {
+	t := mockTablePayPerRequest()
+	t.TableArn = aws.String("arn:aws:dynamodb:ap-northeast-1:123456789012:table/happy-bucket")
+	return t
+}
+
+func mockTableInvalidKeySchema() *types.TableDescription {
+	t := mockTablePayPerRequest()
+	t.KeySchema[0].AttributeName = aws.String("id")
+	t.AttributeDefinitions[0].AttributeName = aws.String("id")
+	return t
+}
+
+// -----------------------------------
+// For initKMS test
+// -----------------------------------
+
+type mockSTSGetCallerIdentityAPI func(ctx context.Context,
+	params *sts.GetCallerIdentityInput,
+	optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+
+func (m mockSTSGetCallerIdentityAPI) GetCallerIdentity(ctx context.Context,
+	params *sts.GetCallerIdentityInput,
+	optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+
+	return m(ctx, params, optFns...)
+}
+
+func mockGetCallerIdentityOK(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String("123456789012"),
+		Arn:     aws.String("arn:aws:iam::123456789012:user/happy-user"),
+	}, nil
+}
+func mockGetCallerIdentityNG(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return nil, errors.New("some error")
+}
+
+type mockKMSClientAllSuccess struct{}
+
+func (m mockKMSClientAllSuccess) CreateKey(ctx context.Context, params *kms.CreateKeyInput, optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error) {
+	return &kms.CreateKeyOutput{
+		KeyMetadata: &kmstypes.KeyMetadata{
+			KeyId: aws.String("happy-key"),
+			Arn:   aws.String("arn:aws:kms:ap-northeast-1:123456789012:key/happy-key"),
+		},
+	}, nil
+}
+func (m mockKMSClientAllSuccess) EnableKeyRotation(ctx context.Context, params *kms.EnableKeyRotationInput, optFns ...func(*kms.Options)) (*kms.EnableKeyRotationOutput, error) {
+	return &kms.EnableKeyRotationOutput{}, nil
+}
+func (m mockKMSClientAllSuccess) CreateAlias(ctx context.Context, params *kms.CreateAliasInput, optFns ...func(*kms.Options)) (*kms.CreateAliasOutput, error) {
+	return &kms.CreateAliasOutput{}, nil
+}
+func (m mockKMSClientAllSuccess) ScheduleKeyDeletion(ctx context.Context, params *kms.ScheduleKeyDeletionInput, optFns ...func(*kms.Options)) (*kms.ScheduleKeyDeletionOutput, error) {
+	return &kms.ScheduleKeyDeletionOutput{}, nil
+}
+func (m mockKMSClientAllSuccess) DeleteAlias(ctx context.Context, params *kms.DeleteAliasInput, optFns ...func(*kms.Options)) (*kms.DeleteAliasOutput, error) {
+	return &kms.DeleteAliasOutput{}, nil
+}
+func (m mockKMSClientAllSuccess) DescribeKey(ctx context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	return &kms.DescribeKeyOutput{
+		KeyMetadata: &kmstypes.KeyMetadata{
+			KeyId: aws.String("happy-key"),
+			Arn:   aws.String("arn:aws:kms:ap-northeast-1:123456789012:key/happy-key"),
 		},
 	}, nil
 }
 
-type mockDynamoDBClientFailureCreateTableNG struct{}
+type mockKMSClientCreateKeyFailure struct {
+	mockKMSClientAllSuccess
+}
 
-func (m mockDynamoDBClientFailureCreateTableNG) CreateTable(ctx context.Context,
-	params *dynamodb.CreateTableInput,
-	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+func (m mockKMSClientCreateKeyFailure) CreateKey(ctx context.Context, params *kms.CreateKeyInput, optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error) {
 	return nil, errors.New("some error")
 }
 
-func (m mockDynamoDBClientFailureCreateTableNG) DescribeTable(ctx context.Context,
-	params *dynamodb.DescribeTableInput,
-	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+type mockKMSClientEnableKeyRotationFailure struct {
+	mockKMSClientAllSuccess
+}
 
-	return &dynamodb.DescribeTableOutput{}, nil
+func (m mockKMSClientEnableKeyRotationFailure) EnableKeyRotation(ctx context.Context, params *kms.EnableKeyRotationInput, optFns ...func(*kms.Options)) (*kms.EnableKeyRotationOutput, error) {
+	return nil, errors.New("some error")
 }
 
-type mockDynamoDBClientFailureDescribeTableNG struct{}
+type mockKMSClientCreateAliasFailure struct {
+	mockKMSClientAllSuccess
+}
 
-func (m mockDynamoDBClientFailureDescribeTableNG) CreateTable(ctx context.Context,
-	params *dynamodb.CreateTableInput,
-	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
-	return &dynamodb.CreateTableOutput{}, nil
+func (m mockKMSClientCreateAliasFailure) CreateAlias(ctx context.Context, params *kms.CreateAliasInput, optFns ...func(*kms.Options)) (*kms.CreateAliasOutput, error) {
+	return nil, errors.New("some error")
 }
 
-func (m mockDynamoDBClientFailureDescribeTableNG) DescribeTable(ctx context.Context,
-	params *dynamodb.DescribeTableInput,
-	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+// -----------------------------------
+// For initDynamoDBAutoScaling test
+// -----------------------------------
 
-	return nil, errors.New("some error")
+// mockAutoScalingClient behaves like Application Auto Scaling which holds scalable targets and policies of a single table.
+// Both are keyed by scalable dimension.
+type mockAutoScalingClient struct {
+	targets     map[autoscalingtypes.ScalableDimension]*autoscalingtypes.ScalableTarget
+	policies    map[autoscalingtypes.ScalableDimension]*autoscalingtypes.ScalingPolicy
+	registerErr error
+	putErr      error
+	registered  int
+}
+
+func newMockAutoScalingClient() *mockAutoScalingClient {
+	return &mockAutoScalingClient{
+		targets:  map[autoscalingtypes.ScalableDimension]*autoscalingtypes.ScalableTarget{},
+		policies: map[autoscalingtypes.ScalableDimension]*autoscalingtypes.ScalingPolicy{},
+	}
+}
+
+func (m *mockAutoScalingClient) DescribeScalableTargets(ctx context.Context, params *applicationautoscaling.DescribeScalableTargetsInput, optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DescribeScalableTargetsOutput, error) {
+	out := &applicationautoscaling.DescribeScalableTargetsOutput{}
+	if t, ok := m.targets[params.ScalableDimension]; ok {
+		out.ScalableTargets = append(out.ScalableTargets, *t)
+	}
+	return out, nil
+}
+func (m *mockAutoScalingClient) RegisterScalableTarget(ctx context.Context, params *applicationautoscaling.RegisterScalableTargetInput, optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.RegisterScalableTargetOutput, error) {
+	if m.registerErr != nil {
+		return nil, m.registerErr
+	}
+	m.registered++
+	m.targets[params.ScalableDimension] = &autoscalingtypes.ScalableTarget{
+		ResourceId:        params.ResourceId,
+		ScalableDimension: params.ScalableDimension,
+		MinCapacity:       params.MinCapacity,
+		MaxCapacity:       params.MaxCapacity,
+	}
+	return &applicationautoscaling.RegisterScalableTargetOutput{}, nil
+}
+func (m *mockAutoScalingClient) DeregisterScalableTarget(ctx context.Context, params *applicationautoscaling.DeregisterScalableTargetInput, optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DeregisterScalableTargetOutput, error) {
+	delete(m.targets, params.ScalableDimension)
+	delete(m.policies, params.ScalableDimension)
+	return &applicationautoscaling.DeregisterScalableTargetOutput{}, nil
+}
+func (m *mockAutoScalingClient) DescribeScalingPolicies(ctx context.Context, params *applicationautoscaling.DescribeScalingPoliciesInput, optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DescribeScalingPoliciesOutput, error) {
+	out := &applicationautoscaling.DescribeScalingPoliciesOutput{}
+	if p, ok := m.policies[params.ScalableDimension]; ok {
+		out.ScalingPolicies = append(out.ScalingPolicies, *p)
+	}
+	return out, nil
+}
+func (m *mockAutoScalingClient) PutScalingPolicy(ctx context.Context, params *applicationautoscaling.PutScalingPolicyInput, optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.PutScalingPolicyOutput, error) {
+	if m.putErr != nil {
+		return nil, m.putErr
+	}
+	m.policies[params.ScalableDimension] = &autoscalingtypes.ScalingPolicy{
+		PolicyName:                               params.PolicyName,
+		ScalableDimension:                        params.ScalableDimension,
+		PolicyType:                               params.PolicyType,
+		TargetTrackingScalingPolicyConfiguration: params.TargetTrackingScalingPolicyConfiguration,
+	}
+	return &applicationautoscaling.PutScalingPolicyOutput{}, nil
+}
+func (m *mockAutoScalingClient) DeleteScalingPolicy(ctx context.Context, params *applicationautoscaling.DeleteScalingPolicyInput, optFns ...func(*applicationautoscaling.Options)) (*applicationautoscaling.DeleteScalingPolicyOutput, error) {
+	delete(m.policies, params.ScalableDimension)
+	return &applicationautoscaling.DeleteScalingPolicyOutput{}, nil
+}
+
+// mockAutoScalingClientRegistered returns the client where both dimensions are registered with the bounds and target.
+func mockAutoScalingClientRegistered(minCapacity int32, maxCapacity int32, target float64) *mockAutoScalingClient {
+	m := newMockAutoScalingClient()
+	for _, d := range []autoscalingtypes.ScalableDimension{
+		autoscalingtypes.ScalableDimensionDynamoDBTableReadCapacityUnits,
+		autoscalingtypes.ScalableDimensionDynamoDBTableWriteCapacityUnits,
+	} {
+		m.targets[d] = &autoscalingtypes.ScalableTarget{
+			ScalableDimension: d,
+			MinCapacity:       aws.Int32(minCapacity),
+			MaxCapacity:       aws.Int32(maxCapacity),
+		}
+		m.policies[d] = &autoscalingtypes.ScalingPolicy{
+			ScalableDimension: d,
+			TargetTrackingScalingPolicyConfiguration: &autoscalingtypes.TargetTrackingScalingPolicyConfiguration{
+				TargetValue: aws.Float64(target),
+			},
+		}
+	}
+	return m
+}
+
+// -----------------------------------
+// For initCloudTrail test
+// -----------------------------------
+
+// mockCloudTrailClient behaves like CloudTrail which has (or doesn't have) the single trail.
+// A new trail has the default selector which logs all management events.
+type mockCloudTrailClient struct {
+	trail     *cloudtrailtypes.Trail
+	selectors []cloudtrailtypes.EventSelector
+	advanced  []cloudtrailtypes.AdvancedEventSelector
+	putErr    error
+	putCalls  int
+	calls     []string
+}
+
+func (m *mockCloudTrailClient) GetTrail(ctx context.Context, params *cloudtrail.GetTrailInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetTrailOutput, error) {
+	if m.trail == nil {
+		return nil, &cloudtrailtypes.TrailNotFoundException{}
+	}
+	return &cloudtrail.GetTrailOutput{Trail: m.trail}, nil
+}
+func (m *mockCloudTrailClient) CreateTrail(ctx context.Context, params *cloudtrail.CreateTrailInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.CreateTrailOutput, error) {
+	m.calls = append(m.calls, "CreateTrail")
+	arn := "arn:aws:cloudtrail:ap-northeast-1:123456789012:trail/" + *params.Name
+	m.trail = &cloudtrailtypes.Trail{Name: params.Name, TrailARN: aws.String(arn), S3BucketName: params.S3BucketName}
+	m.selectors = []cloudtrailtypes.EventSelector{{ReadWriteType: cloudtrailtypes.ReadWriteTypeAll, IncludeManagementEvents: aws.Bool(true)}}
+	return &cloudtrail.CreateTrailOutput{Name: params.Name, TrailARN: aws.String(arn)}, nil
+}
+func (m *mockCloudTrailClient) StartLogging(ctx context.Context, params *cloudtrail.StartLoggingInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.StartLoggingOutput, error) {
+	m.calls = append(m.calls, "StartLogging")
+	return &cloudtrail.StartLoggingOutput{}, nil
+}
+func (m *mockCloudTrailClient) DeleteTrail(ctx context.Context, params *cloudtrail.DeleteTrailInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.DeleteTrailOutput, error) {
+	m.calls = append(m.calls, "DeleteTrail")
+	m.trail = nil
+	return &cloudtrail.DeleteTrailOutput{}, nil
+}
+func (m *mockCloudTrailClient) GetEventSelectors(ctx context.Context, params *cloudtrail.GetEventSelectorsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetEventSelectorsOutput, error) {
+	return &cloudtrail.GetEventSelectorsOutput{EventSelectors: m.selectors, AdvancedEventSelectors: m.advanced}, nil
+}
+func (m *mockCloudTrailClient) PutEventSelectors(ctx context.Context, params *cloudtrail.PutEventSelectorsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.PutEventSelectorsOutput, error) {
+	m.calls = append(m.calls, "PutEventSelectors")
+	if m.putErr != nil {
+		return nil, m.putErr
+	}
+	m.putCalls++
+	m.selectors, m.advanced = params.EventSelectors, params.AdvancedEventSelectors
+	return &cloudtrail.PutEventSelectorsOutput{}, nil
+}
+
+// -----------------------------------
+// For initReplicationRole test
+// -----------------------------------
+
+// mockIAMClient behaves like IAM which has (or doesn't have) the single role with inline policies.
+// Policy documents are returned URL-encoded like IAM does.
+type mockIAMClient struct {
+	role     *iamtypes.Role
+	policies map[string]string
+	putErr   error
+	putCalls int
+	calls    []string
+}
+
+func (m *mockIAMClient) GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	if m.role == nil {
+		return nil, &iamtypes.NoSuchEntityException{}
+	}
+	return &iam.GetRoleOutput{Role: m.role}, nil
+}
+func (m *mockIAMClient) CreateRole(ctx context.Context, params *iam.CreateRoleInput, optFns ...func(*iam.Options)) (*iam.CreateRoleOutput, error) {
+	m.calls = append(m.calls, "CreateRole")
+	m.role = &iamtypes.Role{
+		RoleName:                 params.RoleName,
+		Arn:                      aws.String("arn:aws:iam::123456789012:role/" + *params.RoleName),
+		AssumeRolePolicyDocument: aws.String(url.QueryEscape(*params.AssumeRolePolicyDocument)),
+	}
+	return &iam.CreateRoleOutput{Role: m.role}, nil
+}
+func (m *mockIAMClient) DeleteRole(ctx context.Context, params *iam.DeleteRoleInput, optFns ...func(*iam.Options)) (*iam.DeleteRoleOutput, error) {
+	m.calls = append(m.calls, "DeleteRole")
+	m.role = nil
+	return &iam.DeleteRoleOutput{}, nil
+}
+func (m *mockIAMClient) GetRolePolicy(ctx context.Context, params *iam.GetRolePolicyInput, optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error) {
+	policy, ok := m.policies[*params.PolicyName]
+	if !ok {
+		return nil, &iamtypes.NoSuchEntityException{}
+	}
+	return &iam.GetRolePolicyOutput{PolicyName: params.PolicyName, PolicyDocument: aws.String(url.QueryEscape(policy))}, nil
+}
+func (m *mockIAMClient) PutRolePolicy(ctx context.Context, params *iam.PutRolePolicyInput, optFns ...func(*iam.Options)) (*iam.PutRolePolicyOutput, error) {
+	m.calls = append(m.calls, "PutRolePolicy")
+	if m.putErr != nil {
+		return nil, m.putErr
+	}
+	m.putCalls++
+	if m.policies == nil {
+		m.policies = map[string]string{}
+	}
+	m.policies[*params.PolicyName] = *params.PolicyDocument
+	return &iam.PutRolePolicyOutput{}, nil
+}
+func (m *mockIAMClient) DeleteRolePolicy(ctx context.Context, params *iam.DeleteRolePolicyInput, optFns ...func(*iam.Options)) (*iam.DeleteRolePolicyOutput, error) {
+	m.calls = append(m.calls, "DeleteRolePolicy")
+	delete(m.policies, *params.PolicyName)
+	return &iam.DeleteRolePolicyOutput{}, nil
 }
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// tagFlags holds the raw values of repeatable --tag key=value flags.
+var tagFlags []string
+
+// maxAwsTags is the number of tags which both S3 bucket and DynamoDB table accept.
+const maxAwsTags = 50
+
+// defaultAwsTags returns the tags put on every resource tfbackend creates.
+func defaultAwsTags() map[string]string {
+	return map[string]string{
+		"ManagedBy":        "tfbackend",
+		"TfbackendVersion": version,
+	}
+}
+
+// newAwsTags merges default tags, 'tags' section of the config file and --tag flags in this order.
+// The latter overrides the former for the same key.
+// Viper lowercases keys of maps in the config file, so the section is a list of key=value like --tag:
+//
+//	tags:
+//	  - CostCenter=1234
+func newAwsTags() (map[string]string, error) {
+	return buildAwsTags(append(viper.GetStringSlice("tags"), tagFlags...))
+}
+
+// buildAwsTags parses key=value pairs and merges them into the default tags.
+func buildAwsTags(pairs []string) (map[string]string, error) {
+	tags := defaultAwsTags()
+	for _, p := range pairs {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("tag must be key=value: %v", p)
+		}
+		tags[kv[0]] = kv[1]
+	}
+
+	for k, v := range tags {
+		if err := validateAwsTag(k, v); err != nil {
+			return nil, err
+		}
+	}
+	if len(tags) > maxAwsTags {
+		return nil, fmt.Errorf("too many tags: %v (max %v including default tags)", len(tags), maxAwsTags)
+	}
+	return tags, nil
+}
+
+// validateAwsTag checks the restrictions of tag common to S3 and DynamoDB.
+func validateAwsTag(key string, value string) error {
+	if key == "" || len(key) > 128 {
+		return fmt.Errorf("tag key must be 1 to 128 characters: %q", key)
+	}
+	if len(value) > 256 {
+		return fmt.Errorf("tag value must be 256 characters or less: %v", key)
+	}
+	if strings.HasPrefix(strings.ToLower(key), "aws:") {
+		return fmt.Errorf("tag key must not start with 'aws:': %v", key)
+	}
+	return nil
+}
+
+// missingTags returns the desired tags which current doesn't have with the same value.
+func missingTags(current map[string]string, desired map[string]string) map[string]string {
+	missing := map[string]string{}
+	for k, v := range desired {
+		if cur, ok := current[k]; !ok || cur != v {
+			missing[k] = v
+		}
+	}
+	return missing
+}
+
+// mergeTags returns a new map which has tags of base overridden by tags of overlay.
+func mergeTags(base map[string]string, overlay map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		merged[k] = v
+	}
+	return merged
+}
+
+// formatTags returns tags as "key=value" joined with comma in key order.
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+tags[k])
+	}
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_buildAwsTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "S01: Only default tags",
+			pairs:   nil,
+			want:    map[string]string{"ManagedBy": "tfbackend", "TfbackendVersion": version},
+			wantErr: false,
+		},
+		{
+			name:    "S02: The latter overrides the former",
+			pairs:   []string{"Team=infra", "ManagedBy=platform", "Team=sre", "Note=a=b"},
+			want:    map[string]string{"ManagedBy": "platform", "TfbackendVersion": version, "Team": "sre", "Note": "a=b"},
+			wantErr: false,
+		},
+		{
+			name:    "S03: Empty value",
+			pairs:   []string{"Team="},
+			want:    map[string]string{"ManagedBy": "tfbackend", "TfbackendVersion": version, "Team": ""},
+			wantErr: false,
+		},
+		{
+			name:    "F01: Without '='",
+			pairs:   []string{"Team"},
+			wantErr: true,
+		},
+		{
+			name:    "F02: Empty key",
+			pairs:   []string{"=infra"},
+			wantErr: true,
+		},
+		{
+			name:    "F03: Reserved prefix",
+			pairs:   []string{"AWS:Team=infra"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildAwsTags(tt.pairs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("buildAwsTags() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildAwsTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_missingTags(t *testing.T) {
+	current := map[string]string{"ManagedBy": "manual", "Team": "infra"}
+	desired := map[string]string{"ManagedBy": "tfbackend", "Team": "infra", "Env": "prod"}
+
+	want := map[string]string{"ManagedBy": "tfbackend", "Env": "prod"}
+	if got := missingTags(current, desired); !reflect.DeepEqual(got, want) {
+		t.Errorf("missingTags() = %v, want %v", got, want)
+	}
+}
+
+func Test_formatTags(t *testing.T) {
+	tags := map[string]string{"Team": "infra", "Env": "prod", "ManagedBy": "tfbackend"}
+
+	want := "Env=prod, ManagedBy=tfbackend, Team=infra"
+	if got := formatTags(tags); got != want {
+		t.Errorf("formatTags() = %v, want %v", got, want)
+	}
+}
@@ -1,45 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"reflect"
 	"testing"
-)
-
-func Test_validateBucketName(t *testing.T) {
-	type args struct {
-		bucketName string
-	}
-
-	// Given
-	tests := []struct {
-		name string
-		args args
-		want bool
-	}{
-		{
-			name: "S01: Happy path",
-			args: args{bucketName: "success-bucket"},
-			want: true,
-		},
-		{
-			name: "F01: Contains capitals",
-			args: args{bucketName: "FAILURE-BUCKET"},
-			want: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// When
-			res := validateBucketName(tt.args.bucketName)
 
-			// Then
-			if res != tt.want {
-				t.Errorf("validateBucketName() res = %v, want = %v", res, tt.want)
-			}
-		})
-	}
-}
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
 
 func Test_validateBillingMode(t *testing.T) {
 	type args struct {
@@ -86,6 +54,7 @@ func Test_initDynamoDB(t *testing.T) {
 		c           DynamoDBClientable
 		tableName   string
 		billingMode string
+		opt         initDynamoDBOption
 	}
 	tests := []struct {
 		name    string
@@ -96,50 +65,214 @@ func Test_initDynamoDB(t *testing.T) {
 		{
 			name: "S01: PROVISIONED, Write=5, Read=5",
 			args: args{
-				c:           mockDynamoDBClientAllSuccessProvisionedWrite5Read5{},
+				c:           &mockDynamoDBClient{table: mockTableProvisionedWrite5Read5()},
 				tableName:   "happy-bucket",
 				billingMode: "PROVISIONED",
 			},
 			want: &initDynamoDBResult{
-				TableName:     "happy-bucket",
-				BillingMode:   "PROVISIONED",
-				WriteCapacity: "5",
-				ReadCapacity:  "5",
+				TableName:           "happy-bucket",
+				BillingMode:         "PROVISIONED",
+				WriteCapacity:       "5",
+				ReadCapacity:        "5",
+				PointInTimeRecovery: "Disabled",
+				DeletionProtection:  "Disabled",
+				Encryption:          "AWS owned key",
 			},
 			wantErr: false,
 		},
 		{
 			name: "S02: PROVISIONED, Write=5, Read=5. AWS doesn't specify BillingModeSummary.",
 			args: args{
-				c:           mockDynamoDBClientAllSuccessProvisionedWrite5Read5WithoutBillingModeSummary{},
+				c:           &mockDynamoDBClient{table: mockTableProvisionedWrite5Read5WithoutBillingModeSummary()},
 				tableName:   "happy-bucket",
 				billingMode: "PROVISIONED",
 			},
 			want: &initDynamoDBResult{
-				TableName:     "happy-bucket",
-				BillingMode:   "PROVISIONED",
-				WriteCapacity: "5",
-				ReadCapacity:  "5",
+				TableName:           "happy-bucket",
+				BillingMode:         "PROVISIONED",
+				WriteCapacity:       "5",
+				ReadCapacity:        "5",
+				PointInTimeRecovery: "Disabled",
+				DeletionProtection:  "Disabled",
+				Encryption:          "AWS owned key",
 			},
 			wantErr: false,
 		},
 		{
 			name: "S03: PAY_PER_REQUEST",
 			args: args{
-				c:           mockDynamoDBClientAllSuccessPayPerRequest{},
+				c:           &mockDynamoDBClient{table: mockTablePayPerRequest()},
 				tableName:   "happy-bucket",
 				billingMode: "PAY_PER_REQUEST",
 			},
 			want: &initDynamoDBResult{
-				TableName:   "happy-bucket",
-				BillingMode: "PAY_PER_REQUEST",
+				TableName:           "happy-bucket",
+				BillingMode:         "PAY_PER_REQUEST",
+				PointInTimeRecovery: "Disabled",
+				DeletionProtection:  "Disabled",
+				Encryption:          "AWS owned key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S04: Existing table, same billing mode",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTablePayPerRequest(), updateErr: errors.New("must not be called")},
+				tableName:   "happy-bucket",
+				billingMode: "PAY_PER_REQUEST",
+			},
+			want: &initDynamoDBResult{
+				TableName:           "happy-bucket",
+				BillingMode:         "PAY_PER_REQUEST",
+				PointInTimeRecovery: "Disabled",
+				DeletionProtection:  "Disabled",
+				Encryption:          "AWS owned key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S05: Existing table, billing mode is updated",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTablePayPerRequest()},
+				tableName:   "happy-bucket",
+				billingMode: "PROVISIONED",
+			},
+			want: &initDynamoDBResult{
+				TableName:           "happy-bucket",
+				BillingMode:         "PROVISIONED",
+				WriteCapacity:       "5",
+				ReadCapacity:        "5",
+				PointInTimeRecovery: "Disabled",
+				DeletionProtection:  "Disabled",
+				Encryption:          "AWS owned key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S06: New table with PITR, SSE-KMS and deletion protection",
+			args: args{
+				c:           &mockDynamoDBClient{table: mockTablePayPerRequest()},
+				tableName:   "happy-bucket",
+				billingMode: "PAY_PER_REQUEST",
+				opt: initDynamoDBOption{
+					PointInTimeRecovery: true,
+					DeletionProtection:  true,
+					KMSKeyID:            "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				},
+			},
+			want: &initDynamoDBResult{
+				TableName:           "happy-bucket",
+				BillingMode:         "PAY_PER_REQUEST",
+				PointInTimeRecovery: "Enabled",
+				DeletionProtection:  "Enabled",
+				Encryption:          "KMS",
+				KMSKeyID:            "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S07: Existing table, PITR and deletion protection are already enabled",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, pitr: true, table: mockTableProtected(), updateErr: errors.New("must not be called"), pitrErr: errors.New("must not be called")},
+				tableName:   "happy-bucket",
+				billingMode: "PAY_PER_REQUEST",
+				opt: initDynamoDBOption{
+					PointInTimeRecovery: true,
+					DeletionProtection:  true,
+				},
+			},
+			want: &initDynamoDBResult{
+				TableName:           "happy-bucket",
+				BillingMode:         "PAY_PER_REQUEST",
+				PointInTimeRecovery: "Enabled",
+				DeletionProtection:  "Enabled",
+				Encryption:          "AWS owned key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S08: Existing table, provisioned capacity is updated",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableProvisionedWrite5Read5()},
+				tableName:   "happy-bucket",
+				billingMode: "PROVISIONED",
+				opt:         initDynamoDBOption{Capacity: tableCapacity{Read: 20, Write: 50}},
+			},
+			want: &initDynamoDBResult{
+				TableName:           "happy-bucket",
+				BillingMode:         "PROVISIONED",
+				WriteCapacity:       "50",
+				ReadCapacity:        "20",
+				PointInTimeRecovery: "Disabled",
+				DeletionProtection:  "Disabled",
+				Encryption:          "AWS owned key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S09: Existing table with auto scaling, capacity is left to auto scaling",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableProvisionedWrite5Read5(), updateErr: errors.New("must not be called")},
+				tableName:   "happy-bucket",
+				billingMode: "PROVISIONED",
+				opt: initDynamoDBOption{
+					Capacity:    tableCapacity{Read: 20, Write: 50},
+					AutoScaling: &autoScalingOption{MinCapacity: 5, MaxCapacity: 200, TargetUtilization: 70},
+				},
+			},
+			want: &initDynamoDBResult{
+				TableName:           "happy-bucket",
+				BillingMode:         "PROVISIONED",
+				WriteCapacity:       "5",
+				ReadCapacity:        "5",
+				PointInTimeRecovery: "Disabled",
+				DeletionProtection:  "Disabled",
+				Encryption:          "AWS owned key",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S10: New table with tags",
+			args: args{
+				c:           &mockDynamoDBClient{table: mockTableWithArn()},
+				tableName:   "happy-bucket",
+				billingMode: "PAY_PER_REQUEST",
+				opt:         initDynamoDBOption{Tags: map[string]string{"ManagedBy": "tfbackend"}},
+			},
+			want: &initDynamoDBResult{
+				TableName:           "happy-bucket",
+				TableArn:            "arn:aws:dynamodb:ap-northeast-1:123456789012:table/happy-bucket",
+				BillingMode:         "PAY_PER_REQUEST",
+				PointInTimeRecovery: "Disabled",
+				DeletionProtection:  "Disabled",
+				Encryption:          "AWS owned key",
+				Tags:                map[string]string{"ManagedBy": "tfbackend"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "S11: Existing table, tags are merged with the existing ones",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableWithArn(), tags: map[string]string{"Team": "infra", "ManagedBy": "manual"}},
+				tableName:   "happy-bucket",
+				billingMode: "PAY_PER_REQUEST",
+				opt:         initDynamoDBOption{Tags: map[string]string{"ManagedBy": "tfbackend"}},
+			},
+			want: &initDynamoDBResult{
+				TableName:           "happy-bucket",
+				TableArn:            "arn:aws:dynamodb:ap-northeast-1:123456789012:table/happy-bucket",
+				BillingMode:         "PAY_PER_REQUEST",
+				PointInTimeRecovery: "Disabled",
+				DeletionProtection:  "Disabled",
+				Encryption:          "AWS owned key",
+				Tags:                map[string]string{"Team": "infra", "ManagedBy": "tfbackend"},
 			},
 			wantErr: false,
 		},
 		{
 			name: "F01: CreateTable fails",
 			args: args{
-				c:           mockDynamoDBClientFailureCreateTableNG{},
+				c:           &mockDynamoDBClient{table: mockTablePayPerRequest(), createErr: errors.New("some error")},
 				tableName:   "failure-bucket",
 				billingMode: "PAY_PER_REQUEST",
 			},
@@ -149,9 +282,51 @@ func Test_initDynamoDB(t *testing.T) {
 		{
 			name: "F02: DescribeTable fails",
 			args: args{
-				c:           mockDynamoDBClientFailureDescribeTableNG{},
+				c:           &mockDynamoDBClient{table: mockTablePayPerRequest(), describeErr: errors.New("some error")},
+				tableName:   "failure-bucket",
+				billingMode: "PAY_PER_REQUEST",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F03: Existing table has invalid key schema",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableInvalidKeySchema()},
+				tableName:   "failure-bucket",
+				billingMode: "PAY_PER_REQUEST",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F04: UpdateTable fails",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTablePayPerRequest(), updateErr: errors.New("some error")},
+				tableName:   "failure-bucket",
+				billingMode: "PROVISIONED",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F05: UpdateContinuousBackups fails",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTablePayPerRequest(), pitrErr: errors.New("some error")},
 				tableName:   "failure-bucket",
 				billingMode: "PAY_PER_REQUEST",
+				opt:         initDynamoDBOption{PointInTimeRecovery: true},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F06: TagResource fails",
+			args: args{
+				c:           &mockDynamoDBClient{exists: true, table: mockTableWithArn(), tagErr: errors.New("some error")},
+				tableName:   "failure-table",
+				billingMode: "PAY_PER_REQUEST",
+				opt:         initDynamoDBOption{Tags: map[string]string{"ManagedBy": "tfbackend"}},
 			},
 			want:    nil,
 			wantErr: true,
@@ -159,7 +334,7 @@ func Test_initDynamoDB(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := initDynamoDB(tt.args.c, tt.args.tableName, tt.args.billingMode)
+			got, err := initDynamoDB(tt.args.c, tt.args.tableName, tt.args.billingMode, tt.args.opt, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("initDynamoDB() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -172,10 +347,17 @@ func Test_initDynamoDB(t *testing.T) {
 
 func Test_initDynamoDBResult_createTableInput(t *testing.T) {
 	type fields struct {
-		TableName     string
-		BillingMode   string
-		WriteCapacity string
-		ReadCapacity  string
+		TableName           string
+		BillingMode         string
+		WriteCapacity       string
+		ReadCapacity        string
+		PointInTimeRecovery string
+		DeletionProtection  string
+		Encryption          string
+		KMSKeyID            string
+		AutoScalingMin      string
+		AutoScalingMax      string
+		AutoScalingTarget   string
 	}
 	tests := []struct {
 		name       string
@@ -211,14 +393,64 @@ func Test_initDynamoDBResult_createTableInput(t *testing.T) {
 				{"Billing mode", "PAY_PER_REQUEST"},
 			},
 		},
+		{
+			name: "S03: PITR, deletion protection and SSE-KMS",
+			fields: fields{
+				TableName:           "happy-table",
+				BillingMode:         "PAY_PER_REQUEST",
+				PointInTimeRecovery: "Enabled",
+				DeletionProtection:  "Enabled",
+				Encryption:          "KMS",
+				KMSKeyID:            "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+			},
+			wantHeader: []string{"PARAMETER", "VALUE"},
+			wantBody: [][]string{
+				{"Table name", "happy-table"},
+				{"Billing mode", "PAY_PER_REQUEST"},
+				{"Point-in-time recovery", "Enabled"},
+				{"Deletion protection", "Enabled"},
+				{"Encryption", "KMS"},
+				{"KMS key", "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key"},
+			},
+		},
+		{
+			name: "S04: Auto scaling",
+			fields: fields{
+				TableName:         "happy-table",
+				BillingMode:       "PROVISIONED",
+				WriteCapacity:     "5",
+				ReadCapacity:      "5",
+				AutoScalingMin:    "5",
+				AutoScalingMax:    "200",
+				AutoScalingTarget: "70%",
+			},
+			wantHeader: []string{"PARAMETER", "VALUE"},
+			wantBody: [][]string{
+				{"Table name", "happy-table"},
+				{"Billing mode", "PROVISIONED"},
+				{"Write capacity", "5"},
+				{"Read capacity", "5"},
+				{"Auto scaling min capacity", "5"},
+				{"Auto scaling max capacity", "200"},
+				{"Auto scaling target utilization", "70%"},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			i := &initDynamoDBResult{
-				TableName:     tt.fields.TableName,
-				BillingMode:   tt.fields.BillingMode,
-				WriteCapacity: tt.fields.WriteCapacity,
-				ReadCapacity:  tt.fields.ReadCapacity,
+				TableName:           tt.fields.TableName,
+				BillingMode:         tt.fields.BillingMode,
+				WriteCapacity:       tt.fields.WriteCapacity,
+				ReadCapacity:        tt.fields.ReadCapacity,
+				PointInTimeRecovery: tt.fields.PointInTimeRecovery,
+				DeletionProtection:  tt.fields.DeletionProtection,
+				Encryption:          tt.fields.Encryption,
+				KMSKeyID:            tt.fields.KMSKeyID,
+
+				AutoScalingMinCapacity:       tt.fields.AutoScalingMin,
+				AutoScalingMaxCapacity:       tt.fields.AutoScalingMax,
+				AutoScalingTargetUtilization: tt.fields.AutoScalingTarget,
 			}
 			gotHeader, gotBody := i.createTableInput()
 			if !reflect.DeepEqual(gotHeader, tt.wantHeader) {
@@ -236,6 +468,7 @@ func Test_initS3(t *testing.T) {
 		c          S3Clientable
 		bucketName string
 		region     string
+		opt        initS3Option
 	}
 	tests := []struct {
 		name    string
@@ -252,104 +485,756 @@ func Test_initS3(t *testing.T) {
 			},
 			want: &initS3Result{
 				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
 				Region:            "ap-northeast-1",
 				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
 				Encryption:        "AES256",
 				Versioning:        "Enabled",
 			},
 			wantErr: false,
 		},
 		{
-			name: "F01: CreateBucket fails",
+			name: "S02: Happy path, SSE-KMS",
 			args: args{
-				c:          mockS3ClientCreateBucketFailure{},
-				bucketName: "error-bucket",
+				c:          mockS3ClientAllSuccessKMS{},
+				bucketName: "happy-bucket",
 				region:     "ap-northeast-1",
+				opt: initS3Option{
+					KMSKeyID: "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				},
 			},
-			want:    nil,
-			wantErr: true,
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "aws:kms",
+				KMSKeyID:          "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				BucketKey:         "Enabled",
+				Versioning:        "Enabled",
+			},
+			wantErr: false,
 		},
 		{
-			name: "F02: PutPublicAccessBlock fails",
+			name: "S03: S3-compatible object store skips AWS-only steps",
 			args: args{
-				c:          mockS3ClientPutPublicAccessBlockFailure{},
-				bucketName: "error-bucket",
-				region:     "ap-northeast-1",
+				c:          mockS3ClientS3Compatible{},
+				bucketName: "happy-bucket",
+				region:     "us-east-1",
+				opt: initS3Option{
+					S3Compatible: true,
+				},
 			},
-			want:    nil,
-			wantErr: true,
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "",
+				BlockPublicAccess: "Skipped",
+				ObjectOwnership:   "Skipped",
+				Encryption:        "Skipped",
+				Versioning:        "Enabled",
+			},
+			wantErr: false,
 		},
 		{
-			name: "F03: PutBucketEncryption fails",
+			name: "S04: Happy path, tags",
 			args: args{
-				c:          mockS3ClientPutBucketEncryptionFailure{},
-				bucketName: "error-bucket",
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "happy-bucket",
 				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Tags: map[string]string{"ManagedBy": "tfbackend"},
+				},
 			},
-			want:    nil,
-			wantErr: true,
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				Tags:              map[string]string{"ManagedBy": "tfbackend"},
+			},
+			wantErr: false,
 		},
 		{
-			name: "F04: PutBucketVersioning fails",
+			name: "S05: Happy path, bucket policy",
 			args: args{
-				c:          mockS3ClientPutBucketVersioningFailure{},
-				bucketName: "error-bucket",
+				c:          &mockS3ClientNewBucket{},
+				bucketName: "happy-bucket",
 				region:     "ap-northeast-1",
+				opt: initS3Option{
+					BucketPolicy: &bucketPolicyOption{DenyUnencryptedUploads: true},
+				},
 			},
-			want:    nil,
-			wantErr: true,
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				BucketPolicy:      "DenyInsecureTransport, DenyUnencryptedObjectUploads",
+			},
+			wantErr: false,
 		},
 		{
-			name: "F05: GetBucketLocation fails",
+			name: "S06: S3-compatible object store skips bucket policy",
 			args: args{
-				c:          mockS3ClientGetBucketLocationFailure{},
-				bucketName: "error-bucket",
-				region:     "ap-northeast-1",
+				c:          mockS3ClientS3Compatible{},
+				bucketName: "happy-bucket",
+				region:     "us-east-1",
+				opt: initS3Option{
+					S3Compatible: true,
+					BucketPolicy: &bucketPolicyOption{},
+				},
 			},
-			want:    nil,
-			wantErr: true,
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "",
+				BlockPublicAccess: "Skipped",
+				ObjectOwnership:   "Skipped",
+				Encryption:        "Skipped",
+				Versioning:        "Enabled",
+				BucketPolicy:      "Skipped",
+			},
+			wantErr: false,
 		},
 		{
-			name: "F06: GetPublicAccessBlock fails",
+			name: "S07: Happy path, lifecycle rule",
 			args: args{
-				c:          mockS3ClientGetPublicAccessBlockFailure{},
-				bucketName: "error-bucket",
+				c:          &mockS3ClientNewBucket{},
+				bucketName: "happy-bucket",
 				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Lifecycle: lifecycleOption{
+						NoncurrentVersionExpirationDays: 90,
+						NoncurrentVersionsToKeep:        3,
+						ExpireDeleteMarkers:             true,
+						AbortIncompleteUploadDays:       7,
+					},
+				},
 			},
-			want:    nil,
-			wantErr: true,
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				Lifecycle:         "Noncurrent versions expire after 90 days (newest 3 kept), Expired delete markers are removed, Incomplete uploads abort after 7 days",
+			},
+			wantErr: false,
 		},
 		{
-			name: "F07: GetBucketEncryption fails",
+			name: "S08: Happy path, server access logging",
 			args: args{
-				c:          mockS3ClientGetBucketEncryptionFailure{},
-				bucketName: "error-bucket",
+				c:          &mockS3ClientNewBucket{},
+				bucketName: "happy-bucket",
 				region:     "ap-northeast-1",
+				opt: initS3Option{
+					AccessLogging: newAccessLogging("happy-log-bucket", "", "happy-bucket"),
+				},
 			},
-			want:    nil,
-			wantErr: true,
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				AccessLogging:     "s3://happy-log-bucket/s3-access-logs/happy-bucket/",
+			},
+			wantErr: false,
 		},
 		{
-			name: "F08: GetBucketVersioning fails",
+			name: "S09: Happy path, replication",
 			args: args{
-				c:          mockS3ClientGetBucketVersioningFailure{},
-				bucketName: "error-bucket",
+				c:          &mockS3ClientNewBucket{},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Replication: &replicationOption{
+						RoleArn:         "arn:aws:iam::123456789012:role/happy-role",
+						ReplicaBucket:   "happy-bucket-replica",
+						ReplicaRegion:   "us-west-2",
+						ReplicaKMSKeyID: "arn:aws:kms:us-west-2:123456789012:key/replica-key",
+					},
+				},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				Replication:       "arn:aws:s3:::happy-bucket-replica (delete markers, KMS key arn:aws:kms:us-west-2:123456789012:key/replica-key)",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S10: Happy path, S3 lockfile",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt:        initS3Option{Lockfile: true},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				Lockfile:          "Supported",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S11: Happy path, Object Lock",
+			args: args{
+				c:          &mockS3ClientNewBucket{},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt:        initS3Option{ObjectLock: &objectLockOption{Mode: s3types.ObjectLockRetentionModeCompliance, Days: 30}},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				ObjectLock:        "COMPLIANCE 30 days",
+			},
+			wantErr: false,
+		},
+		{
+			name: "F00: HeadBucket fails",
+			args: args{
+				c:          mockS3ClientHeadBucketFailure{},
+				bucketName: "error-bucket",
 				region:     "ap-northeast-1",
 			},
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "F01: CreateBucket fails",
+			args: args{
+				c:          mockS3ClientCreateBucketFailure{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F02: PutPublicAccessBlock fails",
+			args: args{
+				c:          mockS3ClientPutPublicAccessBlockFailure{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F03: PutBucketEncryption fails",
+			args: args{
+				c:          mockS3ClientPutBucketEncryptionFailure{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F04: PutBucketVersioning fails",
+			args: args{
+				c:          mockS3ClientPutBucketVersioningFailure{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F05: GetBucketLocation fails",
+			args: args{
+				c:          mockS3ClientGetBucketLocationFailure{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F06: GetPublicAccessBlock fails",
+			args: args{
+				c:          mockS3ClientGetPublicAccessBlockFailure{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F07: GetBucketEncryption fails",
+			args: args{
+				c:          mockS3ClientGetBucketEncryptionFailure{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F08: GetBucketVersioning fails",
+			args: args{
+				c:          mockS3ClientGetBucketVersioningFailure{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F09: NotImplemented without --s3-compatible",
+			args: args{
+				c:          mockS3ClientS3Compatible{},
+				bucketName: "error-bucket",
+				region:     "us-east-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F10: Tags are not found in confirmation",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Tags: map[string]string{"Team": "infra"},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F11: Bucket policy is not found in confirmation",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					BucketPolicy: &bucketPolicyOption{},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F12: Incorrect KMS key is denied without SSE-KMS",
+			args: args{
+				c:          &mockS3ClientNewBucket{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					BucketPolicy: &bucketPolicyOption{DenyIncorrectKMSKey: true},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F13: Lifecycle rule is not found in confirmation",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Lifecycle: lifecycleOption{AbortIncompleteUploadDays: 7},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F14: Server access logging is not found in confirmation",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					AccessLogging: newAccessLogging("error-log-bucket", "", "error-bucket"),
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F15: Replication is not found in confirmation",
+			args: args{
+				c:          mockS3ClientAllSuccess{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Replication: &replicationOption{
+						RoleArn:       "arn:aws:iam::123456789012:role/error-role",
+						ReplicaBucket: "error-bucket-replica",
+						ReplicaRegion: "us-west-2",
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F16: Bucket doesn't support conditional writes for S3 lockfile",
+			args: args{
+				c:          &mockS3ClientConditionalWrites{ignoreIfNoneMatch: true},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt:        initS3Option{Lockfile: true},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F17: Existing bucket without Object Lock",
+			args: args{
+				c:          &mockS3ClientExistingBucket{versioning: s3types.BucketVersioningStatusEnabled},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+				opt:        initS3Option{ObjectLock: &objectLockOption{Mode: s3types.ObjectLockRetentionModeGovernance, Days: 30}},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F18: ACLs are not disabled in confirmation",
+			args: args{
+				c:          mockS3ClientOwnershipNotEnforced{},
+				bucketName: "error-bucket",
+				region:     "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := initS3(tt.args.c, tt.args.bucketName, tt.args.region, tt.args.opt, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initS3() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initS3() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_initS3_ExistingBucket(t *testing.T) {
+	type args struct {
+		c          *mockS3ClientExistingBucket
+		bucketName string
+		region     string
+		opt        initS3Option
+	}
+	tests := []struct {
+		name         string
+		args         args
+		want         *initS3Result
+		wantPutCalls int
+	}{
+		{
+			name: "S01: Already converged",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					publicAccessBlock: &s3types.PublicAccessBlockConfiguration{
+						BlockPublicAcls:       true,
+						BlockPublicPolicy:     true,
+						IgnorePublicAcls:      true,
+						RestrictPublicBuckets: true,
+					},
+					encryption: &s3types.ServerSideEncryptionConfiguration{
+						Rules: []s3types.ServerSideEncryptionRule{
+							{
+								ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{
+									SSEAlgorithm: s3types.ServerSideEncryptionAes256,
+								},
+							},
+						},
+					},
+					versioning: s3types.BucketVersioningStatusEnabled,
+					ownership:  &s3types.OwnershipControls{Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: s3types.ObjectOwnershipBucketOwnerEnforced}}},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+			},
+			wantPutCalls: 0,
+		},
+		{
+			name: "S02: Nothing configured",
+			args: args{
+				c:          &mockS3ClientExistingBucket{},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					KMSKeyID: "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "aws:kms",
+				KMSKeyID:          "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				BucketKey:         "Enabled",
+				Versioning:        "Enabled",
+			},
+			wantPutCalls: 4,
+		},
+		{
+			name: "S03: Tags are merged with the existing ones",
+			args: args{
+				c:          &mockS3ClientExistingBucket{tags: map[string]string{"Team": "infra", "ManagedBy": "manual"}},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Tags: map[string]string{"ManagedBy": "tfbackend"},
+				},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				Tags:              map[string]string{"Team": "infra", "ManagedBy": "tfbackend"},
+			},
+			wantPutCalls: 5,
+		},
+		{
+			name: "S04: Bucket policy is merged with the existing statements",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					policy: aws.String(`{"Version":"2012-10-17","Statement":{"Sid":"AllowReadOnly","Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789012:role/reader"},"Action":"s3:GetObject","Resource":"arn:aws:s3:::happy-bucket/*"}}`),
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					BucketPolicy: &bucketPolicyOption{},
+				},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				BucketPolicy:      "DenyInsecureTransport",
+			},
+			wantPutCalls: 5,
+		},
+		{
+			name: "S05: Lifecycle rule is added to the existing rules",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					lifecycleRules: []s3types.LifecycleRule{
+						{
+							ID:         aws.String("expire-logs"),
+							Status:     s3types.ExpirationStatusEnabled,
+							Filter:     &s3types.LifecycleRuleFilterMemberPrefix{Value: "logs/"},
+							Expiration: &s3types.LifecycleExpiration{Days: 30},
+						},
+					},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Lifecycle: lifecycleOption{NoncurrentVersionTransitionDays: 30, StorageClass: "GLACIER"},
+				},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				Lifecycle:         "Noncurrent versions move to GLACIER after 30 days",
+			},
+			wantPutCalls: 5,
+		},
+		{
+			name: "S06: Server access logging is moved to the log bucket",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					logging: &s3types.LoggingEnabled{TargetBucket: aws.String("old-log-bucket"), TargetPrefix: aws.String("logs/")},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					AccessLogging: newAccessLogging("happy-log-bucket", "happy/", "happy-bucket"),
+				},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				AccessLogging:     "s3://happy-log-bucket/happy/",
+			},
+			wantPutCalls: 5,
+		},
+		{
+			name: "S07: Replication rule is added to the existing rules with the same role",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					publicAccessBlock: &s3types.PublicAccessBlockConfiguration{BlockPublicAcls: true, BlockPublicPolicy: true, IgnorePublicAcls: true, RestrictPublicBuckets: true},
+					encryption: &s3types.ServerSideEncryptionConfiguration{
+						Rules: []s3types.ServerSideEncryptionRule{
+							{ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{SSEAlgorithm: s3types.ServerSideEncryptionAes256}},
+						},
+					},
+					versioning: s3types.BucketVersioningStatusEnabled,
+					ownership:  &s3types.OwnershipControls{Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: s3types.ObjectOwnershipBucketOwnerEnforced}}},
+					replication: &s3types.ReplicationConfiguration{
+						Role: aws.String("arn:aws:iam::123456789012:role/happy-role"),
+						Rules: []s3types.ReplicationRule{
+							{ID: aws.String("other-rule"), Priority: 2, Status: s3types.ReplicationRuleStatusEnabled},
+						},
+					},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt: initS3Option{
+					Replication: &replicationOption{
+						RoleArn:       "arn:aws:iam::123456789012:role/happy-role",
+						ReplicaBucket: "happy-bucket-replica",
+						ReplicaRegion: "us-west-2",
+					},
+				},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				Replication:       "arn:aws:s3:::happy-bucket-replica (delete markers)",
+			},
+			wantPutCalls: 1,
+		},
+		{
+			name: "S08: Default retention of Object Lock is changed",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					publicAccessBlock: &s3types.PublicAccessBlockConfiguration{BlockPublicAcls: true, BlockPublicPolicy: true, IgnorePublicAcls: true, RestrictPublicBuckets: true},
+					encryption: &s3types.ServerSideEncryptionConfiguration{
+						Rules: []s3types.ServerSideEncryptionRule{
+							{ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{SSEAlgorithm: s3types.ServerSideEncryptionAes256}},
+						},
+					},
+					versioning: s3types.BucketVersioningStatusEnabled,
+					ownership:  &s3types.OwnershipControls{Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: s3types.ObjectOwnershipBucketOwnerEnforced}}},
+					objectLock: &s3types.ObjectLockConfiguration{ObjectLockEnabled: s3types.ObjectLockEnabledEnabled},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+				opt:        initS3Option{ObjectLock: &objectLockOption{Mode: s3types.ObjectLockRetentionModeGovernance, Days: 90}},
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+				ObjectLock:        "GOVERNANCE 90 days",
+			},
+			wantPutCalls: 1,
+		},
+		{
+			name: "S09: ACLs enabled by the older default are disabled",
+			args: args{
+				c: &mockS3ClientExistingBucket{
+					publicAccessBlock: &s3types.PublicAccessBlockConfiguration{BlockPublicAcls: true, BlockPublicPolicy: true, IgnorePublicAcls: true, RestrictPublicBuckets: true},
+					encryption: &s3types.ServerSideEncryptionConfiguration{
+						Rules: []s3types.ServerSideEncryptionRule{
+							{ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{SSEAlgorithm: s3types.ServerSideEncryptionAes256}},
+						},
+					},
+					versioning: s3types.BucketVersioningStatusEnabled,
+					ownership:  &s3types.OwnershipControls{Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: s3types.ObjectOwnershipObjectWriter}}},
+				},
+				bucketName: "happy-bucket",
+				region:     "ap-northeast-1",
+			},
+			want: &initS3Result{
+				BucketName:        "happy-bucket",
+				BucketArn:         "arn:aws:s3:::happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+			},
+			wantPutCalls: 1,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := initS3(tt.args.c, tt.args.bucketName, tt.args.region)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("initS3() error = %v, wantErr %v", err, tt.wantErr)
+			got, err := initS3(tt.args.c, tt.args.bucketName, tt.args.region, tt.args.opt, nil)
+			if err != nil {
+				t.Errorf("initS3() error = %v, want nil", err)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("initS3() = %v, want %v", got, tt.want)
 			}
+			if tt.args.c.putCalls != tt.wantPutCalls {
+				t.Errorf("initS3() put calls = %v, want %v", tt.args.c.putCalls, tt.wantPutCalls)
+			}
 		})
 	}
 }
@@ -359,8 +1244,12 @@ func Test_initS3Result_createTableInput(t *testing.T) {
 		BucketName        string
 		Region            string
 		BlockPublicAccess string
+		ObjectOwnership   string
 		Encryption        string
+		KMSKeyID          string
+		BucketKey         string
 		Versioning        string
+		Tags              map[string]string
 	}
 	tests := []struct {
 		name       string
@@ -374,16 +1263,64 @@ func Test_initS3Result_createTableInput(t *testing.T) {
 				BucketName:        "happy-bucket",
 				Region:            "ap-northeast-1",
 				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "AES256",
+				Versioning:        "Enabled",
+			},
+			wantHeader: []string{"PARAMETER", "VALUE"},
+			wantBody: [][]string{
+				{"Bucket name", "happy-bucket"},
+				{"Region", "ap-northeast-1"},
+				{"Block Public Access", "Enabled"},
+				{"Object Ownership", "ACLs disabled"},
+				{"Encryption", "AES256"},
+				{"Versioning", "Enabled"},
+			},
+		},
+		{
+			name: "S02: SSE-KMS",
+			fields: fields{
+				BucketName:        "happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
+				Encryption:        "aws:kms",
+				KMSKeyID:          "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				BucketKey:         "Enabled",
+				Versioning:        "Enabled",
+			},
+			wantHeader: []string{"PARAMETER", "VALUE"},
+			wantBody: [][]string{
+				{"Bucket name", "happy-bucket"},
+				{"Region", "ap-northeast-1"},
+				{"Block Public Access", "Enabled"},
+				{"Object Ownership", "ACLs disabled"},
+				{"Encryption", "aws:kms"},
+				{"KMS key", "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key"},
+				{"Bucket key", "Enabled"},
+				{"Versioning", "Enabled"},
+			},
+		},
+		{
+			name: "S03: Tags",
+			fields: fields{
+				BucketName:        "happy-bucket",
+				Region:            "ap-northeast-1",
+				BlockPublicAccess: "Enabled",
+				ObjectOwnership:   "ACLs disabled",
 				Encryption:        "AES256",
 				Versioning:        "Enabled",
+				Tags:              map[string]string{"Team": "infra", "ManagedBy": "tfbackend"},
 			},
 			wantHeader: []string{"PARAMETER", "VALUE"},
 			wantBody: [][]string{
 				{"Bucket name", "happy-bucket"},
 				{"Region", "ap-northeast-1"},
 				{"Block Public Access", "Enabled"},
+				{"Object Ownership", "ACLs disabled"},
 				{"Encryption", "AES256"},
 				{"Versioning", "Enabled"},
+				{"Tags", "ManagedBy=tfbackend, Team=infra"},
 			},
 		},
 	}
@@ -393,8 +1330,12 @@ func Test_initS3Result_createTableInput(t *testing.T) {
 				BucketName:        tt.fields.BucketName,
 				Region:            tt.fields.Region,
 				BlockPublicAccess: tt.fields.BlockPublicAccess,
+				ObjectOwnership:   tt.fields.ObjectOwnership,
 				Encryption:        tt.fields.Encryption,
+				KMSKeyID:          tt.fields.KMSKeyID,
+				BucketKey:         tt.fields.BucketKey,
 				Versioning:        tt.fields.Versioning,
+				Tags:              tt.fields.Tags,
 			}
 			gotHeader, gotBody := i.createTableInput()
 			if !reflect.DeepEqual(gotHeader, tt.wantHeader) {
@@ -406,3 +1347,389 @@ func Test_initS3Result_createTableInput(t *testing.T) {
 		})
 	}
 }
+
+func Test_initKMS(t *testing.T) {
+	type args struct {
+		c         KMSClientable
+		s         STSGetCallerIdentityAPI
+		aliasName string
+		region    string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *initKMSResult
+		wantErr bool
+	}{
+		{
+			name: "S01: Happy path",
+			args: args{
+				c:         mockKMSClientAllSuccess{},
+				s:         mockSTSGetCallerIdentityAPI(mockGetCallerIdentityOK),
+				aliasName: "alias/tfbackend/happy-bucket",
+				region:    "ap-northeast-1",
+			},
+			want: &initKMSResult{
+				KeyID:       "happy-key",
+				KeyArn:      "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+				Alias:       "alias/tfbackend/happy-bucket",
+				KeyRotation: "Enabled",
+			},
+			wantErr: false,
+		},
+		{
+			name: "F01: GetCallerIdentity fails",
+			args: args{
+				c:         mockKMSClientAllSuccess{},
+				s:         mockSTSGetCallerIdentityAPI(mockGetCallerIdentityNG),
+				aliasName: "alias/tfbackend/error-bucket",
+				region:    "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F02: CreateKey fails",
+			args: args{
+				c:         mockKMSClientCreateKeyFailure{},
+				s:         mockSTSGetCallerIdentityAPI(mockGetCallerIdentityOK),
+				aliasName: "alias/tfbackend/error-bucket",
+				region:    "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F03: EnableKeyRotation fails",
+			args: args{
+				c:         mockKMSClientEnableKeyRotationFailure{},
+				s:         mockSTSGetCallerIdentityAPI(mockGetCallerIdentityOK),
+				aliasName: "alias/tfbackend/error-bucket",
+				region:    "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F04: CreateAlias fails",
+			args: args{
+				c:         mockKMSClientCreateAliasFailure{},
+				s:         mockSTSGetCallerIdentityAPI(mockGetCallerIdentityOK),
+				aliasName: "alias/tfbackend/error-bucket",
+				region:    "ap-northeast-1",
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := initKMS(tt.args.c, tt.args.s, tt.args.aliasName, tt.args.region, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initKMS() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initKMS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_defaultKMSKeyAlias(t *testing.T) {
+	tests := []struct {
+		name       string
+		bucketName string
+		want       string
+	}{
+		{
+			name:       "S01: Without dots",
+			bucketName: "happy-bucket",
+			want:       "alias/tfbackend/happy-bucket",
+		},
+		{
+			name:       "S02: With dots",
+			bucketName: "happy.bucket.example",
+			want:       "alias/tfbackend/happy-bucket-example",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaultKMSKeyAlias(tt.bucketName); got != tt.want {
+				t.Errorf("defaultKMSKeyAlias() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_initS3_Rollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		c         *mockS3ClientRollbackRecorder
+		opt       initS3Option
+		wantErr   bool
+		wantCalls []string
+	}{
+		{
+			name:      "S01: New bucket is deleted",
+			c:         &mockS3ClientRollbackRecorder{S3Clientable: mockS3ClientAllSuccess{}},
+			wantErr:   false,
+			wantCalls: []string{"DeleteBucket"},
+		},
+		{
+			name:      "S02: Nothing is recorded before CreateBucket succeeds",
+			c:         &mockS3ClientRollbackRecorder{S3Clientable: mockS3ClientCreateBucketFailure{}},
+			wantErr:   true,
+			wantCalls: nil,
+		},
+		{
+			name: "S03: Settings of existing bucket are restored in reverse order",
+			c: &mockS3ClientRollbackRecorder{S3Clientable: &mockS3ClientExistingBucket{
+				putVersioningErr: errors.New("some error"),
+			}},
+			wantErr:   true,
+			wantCalls: []string{"DeleteBucketEncryption", "DeleteBucketOwnershipControls", "DeletePublicAccessBlock"},
+		},
+		{
+			name:      "S04: Tags added to existing bucket are deleted",
+			c:         &mockS3ClientRollbackRecorder{S3Clientable: &mockS3ClientExistingBucket{}},
+			opt:       initS3Option{Tags: map[string]string{"ManagedBy": "tfbackend"}},
+			wantErr:   false,
+			wantCalls: []string{"DeleteBucketTagging", "DeleteBucketEncryption", "DeleteBucketOwnershipControls", "DeletePublicAccessBlock"},
+		},
+		{
+			name:      "S05: Bucket policy attached to existing bucket is deleted",
+			c:         &mockS3ClientRollbackRecorder{S3Clientable: &mockS3ClientExistingBucket{}},
+			opt:       initS3Option{BucketPolicy: &bucketPolicyOption{}},
+			wantErr:   false,
+			wantCalls: []string{"DeleteBucketPolicy", "DeleteBucketEncryption", "DeleteBucketOwnershipControls", "DeletePublicAccessBlock"},
+		},
+		{
+			name:      "S06: Lifecycle rule added to existing bucket is deleted",
+			c:         &mockS3ClientRollbackRecorder{S3Clientable: &mockS3ClientExistingBucket{}},
+			opt:       initS3Option{Lifecycle: lifecycleOption{ExpireDeleteMarkers: true}},
+			wantErr:   false,
+			wantCalls: []string{"DeleteBucketLifecycle", "DeleteBucketEncryption", "DeleteBucketOwnershipControls", "DeletePublicAccessBlock"},
+		},
+		{
+			name: "S07: Replication configured on existing bucket is deleted",
+			c:    &mockS3ClientRollbackRecorder{S3Clientable: &mockS3ClientExistingBucket{}},
+			opt: initS3Option{Replication: &replicationOption{
+				RoleArn:       "arn:aws:iam::123456789012:role/happy-role",
+				ReplicaBucket: "happy-bucket-replica",
+				ReplicaRegion: "us-west-2",
+			}},
+			wantErr:   false,
+			wantCalls: []string{"DeleteBucketReplication", "DeleteBucketEncryption", "DeleteBucketOwnershipControls", "DeletePublicAccessBlock"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &transaction{}
+			_, err := initS3(tt.c, "happy-bucket", "ap-northeast-1", tt.opt, tx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initS3() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err := tx.rollback(); err != nil {
+				t.Errorf("transaction.rollback() error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.c.calls, tt.wantCalls) {
+				t.Errorf("transaction.rollback() calls = %v, want %v", tt.c.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func Test_initDynamoDB_Rollback(t *testing.T) {
+	tests := []struct {
+		name        string
+		c           *mockDynamoDBClient
+		billingMode string
+		opt         initDynamoDBOption
+		wantDeleted bool
+		wantBilling string
+		wantTags    map[string]string
+	}{
+		{
+			name:        "S01: New table is deleted",
+			c:           &mockDynamoDBClient{table: mockTablePayPerRequest()},
+			billingMode: "PAY_PER_REQUEST",
+			wantDeleted: true,
+			wantBilling: "PAY_PER_REQUEST",
+		},
+		{
+			name:        "S02: Billing mode of existing table is restored",
+			c:           &mockDynamoDBClient{exists: true, table: mockTablePayPerRequest()},
+			billingMode: "PROVISIONED",
+			wantDeleted: false,
+			wantBilling: "PAY_PER_REQUEST",
+		},
+		{
+			name:        "S03: Deletion protection of new table is disabled before deletion",
+			c:           &mockDynamoDBClient{table: mockTablePayPerRequest()},
+			billingMode: "PAY_PER_REQUEST",
+			opt:         initDynamoDBOption{PointInTimeRecovery: true, DeletionProtection: true},
+			wantDeleted: true,
+			wantBilling: "PAY_PER_REQUEST",
+		},
+		{
+			name:        "S04: Tags of existing table are restored",
+			c:           &mockDynamoDBClient{exists: true, table: mockTableWithArn(), tags: map[string]string{"ManagedBy": "manual"}},
+			billingMode: "PAY_PER_REQUEST",
+			opt:         initDynamoDBOption{Tags: map[string]string{"ManagedBy": "tfbackend", "Team": "infra"}},
+			wantDeleted: false,
+			wantBilling: "PAY_PER_REQUEST",
+			wantTags:    map[string]string{"ManagedBy": "manual"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &transaction{}
+			if _, err := initDynamoDB(tt.c, "happy-table", tt.billingMode, tt.opt, tx); err != nil {
+				t.Errorf("initDynamoDB() error = %v", err)
+				return
+			}
+
+			if err := tx.rollback(); err != nil {
+				t.Errorf("transaction.rollback() error = %v", err)
+			}
+			if tt.c.deleted != tt.wantDeleted {
+				t.Errorf("transaction.rollback() deleted = %v, want %v", tt.c.deleted, tt.wantDeleted)
+			}
+			if got := tableBillingMode(tt.c.table); got != tt.wantBilling {
+				t.Errorf("transaction.rollback() billing mode = %v, want %v", got, tt.wantBilling)
+			}
+			if tt.wantTags != nil && !reflect.DeepEqual(tt.c.tags, tt.wantTags) {
+				t.Errorf("transaction.rollback() tags = %v, want %v", tt.c.tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func Test_initDynamoDBAutoScaling(t *testing.T) {
+	opt := autoScalingOption{MinCapacity: 5, MaxCapacity: 200, TargetUtilization: 70}
+	tests := []struct {
+		name           string
+		c              *mockAutoScalingClient
+		want           *initDynamoDBResult
+		wantRegistered int
+		wantErr        bool
+	}{
+		{
+			name: "S01: New scalable targets and policies",
+			c:    newMockAutoScalingClient(),
+			want: &initDynamoDBResult{
+				AutoScalingMinCapacity:       "5",
+				AutoScalingMaxCapacity:       "200",
+				AutoScalingTargetUtilization: "70%",
+			},
+			wantRegistered: 2,
+			wantErr:        false,
+		},
+		{
+			name: "S02: Already converged",
+			c:    mockAutoScalingClientRegistered(5, 200, 70),
+			want: &initDynamoDBResult{
+				AutoScalingMinCapacity:       "5",
+				AutoScalingMaxCapacity:       "200",
+				AutoScalingTargetUtilization: "70%",
+			},
+			wantRegistered: 0,
+			wantErr:        false,
+		},
+		{
+			name: "S03: Bounds and target of existing targets are updated",
+			c:    mockAutoScalingClientRegistered(1, 10, 50),
+			want: &initDynamoDBResult{
+				AutoScalingMinCapacity:       "5",
+				AutoScalingMaxCapacity:       "200",
+				AutoScalingTargetUtilization: "70%",
+			},
+			wantRegistered: 2,
+			wantErr:        false,
+		},
+		{
+			name:    "F01: RegisterScalableTarget fails",
+			c:       &mockAutoScalingClient{registerErr: errors.New("some error")},
+			wantErr: true,
+		},
+		{
+			name: "F02: PutScalingPolicy fails",
+			c: func() *mockAutoScalingClient {
+				m := newMockAutoScalingClient()
+				m.putErr = errors.New("some error")
+				return m
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &initDynamoDBResult{}
+			err := initDynamoDBAutoScaling(tt.c, "happy-table", opt, got, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initDynamoDBAutoScaling() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initDynamoDBAutoScaling() = %v, want %v", got, tt.want)
+			}
+			if tt.c.registered != tt.wantRegistered {
+				t.Errorf("initDynamoDBAutoScaling() registered %v times, want %v", tt.c.registered, tt.wantRegistered)
+			}
+		})
+	}
+}
+
+func Test_initDynamoDBAutoScaling_Rollback(t *testing.T) {
+	opt := autoScalingOption{MinCapacity: 5, MaxCapacity: 200, TargetUtilization: 70}
+	tests := []struct {
+		name        string
+		c           *mockAutoScalingClient
+		wantTargets int
+		wantMax     int32
+		wantTarget  float64
+	}{
+		{
+			name:        "S01: New scalable targets are deregistered",
+			c:           newMockAutoScalingClient(),
+			wantTargets: 0,
+		},
+		{
+			name:        "S02: Bounds and target of existing targets are restored",
+			c:           mockAutoScalingClientRegistered(1, 10, 50),
+			wantTargets: 2,
+			wantMax:     10,
+			wantTarget:  50,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &transaction{}
+			if err := initDynamoDBAutoScaling(tt.c, "happy-table", opt, &initDynamoDBResult{}, tx); err != nil {
+				t.Errorf("initDynamoDBAutoScaling() error = %v", err)
+				return
+			}
+
+			if err := tx.rollback(); err != nil {
+				t.Errorf("transaction.rollback() error = %v", err)
+			}
+			if len(tt.c.targets) != tt.wantTargets {
+				t.Errorf("transaction.rollback() targets = %v, want %v", len(tt.c.targets), tt.wantTargets)
+			}
+			for d, target := range tt.c.targets {
+				if *target.MaxCapacity != tt.wantMax {
+					t.Errorf("transaction.rollback() max capacity of %v = %v, want %v", d, *target.MaxCapacity, tt.wantMax)
+				}
+				if got := *tt.c.policies[d].TargetTrackingScalingPolicyConfiguration.TargetValue; got != tt.wantTarget {
+					t.Errorf("transaction.rollback() target value of %v = %v, want %v", d, got, tt.wantTarget)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// checkResult is the result of a compliance check.
+type checkResult string
+
+const (
+	checkResultPass checkResult = "PASS"
+	checkResultWarn checkResult = "WARN"
+	checkResultFail checkResult = "FAIL"
+)
+
+type verifyCheck struct {
+	Resource string
+	Name     string
+	Result   checkResult
+	Detail   string
+}
+
+func NewCmdAwsVerify() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check existing S3 bucket and DynamoDB table against the tfbackend baseline.",
+		Long: `Check existing S3 bucket and DynamoDB table against the tfbackend baseline.
+
+Only read-only APIs are called. Each check results in PASS, WARN or FAIL,
+and the command exits with non-zero status if any check fails.
+
+S3 bucket
+- Block public access: all four flags are enabled
+- ACLs disabled: Object Ownership is BucketOwnerEnforced
+- Default encryption: SSE-S3 or SSE-KMS
+- Versioning: Enabled
+- Bucket policy: denies requests without TLS (WARN if not)
+
+DynamoDB table
+- Hash key: only 'LockID' (string)
+- Billing mode: same as --billing-mode (WARN if not)
+- Point-in-time recovery: Enabled (WARN if not)
+`,
+		SilenceUsage: true,
+		RunE:         runCmdAwsVerify,
+	}
+
+	return cmd
+}
+
+func runCmdAwsVerify(cmd *cobra.Command, args []string) error {
+	// Validation
+	if err := validateAwsNames(); err != nil {
+		return err
+	}
+	if !validateBillingMode(billingMode) {
+		return fmt.Errorf("billing mode must be 'PAY_PER_REQUEST' or 'PROVISIONED': %v", billingMode)
+	}
+
+	// Load config
+	cfg, err := loadAwsConfig()
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	checks, err := verifyS3(newS3Client(cfg), bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to verify s3 bucket: %w", err)
+	}
+
+	if tableName != "" {
+		dynamoChecks, err := verifyDynamoDB(dynamodb.NewFromConfig(cfg), tableName, billingMode)
+		if err != nil {
+			return fmt.Errorf("failed to verify dynamodb table: %w", err)
+		}
+		checks = append(checks, dynamoChecks...)
+	}
+
+	printVerifyChecks(os.Stdout, checks)
+
+	failed := 0
+	for _, c := range checks {
+		if c.Result == checkResultFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v check(s) failed", failed)
+	}
+	return nil
+}
+
+// verifyS3 checks the existing bucket against the baseline.
+func verifyS3(c S3Clientable, bucketName string) ([]verifyCheck, error) {
+	const resource = "s3_bucket"
+
+	exists, err := s3BucketExists(context.TODO(), c, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existence of s3 bucket: %w", err)
+	}
+	if !exists {
+		return []verifyCheck{
+			{Resource: resource, Name: "Existence", Result: checkResultFail, Detail: "Bucket doesn't exist"},
+		}, nil
+	}
+
+	var checks []verifyCheck
+
+	// Block public access
+	check := verifyCheck{Resource: resource, Name: "Block public access"}
+	blockRes, err := getPublicAccessBlock(context.TODO(), c, bucketName)
+	if err != nil && !isAPIErrorCode(err, "NoSuchPublicAccessBlockConfiguration") {
+		return nil, fmt.Errorf("failed to get block public access status: %w", err)
+	}
+	if err != nil {
+		check.Detail = publicAccessBlockStatus(nil)
+	} else {
+		check.Detail = publicAccessBlockStatus(blockRes.PublicAccessBlockConfiguration)
+	}
+	check.Result = checkResultFail
+	if err == nil && isAllPublicAccessBlocked(blockRes.PublicAccessBlockConfiguration) {
+		check.Result = checkResultPass
+	}
+	checks = append(checks, check)
+
+	// Object ownership
+	ownership, err := getBucketOwnershipControls(context.TODO(), c, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ownership controls: %w", err)
+	}
+	check = verifyCheck{Resource: resource, Name: "ACLs disabled", Result: checkResultFail, Detail: objectOwnershipStatus(ownership)}
+	if isBucketOwnerEnforced(ownership) {
+		check.Result = checkResultPass
+	}
+	checks = append(checks, check)
+
+	// Default encryption
+	check = verifyCheck{Resource: resource, Name: "Default encryption", Result: checkResultFail, Detail: "Not configured"}
+	encryptionRes, err := getBucketEncryption(context.TODO(), c, bucketName)
+	if err != nil && !isAPIErrorCode(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		return nil, fmt.Errorf("failed to get bucket encryption status: %w", err)
+	}
+	if err == nil && len(encryptionRes.ServerSideEncryptionConfiguration.Rules) > 0 {
+		if rule := encryptionRes.ServerSideEncryptionConfiguration.Rules[0]; rule.ApplyServerSideEncryptionByDefault != nil {
+			check.Result = checkResultPass
+			check.Detail = string(rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+		}
+	}
+	checks = append(checks, check)
+
+	// Versioning
+	versioningRes, err := getBucketVersioning(context.TODO(), c, bucketName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket versioning status: %w", err)
+	}
+	check = verifyCheck{Resource: resource, Name: "Versioning", Result: checkResultFail, Detail: string(versioningRes.Status)}
+	if versioningRes.Status == s3types.BucketVersioningStatusEnabled {
+		check.Result = checkResultPass
+	}
+	if check.Detail == "" {
+		check.Detail = "Not configured"
+	}
+	checks = append(checks, check)
+
+	// TLS-only bucket policy
+	check = verifyCheck{Resource: resource, Name: "TLS-only bucket policy", Result: checkResultWarn, Detail: "No bucket policy"}
+	policyRes, err := getBucketPolicy(context.TODO(), c, bucketName)
+	if err != nil && !isAPIErrorCode(err, "NoSuchBucketPolicy") {
+		return nil, fmt.Errorf("failed to get bucket policy: %w", err)
+	}
+	if err == nil && policyRes.Policy != nil {
+		check.Detail = "Requests without TLS are not denied"
+		if isTLSOnlyBucketPolicy(*policyRes.Policy) {
+			check.Result = checkResultPass
+			check.Detail = "Requests without TLS are denied"
+		}
+	}
+	checks = append(checks, check)
+
+	return checks, nil
+}
+
+// verifyDynamoDB checks the existing table against the baseline.
+func verifyDynamoDB(c DynamoDBClientable, tableName string, billingMode string) ([]verifyCheck, error) {
+	const resource = "dynamodb_table"
+
+	desc, err := describeDynamoDBTable(context.TODO(), c, tableName)
+	if err != nil {
+		if isAPIErrorCode(err, "ResourceNotFoundException") {
+			return []verifyCheck{
+				{Resource: resource, Name: "Existence", Result: checkResultFail, Detail: "Table doesn't exist"},
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to describe dynamodb table: %w", err)
+	}
+
+	var checks []verifyCheck
+
+	// Key schema
+	check := verifyCheck{Resource: resource, Name: "Hash key", Result: checkResultFail, Detail: "Must have only 'LockID' (string) as hash key"}
+	if hasLockIDKeySchema(desc.Table) {
+		check.Result = checkResultPass
+		check.Detail = "LockID (S)"
+	}
+	checks = append(checks, check)
+
+	// Billing mode
+	current := tableBillingMode(desc.Table)
+	check = verifyCheck{Resource: resource, Name: "Billing mode", Result: checkResultPass, Detail: current}
+	if current != billingMode {
+		check.Result = checkResultWarn
+		check.Detail = fmt.Sprintf("%v (expected %v)", current, billingMode)
+	}
+	checks = append(checks, check)
+
+	// Point-in-time recovery
+	backupsRes, err := describeDynamoDBContinuousBackups(context.TODO(), c, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe continuous backups: %w", err)
+	}
+	check = verifyCheck{Resource: resource, Name: "Point-in-time recovery", Result: checkResultWarn, Detail: string(dynamodbtypes.PointInTimeRecoveryStatusDisabled)}
+	if d := backupsRes.ContinuousBackupsDescription; d != nil && d.PointInTimeRecoveryDescription != nil {
+		check.Detail = string(d.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus)
+		if d.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus == dynamodbtypes.PointInTimeRecoveryStatusEnabled {
+			check.Result = checkResultPass
+		}
+	}
+	checks = append(checks, check)
+
+	// Deletion protection
+	check = verifyCheck{Resource: resource, Name: "Deletion protection", Result: checkResultWarn, Detail: "Disabled"}
+	if desc.Table.DeletionProtectionEnabled != nil && *desc.Table.DeletionProtectionEnabled {
+		check.Result = checkResultPass
+		check.Detail = "Enabled"
+	}
+	checks = append(checks, check)
+
+	return checks, nil
+}
+
+// isTLSOnlyBucketPolicy checks if the policy denies all S3 actions requested without TLS.
+func isTLSOnlyBucketPolicy(policy string) bool {
+	var doc struct {
+		Statement json.RawMessage
+	}
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		return false
+	}
+
+	// Statement can be a single object or an array of objects.
+	var statements []map[string]interface{}
+	if err := json.Unmarshal(doc.Statement, &statements); err != nil {
+		var statement map[string]interface{}
+		if err := json.Unmarshal(doc.Statement, &statement); err != nil {
+			return false
+		}
+		statements = append(statements, statement)
+	}
+
+	for _, st := range statements {
+		if st["Effect"] != "Deny" {
+			continue
+		}
+		if !containsPolicyValue(st["Action"], "s3:*") {
+			continue
+		}
+		condition, _ := st["Condition"].(map[string]interface{})
+		boolCondition, _ := condition["Bool"].(map[string]interface{})
+		if containsPolicyValue(boolCondition["aws:SecureTransport"], "false") {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPolicyValue checks if the policy element, which is a single value or a list, contains the value.
+func containsPolicyValue(element interface{}, value string) bool {
+	switch v := element.(type) {
+	case string:
+		return v == value
+	case bool:
+		return fmt.Sprint(v) == value
+	case []interface{}:
+		for _, e := range v {
+			if containsPolicyValue(e, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// printVerifyChecks prints the checks as a table followed by the summary.
+func printVerifyChecks(w io.Writer, checks []verifyCheck) {
+	var pass, warn, fail int
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"RESOURCE", "CHECK", "RESULT", "DETAIL"})
+	for _, c := range checks {
+		switch c.Result {
+		case checkResultPass:
+			pass++
+		case checkResultWarn:
+			warn++
+		case checkResultFail:
+			fail++
+		}
+		table.Append([]string{c.Resource, c.Name, string(c.Result), c.Detail})
+	}
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.Render()
+
+	fmt.Fprintf(w, "\nVerify: %v passed, %v warned, %v failed.\n", pass, warn, fail)
+}
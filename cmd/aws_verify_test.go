@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const mockTLSOnlyBucketPolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "DenyInsecureTransport",
+      "Effect": "Deny",
+      "Principal": "*",
+      "Action": "s3:*",
+      "Resource": ["arn:aws:s3:::happy-bucket", "arn:aws:s3:::happy-bucket/*"],
+      "Condition": {"Bool": {"aws:SecureTransport": "false"}}
+    }
+  ]
+}`
+
+func Test_verifyS3(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  S3Clientable
+		want    map[string]checkResult
+		wantErr bool
+	}{
+		{
+			name: "S01: Compliant bucket",
+			client: &mockS3ClientExistingBucket{
+				publicAccessBlock: &s3types.PublicAccessBlockConfiguration{BlockPublicAcls: true, BlockPublicPolicy: true, IgnorePublicAcls: true, RestrictPublicBuckets: true},
+				encryption: &s3types.ServerSideEncryptionConfiguration{
+					Rules: []s3types.ServerSideEncryptionRule{
+						{
+							ApplyServerSideEncryptionByDefault: &s3types.ServerSideEncryptionByDefault{
+								SSEAlgorithm: s3types.ServerSideEncryptionAes256,
+							},
+						},
+					},
+				},
+				versioning: s3types.BucketVersioningStatusEnabled,
+				policy:     aws.String(mockTLSOnlyBucketPolicy),
+				ownership:  &s3types.OwnershipControls{Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: s3types.ObjectOwnershipBucketOwnerEnforced}}},
+			},
+			want: map[string]checkResult{
+				"Block public access":    checkResultPass,
+				"ACLs disabled":          checkResultPass,
+				"Default encryption":     checkResultPass,
+				"Versioning":             checkResultPass,
+				"TLS-only bucket policy": checkResultPass,
+			},
+			wantErr: false,
+		},
+		{
+			name: "S02: Partially blocked and unversioned bucket without policy",
+			client: &mockS3ClientExistingBucket{
+				publicAccessBlock: &s3types.PublicAccessBlockConfiguration{BlockPublicAcls: true, BlockPublicPolicy: true},
+				encryption:        nil,
+				versioning:        s3types.BucketVersioningStatusSuspended,
+			},
+			want: map[string]checkResult{
+				"Block public access":    checkResultFail,
+				"ACLs disabled":          checkResultFail,
+				"Default encryption":     checkResultFail,
+				"Versioning":             checkResultFail,
+				"TLS-only bucket policy": checkResultWarn,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "S03: Bucket doesn't exist",
+			client: mockS3ClientAllSuccess{},
+			want: map[string]checkResult{
+				"Existence": checkResultFail,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "F01: HeadBucket failure",
+			client:  mockS3ClientHeadBucketFailure{},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifyS3(tt.client, "happy-bucket")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyS3() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("verifyS3() = %v, want %v", got, tt.want)
+				return
+			}
+			for _, c := range got {
+				if c.Result != tt.want[c.Name] {
+					t.Errorf("verifyS3() %v = %v (%v), want %v", c.Name, c.Result, c.Detail, tt.want[c.Name])
+				}
+			}
+		})
+	}
+}
+
+func Test_verifyDynamoDB(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      *mockDynamoDBClient
+		billingMode string
+		want        map[string]checkResult
+		wantErr     bool
+	}{
+		{
+			name:        "S01: Compliant table",
+			client:      &mockDynamoDBClient{exists: true, table: mockTableProtected(), pitr: true},
+			billingMode: "PAY_PER_REQUEST",
+			want: map[string]checkResult{
+				"Hash key":               checkResultPass,
+				"Billing mode":           checkResultPass,
+				"Point-in-time recovery": checkResultPass,
+				"Deletion protection":    checkResultPass,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "S02: Unexpected billing mode without PITR",
+			client:      &mockDynamoDBClient{exists: true, table: mockTableProvisionedWrite5Read5()},
+			billingMode: "PAY_PER_REQUEST",
+			want: map[string]checkResult{
+				"Hash key":               checkResultPass,
+				"Billing mode":           checkResultWarn,
+				"Point-in-time recovery": checkResultWarn,
+				"Deletion protection":    checkResultWarn,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "S03: Invalid key schema",
+			client:      &mockDynamoDBClient{exists: true, table: mockTableInvalidKeySchema(), pitr: true},
+			billingMode: "PAY_PER_REQUEST",
+			want: map[string]checkResult{
+				"Hash key":               checkResultFail,
+				"Billing mode":           checkResultPass,
+				"Point-in-time recovery": checkResultPass,
+				"Deletion protection":    checkResultWarn,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "S04: Table doesn't exist",
+			client:      &mockDynamoDBClient{exists: false},
+			billingMode: "PROVISIONED",
+			want: map[string]checkResult{
+				"Existence": checkResultFail,
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifyDynamoDB(tt.client, "happy-table", tt.billingMode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyDynamoDB() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("verifyDynamoDB() = %v, want %v", got, tt.want)
+				return
+			}
+			for _, c := range got {
+				if c.Result != tt.want[c.Name] {
+					t.Errorf("verifyDynamoDB() %v = %v (%v), want %v", c.Name, c.Result, c.Detail, tt.want[c.Name])
+				}
+			}
+		})
+	}
+}
+
+func Test_isTLSOnlyBucketPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   bool
+	}{
+		{
+			name:   "S01: TLS-only policy",
+			policy: mockTLSOnlyBucketPolicy,
+			want:   true,
+		},
+		{
+			name:   "S02: Single statement with action list and boolean condition",
+			policy: `{"Statement": {"Effect": "Deny", "Principal": "*", "Action": ["s3:*"], "Condition": {"Bool": {"aws:SecureTransport": false}}}}`,
+			want:   true,
+		},
+		{
+			name:   "S03: Allow statement",
+			policy: `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:*", "Condition": {"Bool": {"aws:SecureTransport": "false"}}}]}`,
+			want:   false,
+		},
+		{
+			name:   "S04: Only some actions are denied",
+			policy: `{"Statement": [{"Effect": "Deny", "Principal": "*", "Action": "s3:GetObject", "Condition": {"Bool": {"aws:SecureTransport": "false"}}}]}`,
+			want:   false,
+		},
+		{
+			name:   "S05: Invalid JSON",
+			policy: `{`,
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTLSOnlyBucketPolicy(tt.policy); got != tt.want {
+				t.Errorf("isTLSOnlyBucketPolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_publicAccessBlockStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *s3types.PublicAccessBlockConfiguration
+		want string
+	}{
+		{
+			name: "S01: All enabled",
+			cfg:  &s3types.PublicAccessBlockConfiguration{BlockPublicAcls: true, BlockPublicPolicy: true, IgnorePublicAcls: true, RestrictPublicBuckets: true},
+			want: "Enabled",
+		},
+		{
+			name: "S02: All disabled",
+			cfg:  &s3types.PublicAccessBlockConfiguration{},
+			want: "Disabled",
+		},
+		{
+			name: "S03: Partially enabled",
+			cfg:  &s3types.PublicAccessBlockConfiguration{BlockPublicAcls: true, BlockPublicPolicy: true},
+			want: "Partially enabled (disabled: IgnorePublicAcls, RestrictPublicBuckets)",
+		},
+		{
+			name: "S04: Not configured",
+			cfg:  nil,
+			want: "Not configured",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := publicAccessBlockStatus(tt.cfg); got != tt.want {
+				t.Errorf("publicAccessBlockStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_printVerifyChecks(t *testing.T) {
+	var buf bytes.Buffer
+	printVerifyChecks(&buf, []verifyCheck{
+		{Resource: "s3_bucket", Name: "Versioning", Result: checkResultPass, Detail: "Enabled"},
+		{Resource: "s3_bucket", Name: "TLS-only bucket policy", Result: checkResultWarn, Detail: "No bucket policy"},
+		{Resource: "dynamodb_table", Name: "Hash key", Result: checkResultFail, Detail: "Must have only 'LockID' (string) as hash key"},
+	})
+	if want := "Verify: 1 passed, 1 warned, 1 failed."; !strings.Contains(buf.String(), want) {
+		t.Errorf("printVerifyChecks() = %v, want to contain %v", buf.String(), want)
+	}
+}
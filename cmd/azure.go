@@ -0,0 +1,411 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	azureSubscriptionID      string
+	azureResourceGroupName   string
+	azureStorageAccountName  string
+	azureContainerName       string
+	azureLocation            string
+	azureSkuName             string
+	azureSoftDeleteRetention int
+)
+
+// initAzureOption holds settings of the terraform backend on Azure Storage.
+type initAzureOption struct {
+	Location string
+	SkuName  string
+	// SoftDeleteRetentionDays is the number of days deleted blobs are kept.
+	SoftDeleteRetentionDays int
+}
+
+// azureSetting is a setting applied by a step.
+type azureSetting struct {
+	step      string
+	isDesired bool
+	// apply applies the setting, and restore reverts it to the value before the step.
+	apply   func() error
+	restore func() error
+}
+
+type initAzureResult struct {
+	ResourceGroupName     string `json:"resource_group_name" yaml:"resource_group_name"`
+	StorageAccountName    string `json:"storage_account_name" yaml:"storage_account_name"`
+	ContainerName         string `json:"container_name" yaml:"container_name"`
+	Location              string `json:"location" yaml:"location"`
+	HTTPSOnly             string `json:"https_only" yaml:"https_only"`
+	MinimumTLSVersion     string `json:"minimum_tls_version" yaml:"minimum_tls_version"`
+	AllowBlobPublicAccess string `json:"allow_blob_public_access" yaml:"allow_blob_public_access"`
+	BlobVersioning        string `json:"blob_versioning" yaml:"blob_versioning"`
+	BlobSoftDelete        string `json:"blob_soft_delete" yaml:"blob_soft_delete"`
+}
+
+func NewCmdAzure() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "azure",
+		Short: "Create Azure Storage container for terraform backend.",
+		Long: `Create Azure Storage container for terraform backend.
+
+Resource group, storage account and blob container are created.
+By default, the storage account configuration is below.
+- Kind: StorageV2, SKU: Standard_LRS
+- Enabled HTTPS-only traffic
+- Minimum TLS version: TLS1_2
+- Disabled public blob access
+- Enabled blob versioning
+- Enabled blob soft delete (7 days)
+
+The blob container is created with private access.
+Azure Storage provides state locking by blob lease, so no lock table is needed.
+
+If the resources already exist, they are adopted
+and each setting is converged to the configuration above.
+
+Authentication uses AZURE_ACCESS_TOKEN or 'az account get-access-token'.
+`,
+		SilenceUsage: true,
+		RunE:         runCmdAzure,
+	}
+
+	// flag
+	cmd.Flags().StringVarP(&azureSubscriptionID, "subscription", "", os.Getenv("AZURE_SUBSCRIPTION_ID"), "ID of Azure subscription. Default is AZURE_SUBSCRIPTION_ID.")
+	cmd.Flags().StringVarP(&azureResourceGroupName, "resource-group", "", "", "Name of resource group to create.")
+	cmd.MarkFlagRequired("resource-group")
+	cmd.Flags().StringVarP(&azureStorageAccountName, "storage-account", "", "", "Name of storage account to create.")
+	cmd.MarkFlagRequired("storage-account")
+	cmd.Flags().StringVarP(&azureContainerName, "container", "", "tfstate", "Name of blob container to create.")
+	cmd.Flags().StringVarP(&azureLocation, "location", "", "", "Location of the resources, e.g. japaneast.")
+	cmd.MarkFlagRequired("location")
+	cmd.Flags().StringVarP(&azureSkuName, "sku", "", "Standard_LRS", "SKU of storage account, e.g. Standard_LRS, Standard_ZRS or Standard_GRS.")
+	cmd.Flags().IntVarP(&azureSoftDeleteRetention, "soft-delete-retention-days", "", 7, "Days to keep deleted blobs. Between 1 and 365.")
+	cmd.Flags().BoolVarP(&noRollback, "no-rollback", "", false, "Don't rollback resources created or updated before a failure. Useful for debugging.")
+
+	return cmd
+}
+
+func runCmdAzure(cmd *cobra.Command, args []string) error {
+	// Validation
+	if azureSubscriptionID == "" {
+		return fmt.Errorf("subscription is not specified. Set --subscription or AZURE_SUBSCRIPTION_ID")
+	}
+	if !validateStorageAccountName(azureStorageAccountName) {
+		return fmt.Errorf("storage account name must be 3-24 characters of lowercase letters and numbers: %v", azureStorageAccountName)
+	}
+	if !validateContainerName(azureContainerName) {
+		return fmt.Errorf("container name must be 3-63 characters of lowercase letters, numbers and hyphens: %v", azureContainerName)
+	}
+	if azureSoftDeleteRetention < 1 || azureSoftDeleteRetention > 365 {
+		return fmt.Errorf("--soft-delete-retention-days must be between 1 and 365: %v", azureSoftDeleteRetention)
+	}
+
+	opt := initAzureOption{
+		Location:                azureLocation,
+		SkuName:                 azureSkuName,
+		SoftDeleteRetentionDays: azureSoftDeleteRetention,
+	}
+
+	tx := &transaction{}
+	res, err := initAzure(newAzureClient(azureSubscriptionID), azureResourceGroupName, azureStorageAccountName, azureContainerName, opt, tx)
+	if err != nil {
+		return abortTransaction(tx, fmt.Errorf("failed to initialize azure storage: %w", err))
+	}
+
+	printCyan(fmt.Sprintf("Successfully create terraform backend - azure storage container: %v\n", azureContainerName))
+	fmt.Fprintf(progressOut, "Backend ... \n\n")
+	bTable := tablewriter.NewWriter(os.Stdout)
+	h, b := res.createBackendTableInput()
+	bTable.SetHeader(h)
+	for _, v := range b {
+		bTable.Append(v)
+	}
+	bTable.SetAlignment(tablewriter.ALIGN_LEFT)
+	bTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	bTable.SetCenterSeparator("|")
+	bTable.Render()
+
+	fmt.Fprintf(progressOut, "\nDetail ... \n\n")
+	dTable := tablewriter.NewWriter(os.Stdout)
+	h, b = res.createTableInput()
+	dTable.SetHeader(h)
+	for _, v := range b {
+		dTable.Append(v)
+	}
+	dTable.SetAlignment(tablewriter.ALIGN_LEFT)
+	dTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	dTable.SetCenterSeparator("|")
+	dTable.Render()
+
+	return nil
+}
+
+// initAzure setup terraform backend on Azure Storage with messages.
+func initAzure(c AzureClientable, resourceGroup string, accountName string, containerName string, opt initAzureOption, tx *transaction) (*initAzureResult, error) {
+	progress.section("azure_storage", "🚀 Start to create terraform backend: azure storage ...")
+
+	// Create resource group. If the group already exists, adopt it.
+	progress.begin("Creating resource group")
+	_, err := c.GetResourceGroup(context.TODO(), resourceGroup)
+	if err != nil && !isAzureStatusCode(err, 404) {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of resource group: %w", err)
+	}
+	if err == nil {
+		progress.end(stepStatusUnchanged)
+	} else {
+		if _, err := c.CreateResourceGroup(context.TODO(), resourceGroup, opt.Location); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create resource group: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete resource group %v", resourceGroup), func() error {
+			return c.DeleteResourceGroup(context.TODO(), resourceGroup)
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Create storage account with the secure settings. If the account already exists, adopt it.
+	progress.begin("Creating storage account")
+	account, err := c.GetStorageAccount(context.TODO(), resourceGroup, accountName)
+	adopted := err == nil
+	if err != nil && !isAzureStatusCode(err, 404) {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of storage account: %w", err)
+	}
+	if adopted {
+		progress.end(stepStatusUnchanged)
+	} else {
+		account, err = c.CreateStorageAccount(context.TODO(), resourceGroup, &azureStorageAccount{
+			Name:     accountName,
+			Location: opt.Location,
+			Kind:     "StorageV2",
+			Sku:      &azureSku{Name: opt.SkuName},
+			Properties: &azureStorageAccountProperties{
+				SupportsHTTPSTrafficOnly: boolPtr(true),
+				MinimumTLSVersion:        "TLS1_2",
+				AllowBlobPublicAccess:    boolPtr(false),
+			},
+		})
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create storage account: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete storage account %v", accountName), func() error {
+			return c.DeleteStorageAccount(context.TODO(), resourceGroup, accountName)
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	blobService, err := c.GetBlobServiceProperties(context.TODO(), resourceGroup, accountName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob service properties: %w", err)
+	}
+
+	updateAccount := func(p *azureStorageAccountProperties) func() error {
+		return func() error {
+			_, err := c.UpdateStorageAccount(context.TODO(), resourceGroup, accountName, p)
+			return err
+		}
+	}
+	setBlobService := func(p *azureBlobServiceProperties) func() error {
+		return func() error {
+			return c.SetBlobServiceProperties(context.TODO(), resourceGroup, accountName, p)
+		}
+	}
+	softDelete := &azureDeleteRetentionPolicy{Enabled: true, Days: opt.SoftDeleteRetentionDays}
+	settings := []azureSetting{
+		{
+			step:      "Activate HTTPS-only traffic",
+			isDesired: isAzureHTTPSOnly(account),
+			apply:     updateAccount(&azureStorageAccountProperties{SupportsHTTPSTrafficOnly: boolPtr(true)}),
+			restore:   updateAccount(&azureStorageAccountProperties{SupportsHTTPSTrafficOnly: boolPtr(isAzureHTTPSOnly(account))}),
+		},
+		{
+			step:      "Apply minimum TLS version",
+			isDesired: azureMinimumTLSVersion(account) == "TLS1_2",
+			apply:     updateAccount(&azureStorageAccountProperties{MinimumTLSVersion: "TLS1_2"}),
+			restore:   updateAccount(&azureStorageAccountProperties{MinimumTLSVersion: azureMinimumTLSVersion(account)}),
+		},
+		{
+			step:      "Disable public blob access",
+			isDesired: !isAzureBlobPublicAccessAllowed(account),
+			apply:     updateAccount(&azureStorageAccountProperties{AllowBlobPublicAccess: boolPtr(false)}),
+			restore:   updateAccount(&azureStorageAccountProperties{AllowBlobPublicAccess: boolPtr(isAzureBlobPublicAccessAllowed(account))}),
+		},
+		{
+			step:      "Activate blob versioning",
+			isDesired: isAzureBlobVersioningEnabled(blobService),
+			apply:     setBlobService(&azureBlobServiceProperties{IsVersioningEnabled: boolPtr(true)}),
+			restore:   setBlobService(&azureBlobServiceProperties{IsVersioningEnabled: boolPtr(false)}),
+		},
+		{
+			step:      "Activate blob soft delete",
+			isDesired: hasAzureBlobSoftDelete(blobService, opt.SoftDeleteRetentionDays),
+			apply:     setBlobService(&azureBlobServiceProperties{DeleteRetentionPolicy: softDelete}),
+			restore:   setBlobService(&azureBlobServiceProperties{DeleteRetentionPolicy: azureBlobSoftDeletePolicy(blobService)}),
+		},
+	}
+
+	for _, s := range settings {
+		progress.begin(s.step)
+		// The new storage account is created with the secure settings, so only the blob service needs changes.
+		if s.isDesired {
+			if adopted {
+				progress.end(stepStatusUnchanged)
+			} else {
+				progress.end(stepStatusCreated)
+			}
+			continue
+		}
+		if err := s.apply(); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to %v: %w", s.step, err)
+		}
+		if adopted {
+			tx.record(fmt.Sprintf("Restore %v of storage account %v", s.step, accountName), s.restore)
+		}
+		progress.end(appliedStatus(adopted))
+	}
+
+	// Create blob container. If the container already exists, adopt it.
+	progress.begin("Creating blob container")
+	_, err = c.GetContainer(context.TODO(), resourceGroup, accountName, containerName)
+	if err != nil && !isAzureStatusCode(err, 404) {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of blob container: %w", err)
+	}
+	if err == nil {
+		progress.end(stepStatusUnchanged)
+	} else {
+		if _, err := c.CreateContainer(context.TODO(), resourceGroup, accountName, containerName); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create blob container: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete blob container %v", containerName), func() error {
+			return c.DeleteContainer(context.TODO(), resourceGroup, accountName, containerName)
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Describe storage account
+	progress.begin("Confirmation - Get storage account")
+	account, err = c.GetStorageAccount(context.TODO(), resourceGroup, accountName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created storage account, but failed to describe storage account: %w", err)
+	}
+	blobService, err = c.GetBlobServiceProperties(context.TODO(), resourceGroup, accountName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created storage account, but failed to get blob service properties: %w", err)
+	}
+	progress.end(stepStatusSuccess)
+
+	res := initAzureResult{
+		ResourceGroupName:     resourceGroup,
+		StorageAccountName:    accountName,
+		ContainerName:         containerName,
+		Location:              account.Location,
+		HTTPSOnly:             enabledOrDisabled(isAzureHTTPSOnly(account)),
+		MinimumTLSVersion:     azureMinimumTLSVersion(account),
+		AllowBlobPublicAccess: enabledOrDisabled(isAzureBlobPublicAccessAllowed(account)),
+		BlobVersioning:        enabledOrDisabled(isAzureBlobVersioningEnabled(blobService)),
+		BlobSoftDelete:        "Disabled",
+	}
+	if p := azureBlobSoftDeletePolicy(blobService); p.Enabled {
+		res.BlobSoftDelete = strconv.Itoa(p.Days) + " days"
+	}
+
+	return &res, nil
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// isAzureHTTPSOnly returns if the account accepts only HTTPS. An unset value is treated as false.
+func isAzureHTTPSOnly(a *azureStorageAccount) bool {
+	return a != nil && a.Properties != nil && a.Properties.SupportsHTTPSTrafficOnly != nil &&
+		*a.Properties.SupportsHTTPSTrafficOnly
+}
+
+// azureMinimumTLSVersion returns minimum TLS version of the account. "TLS1_0" is the default.
+func azureMinimumTLSVersion(a *azureStorageAccount) string {
+	if a == nil || a.Properties == nil || a.Properties.MinimumTLSVersion == "" {
+		return "TLS1_0"
+	}
+	return a.Properties.MinimumTLSVersion
+}
+
+// isAzureBlobPublicAccessAllowed returns if the account allows public blob access. An unset value is treated as true.
+func isAzureBlobPublicAccessAllowed(a *azureStorageAccount) bool {
+	if a == nil || a.Properties == nil || a.Properties.AllowBlobPublicAccess == nil {
+		return true
+	}
+	return *a.Properties.AllowBlobPublicAccess
+}
+
+func isAzureBlobVersioningEnabled(p *azureBlobServiceProperties) bool {
+	return p != nil && p.IsVersioningEnabled != nil && *p.IsVersioningEnabled
+}
+
+// azureBlobSoftDeletePolicy returns delete retention policy of the blob service. Disabled is the default.
+func azureBlobSoftDeletePolicy(p *azureBlobServiceProperties) *azureDeleteRetentionPolicy {
+	if p == nil || p.DeleteRetentionPolicy == nil {
+		return &azureDeleteRetentionPolicy{Enabled: false}
+	}
+	return p.DeleteRetentionPolicy
+}
+
+func hasAzureBlobSoftDelete(p *azureBlobServiceProperties, days int) bool {
+	policy := azureBlobSoftDeletePolicy(p)
+	return policy.Enabled && policy.Days == days
+}
+
+var (
+	storageAccountNamePattern = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	containerNamePattern      = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+)
+
+// validateStorageAccountName checks if the name is 3-24 characters of lowercase letters and numbers.
+func validateStorageAccountName(n string) bool {
+	return storageAccountNamePattern.MatchString(n)
+}
+
+// validateContainerName checks if the name is 3-63 characters of lowercase letters, numbers and single hyphens.
+func validateContainerName(n string) bool {
+	return containerNamePattern.MatchString(n) && !strings.Contains(n, "--")
+}
+
+// createBackendTableInput returns the attributes of azurerm backend.
+func (i *initAzureResult) createBackendTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"resource_group_name", i.ResourceGroupName},
+		{"storage_account_name", i.StorageAccountName},
+		{"container_name", i.ContainerName},
+	}
+	return h, b
+}
+
+func (i *initAzureResult) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Location", i.Location},
+		{"HTTPS only", i.HTTPSOnly},
+		{"Minimum TLS version", i.MinimumTLSVersion},
+		{"Public blob access", i.AllowBlobPublicAccess},
+		{"Blob versioning", i.BlobVersioning},
+		{"Blob soft delete", i.BlobSoftDelete},
+	}
+	return h, b
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+)
+
+// -----------------------------------
+// For initAzure test
+// -----------------------------------
+
+// mockAzureClient holds resources in memory. The resource is nil if it doesn't exist.
+type mockAzureClient struct {
+	resourceGroup *azureResourceGroup
+	account       *azureStorageAccount
+	blobService   *azureBlobServiceProperties
+	container     *azureContainer
+
+	// failOn is the name of the method which returns error.
+	failOn string
+	// updated is the number of calls to update the existing settings.
+	updated int
+}
+
+func (m *mockAzureClient) err(method string) error {
+	if m.failOn == method {
+		return &azureAPIError{StatusCode: http.StatusForbidden, Code: "AuthorizationFailed", Message: "forbidden"}
+	}
+	return nil
+}
+
+func mockAzureNotFound() error {
+	return &azureAPIError{StatusCode: http.StatusNotFound, Code: "ResourceNotFound", Message: "not found"}
+}
+
+func (m *mockAzureClient) GetResourceGroup(ctx context.Context, name string) (*azureResourceGroup, error) {
+	if err := m.err("GetResourceGroup"); err != nil {
+		return nil, err
+	}
+	if m.resourceGroup == nil {
+		return nil, mockAzureNotFound()
+	}
+	return m.resourceGroup, nil
+}
+
+func (m *mockAzureClient) CreateResourceGroup(ctx context.Context, name string, location string) (*azureResourceGroup, error) {
+	if err := m.err("CreateResourceGroup"); err != nil {
+		return nil, err
+	}
+	m.resourceGroup = &azureResourceGroup{Name: name, Location: location}
+	return m.resourceGroup, nil
+}
+
+func (m *mockAzureClient) DeleteResourceGroup(ctx context.Context, name string) error {
+	m.resourceGroup = nil
+	m.account = nil
+	m.container = nil
+	return nil
+}
+
+func (m *mockAzureClient) GetStorageAccount(ctx context.Context, resourceGroup string, name string) (*azureStorageAccount, error) {
+	if m.account == nil {
+		return nil, mockAzureNotFound()
+	}
+	return m.account, nil
+}
+
+func (m *mockAzureClient) CreateStorageAccount(ctx context.Context, resourceGroup string, account *azureStorageAccount) (*azureStorageAccount, error) {
+	if err := m.err("CreateStorageAccount"); err != nil {
+		return nil, err
+	}
+	a := *account
+	p := *account.Properties
+	p.ProvisioningState = "Succeeded"
+	a.Properties = &p
+	m.account = &a
+	m.blobService = &azureBlobServiceProperties{}
+	return m.account, nil
+}
+
+func (m *mockAzureClient) UpdateStorageAccount(ctx context.Context, resourceGroup string, name string, properties *azureStorageAccountProperties) (*azureStorageAccount, error) {
+	if err := m.err("UpdateStorageAccount"); err != nil {
+		return nil, err
+	}
+	m.updated++
+	a := *m.account
+	p := azureStorageAccountProperties{}
+	if a.Properties != nil {
+		p = *a.Properties
+	}
+	if properties.SupportsHTTPSTrafficOnly != nil {
+		p.SupportsHTTPSTrafficOnly = properties.SupportsHTTPSTrafficOnly
+	}
+	if properties.MinimumTLSVersion != "" {
+		p.MinimumTLSVersion = properties.MinimumTLSVersion
+	}
+	if properties.AllowBlobPublicAccess != nil {
+		p.AllowBlobPublicAccess = properties.AllowBlobPublicAccess
+	}
+	a.Properties = &p
+	m.account = &a
+	return m.account, nil
+}
+
+func (m *mockAzureClient) DeleteStorageAccount(ctx context.Context, resourceGroup string, name string) error {
+	m.account = nil
+	m.container = nil
+	return nil
+}
+
+func (m *mockAzureClient) GetBlobServiceProperties(ctx context.Context, resourceGroup string, account string) (*azureBlobServiceProperties, error) {
+	return m.blobService, nil
+}
+
+func (m *mockAzureClient) SetBlobServiceProperties(ctx context.Context, resourceGroup string, account string, properties *azureBlobServiceProperties) error {
+	if err := m.err("SetBlobServiceProperties"); err != nil {
+		return err
+	}
+	m.updated++
+	p := *m.blobService
+	if properties.IsVersioningEnabled != nil {
+		p.IsVersioningEnabled = properties.IsVersioningEnabled
+	}
+	if properties.DeleteRetentionPolicy != nil {
+		p.DeleteRetentionPolicy = properties.DeleteRetentionPolicy
+	}
+	m.blobService = &p
+	return nil
+}
+
+func (m *mockAzureClient) GetContainer(ctx context.Context, resourceGroup string, account string, name string) (*azureContainer, error) {
+	if m.container == nil {
+		return nil, mockAzureNotFound()
+	}
+	return m.container, nil
+}
+
+func (m *mockAzureClient) CreateContainer(ctx context.Context, resourceGroup string, account string, name string) (*azureContainer, error) {
+	if err := m.err("CreateContainer"); err != nil {
+		return nil, err
+	}
+	m.container = &azureContainer{Name: name, Properties: azureContainerProperties{PublicAccess: "None"}}
+	return m.container, nil
+}
+
+func (m *mockAzureClient) DeleteContainer(ctx context.Context, resourceGroup string, account string, name string) error {
+	m.container = nil
+	return nil
+}
+
+// mockAzureClientConverged returns the client which already has the backend of the baseline.
+func mockAzureClientConverged() *mockAzureClient {
+	return &mockAzureClient{
+		resourceGroup: &azureResourceGroup{Name: "happy-rg", Location: "japaneast"},
+		account: &azureStorageAccount{
+			Name:     "happyaccount",
+			Location: "japaneast",
+			Properties: &azureStorageAccountProperties{
+				ProvisioningState:        "Succeeded",
+				SupportsHTTPSTrafficOnly: boolPtr(true),
+				MinimumTLSVersion:        "TLS1_2",
+				AllowBlobPublicAccess:    boolPtr(false),
+			},
+		},
+		blobService: &azureBlobServiceProperties{
+			IsVersioningEnabled:   boolPtr(true),
+			DeleteRetentionPolicy: &azureDeleteRetentionPolicy{Enabled: true, Days: 7},
+		},
+		container: &azureContainer{Name: "tfstate", Properties: azureContainerProperties{PublicAccess: "None"}},
+	}
+}
+
+// mockAzureClientInsecureAccount returns the client which has the storage account with the default settings of old API versions.
+func mockAzureClientInsecureAccount() *mockAzureClient {
+	return &mockAzureClient{
+		resourceGroup: &azureResourceGroup{Name: "happy-rg", Location: "japaneast"},
+		account: &azureStorageAccount{
+			Name:       "happyaccount",
+			Location:   "japaneast",
+			Properties: &azureStorageAccountProperties{ProvisioningState: "Succeeded"},
+		},
+		blobService: &azureBlobServiceProperties{},
+	}
+}
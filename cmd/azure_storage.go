@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	azureResourceGroupAPIVersion = "2021-04-01"
+	azureStorageAPIVersion       = "2021-09-01"
+)
+
+// AzureClientable is the subset of Azure Resource Manager API used by tfbackend.
+type AzureClientable interface {
+	GetResourceGroup(ctx context.Context, name string) (*azureResourceGroup, error)
+	CreateResourceGroup(ctx context.Context, name string, location string) (*azureResourceGroup, error)
+	DeleteResourceGroup(ctx context.Context, name string) error
+
+	GetStorageAccount(ctx context.Context, resourceGroup string, name string) (*azureStorageAccount, error)
+	// CreateStorageAccount waits until provisioning of the account completes.
+	CreateStorageAccount(ctx context.Context, resourceGroup string, account *azureStorageAccount) (*azureStorageAccount, error)
+	UpdateStorageAccount(ctx context.Context, resourceGroup string, name string, properties *azureStorageAccountProperties) (*azureStorageAccount, error)
+	DeleteStorageAccount(ctx context.Context, resourceGroup string, name string) error
+
+	GetBlobServiceProperties(ctx context.Context, resourceGroup string, account string) (*azureBlobServiceProperties, error)
+	// SetBlobServiceProperties changes only the properties which are not nil.
+	SetBlobServiceProperties(ctx context.Context, resourceGroup string, account string, properties *azureBlobServiceProperties) error
+
+	GetContainer(ctx context.Context, resourceGroup string, account string, name string) (*azureContainer, error)
+	CreateContainer(ctx context.Context, resourceGroup string, account string, name string) (*azureContainer, error)
+	DeleteContainer(ctx context.Context, resourceGroup string, account string, name string) error
+}
+
+type azureResourceGroup struct {
+	Name     string `json:"name,omitempty"`
+	Location string `json:"location"`
+}
+
+// azureStorageAccount is the storage account resource. Only the fields tfbackend manages are declared.
+type azureStorageAccount struct {
+	Name       string                         `json:"name,omitempty"`
+	Location   string                         `json:"location,omitempty"`
+	Kind       string                         `json:"kind,omitempty"`
+	Sku        *azureSku                      `json:"sku,omitempty"`
+	Properties *azureStorageAccountProperties `json:"properties,omitempty"`
+}
+
+type azureSku struct {
+	Name string `json:"name"`
+}
+
+type azureStorageAccountProperties struct {
+	ProvisioningState        string `json:"provisioningState,omitempty"`
+	SupportsHTTPSTrafficOnly *bool  `json:"supportsHttpsTrafficOnly,omitempty"`
+	MinimumTLSVersion        string `json:"minimumTlsVersion,omitempty"`
+	AllowBlobPublicAccess    *bool  `json:"allowBlobPublicAccess,omitempty"`
+}
+
+// azureBlobServiceProperties is the blob service resource of the storage account.
+type azureBlobServiceProperties struct {
+	IsVersioningEnabled   *bool                       `json:"isVersioningEnabled,omitempty"`
+	DeleteRetentionPolicy *azureDeleteRetentionPolicy `json:"deleteRetentionPolicy,omitempty"`
+}
+
+type azureDeleteRetentionPolicy struct {
+	Enabled bool `json:"enabled"`
+	Days    int  `json:"days,omitempty"`
+}
+
+type azureContainer struct {
+	Name       string                   `json:"name,omitempty"`
+	Properties azureContainerProperties `json:"properties"`
+}
+
+type azureContainerProperties struct {
+	PublicAccess string `json:"publicAccess,omitempty"`
+}
+
+// azureAPIError is the error response of Azure Resource Manager API.
+type azureAPIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *azureAPIError) Error() string {
+	return fmt.Sprintf("azure api error: status %v: %v: %v", e.StatusCode, e.Code, e.Message)
+}
+
+// isAzureStatusCode checks if err is the error response with one of the status codes.
+func isAzureStatusCode(err error, codes ...int) bool {
+	var apiErr *azureAPIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, c := range codes {
+		if apiErr.StatusCode == c {
+			return true
+		}
+	}
+	return false
+}
+
+// azureClient calls Azure Resource Manager API over HTTP.
+type azureClient struct {
+	endpoint       string
+	subscriptionID string
+	httpClient     *http.Client
+	// token returns OAuth 2.0 access token. If nil, requests are sent without Authorization header.
+	token func() (string, error)
+	// pollInterval is the interval to check provisioning state of the storage account.
+	pollInterval time.Duration
+}
+
+// newAzureClient returns a client for Azure Resource Manager of the subscription.
+// The access token is taken from AZURE_ACCESS_TOKEN or 'az account get-access-token'.
+func newAzureClient(subscriptionID string) *azureClient {
+	return &azureClient{
+		endpoint:       "https://management.azure.com",
+		subscriptionID: subscriptionID,
+		httpClient:     http.DefaultClient,
+		token:          azAccessToken,
+		pollInterval:   5 * time.Second,
+	}
+}
+
+// azAccessToken returns access token of the user logged in with Azure CLI.
+func azAccessToken() (string, error) {
+	if token := os.Getenv("AZURE_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	out, err := exec.Command("az", "account", "get-access-token",
+		"--resource", "https://management.azure.com/", "--query", "accessToken", "--output", "tsv").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token. Set AZURE_ACCESS_TOKEN or login with az: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (c *azureClient) resourceGroupPath(name string) string {
+	return fmt.Sprintf("/subscriptions/%v/resourcegroups/%v?api-version=%v",
+		url.PathEscape(c.subscriptionID), url.PathEscape(name), azureResourceGroupAPIVersion)
+}
+
+// storagePath returns the path of the resource under Microsoft.Storage provider.
+func (c *azureClient) storagePath(resourceGroup string, resource string) string {
+	return fmt.Sprintf("/subscriptions/%v/resourceGroups/%v/providers/Microsoft.Storage/%v?api-version=%v",
+		url.PathEscape(c.subscriptionID), url.PathEscape(resourceGroup), resource, azureStorageAPIVersion)
+}
+
+func (c *azureClient) GetResourceGroup(ctx context.Context, name string) (*azureResourceGroup, error) {
+	var rg azureResourceGroup
+	if err := c.do(ctx, http.MethodGet, c.resourceGroupPath(name), nil, &rg); err != nil {
+		return nil, err
+	}
+	return &rg, nil
+}
+
+func (c *azureClient) CreateResourceGroup(ctx context.Context, name string, location string) (*azureResourceGroup, error) {
+	var rg azureResourceGroup
+	if err := c.do(ctx, http.MethodPut, c.resourceGroupPath(name), &azureResourceGroup{Location: location}, &rg); err != nil {
+		return nil, err
+	}
+	return &rg, nil
+}
+
+// DeleteResourceGroup starts deletion of the resource group and doesn't wait for the completion.
+func (c *azureClient) DeleteResourceGroup(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, c.resourceGroupPath(name), nil, nil)
+}
+
+func (c *azureClient) GetStorageAccount(ctx context.Context, resourceGroup string, name string) (*azureStorageAccount, error) {
+	var a azureStorageAccount
+	path := c.storagePath(resourceGroup, "storageAccounts/"+url.PathEscape(name))
+	if err := c.do(ctx, http.MethodGet, path, nil, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (c *azureClient) CreateStorageAccount(ctx context.Context, resourceGroup string, account *azureStorageAccount) (*azureStorageAccount, error) {
+	path := c.storagePath(resourceGroup, "storageAccounts/"+url.PathEscape(account.Name))
+	if err := c.do(ctx, http.MethodPut, path, account, nil); err != nil {
+		return nil, err
+	}
+
+	// Creation of storage account is asynchronous.
+	for {
+		a, err := c.GetStorageAccount(ctx, resourceGroup, account.Name)
+		if err != nil {
+			return nil, err
+		}
+		if a.Properties != nil {
+			switch a.Properties.ProvisioningState {
+			case "Succeeded":
+				return a, nil
+			case "Failed", "Canceled":
+				return nil, fmt.Errorf("provisioning of storage account %v %v", account.Name, strings.ToLower(a.Properties.ProvisioningState))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+func (c *azureClient) UpdateStorageAccount(ctx context.Context, resourceGroup string, name string, properties *azureStorageAccountProperties) (*azureStorageAccount, error) {
+	var a azureStorageAccount
+	path := c.storagePath(resourceGroup, "storageAccounts/"+url.PathEscape(name))
+	if err := c.do(ctx, http.MethodPatch, path, &azureStorageAccount{Properties: properties}, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (c *azureClient) DeleteStorageAccount(ctx context.Context, resourceGroup string, name string) error {
+	path := c.storagePath(resourceGroup, "storageAccounts/"+url.PathEscape(name))
+	return c.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+func (c *azureClient) GetBlobServiceProperties(ctx context.Context, resourceGroup string, account string) (*azureBlobServiceProperties, error) {
+	var res struct {
+		Properties azureBlobServiceProperties `json:"properties"`
+	}
+	path := c.storagePath(resourceGroup, "storageAccounts/"+url.PathEscape(account)+"/blobServices/default")
+	if err := c.do(ctx, http.MethodGet, path, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res.Properties, nil
+}
+
+func (c *azureClient) SetBlobServiceProperties(ctx context.Context, resourceGroup string, account string, properties *azureBlobServiceProperties) error {
+	body := map[string]interface{}{"properties": properties}
+	path := c.storagePath(resourceGroup, "storageAccounts/"+url.PathEscape(account)+"/blobServices/default")
+	return c.do(ctx, http.MethodPut, path, body, nil)
+}
+
+func (c *azureClient) GetContainer(ctx context.Context, resourceGroup string, account string, name string) (*azureContainer, error) {
+	var ct azureContainer
+	path := c.storagePath(resourceGroup, "storageAccounts/"+url.PathEscape(account)+"/blobServices/default/containers/"+url.PathEscape(name))
+	if err := c.do(ctx, http.MethodGet, path, nil, &ct); err != nil {
+		return nil, err
+	}
+	return &ct, nil
+}
+
+func (c *azureClient) CreateContainer(ctx context.Context, resourceGroup string, account string, name string) (*azureContainer, error) {
+	var ct azureContainer
+	body := &azureContainer{Properties: azureContainerProperties{PublicAccess: "None"}}
+	path := c.storagePath(resourceGroup, "storageAccounts/"+url.PathEscape(account)+"/blobServices/default/containers/"+url.PathEscape(name))
+	if err := c.do(ctx, http.MethodPut, path, body, &ct); err != nil {
+		return nil, err
+	}
+	return &ct, nil
+}
+
+func (c *azureClient) DeleteContainer(ctx context.Context, resourceGroup string, account string, name string) error {
+	path := c.storagePath(resourceGroup, "storageAccounts/"+url.PathEscape(account)+"/blobServices/default/containers/"+url.PathEscape(name))
+	return c.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// do sends the request with JSON body and decodes JSON response into out.
+func (c *azureClient) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != nil {
+		token, err := c.token()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		apiErr := &azureAPIError{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(b))}
+		if json.Unmarshal(b, &e) == nil && e.Error.Code != "" {
+			apiErr.Code = e.Error.Code
+			apiErr.Message = e.Error.Message
+		}
+		return apiErr
+	}
+
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
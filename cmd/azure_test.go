@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_initAzure(t *testing.T) {
+	opt := initAzureOption{Location: "japaneast", SkuName: "Standard_LRS", SoftDeleteRetentionDays: 7}
+	converged := &initAzureResult{
+		ResourceGroupName:     "happy-rg",
+		StorageAccountName:    "happyaccount",
+		ContainerName:         "tfstate",
+		Location:              "japaneast",
+		HTTPSOnly:             "Enabled",
+		MinimumTLSVersion:     "TLS1_2",
+		AllowBlobPublicAccess: "Disabled",
+		BlobVersioning:        "Enabled",
+		BlobSoftDelete:        "7 days",
+	}
+
+	tests := []struct {
+		name        string
+		client      *mockAzureClient
+		want        *initAzureResult
+		wantUpdated int
+		wantErr     bool
+	}{
+		{
+			name:        "S01: Happy path",
+			client:      &mockAzureClient{},
+			want:        converged,
+			wantUpdated: 2,
+			wantErr:     false,
+		},
+		{
+			name:        "S02: Adopt converged backend",
+			client:      mockAzureClientConverged(),
+			want:        converged,
+			wantUpdated: 0,
+			wantErr:     false,
+		},
+		{
+			name:        "S03: Adopt insecure storage account",
+			client:      mockAzureClientInsecureAccount(),
+			want:        converged,
+			wantUpdated: 5,
+			wantErr:     false,
+		},
+		{
+			name:    "F01: Creating resource group failure",
+			client:  &mockAzureClient{failOn: "CreateResourceGroup"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "F02: Creating storage account failure",
+			client:  &mockAzureClient{failOn: "CreateStorageAccount"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "F03: Creating blob container failure",
+			client:  &mockAzureClient{failOn: "CreateContainer"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := initAzure(tt.client, "happy-rg", "happyaccount", "tfstate", opt, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initAzure() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initAzure() = %v, want %v", got, tt.want)
+			}
+			if err == nil && tt.client.updated != tt.wantUpdated {
+				t.Errorf("initAzure() updated %v settings, want %v", tt.client.updated, tt.wantUpdated)
+			}
+		})
+	}
+}
+
+func Test_initAzure_Rollback(t *testing.T) {
+	t.Run("S01: Delete created resources", func(t *testing.T) {
+		client := &mockAzureClient{failOn: "CreateContainer"}
+		tx := &transaction{}
+		if _, err := initAzure(client, "happy-rg", "happyaccount", "tfstate", initAzureOption{Location: "japaneast", SoftDeleteRetentionDays: 7}, tx); err == nil {
+			t.Fatalf("initAzure() error = nil, want error")
+		}
+		if err := tx.rollback(); err != nil {
+			t.Fatalf("transaction.rollback() error = %v", err)
+		}
+		if client.resourceGroup != nil || client.account != nil {
+			t.Errorf("rollback didn't delete created resources: %+v, %+v", client.resourceGroup, client.account)
+		}
+	})
+
+	t.Run("S02: Restore settings of adopted storage account", func(t *testing.T) {
+		client := mockAzureClientInsecureAccount()
+		client.failOn = "CreateContainer"
+		tx := &transaction{}
+		if _, err := initAzure(client, "happy-rg", "happyaccount", "tfstate", initAzureOption{Location: "japaneast", SoftDeleteRetentionDays: 7}, tx); err == nil {
+			t.Fatalf("initAzure() error = nil, want error")
+		}
+		if err := tx.rollback(); err != nil {
+			t.Fatalf("transaction.rollback() error = %v", err)
+		}
+		if client.resourceGroup == nil || client.account == nil {
+			t.Fatalf("rollback deleted adopted resources")
+		}
+		if isAzureHTTPSOnly(client.account) || azureMinimumTLSVersion(client.account) != "TLS1_0" ||
+			!isAzureBlobPublicAccessAllowed(client.account) || isAzureBlobVersioningEnabled(client.blobService) ||
+			azureBlobSoftDeletePolicy(client.blobService).Enabled {
+			t.Errorf("rollback didn't restore settings: %+v, %+v", client.account.Properties, client.blobService)
+		}
+	})
+}
+
+func Test_validateContainerName(t *testing.T) {
+	tests := []struct {
+		name          string
+		containerName string
+		want          bool
+	}{
+		{name: "S01: Valid name", containerName: "tfstate", want: true},
+		{name: "S02: Valid name with hyphen", containerName: "a-b", want: true},
+		{name: "S03: Too short", containerName: "ab", want: false},
+		{name: "S04: Consecutive hyphens", containerName: "tf--state", want: false},
+		{name: "S05: Starts with hyphen", containerName: "-tfstate", want: false},
+		{name: "S06: Capital letter", containerName: "tfState", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateContainerName(tt.containerName); got != tt.want {
+				t.Errorf("validateContainerName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+type CloudTrailGetTrailAPI interface {
+	GetTrail(ctx context.Context,
+		params *cloudtrail.GetTrailInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetTrailOutput, error)
+}
+
+func getTrail(c context.Context, api CloudTrailGetTrailAPI, trailName string) (*cloudtrail.GetTrailOutput, error) {
+	in := &cloudtrail.GetTrailInput{
+		Name: aws.String(trailName),
+	}
+	return api.GetTrail(c, in)
+}
+
+type CloudTrailCreateTrailAPI interface {
+	CreateTrail(ctx context.Context,
+		params *cloudtrail.CreateTrailInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.CreateTrailOutput, error)
+}
+
+// createTrail creates a single-region trail with log file validation, which delivers logs under keyPrefix of the bucket.
+func createTrail(c context.Context, api CloudTrailCreateTrailAPI, trailName string, bucketName string, keyPrefix string, tags map[string]string) (*cloudtrail.CreateTrailOutput, error) {
+	in := &cloudtrail.CreateTrailInput{
+		Name:                    aws.String(trailName),
+		S3BucketName:            aws.String(bucketName),
+		S3KeyPrefix:             aws.String(keyPrefix),
+		EnableLogFileValidation: aws.Bool(true),
+	}
+	for k, v := range tags {
+		in.TagsList = append(in.TagsList, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return api.CreateTrail(c, in)
+}
+
+type CloudTrailStartLoggingAPI interface {
+	StartLogging(ctx context.Context,
+		params *cloudtrail.StartLoggingInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.StartLoggingOutput, error)
+}
+
+func startTrailLogging(c context.Context, api CloudTrailStartLoggingAPI, trailName string) (*cloudtrail.StartLoggingOutput, error) {
+	in := &cloudtrail.StartLoggingInput{
+		Name: aws.String(trailName),
+	}
+	return api.StartLogging(c, in)
+}
+
+type CloudTrailDeleteTrailAPI interface {
+	DeleteTrail(ctx context.Context,
+		params *cloudtrail.DeleteTrailInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.DeleteTrailOutput, error)
+}
+
+func deleteTrail(c context.Context, api CloudTrailDeleteTrailAPI, trailName string) (*cloudtrail.DeleteTrailOutput, error) {
+	in := &cloudtrail.DeleteTrailInput{
+		Name: aws.String(trailName),
+	}
+	return api.DeleteTrail(c, in)
+}
+
+type CloudTrailGetEventSelectorsAPI interface {
+	GetEventSelectors(ctx context.Context,
+		params *cloudtrail.GetEventSelectorsInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetEventSelectorsOutput, error)
+}
+
+func getEventSelectors(c context.Context, api CloudTrailGetEventSelectorsAPI, trailName string) (*cloudtrail.GetEventSelectorsOutput, error) {
+	in := &cloudtrail.GetEventSelectorsInput{
+		TrailName: aws.String(trailName),
+	}
+	return api.GetEventSelectors(c, in)
+}
+
+type CloudTrailPutEventSelectorsAPI interface {
+	PutEventSelectors(ctx context.Context,
+		params *cloudtrail.PutEventSelectorsInput,
+		optFns ...func(*cloudtrail.Options)) (*cloudtrail.PutEventSelectorsOutput, error)
+}
+
+// putEventSelectors replaces the event selectors of the trail. Either basic or advanced selectors can be used at once.
+func putEventSelectors(c context.Context, api CloudTrailPutEventSelectorsAPI, trailName string, selectors []types.EventSelector, advanced []types.AdvancedEventSelector) (*cloudtrail.PutEventSelectorsOutput, error) {
+	in := &cloudtrail.PutEventSelectorsInput{
+		TrailName:              aws.String(trailName),
+		EventSelectors:         selectors,
+		AdvancedEventSelectors: advanced,
+	}
+	return api.PutEventSelectors(c, in)
+}
@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	consulAddress    string
+	consulPath       string
+	consulPolicyName string
+	consulSkipACL    bool
+)
+
+// initConsulOption holds settings of the terraform backend on Consul.
+type initConsulOption struct {
+	PolicyName string
+	// SkipACL skips creation of ACL policy and token, for the agent without ACL.
+	SkipACL bool
+}
+
+type initConsulResult struct {
+	Path            string `json:"path" yaml:"path"`
+	PolicyName      string `json:"policy_name,omitempty" yaml:"policy_name,omitempty"`
+	TokenAccessorID string `json:"token_accessor_id,omitempty" yaml:"token_accessor_id,omitempty"`
+	// TokenSecretID is set only when the token is created.
+	TokenSecretID string `json:"token_secret_id,omitempty" yaml:"token_secret_id,omitempty"`
+}
+
+func NewCmdConsul() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "consul",
+		Short: "Create Consul KV prefix and ACL token for terraform backend.",
+		Long: `Create Consul KV prefix and ACL token for terraform backend.
+
+The KV prefix, an ACL policy and an ACL token with the policy are created.
+The policy allows only the followings.
+- Write keys under the path
+- Create sessions, which terraform consul backend uses for state locking
+
+At the end, the matching backend "consul" block is printed.
+The secret ID of the token is printed only when the token is created.
+
+The agent address is taken from --address or CONSUL_HTTP_ADDR,
+and the management token from CONSUL_HTTP_TOKEN.
+If ACL is disabled on the agent (e.g. 'consul agent -dev'), pass --skip-acl.
+`,
+		SilenceUsage: true,
+		RunE:         runCmdConsul,
+	}
+
+	defaultAddress := os.Getenv("CONSUL_HTTP_ADDR")
+	if defaultAddress == "" {
+		defaultAddress = "127.0.0.1:8500"
+	}
+
+	// flag
+	cmd.Flags().StringVarP(&consulAddress, "address", "", defaultAddress, "Address of Consul agent, e.g. 127.0.0.1:8500 or https://consul.example.com. Default is CONSUL_HTTP_ADDR.")
+	cmd.Flags().StringVarP(&consulPath, "path", "", "", "KV path of terraform state, e.g. terraform/state.")
+	cmd.MarkFlagRequired("path")
+	cmd.Flags().StringVarP(&consulPolicyName, "policy-name", "", "", "Name of ACL policy to create. Default is 'tfbackend-' followed by the path.")
+	cmd.Flags().BoolVarP(&consulSkipACL, "skip-acl", "", false, "Skip creation of ACL policy and token.")
+	cmd.Flags().BoolVarP(&noRollback, "no-rollback", "", false, "Don't rollback resources created or updated before a failure. Useful for debugging.")
+
+	return cmd
+}
+
+func runCmdConsul(cmd *cobra.Command, args []string) error {
+	// Validation
+	path := strings.Trim(consulPath, "/")
+	if path == "" {
+		return fmt.Errorf("path must not be empty: %v", consulPath)
+	}
+	endpoint, err := consulEndpoint(consulAddress)
+	if err != nil {
+		return err
+	}
+
+	opt := initConsulOption{
+		PolicyName: consulPolicyName,
+		SkipACL:    consulSkipACL,
+	}
+	if opt.PolicyName == "" {
+		opt.PolicyName = "tfbackend-" + strings.ReplaceAll(path, "/", "-")
+	}
+
+	client := &consulClient{endpoint: endpoint.String(), httpClient: http.DefaultClient, token: os.Getenv("CONSUL_HTTP_TOKEN")}
+	tx := &transaction{}
+	res, err := initConsul(client, path, opt, tx)
+	if err != nil {
+		return abortTransaction(tx, fmt.Errorf("failed to initialize consul backend: %w", err))
+	}
+
+	printCyan(fmt.Sprintf("Successfully create terraform backend - consul kv: %v\n", path))
+	fmt.Fprintf(progressOut, "Detail ... \n\n")
+	cTable := tablewriter.NewWriter(os.Stdout)
+	h, b := res.createTableInput()
+	cTable.SetHeader(h)
+	for _, v := range b {
+		cTable.Append(v)
+	}
+	cTable.SetAlignment(tablewriter.ALIGN_LEFT)
+	cTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	cTable.SetCenterSeparator("|")
+	cTable.Render()
+
+	fmt.Fprintf(progressOut, "\nBackend ... \n\n")
+	return renderBackendBlock(os.Stdout, "consul", consulBackendAttributes(endpoint, path), false)
+}
+
+// initConsul setup terraform backend KV prefix and ACL on Consul with messages.
+func initConsul(c ConsulClientable, path string, opt initConsulOption, tx *transaction) (*initConsulResult, error) {
+	progress.section("consul_kv", "🚀 Start to create terraform backend: consul kv ...")
+
+	res := initConsulResult{Path: path}
+
+	// Create KV prefix. If the prefix already exists, adopt it.
+	progress.begin("Creating kv prefix")
+	prefix := path + "/"
+	exists, err := c.GetKV(context.TODO(), prefix)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of kv prefix: %w", err)
+	}
+	if exists {
+		progress.end(stepStatusUnchanged)
+	} else {
+		if err := c.PutKV(context.TODO(), prefix, nil); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create kv prefix: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete kv prefix %v", prefix), func() error {
+			return c.DeleteKV(context.TODO(), prefix)
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	if !opt.SkipACL {
+		policy, err := createConsulPolicy(c, path, opt.PolicyName, tx)
+		if err != nil {
+			return nil, err
+		}
+		res.PolicyName = policy.Name
+
+		token, err := createConsulToken(c, policy, tx)
+		if err != nil {
+			return nil, err
+		}
+		res.TokenAccessorID = token.AccessorID
+		res.TokenSecretID = token.SecretID
+	}
+
+	// Describe KV prefix
+	progress.begin("Confirmation - Get kv prefix")
+	exists, err = c.GetKV(context.TODO(), prefix)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created kv prefix, but failed to get kv prefix: %w", err)
+	}
+	if !exists {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created kv prefix, but kv prefix %v is not found", prefix)
+	}
+	progress.end(stepStatusSuccess)
+
+	return &res, nil
+}
+
+// createConsulPolicy creates ACL policy for the path. If the policy already exists, its rules are converged.
+func createConsulPolicy(c ConsulClientable, path string, name string, tx *transaction) (*consulACLPolicy, error) {
+	progress.begin("Creating acl policy")
+	rules := consulPolicyRules(path)
+	current, err := c.ReadPolicyByName(context.TODO(), name)
+	if err != nil {
+		progress.fail()
+		if isConsulACLDisabled(err) {
+			return nil, fmt.Errorf("acl is disabled on the agent. Pass --skip-acl: %w", err)
+		}
+		return nil, fmt.Errorf("failed to read acl policy: %w", err)
+	}
+
+	if current == nil {
+		policy, err := c.CreatePolicy(context.TODO(), &consulACLPolicy{
+			Name:        name,
+			Description: "Terraform backend created by tfbackend",
+			Rules:       rules,
+		})
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create acl policy: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete acl policy %v", name), func() error {
+			return c.DeletePolicy(context.TODO(), policy.ID)
+		})
+		progress.end(stepStatusCreated)
+		return policy, nil
+	}
+
+	if current.Rules == rules {
+		progress.end(stepStatusUnchanged)
+		return current, nil
+	}
+	desired := *current
+	desired.Rules = rules
+	policy, err := c.UpdatePolicy(context.TODO(), &desired)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to update acl policy: %w", err)
+	}
+	tx.record(fmt.Sprintf("Restore rules of acl policy %v", name), func() error {
+		_, err := c.UpdatePolicy(context.TODO(), current)
+		return err
+	})
+	progress.end(stepStatusUpdated)
+	return policy, nil
+}
+
+// createConsulToken creates ACL token with the policy. If a token with the policy already exists, it is adopted.
+func createConsulToken(c ConsulClientable, policy *consulACLPolicy, tx *transaction) (*consulACLToken, error) {
+	progress.begin("Creating acl token")
+	tokens, err := c.ListTokensByPolicy(context.TODO(), policy.ID)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to list acl tokens: %w", err)
+	}
+	if len(tokens) > 0 {
+		progress.end(stepStatusUnchanged)
+		token := tokens[0]
+		token.SecretID = ""
+		return &token, nil
+	}
+
+	token, err := c.CreateToken(context.TODO(), &consulACLToken{
+		Description: "Terraform backend created by tfbackend",
+		Policies:    []consulACLPolicyLink{{ID: policy.ID}},
+	})
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to create acl token: %w", err)
+	}
+	tx.record(fmt.Sprintf("Delete acl token %v", token.AccessorID), func() error {
+		return c.DeleteToken(context.TODO(), token.AccessorID)
+	})
+	progress.end(stepStatusCreated)
+	return token, nil
+}
+
+// consulPolicyRules returns the rules which allow terraform to write the state and lock it.
+// The lock is stored under the path, and a session is needed to hold the lock.
+// The prefix ends with a slash, so that sibling keys such as "<path>-prod" are not writable.
+func consulPolicyRules(path string) string {
+	return fmt.Sprintf(`key %q {
+  policy = "write"
+}
+
+key_prefix %q {
+  policy = "write"
+}
+
+session_prefix "" {
+  policy = "write"
+}
+`, path, path+"/")
+}
+
+// consulEndpoint parses the address. The scheme is http if omitted.
+func consulEndpoint(address string) (*url.URL, error) {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid consul address: %v", address)
+	}
+	return u, nil
+}
+
+// consulBackendAttributes returns the lines of backend "consul" block. The token is not written.
+func consulBackendAttributes(endpoint *url.URL, path string) []backendAttribute {
+	return alignBackendAttributes([]backendAttribute{
+		{Name: "address", Value: strconv.Quote(endpoint.Host)},
+		{Name: "scheme", Value: strconv.Quote(endpoint.Scheme)},
+		{Name: "path", Value: strconv.Quote(path)},
+	})
+}
+
+func (i *initConsulResult) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Path", i.Path},
+	}
+	if i.PolicyName == "" {
+		b = append(b, []string{"ACL", "Skipped"})
+		return h, b
+	}
+	b = append(b, []string{"ACL policy", i.PolicyName})
+	b = append(b, []string{"ACL token accessor ID", i.TokenAccessorID})
+	if i.TokenSecretID != "" {
+		b = append(b, []string{"ACL token secret ID", i.TokenSecretID})
+	}
+	return h, b
+}
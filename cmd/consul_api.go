@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ConsulClientable is the subset of Consul HTTP API used by tfbackend.
+type ConsulClientable interface {
+	GetKV(ctx context.Context, key string) (bool, error)
+	PutKV(ctx context.Context, key string, value []byte) error
+	DeleteKV(ctx context.Context, key string) error
+
+	// ReadPolicyByName returns nil if the policy doesn't exist.
+	ReadPolicyByName(ctx context.Context, name string) (*consulACLPolicy, error)
+	CreatePolicy(ctx context.Context, policy *consulACLPolicy) (*consulACLPolicy, error)
+	UpdatePolicy(ctx context.Context, policy *consulACLPolicy) (*consulACLPolicy, error)
+	DeletePolicy(ctx context.Context, id string) error
+
+	ListTokensByPolicy(ctx context.Context, policyID string) ([]consulACLToken, error)
+	CreateToken(ctx context.Context, token *consulACLToken) (*consulACLToken, error)
+	DeleteToken(ctx context.Context, accessorID string) error
+}
+
+type consulACLPolicy struct {
+	ID          string `json:"ID,omitempty"`
+	Name        string `json:"Name"`
+	Description string `json:"Description,omitempty"`
+	Rules       string `json:"Rules"`
+}
+
+type consulACLToken struct {
+	AccessorID  string                `json:"AccessorID,omitempty"`
+	SecretID    string                `json:"SecretID,omitempty"`
+	Description string                `json:"Description,omitempty"`
+	Policies    []consulACLPolicyLink `json:"Policies"`
+}
+
+type consulACLPolicyLink struct {
+	ID   string `json:"ID,omitempty"`
+	Name string `json:"Name,omitempty"`
+}
+
+// consulAPIError is the error response of Consul HTTP API. The body is plain text.
+type consulAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *consulAPIError) Error() string {
+	return fmt.Sprintf("consul api error: status %v: %v", e.StatusCode, e.Message)
+}
+
+// isConsulStatusCode checks if err is the error response with one of the status codes.
+func isConsulStatusCode(err error, codes ...int) bool {
+	var apiErr *consulAPIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, c := range codes {
+		if apiErr.StatusCode == c {
+			return true
+		}
+	}
+	return false
+}
+
+// isConsulACLDisabled checks if err means the agent runs without ACL, e.g. 'consul agent -dev'.
+func isConsulACLDisabled(err error) bool {
+	var apiErr *consulAPIError
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.Message, "ACL support disabled")
+}
+
+func isConsulACLNotFound(err error) bool {
+	var apiErr *consulAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden && strings.Contains(apiErr.Message, "ACL not found")
+}
+
+// consulClient calls Consul HTTP API.
+type consulClient struct {
+	endpoint   string
+	httpClient *http.Client
+	// token is sent as X-Consul-Token if not empty.
+	token string
+}
+
+func (c *consulClient) GetKV(ctx context.Context, key string) (bool, error) {
+	err := c.do(ctx, http.MethodGet, "/v1/kv/"+consulKeyPath(key), nil, nil)
+	if isConsulStatusCode(err, http.StatusNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *consulClient) PutKV(ctx context.Context, key string, value []byte) error {
+	return c.do(ctx, http.MethodPut, "/v1/kv/"+consulKeyPath(key), value, nil)
+}
+
+func (c *consulClient) DeleteKV(ctx context.Context, key string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/kv/"+consulKeyPath(key), nil, nil)
+}
+
+func (c *consulClient) ReadPolicyByName(ctx context.Context, name string) (*consulACLPolicy, error) {
+	var p consulACLPolicy
+	err := c.do(ctx, http.MethodGet, "/v1/acl/policy/name/"+url.PathEscape(name), nil, &p)
+	// Depending on the version, missing policy results in 404, 403 "ACL not found" or null.
+	if isConsulStatusCode(err, http.StatusNotFound) || isConsulACLNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.ID == "" {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (c *consulClient) CreatePolicy(ctx context.Context, policy *consulACLPolicy) (*consulACLPolicy, error) {
+	var p consulACLPolicy
+	if err := c.do(ctx, http.MethodPut, "/v1/acl/policy", policy, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (c *consulClient) UpdatePolicy(ctx context.Context, policy *consulACLPolicy) (*consulACLPolicy, error) {
+	var p consulACLPolicy
+	if err := c.do(ctx, http.MethodPut, "/v1/acl/policy/"+url.PathEscape(policy.ID), policy, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (c *consulClient) DeletePolicy(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/acl/policy/"+url.PathEscape(id), nil, nil)
+}
+
+func (c *consulClient) ListTokensByPolicy(ctx context.Context, policyID string) ([]consulACLToken, error) {
+	var tokens []consulACLToken
+	if err := c.do(ctx, http.MethodGet, "/v1/acl/tokens?policy="+url.QueryEscape(policyID), nil, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (c *consulClient) CreateToken(ctx context.Context, token *consulACLToken) (*consulACLToken, error) {
+	var t consulACLToken
+	if err := c.do(ctx, http.MethodPut, "/v1/acl/token", token, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (c *consulClient) DeleteToken(ctx context.Context, accessorID string) error {
+	return c.do(ctx, http.MethodDelete, "/v1/acl/token/"+url.PathEscape(accessorID), nil, nil)
+}
+
+// consulKeyPath escapes each segment of the key.
+func consulKeyPath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// do sends the request and decodes JSON response into out. []byte body is sent as is, and others as JSON.
+func (c *consulClient) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	switch v := in.(type) {
+	case nil:
+	case []byte:
+		body = bytes.NewReader(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 {
+		return &consulAPIError{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(b))}
+	}
+
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
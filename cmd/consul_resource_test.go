@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+)
+
+// -----------------------------------
+// For initConsul test
+// -----------------------------------
+
+// mockConsulClient holds KV, policies and tokens in memory.
+type mockConsulClient struct {
+	kv       map[string]bool
+	policies map[string]*consulACLPolicy
+	tokens   []consulACLToken
+	// aclDisabled behaves like 'consul agent -dev'.
+	aclDisabled bool
+	// failOn is the name of the method which returns error.
+	failOn string
+}
+
+func newMockConsulClient() *mockConsulClient {
+	return &mockConsulClient{kv: map[string]bool{}, policies: map[string]*consulACLPolicy{}}
+}
+
+func (m *mockConsulClient) err(method string) error {
+	if m.aclDisabled && method != "GetKV" && method != "PutKV" && method != "DeleteKV" {
+		return &consulAPIError{StatusCode: http.StatusUnauthorized, Message: "ACL support disabled"}
+	}
+	if m.failOn == method {
+		return &consulAPIError{StatusCode: http.StatusForbidden, Message: "Permission denied"}
+	}
+	return nil
+}
+
+func (m *mockConsulClient) GetKV(ctx context.Context, key string) (bool, error) {
+	if err := m.err("GetKV"); err != nil {
+		return false, err
+	}
+	return m.kv[key], nil
+}
+
+func (m *mockConsulClient) PutKV(ctx context.Context, key string, value []byte) error {
+	if err := m.err("PutKV"); err != nil {
+		return err
+	}
+	m.kv[key] = true
+	return nil
+}
+
+func (m *mockConsulClient) DeleteKV(ctx context.Context, key string) error {
+	delete(m.kv, key)
+	return nil
+}
+
+func (m *mockConsulClient) ReadPolicyByName(ctx context.Context, name string) (*consulACLPolicy, error) {
+	if err := m.err("ReadPolicyByName"); err != nil {
+		return nil, err
+	}
+	return m.policies[name], nil
+}
+
+func (m *mockConsulClient) CreatePolicy(ctx context.Context, policy *consulACLPolicy) (*consulACLPolicy, error) {
+	if err := m.err("CreatePolicy"); err != nil {
+		return nil, err
+	}
+	p := *policy
+	p.ID = "policy-" + p.Name
+	m.policies[p.Name] = &p
+	return &p, nil
+}
+
+func (m *mockConsulClient) UpdatePolicy(ctx context.Context, policy *consulACLPolicy) (*consulACLPolicy, error) {
+	if err := m.err("UpdatePolicy"); err != nil {
+		return nil, err
+	}
+	p := *policy
+	m.policies[p.Name] = &p
+	return &p, nil
+}
+
+func (m *mockConsulClient) DeletePolicy(ctx context.Context, id string) error {
+	for name, p := range m.policies {
+		if p.ID == id {
+			delete(m.policies, name)
+		}
+	}
+	return nil
+}
+
+func (m *mockConsulClient) ListTokensByPolicy(ctx context.Context, policyID string) ([]consulACLToken, error) {
+	if err := m.err("ListTokensByPolicy"); err != nil {
+		return nil, err
+	}
+	var res []consulACLToken
+	for _, t := range m.tokens {
+		for _, p := range t.Policies {
+			if p.ID == policyID {
+				res = append(res, t)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (m *mockConsulClient) CreateToken(ctx context.Context, token *consulACLToken) (*consulACLToken, error) {
+	if err := m.err("CreateToken"); err != nil {
+		return nil, err
+	}
+	t := *token
+	t.AccessorID = "happy-accessor"
+	t.SecretID = "happy-secret"
+	m.tokens = append(m.tokens, t)
+	return &t, nil
+}
+
+func (m *mockConsulClient) DeleteToken(ctx context.Context, accessorID string) error {
+	var tokens []consulACLToken
+	for _, t := range m.tokens {
+		if t.AccessorID != accessorID {
+			tokens = append(tokens, t)
+		}
+	}
+	m.tokens = tokens
+	return nil
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func Test_initConsul(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  func() *mockConsulClient
+		opt     initConsulOption
+		want    *initConsulResult
+		wantErr bool
+	}{
+		{
+			name:   "S01: Happy path",
+			client: newMockConsulClient,
+			opt:    initConsulOption{PolicyName: "tfbackend-terraform-state"},
+			want: &initConsulResult{
+				Path:            "terraform/state",
+				PolicyName:      "tfbackend-terraform-state",
+				TokenAccessorID: "happy-accessor",
+				TokenSecretID:   "happy-secret",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S02: Adopt existing policy and token",
+			client: func() *mockConsulClient {
+				m := newMockConsulClient()
+				m.kv["terraform/state/"] = true
+				m.policies["tfbackend-terraform-state"] = &consulACLPolicy{ID: "happy-policy", Name: "tfbackend-terraform-state", Rules: `key_prefix "" { policy = "write" }`}
+				m.tokens = []consulACLToken{{AccessorID: "existing-accessor", SecretID: "existing-secret", Policies: []consulACLPolicyLink{{ID: "happy-policy"}}}}
+				return m
+			},
+			opt: initConsulOption{PolicyName: "tfbackend-terraform-state"},
+			want: &initConsulResult{
+				Path:            "terraform/state",
+				PolicyName:      "tfbackend-terraform-state",
+				TokenAccessorID: "existing-accessor",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S03: Skip ACL",
+			client: func() *mockConsulClient {
+				m := newMockConsulClient()
+				m.aclDisabled = true
+				return m
+			},
+			opt: initConsulOption{PolicyName: "tfbackend-terraform-state", SkipACL: true},
+			want: &initConsulResult{
+				Path: "terraform/state",
+			},
+			wantErr: false,
+		},
+		{
+			name: "F01: ACL is disabled",
+			client: func() *mockConsulClient {
+				m := newMockConsulClient()
+				m.aclDisabled = true
+				return m
+			},
+			opt:     initConsulOption{PolicyName: "tfbackend-terraform-state"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F02: Creating token failure",
+			client: func() *mockConsulClient {
+				m := newMockConsulClient()
+				m.failOn = "CreateToken"
+				return m
+			},
+			opt:     initConsulOption{PolicyName: "tfbackend-terraform-state"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := tt.client()
+			got, err := initConsul(client, "terraform/state", tt.opt, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initConsul() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initConsul() = %v, want %v", got, tt.want)
+			}
+			if p := client.policies[tt.opt.PolicyName]; err == nil && !tt.opt.SkipACL && p.Rules != consulPolicyRules("terraform/state") {
+				t.Errorf("initConsul() policy rules = %v, want %v", p.Rules, consulPolicyRules("terraform/state"))
+			}
+		})
+	}
+}
+
+func Test_initConsul_Rollback(t *testing.T) {
+	client := newMockConsulClient()
+	client.failOn = "CreateToken"
+	tx := &transaction{}
+	if _, err := initConsul(client, "terraform/state", initConsulOption{PolicyName: "tfbackend-terraform-state"}, tx); err == nil {
+		t.Fatalf("initConsul() error = nil, want error")
+	}
+	if err := tx.rollback(); err != nil {
+		t.Fatalf("transaction.rollback() error = %v", err)
+	}
+	if len(client.kv) != 0 || len(client.policies) != 0 {
+		t.Errorf("rollback didn't delete created resources: %v, %v", client.kv, client.policies)
+	}
+}
+
+func Test_consulBackendAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{
+			name:    "S01: Address without scheme",
+			address: "127.0.0.1:8500",
+			want: `terraform {
+  backend "consul" {
+    address = "127.0.0.1:8500"
+    scheme  = "http"
+    path    = "terraform/state"
+  }
+}
+`,
+		},
+		{
+			name:    "S02: Address with https",
+			address: "https://consul.example.com",
+			want: `terraform {
+  backend "consul" {
+    address = "consul.example.com"
+    scheme  = "https"
+    path    = "terraform/state"
+  }
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, err := consulEndpoint(tt.address)
+			if err != nil {
+				t.Fatalf("consulEndpoint() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := renderBackendBlock(&buf, "consul", consulBackendAttributes(endpoint, "terraform/state"), false); err != nil {
+				t.Fatalf("renderBackendBlock() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("renderBackendBlock() = \n%v\nwant \n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_consulPolicyRules(t *testing.T) {
+	want := `key "terraform/state" {
+  policy = "write"
+}
+
+key_prefix "terraform/state/" {
+  policy = "write"
+}
+
+session_prefix "" {
+  policy = "write"
+}
+`
+	if got := consulPolicyRules("terraform/state"); got != want {
+		t.Errorf("consulPolicyRules() = \n%v\nwant \n%v", got, want)
+	}
+}
@@ -3,19 +3,30 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
+// tableCapacity is read and write capacity units of PROVISIONED table.
+type tableCapacity struct {
+	Read  int64
+	Write int64
+}
+
+// defaultTableCapacity is the capacity used when it is not specified.
+var defaultTableCapacity = tableCapacity{Read: 5, Write: 5}
+
 type DynamoDBCreateTableAPI interface {
 	CreateTable(ctx context.Context,
 		params *dynamodb.CreateTableInput,
 		optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
 }
 
-func createDynamoDBTable(c context.Context, api DynamoDBCreateTableAPI, tableName string, billingMode string) (*dynamodb.CreateTableOutput, error) {
+// createDynamoDBTable creates the lock table. The tags are applied at creation time.
+func createDynamoDBTable(c context.Context, api DynamoDBCreateTableAPI, tableName string, billingMode string, capacity tableCapacity, tags map[string]string) (*dynamodb.CreateTableOutput, error) {
 	if billingMode != string(types.BillingModePayPerRequest) && billingMode != string(types.BillingModeProvisioned) {
 		return nil, fmt.Errorf("invalid billing mode")
 	}
@@ -35,18 +46,60 @@ func createDynamoDBTable(c context.Context, api DynamoDBCreateTableAPI, tableNam
 		},
 		TableName:   &tableName,
 		BillingMode: types.BillingMode(billingMode),
+		Tags:        dynamoDBTags(tags),
 	}
 
 	if types.BillingMode(billingMode) == types.BillingModeProvisioned {
 		in.ProvisionedThroughput = &types.ProvisionedThroughput{
-			WriteCapacityUnits: aws.Int64(5),
-			ReadCapacityUnits:  aws.Int64(5),
+			WriteCapacityUnits: aws.Int64(capacity.Write),
+			ReadCapacityUnits:  aws.Int64(capacity.Read),
 		}
 	}
 
 	return api.CreateTable(c, in)
 }
 
+type DynamoDBUpdateTableAPI interface {
+	UpdateTable(ctx context.Context,
+		params *dynamodb.UpdateTableInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error)
+}
+
+// updateDynamoDBTableBillingMode updates billing mode of the table. The capacity is applied only to PROVISIONED table.
+func updateDynamoDBTableBillingMode(c context.Context, api DynamoDBUpdateTableAPI, tableName string, billingMode string, capacity tableCapacity) (*dynamodb.UpdateTableOutput, error) {
+	if billingMode != string(types.BillingModePayPerRequest) && billingMode != string(types.BillingModeProvisioned) {
+		return nil, fmt.Errorf("invalid billing mode")
+	}
+
+	in := &dynamodb.UpdateTableInput{
+		TableName:   &tableName,
+		BillingMode: types.BillingMode(billingMode),
+	}
+
+	if types.BillingMode(billingMode) == types.BillingModeProvisioned {
+		in.ProvisionedThroughput = &types.ProvisionedThroughput{
+			WriteCapacityUnits: aws.Int64(capacity.Write),
+			ReadCapacityUnits:  aws.Int64(capacity.Read),
+		}
+	}
+
+	return api.UpdateTable(c, in)
+}
+
+type DynamoDBDeleteTableAPI interface {
+	DeleteTable(ctx context.Context,
+		params *dynamodb.DeleteTableInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error)
+}
+
+func deleteDynamoDBTable(c context.Context, api DynamoDBDeleteTableAPI, tableName string) (*dynamodb.DeleteTableOutput, error) {
+	in := &dynamodb.DeleteTableInput{
+		TableName: &tableName,
+	}
+
+	return api.DeleteTable(c, in)
+}
+
 type DynamoDBDescribeTableAPI interface {
 	DescribeTable(ctx context.Context,
 		params *dynamodb.DescribeTableInput,
@@ -60,3 +113,168 @@ func describeDynamoDBTable(c context.Context, api DynamoDBDescribeTableAPI, tabl
 
 	return api.DescribeTable(c, in)
 }
+
+type DynamoDBDescribeContinuousBackupsAPI interface {
+	DescribeContinuousBackups(ctx context.Context,
+		params *dynamodb.DescribeContinuousBackupsInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error)
+}
+
+func describeDynamoDBContinuousBackups(c context.Context, api DynamoDBDescribeContinuousBackupsAPI, tableName string) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	in := &dynamodb.DescribeContinuousBackupsInput{
+		TableName: &tableName,
+	}
+	return api.DescribeContinuousBackups(c, in)
+}
+
+type DynamoDBUpdateContinuousBackupsAPI interface {
+	UpdateContinuousBackups(ctx context.Context,
+		params *dynamodb.UpdateContinuousBackupsInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error)
+}
+
+func updateDynamoDBPointInTimeRecovery(c context.Context, api DynamoDBUpdateContinuousBackupsAPI, tableName string, enabled bool) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	in := &dynamodb.UpdateContinuousBackupsInput{
+		TableName: &tableName,
+		PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: aws.Bool(enabled),
+		},
+	}
+	return api.UpdateContinuousBackups(c, in)
+}
+
+func updateDynamoDBTableDeletionProtection(c context.Context, api DynamoDBUpdateTableAPI, tableName string, enabled bool) (*dynamodb.UpdateTableOutput, error) {
+	in := &dynamodb.UpdateTableInput{
+		TableName:                 &tableName,
+		DeletionProtectionEnabled: aws.Bool(enabled),
+	}
+	return api.UpdateTable(c, in)
+}
+
+// updateDynamoDBTableEncryption encrypts the table with the customer managed key.
+// If kmsKeyID is empty, the table is encrypted with AWS owned key, which is the default.
+func updateDynamoDBTableEncryption(c context.Context, api DynamoDBUpdateTableAPI, tableName string, kmsKeyID string) (*dynamodb.UpdateTableOutput, error) {
+	in := &dynamodb.UpdateTableInput{
+		TableName: &tableName,
+		SSESpecification: &types.SSESpecification{
+			Enabled: aws.Bool(false),
+		},
+	}
+	if kmsKeyID != "" {
+		in.SSESpecification = &types.SSESpecification{
+			Enabled:        aws.Bool(true),
+			SSEType:        types.SSETypeKms,
+			KMSMasterKeyId: &kmsKeyID,
+		}
+	}
+	return api.UpdateTable(c, in)
+}
+
+type DynamoDBListTagsOfResourceAPI interface {
+	ListTagsOfResource(ctx context.Context,
+		params *dynamodb.ListTagsOfResourceInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error)
+}
+
+// listDynamoDBTableTags returns all tags of the table.
+func listDynamoDBTableTags(c context.Context, api DynamoDBListTagsOfResourceAPI, tableArn string) (map[string]string, error) {
+	in := &dynamodb.ListTagsOfResourceInput{
+		ResourceArn: &tableArn,
+	}
+
+	tags := map[string]string{}
+	for {
+		out, err := api.ListTagsOfResource(c, in)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range out.Tags {
+			if t.Key != nil && t.Value != nil {
+				tags[*t.Key] = *t.Value
+			}
+		}
+		if out.NextToken == nil {
+			return tags, nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
+
+type DynamoDBTagResourceAPI interface {
+	TagResource(ctx context.Context,
+		params *dynamodb.TagResourceInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error)
+}
+
+// tagDynamoDBTable adds tags to the table. Tags which already exist are overwritten.
+func tagDynamoDBTable(c context.Context, api DynamoDBTagResourceAPI, tableArn string, tags map[string]string) (*dynamodb.TagResourceOutput, error) {
+	in := &dynamodb.TagResourceInput{
+		ResourceArn: &tableArn,
+		Tags:        dynamoDBTags(tags),
+	}
+	return api.TagResource(c, in)
+}
+
+type DynamoDBUntagResourceAPI interface {
+	UntagResource(ctx context.Context,
+		params *dynamodb.UntagResourceInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.UntagResourceOutput, error)
+}
+
+func untagDynamoDBTable(c context.Context, api DynamoDBUntagResourceAPI, tableArn string, keys []string) (*dynamodb.UntagResourceOutput, error) {
+	in := &dynamodb.UntagResourceInput{
+		ResourceArn: &tableArn,
+		TagKeys:     keys,
+	}
+	return api.UntagResource(c, in)
+}
+
+// dynamoDBTags converts tags to the form of DynamoDB API. nil is returned for empty tags.
+func dynamoDBTags(tags map[string]string) []types.Tag {
+	var res []types.Tag
+	for k, v := range tags {
+		res = append(res, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return res
+}
+
+type DynamoDBScanAPI interface {
+	Scan(ctx context.Context,
+		params *dynamodb.ScanInput,
+		optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// scanDynamoDBLocks returns LockID of the items which terraform holds as active locks.
+// Terraform stores lock information in 'Info' attribute, while the items for state checksum only have 'Digest'.
+func scanDynamoDBLocks(c context.Context, api DynamoDBScanAPI, tableName string) ([]string, error) {
+	in := &dynamodb.ScanInput{
+		TableName:            &tableName,
+		FilterExpression:     aws.String("attribute_exists(Info)"),
+		ProjectionExpression: aws.String("LockID"),
+	}
+
+	var locks []string
+	p := dynamodb.NewScanPaginator(api, in)
+	for p.HasMorePages() {
+		out, err := p.NextPage(c)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range out.Items {
+			if v, ok := item["LockID"].(*types.AttributeValueMemberS); ok {
+				locks = append(locks, v.Value)
+			}
+		}
+	}
+	return locks, nil
+}
+
+// waitDynamoDBTableActive waits until the table becomes ACTIVE.
+// A table can't be updated or deleted while it is being created.
+func waitDynamoDBTableActive(c context.Context, api DynamoDBDescribeTableAPI, tableName string, maxWait time.Duration) error {
+	w := dynamodb.NewTableExistsWaiter(api, func(o *dynamodb.TableExistsWaiterOptions) {
+		o.MinDelay = 2 * time.Second
+		o.MaxDelay = 10 * time.Second
+	})
+	return w.Wait(c, &dynamodb.DescribeTableInput{TableName: &tableName}, maxWait)
+}
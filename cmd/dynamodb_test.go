@@ -72,7 +72,7 @@ func Test_createDynamoDBTable_Success(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			// When
-			got, err := createDynamoDBTable(context.Background(), tt.api(t), tt.args.tableName, tt.args.billingMode)
+			got, err := createDynamoDBTable(context.Background(), tt.api(t), tt.args.tableName, tt.args.billingMode, defaultTableCapacity, nil)
 
 			// Then
 			if (err != nil) != tt.wantErr {
@@ -114,7 +114,7 @@ func Test_createDynamoDBTable_Failure(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			// When
-			got, err := createDynamoDBTable(context.Background(), tt.api(t), tt.args.tableName, tt.args.billingMode)
+			got, err := createDynamoDBTable(context.Background(), tt.api(t), tt.args.tableName, tt.args.billingMode, defaultTableCapacity, nil)
 
 			// Then
 			if (err != nil) != tt.wantErr {
@@ -128,6 +128,78 @@ func Test_createDynamoDBTable_Failure(t *testing.T) {
 	}
 }
 
+type mockDynamoDBUpdateTableAPI func(ctx context.Context,
+	params *dynamodb.UpdateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error)
+
+func (m mockDynamoDBUpdateTableAPI) UpdateTable(ctx context.Context,
+	params *dynamodb.UpdateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+
+	return m(ctx, params, optFns...)
+}
+
+func Test_updateDynamoDBTableBillingMode(t *testing.T) {
+	type args struct {
+		tableName   string
+		billingMode string
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantThroughput bool
+		wantErr        bool
+	}{
+		{
+			name: "S01: billingMode=PAY_PER_REQUEST",
+			args: args{
+				tableName:   "TestTable",
+				billingMode: "PAY_PER_REQUEST",
+			},
+			wantThroughput: false,
+			wantErr:        false,
+		},
+		{
+			name: "S02: billingMode=PROVISIONED",
+			args: args{
+				tableName:   "TestTable",
+				billingMode: "PROVISIONED",
+			},
+			wantThroughput: true,
+			wantErr:        false,
+		},
+		{
+			name: "F01: billingMode=invalid",
+			args: args{
+				tableName:   "TestTable",
+				billingMode: "invalid💀",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := mockDynamoDBUpdateTableAPI(func(ctx context.Context,
+				params *dynamodb.UpdateTableInput,
+				optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+
+				if (params.ProvisionedThroughput != nil) != tt.wantThroughput {
+					t.Errorf("updateDynamoDBTableBillingMode() ProvisionedThroughput = %v, want %v", params.ProvisionedThroughput, tt.wantThroughput)
+				}
+				if params.ProvisionedThroughput != nil && (*params.ProvisionedThroughput.ReadCapacityUnits != 20 || *params.ProvisionedThroughput.WriteCapacityUnits != 50) {
+					t.Errorf("updateDynamoDBTableBillingMode() ProvisionedThroughput = %v, want Read=20, Write=50", params.ProvisionedThroughput)
+				}
+				return &dynamodb.UpdateTableOutput{}, nil
+			})
+
+			_, err := updateDynamoDBTableBillingMode(context.Background(), api, tt.args.tableName, tt.args.billingMode, tableCapacity{Read: 20, Write: 50})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("updateDynamoDBTableBillingMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 type mockDynamoDBDescribeTableAPI func(ctx context.Context,
 	params *dynamodb.DescribeTableInput,
 	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
@@ -0,0 +1,379 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcsBucketName              string
+	gcpProject                 string
+	gcsLocation                string
+	gcsKMSKeyName              string
+	gcsNoncurrentRetentionDays int
+)
+
+// initGCSOption holds settings of the terraform backend bucket on Cloud Storage.
+type initGCSOption struct {
+	Project  string
+	Location string
+	// KMSKeyName is the resource name of Cloud KMS key for default encryption. If empty, Google-managed key is used.
+	KMSKeyName string
+	// NoncurrentRetentionDays is the number of days noncurrent versions are kept before deletion.
+	NoncurrentRetentionDays int
+}
+
+// gcsBucketSetting is a setting of the bucket applied by a step.
+type gcsBucketSetting struct {
+	step      string
+	isDesired func(b *gcsBucket) bool
+	// patch applies the setting, and restore reverts it to the value before the step.
+	patch   map[string]interface{}
+	restore map[string]interface{}
+}
+
+type initGCSResult struct {
+	BucketName               string `json:"bucket_name" yaml:"bucket_name"`
+	Location                 string `json:"location" yaml:"location"`
+	UniformBucketLevelAccess string `json:"uniform_bucket_level_access" yaml:"uniform_bucket_level_access"`
+	PublicAccessPrevention   string `json:"public_access_prevention" yaml:"public_access_prevention"`
+	Versioning               string `json:"versioning" yaml:"versioning"`
+	KMSKeyName               string `json:"kms_key_name,omitempty" yaml:"kms_key_name,omitempty"`
+	NoncurrentVersionDeleted string `json:"noncurrent_version_deleted" yaml:"noncurrent_version_deleted"`
+}
+
+func NewCmdGcp() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gcp",
+		Short: "Create Cloud Storage bucket for terraform backend.",
+		Long: `Create Cloud Storage bucket for terraform backend.
+
+By default, the bucket configuration is below.
+- Enabled uniform bucket-level access
+- Enforced public access prevention
+- Enabled object versioning
+- Lifecycle rule: delete noncurrent versions after 90 days
+- Default encryption: Google-managed key
+  (customer-managed key when --kms-key-name is specified)
+
+Cloud Storage provides state locking by itself, so no lock table is needed.
+
+If the bucket already exists and is owned by you, it is adopted
+and each setting is converged to the configuration above.
+
+Authentication uses GOOGLE_OAUTH_ACCESS_TOKEN or 'gcloud auth print-access-token'.
+If STORAGE_EMULATOR_HOST is set, requests are sent to the emulator without authentication.
+`,
+		SilenceUsage: true,
+		RunE:         runCmdGcp,
+	}
+
+	// flag
+	cmd.Flags().StringVarP(&gcsBucketName, "gcs", "", "", "Name of Cloud Storage bucket to create.")
+	cmd.MarkFlagRequired("gcs")
+	cmd.Flags().StringVarP(&gcpProject, "project", "", "", "ID of Google Cloud project which owns the bucket.")
+	cmd.MarkFlagRequired("project")
+	cmd.Flags().StringVarP(&gcsLocation, "location", "", "US", "Location of the bucket, e.g. US, ASIA1 or asia-northeast1.")
+	cmd.Flags().StringVarP(&gcsKMSKeyName, "kms-key-name", "", "", "Resource name of Cloud KMS key for default encryption, e.g. projects/P/locations/L/keyRings/R/cryptoKeys/K.")
+	cmd.Flags().IntVarP(&gcsNoncurrentRetentionDays, "noncurrent-version-retention-days", "", 90, "Days to keep noncurrent versions before deletion.")
+	cmd.Flags().BoolVarP(&noRollback, "no-rollback", "", false, "Don't rollback resources created or updated before a failure. Useful for debugging.")
+
+	return cmd
+}
+
+func runCmdGcp(cmd *cobra.Command, args []string) error {
+	// Validation
+	if err := validateGCSBucketName(gcsBucketName); err != nil {
+		return fmt.Errorf("invalid bucket name %v: %w", gcsBucketName, err)
+	}
+	if gcsNoncurrentRetentionDays < 1 {
+		return fmt.Errorf("--noncurrent-version-retention-days must be positive: %v", gcsNoncurrentRetentionDays)
+	}
+
+	opt := initGCSOption{
+		Project:                 gcpProject,
+		Location:                gcsLocation,
+		KMSKeyName:              gcsKMSKeyName,
+		NoncurrentRetentionDays: gcsNoncurrentRetentionDays,
+	}
+
+	tx := &transaction{}
+	res, err := initGCS(newGCSClient(), gcsBucketName, opt, tx)
+	if err != nil {
+		return abortTransaction(tx, fmt.Errorf("failed to initialize gcs bucket: %w", err))
+	}
+
+	printCyan(fmt.Sprintf("Successfully create terraform backend - gcs bucket: %v\n", gcsBucketName))
+	fmt.Fprintf(progressOut, "Detail ... \n\n")
+	gTable := tablewriter.NewWriter(os.Stdout)
+	h, b := res.createTableInput()
+	gTable.SetHeader(h)
+	for _, v := range b {
+		gTable.Append(v)
+	}
+	gTable.SetAlignment(tablewriter.ALIGN_LEFT)
+	gTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	gTable.SetCenterSeparator("|")
+	gTable.Render()
+
+	return nil
+}
+
+// validateGCSBucketName checks the naming rules of Cloud Storage buckets, and returns the rule which the name breaks.
+// Names with dots are allowed up to 222 characters, but each dot-separated component is limited to 63.
+func validateGCSBucketName(name string) error {
+	maxLength := 63
+	if strings.Contains(name, ".") {
+		maxLength = 222
+	}
+	if len(name) < 3 || len(name) > maxLength {
+		return fmt.Errorf("must be between 3 and %v characters long, but %v characters", maxLength, len(name))
+	}
+	for i, r := range name {
+		if !isLowerAlphanumeric(r) && r != '.' && r != '-' && r != '_' {
+			return fmt.Errorf("must consist of lowercase letters, numbers, dots, hyphens and underscores, but contains %q at position %v", r, i+1)
+		}
+	}
+	if !isLowerAlphanumeric(rune(name[0])) {
+		return fmt.Errorf("must begin with a lowercase letter or number")
+	}
+	if !isLowerAlphanumeric(rune(name[len(name)-1])) {
+		return fmt.Errorf("must end with a lowercase letter or number")
+	}
+	for _, component := range strings.Split(name, ".") {
+		if len(component) == 0 || len(component) > 63 {
+			return fmt.Errorf("each dot-separated component must be between 1 and 63 characters long")
+		}
+	}
+	if ipAddressPattern.MatchString(name) {
+		return fmt.Errorf("must not be formatted as an IP address")
+	}
+	if strings.HasPrefix(name, "goog") {
+		return fmt.Errorf("must not start with the reserved prefix \"goog\"")
+	}
+	if strings.Contains(name, "google") {
+		return fmt.Errorf("must not contain \"google\"")
+	}
+	return nil
+}
+
+// initGCS setup terraform backend bucket on Cloud Storage with messages.
+func initGCS(c GCSClientable, bucketName string, opt initGCSOption, tx *transaction) (*initGCSResult, error) {
+	progress.section("gcs_bucket", "🚀 Start to create terraform backend: gcs bucket ...")
+
+	// Create bucket. If the bucket already exists and is owned by us, adopt it.
+	progress.begin("Creating bucket")
+	current, err := c.GetBucket(context.TODO(), bucketName)
+	exists := err == nil
+	if err != nil && !isGCSStatusCode(err, 404) {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of gcs bucket: %w", err)
+	}
+	if exists {
+		progress.end(stepStatusUnchanged)
+	} else {
+		current, err = c.InsertBucket(context.TODO(), opt.Project, &gcsBucket{Name: bucketName, Location: opt.Location})
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create gcs bucket: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete gcs bucket %v", bucketName), func() error {
+			return c.DeleteBucket(context.TODO(), bucketName)
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Each setting is applied by PATCH, and the previous value is restored by PATCH on rollback.
+	settings := []gcsBucketSetting{
+		{
+			step:      "Activate uniform bucket-level access",
+			isDesired: isGCSUniformBucketLevelAccessEnabled,
+			patch: map[string]interface{}{
+				"iamConfiguration": map[string]interface{}{"uniformBucketLevelAccess": map[string]interface{}{"enabled": true}},
+			},
+			restore: map[string]interface{}{
+				"iamConfiguration": map[string]interface{}{"uniformBucketLevelAccess": map[string]interface{}{"enabled": false}},
+			},
+		},
+		{
+			step:      "Enforce public access prevention",
+			isDesired: isGCSPublicAccessPrevented,
+			patch: map[string]interface{}{
+				"iamConfiguration": map[string]interface{}{"publicAccessPrevention": "enforced"},
+			},
+			restore: map[string]interface{}{
+				"iamConfiguration": map[string]interface{}{"publicAccessPrevention": gcsPublicAccessPrevention(current)},
+			},
+		},
+		{
+			step:      "Activate object versioning",
+			isDesired: isGCSVersioningEnabled,
+			patch:     map[string]interface{}{"versioning": map[string]interface{}{"enabled": true}},
+			restore:   map[string]interface{}{"versioning": map[string]interface{}{"enabled": false}},
+		},
+		{
+			step:      "Apply lifecycle rule for noncurrent versions",
+			isDesired: func(b *gcsBucket) bool { return hasGCSNoncurrentVersionRule(b, opt.NoncurrentRetentionDays) },
+			patch:     map[string]interface{}{"lifecycle": withGCSNoncurrentVersionRule(current.Lifecycle, opt.NoncurrentRetentionDays)},
+			restore:   map[string]interface{}{"lifecycle": current.Lifecycle},
+		},
+	}
+	if opt.KMSKeyName != "" {
+		settings = append(settings, gcsBucketSetting{
+			step:      "Activate default encryption (CMEK)",
+			isDesired: func(b *gcsBucket) bool { return gcsKMSKeyNameOf(b) == opt.KMSKeyName },
+			patch:     map[string]interface{}{"encryption": map[string]interface{}{"defaultKmsKeyName": opt.KMSKeyName}},
+			restore:   map[string]interface{}{"encryption": current.Encryption},
+		})
+	}
+
+	for _, s := range settings {
+		s := s
+		progress.begin(s.step)
+		if exists && s.isDesired(current) {
+			progress.end(stepStatusUnchanged)
+			continue
+		}
+		if _, err := c.PatchBucket(context.TODO(), bucketName, s.patch); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to %v: %w", s.step, err)
+		}
+		if exists {
+			tx.record(fmt.Sprintf("Restore %v of gcs bucket %v", s.step, bucketName), func() error {
+				_, err := c.PatchBucket(context.TODO(), bucketName, s.restore)
+				return err
+			})
+		}
+		progress.end(appliedStatus(exists))
+	}
+
+	// Describe bucket
+	progress.begin("Confirmation - Get bucket")
+	desc, err := c.GetBucket(context.TODO(), bucketName)
+	if err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("successfully created gcs bucket, but failed to describe gcs bucket: %w", err)
+	}
+	progress.end(stepStatusSuccess)
+
+	res := initGCSResult{
+		BucketName:               bucketName,
+		Location:                 desc.Location,
+		UniformBucketLevelAccess: enabledOrDisabled(isGCSUniformBucketLevelAccessEnabled(desc)),
+		PublicAccessPrevention:   gcsPublicAccessPrevention(desc),
+		Versioning:               enabledOrDisabled(isGCSVersioningEnabled(desc)),
+		KMSKeyName:               gcsKMSKeyNameOf(desc),
+		NoncurrentVersionDeleted: "Never",
+	}
+	if hasGCSNoncurrentVersionRule(desc, opt.NoncurrentRetentionDays) {
+		res.NoncurrentVersionDeleted = "After " + strconv.Itoa(opt.NoncurrentRetentionDays) + " days"
+	}
+
+	return &res, nil
+}
+
+func isGCSUniformBucketLevelAccessEnabled(b *gcsBucket) bool {
+	return b != nil && b.IamConfiguration != nil && b.IamConfiguration.UniformBucketLevelAccess != nil &&
+		b.IamConfiguration.UniformBucketLevelAccess.Enabled
+}
+
+func isGCSPublicAccessPrevented(b *gcsBucket) bool {
+	return gcsPublicAccessPrevention(b) == "enforced"
+}
+
+// gcsPublicAccessPrevention returns public access prevention of the bucket. "inherited" is the default.
+func gcsPublicAccessPrevention(b *gcsBucket) string {
+	if b == nil || b.IamConfiguration == nil || b.IamConfiguration.PublicAccessPrevention == "" {
+		return "inherited"
+	}
+	return b.IamConfiguration.PublicAccessPrevention
+}
+
+func isGCSVersioningEnabled(b *gcsBucket) bool {
+	return b != nil && b.Versioning != nil && b.Versioning.Enabled
+}
+
+func gcsKMSKeyNameOf(b *gcsBucket) string {
+	if b == nil || b.Encryption == nil {
+		return ""
+	}
+	return b.Encryption.DefaultKmsKeyName
+}
+
+// hasGCSNoncurrentVersionRule checks if the bucket deletes noncurrent versions after the days.
+func hasGCSNoncurrentVersionRule(b *gcsBucket, days int) bool {
+	if b == nil || b.Lifecycle == nil {
+		return false
+	}
+	for _, r := range b.Lifecycle.Rule {
+		if d, ok := gcsNoncurrentVersionRuleDays(r); ok && d == days {
+			return true
+		}
+	}
+	return false
+}
+
+// withGCSNoncurrentVersionRule returns lifecycle which has the existing rules and the rule for noncurrent versions.
+// Other rules for noncurrent versions are replaced.
+func withGCSNoncurrentVersionRule(lifecycle *gcsLifecycle, days int) *gcsLifecycle {
+	res := &gcsLifecycle{}
+	if lifecycle != nil {
+		for _, r := range lifecycle.Rule {
+			if _, ok := gcsNoncurrentVersionRuleDays(r); ok {
+				continue
+			}
+			res.Rule = append(res.Rule, r)
+		}
+	}
+	res.Rule = append(res.Rule, gcsLifecycleRule{
+		Action:    map[string]interface{}{"type": "Delete"},
+		Condition: map[string]interface{}{"daysSinceNoncurrentTime": days},
+	})
+	return res
+}
+
+// gcsNoncurrentVersionRuleDays returns the days of the rule for noncurrent versions, which deletes objects with
+// the condition of exactly daysSinceNoncurrentTime. Rules scoped by the other conditions, e.g. matchesPrefix, are not.
+func gcsNoncurrentVersionRuleDays(r gcsLifecycleRule) (int, bool) {
+	if len(r.Action) != 1 || r.Action["type"] != "Delete" || len(r.Condition) != 1 {
+		return 0, false
+	}
+	switch d := r.Condition["daysSinceNoncurrentTime"].(type) {
+	case float64:
+		return int(d), d > 0
+	case int:
+		return d, d > 0
+	}
+	return 0, false
+}
+
+func enabledOrDisabled(b bool) string {
+	if b {
+		return "Enabled"
+	}
+	return "Disabled"
+}
+
+func (i *initGCSResult) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Bucket name", i.BucketName},
+		{"Location", i.Location},
+		{"Uniform bucket-level access", i.UniformBucketLevelAccess},
+		{"Public access prevention", i.PublicAccessPrevention},
+		{"Versioning", i.Versioning},
+	}
+	if i.KMSKeyName != "" {
+		b = append(b, []string{"KMS key", i.KMSKeyName})
+	} else {
+		b = append(b, []string{"KMS key", "Google-managed"})
+	}
+	b = append(b, []string{"Noncurrent versions deleted", i.NoncurrentVersionDeleted})
+	return h, b
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// -----------------------------------
+// For initGCS test
+// -----------------------------------
+
+// fakeGCSServer behaves like Cloud Storage JSON API which holds buckets in memory.
+// PATCH merges the request body into the bucket like the real API, and null clears the field.
+type fakeGCSServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	buckets  map[string]map[string]interface{}
+	failOn   string
+	requests []string
+}
+
+func newFakeGCSServer() *fakeGCSServer {
+	f := &fakeGCSServer{buckets: map[string]map[string]interface{}{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// client returns gcsClient which sends requests to the fake server.
+func (f *fakeGCSServer) client() *gcsClient {
+	return &gcsClient{endpoint: f.URL, httpClient: f.Client()}
+}
+
+func (f *fakeGCSServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, r.Method)
+	if f.failOn == r.Method {
+		writeFakeGCSError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/")
+	var body map[string]interface{}
+	if b, _ := ioutil.ReadAll(r.Body); len(b) > 0 {
+		if err := json.Unmarshal(b, &body); err != nil {
+			writeFakeGCSError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/storage/v1/b":
+		if r.URL.Query().Get("project") == "" {
+			writeFakeGCSError(w, http.StatusBadRequest, "project is required")
+			return
+		}
+		bucketName, _ := body["name"].(string)
+		if _, ok := f.buckets[bucketName]; ok {
+			writeFakeGCSError(w, http.StatusConflict, "bucket already exists")
+			return
+		}
+		f.buckets[bucketName] = body
+		json.NewEncoder(w).Encode(body)
+	case r.Method == http.MethodGet:
+		b, ok := f.buckets[name]
+		if !ok {
+			writeFakeGCSError(w, http.StatusNotFound, "bucket not found")
+			return
+		}
+		json.NewEncoder(w).Encode(b)
+	case r.Method == http.MethodPatch:
+		b, ok := f.buckets[name]
+		if !ok {
+			writeFakeGCSError(w, http.StatusNotFound, "bucket not found")
+			return
+		}
+		mergeFakeGCSPatch(b, body)
+		json.NewEncoder(w).Encode(b)
+	case r.Method == http.MethodDelete:
+		delete(f.buckets, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeFakeGCSError(w, http.StatusNotImplemented, "not implemented")
+	}
+}
+
+func mergeFakeGCSPatch(dst map[string]interface{}, patch map[string]interface{}) {
+	for k, v := range patch {
+		if v == nil {
+			delete(dst, k)
+			continue
+		}
+		if pv, ok := v.(map[string]interface{}); ok {
+			if dv, ok := dst[k].(map[string]interface{}); ok {
+				mergeFakeGCSPatch(dv, pv)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+func writeFakeGCSError(w http.ResponseWriter, code int, message string) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{"code": code, "message": message},
+	})
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_initGCS(t *testing.T) {
+	type args struct {
+		bucketName string
+		opt        initGCSOption
+	}
+	tests := []struct {
+		name     string
+		existing map[string]interface{}
+		failOn   string
+		args     args
+		want     *initGCSResult
+		// wantKeptRules are the existing lifecycle rules which must be left as they are.
+		wantKeptRules []gcsLifecycleRule
+		wantErr       bool
+	}{
+		{
+			name: "S01: Happy path",
+			args: args{
+				bucketName: "happy-bucket",
+				opt:        initGCSOption{Project: "happy-project", Location: "ASIA-NORTHEAST1", NoncurrentRetentionDays: 90},
+			},
+			want: &initGCSResult{
+				BucketName:               "happy-bucket",
+				Location:                 "ASIA-NORTHEAST1",
+				UniformBucketLevelAccess: "Enabled",
+				PublicAccessPrevention:   "enforced",
+				Versioning:               "Enabled",
+				NoncurrentVersionDeleted: "After 90 days",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S02: Happy path, CMEK",
+			args: args{
+				bucketName: "happy-bucket",
+				opt: initGCSOption{
+					Project:                 "happy-project",
+					Location:                "US",
+					KMSKeyName:              "projects/happy-project/locations/us/keyRings/happy/cryptoKeys/happy-key",
+					NoncurrentRetentionDays: 30,
+				},
+			},
+			want: &initGCSResult{
+				BucketName:               "happy-bucket",
+				Location:                 "US",
+				UniformBucketLevelAccess: "Enabled",
+				PublicAccessPrevention:   "enforced",
+				Versioning:               "Enabled",
+				KMSKeyName:               "projects/happy-project/locations/us/keyRings/happy/cryptoKeys/happy-key",
+				NoncurrentVersionDeleted: "After 30 days",
+			},
+			wantErr: false,
+		},
+		{
+			name: "S03: Adopt existing bucket and keep other lifecycle rules",
+			existing: map[string]interface{}{
+				"name":       "happy-bucket",
+				"location":   "US",
+				"versioning": map[string]interface{}{"enabled": false},
+				"lifecycle": map[string]interface{}{
+					"rule": []interface{}{
+						map[string]interface{}{
+							"action":    map[string]interface{}{"type": "SetStorageClass", "storageClass": "NEARLINE"},
+							"condition": map[string]interface{}{"age": 30},
+						},
+						map[string]interface{}{
+							"action":    map[string]interface{}{"type": "Delete"},
+							"condition": map[string]interface{}{"daysSinceNoncurrentTime": 90, "matchesPrefix": []interface{}{"logs/"}},
+						},
+					},
+				},
+			},
+			args: args{
+				bucketName: "happy-bucket",
+				opt:        initGCSOption{Project: "happy-project", Location: "US", NoncurrentRetentionDays: 90},
+			},
+			want: &initGCSResult{
+				BucketName:               "happy-bucket",
+				Location:                 "US",
+				UniformBucketLevelAccess: "Enabled",
+				PublicAccessPrevention:   "enforced",
+				Versioning:               "Enabled",
+				NoncurrentVersionDeleted: "After 90 days",
+			},
+			wantKeptRules: []gcsLifecycleRule{
+				{
+					Action:    map[string]interface{}{"type": "SetStorageClass", "storageClass": "NEARLINE"},
+					Condition: map[string]interface{}{"age": float64(30)},
+				},
+				{
+					Action:    map[string]interface{}{"type": "Delete"},
+					Condition: map[string]interface{}{"daysSinceNoncurrentTime": float64(90), "matchesPrefix": []interface{}{"logs/"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:   "F01: Creating bucket failure",
+			failOn: "POST",
+			args: args{
+				bucketName: "happy-bucket",
+				opt:        initGCSOption{Project: "happy-project", Location: "US", NoncurrentRetentionDays: 90},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:   "F02: Patch failure",
+			failOn: "PATCH",
+			args: args{
+				bucketName: "happy-bucket",
+				opt:        initGCSOption{Project: "happy-project", Location: "US", NoncurrentRetentionDays: 90},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeGCSServer()
+			defer server.Close()
+			if tt.existing != nil {
+				server.buckets[tt.args.bucketName] = tt.existing
+			}
+			server.failOn = tt.failOn
+
+			got, err := initGCS(server.client(), tt.args.bucketName, tt.args.opt, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initGCS() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initGCS() = %v, want %v", got, tt.want)
+			}
+			if len(tt.wantKeptRules) > 0 {
+				b, err := server.client().GetBucket(context.TODO(), tt.args.bucketName)
+				if err != nil {
+					t.Fatalf("GetBucket() error = %v", err)
+				}
+				want := append(append([]gcsLifecycleRule{}, tt.wantKeptRules...), gcsLifecycleRule{
+					Action:    map[string]interface{}{"type": "Delete"},
+					Condition: map[string]interface{}{"daysSinceNoncurrentTime": float64(tt.args.opt.NoncurrentRetentionDays)},
+				})
+				if b.Lifecycle == nil || !reflect.DeepEqual(b.Lifecycle.Rule, want) {
+					t.Errorf("initGCS() lifecycle rules = %v, want %v", b.Lifecycle, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_initGCS_Converged(t *testing.T) {
+	server := newFakeGCSServer()
+	defer server.Close()
+	opt := initGCSOption{Project: "happy-project", Location: "US", NoncurrentRetentionDays: 90}
+
+	if _, err := initGCS(server.client(), "happy-bucket", opt, nil); err != nil {
+		t.Fatalf("initGCS() error = %v", err)
+	}
+
+	// Re-running against the converged bucket must not send any PATCH.
+	server.requests = nil
+	if _, err := initGCS(server.client(), "happy-bucket", opt, nil); err != nil {
+		t.Fatalf("initGCS() error = %v", err)
+	}
+	for _, m := range server.requests {
+		if m != "GET" {
+			t.Errorf("initGCS() sent %v to the converged bucket", m)
+		}
+	}
+
+	// The other lifecycle rules are kept and the rule for noncurrent versions is not duplicated.
+	b, _ := server.client().GetBucket(context.TODO(), "happy-bucket")
+	if len(b.Lifecycle.Rule) != 1 {
+		t.Errorf("initGCS() lifecycle rules = %v, want 1 rule", b.Lifecycle.Rule)
+	}
+}
+
+func Test_initGCS_Rollback(t *testing.T) {
+	server := newFakeGCSServer()
+	defer server.Close()
+	server.buckets["happy-bucket"] = map[string]interface{}{
+		"name":       "happy-bucket",
+		"location":   "US",
+		"versioning": map[string]interface{}{"enabled": false},
+	}
+	otherRule := gcsLifecycleRule{
+		Action:    map[string]interface{}{"type": "SetStorageClass", "storageClass": "NEARLINE"},
+		Condition: map[string]interface{}{"age": float64(30)},
+	}
+	server.buckets["happy-bucket"]["lifecycle"] = map[string]interface{}{
+		"rule": []interface{}{map[string]interface{}{"action": otherRule.Action, "condition": otherRule.Condition}},
+	}
+
+	// Rolling back an adopted bucket restores the original settings instead of deleting it.
+	tx := &transaction{}
+	client := server.client()
+	opt := initGCSOption{Project: "happy-project", Location: "US", NoncurrentRetentionDays: 90}
+	if _, err := initGCS(client, "happy-bucket", opt, tx); err != nil {
+		t.Fatalf("initGCS() error = %v", err)
+	}
+	if err := tx.rollback(); err != nil {
+		t.Fatalf("transaction.rollback() error = %v", err)
+	}
+
+	b, err := client.GetBucket(context.TODO(), "happy-bucket")
+	if err != nil {
+		t.Fatalf("GetBucket() error = %v", err)
+	}
+	if isGCSVersioningEnabled(b) || isGCSUniformBucketLevelAccessEnabled(b) || isGCSPublicAccessPrevented(b) {
+		t.Errorf("rollback didn't restore the bucket: %+v", b)
+	}
+	if b.Lifecycle == nil || !reflect.DeepEqual(b.Lifecycle.Rule, []gcsLifecycleRule{otherRule}) {
+		t.Errorf("rollback didn't restore the lifecycle rules: %+v", b.Lifecycle)
+	}
+}
+
+func Test_validateGCSBucketName(t *testing.T) {
+	tests := []struct {
+		name       string
+		bucketName string
+		wantErr    string
+	}{
+		{
+			name:       "S01: Happy path",
+			bucketName: "success_bucket-1",
+		},
+		{
+			name:       "S02: Dots allow more than 63 characters",
+			bucketName: strings.Repeat("a", 63) + "." + strings.Repeat("b", 63),
+		},
+		{
+			name:       "F01: Too short",
+			bucketName: "ab",
+			wantErr:    "between 3 and 63 characters",
+		},
+		{
+			name:       "F02: Too long without dots",
+			bucketName: strings.Repeat("a", 64),
+			wantErr:    "between 3 and 63 characters",
+		},
+		{
+			name:       "F03: Contains capitals",
+			bucketName: "FAILURE-BUCKET",
+			wantErr:    "contains 'F' at position 1",
+		},
+		{
+			name:       "F04: Trailing underscore",
+			bucketName: "failure-bucket_",
+			wantErr:    "must end with",
+		},
+		{
+			name:       "F05: Too long component",
+			bucketName: strings.Repeat("a", 64) + ".bucket",
+			wantErr:    "each dot-separated component",
+		},
+		{
+			name:       "F06: IP address",
+			bucketName: "192.168.5.4",
+			wantErr:    "IP address",
+		},
+		{
+			name:       "F07: goog prefix",
+			bucketName: "goog-failure-bucket",
+			wantErr:    `reserved prefix "goog"`,
+		},
+		{
+			name:       "F08: Contains google",
+			bucketName: "my-google-bucket",
+			wantErr:    `must not contain "google"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGCSBucketName(tt.bucketName)
+			if (err != nil) != (tt.wantErr != "") {
+				t.Fatalf("validateGCSBucketName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateGCSBucketName() error = %v, want it to contain %v", err, tt.wantErr)
+			}
+		})
+	}
+}
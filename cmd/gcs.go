@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GCSClientable is the subset of Cloud Storage JSON API used by tfbackend.
+type GCSClientable interface {
+	GetBucket(ctx context.Context, bucketName string) (*gcsBucket, error)
+	InsertBucket(ctx context.Context, project string, bucket *gcsBucket) (*gcsBucket, error)
+	PatchBucket(ctx context.Context, bucketName string, patch map[string]interface{}) (*gcsBucket, error)
+	DeleteBucket(ctx context.Context, bucketName string) error
+}
+
+// gcsBucket is the bucket resource of Cloud Storage JSON API. Only the fields tfbackend manages are declared.
+type gcsBucket struct {
+	Name             string               `json:"name,omitempty"`
+	Location         string               `json:"location,omitempty"`
+	IamConfiguration *gcsIamConfiguration `json:"iamConfiguration,omitempty"`
+	Versioning       *gcsVersioning       `json:"versioning,omitempty"`
+	Encryption       *gcsEncryption       `json:"encryption,omitempty"`
+	Lifecycle        *gcsLifecycle        `json:"lifecycle,omitempty"`
+}
+
+type gcsIamConfiguration struct {
+	UniformBucketLevelAccess *gcsUniformBucketLevelAccess `json:"uniformBucketLevelAccess,omitempty"`
+	PublicAccessPrevention   string                       `json:"publicAccessPrevention,omitempty"`
+}
+
+type gcsUniformBucketLevelAccess struct {
+	Enabled bool `json:"enabled"`
+}
+
+type gcsVersioning struct {
+	Enabled bool `json:"enabled"`
+}
+
+type gcsEncryption struct {
+	DefaultKmsKeyName string `json:"defaultKmsKeyName,omitempty"`
+}
+
+type gcsLifecycle struct {
+	Rule []gcsLifecycleRule `json:"rule"`
+}
+
+// gcsLifecycleRule keeps action and condition as decoded, because the whole lifecycle is PATCHed
+// and fields which tfbackend doesn't know, e.g. storageClass or age, must survive it.
+type gcsLifecycleRule struct {
+	Action    map[string]interface{} `json:"action"`
+	Condition map[string]interface{} `json:"condition"`
+}
+
+// gcsAPIError is the error response of Cloud Storage JSON API.
+type gcsAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *gcsAPIError) Error() string {
+	return fmt.Sprintf("gcs api error: status %v: %v", e.StatusCode, e.Message)
+}
+
+// isGCSStatusCode checks if err is the error response with one of the status codes.
+func isGCSStatusCode(err error, codes ...int) bool {
+	var apiErr *gcsAPIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, c := range codes {
+		if apiErr.StatusCode == c {
+			return true
+		}
+	}
+	return false
+}
+
+// gcsClient calls Cloud Storage JSON API over HTTP.
+type gcsClient struct {
+	endpoint   string
+	httpClient *http.Client
+	// token returns OAuth 2.0 access token. If nil, requests are sent without Authorization header.
+	token func() (string, error)
+}
+
+// newGCSClient returns a client for Cloud Storage.
+// If STORAGE_EMULATOR_HOST is set, requests are sent to the emulator without authentication.
+// Otherwise the access token is taken from GOOGLE_OAUTH_ACCESS_TOKEN or 'gcloud auth print-access-token'.
+func newGCSClient() *gcsClient {
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		return &gcsClient{endpoint: strings.TrimSuffix(host, "/"), httpClient: http.DefaultClient}
+	}
+	return &gcsClient{
+		endpoint:   "https://storage.googleapis.com",
+		httpClient: http.DefaultClient,
+		token:      gcloudAccessToken,
+	}
+}
+
+// gcloudAccessToken returns access token of the application default user.
+func gcloudAccessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	out, err := exec.Command("gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token. Set GOOGLE_OAUTH_ACCESS_TOKEN or login with gcloud: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (c *gcsClient) GetBucket(ctx context.Context, bucketName string) (*gcsBucket, error) {
+	var b gcsBucket
+	if err := c.do(ctx, http.MethodGet, "/storage/v1/b/"+url.PathEscape(bucketName), nil, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (c *gcsClient) InsertBucket(ctx context.Context, project string, bucket *gcsBucket) (*gcsBucket, error) {
+	var b gcsBucket
+	if err := c.do(ctx, http.MethodPost, "/storage/v1/b?project="+url.QueryEscape(project), bucket, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// PatchBucket updates only the fields in patch. A nil value clears the field.
+func (c *gcsClient) PatchBucket(ctx context.Context, bucketName string, patch map[string]interface{}) (*gcsBucket, error) {
+	var b gcsBucket
+	if err := c.do(ctx, http.MethodPatch, "/storage/v1/b/"+url.PathEscape(bucketName), patch, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (c *gcsClient) DeleteBucket(ctx context.Context, bucketName string) error {
+	return c.do(ctx, http.MethodDelete, "/storage/v1/b/"+url.PathEscape(bucketName), nil, nil)
+}
+
+// do sends the request with JSON body and decodes JSON response into out.
+func (c *gcsClient) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != nil {
+		token, err := c.token()
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		msg := strings.TrimSpace(string(b))
+		if json.Unmarshal(b, &e) == nil && e.Error.Message != "" {
+			msg = e.Error.Message
+		}
+		return &gcsAPIError{StatusCode: res.StatusCode, Message: msg}
+	}
+
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+type IAMGetRoleAPI interface {
+	GetRole(ctx context.Context,
+		params *iam.GetRoleInput,
+		optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+}
+
+func getRole(c context.Context, api IAMGetRoleAPI, roleName string) (*iam.GetRoleOutput, error) {
+	in := &iam.GetRoleInput{
+		RoleName: aws.String(roleName),
+	}
+	return api.GetRole(c, in)
+}
+
+type IAMCreateRoleAPI interface {
+	CreateRole(ctx context.Context,
+		params *iam.CreateRoleInput,
+		optFns ...func(*iam.Options)) (*iam.CreateRoleOutput, error)
+}
+
+func createRole(c context.Context, api IAMCreateRoleAPI, roleName string, assumeRolePolicy string, description string, tags map[string]string) (*iam.CreateRoleOutput, error) {
+	in := &iam.CreateRoleInput{
+		RoleName:                 aws.String(roleName),
+		AssumeRolePolicyDocument: aws.String(assumeRolePolicy),
+		Description:              aws.String(description),
+	}
+	for k, v := range tags {
+		in.Tags = append(in.Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return api.CreateRole(c, in)
+}
+
+type IAMDeleteRoleAPI interface {
+	DeleteRole(ctx context.Context,
+		params *iam.DeleteRoleInput,
+		optFns ...func(*iam.Options)) (*iam.DeleteRoleOutput, error)
+}
+
+func deleteRole(c context.Context, api IAMDeleteRoleAPI, roleName string) (*iam.DeleteRoleOutput, error) {
+	in := &iam.DeleteRoleInput{
+		RoleName: aws.String(roleName),
+	}
+	return api.DeleteRole(c, in)
+}
+
+type IAMGetRolePolicyAPI interface {
+	GetRolePolicy(ctx context.Context,
+		params *iam.GetRolePolicyInput,
+		optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error)
+}
+
+// getRolePolicyDocument returns the inline policy of the role. IAM returns the document URL-encoded, so it is decoded.
+func getRolePolicyDocument(c context.Context, api IAMGetRolePolicyAPI, roleName string, policyName string) (string, error) {
+	in := &iam.GetRolePolicyInput{
+		RoleName:   aws.String(roleName),
+		PolicyName: aws.String(policyName),
+	}
+	out, err := api.GetRolePolicy(c, in)
+	if err != nil {
+		return "", err
+	}
+	return decodePolicyDocument(aws.ToString(out.PolicyDocument))
+}
+
+// decodePolicyDocument decodes the policy document returned by IAM, which is URL-encoded.
+func decodePolicyDocument(document string) (string, error) {
+	return url.QueryUnescape(document)
+}
+
+type IAMPutRolePolicyAPI interface {
+	PutRolePolicy(ctx context.Context,
+		params *iam.PutRolePolicyInput,
+		optFns ...func(*iam.Options)) (*iam.PutRolePolicyOutput, error)
+}
+
+func putRolePolicy(c context.Context, api IAMPutRolePolicyAPI, roleName string, policyName string, policy string) (*iam.PutRolePolicyOutput, error) {
+	in := &iam.PutRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyName:     aws.String(policyName),
+		PolicyDocument: aws.String(policy),
+	}
+	return api.PutRolePolicy(c, in)
+}
+
+type IAMDeleteRolePolicyAPI interface {
+	DeleteRolePolicy(ctx context.Context,
+		params *iam.DeleteRolePolicyInput,
+		optFns ...func(*iam.Options)) (*iam.DeleteRolePolicyOutput, error)
+}
+
+func deleteRolePolicy(c context.Context, api IAMDeleteRolePolicyAPI, roleName string, policyName string) (*iam.DeleteRolePolicyOutput, error) {
+	in := &iam.DeleteRolePolicyInput{
+		RoleName:   aws.String(roleName),
+		PolicyName: aws.String(policyName),
+	}
+	return api.DeleteRolePolicy(c, in)
+}
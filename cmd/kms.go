@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+type KMSCreateKeyAPI interface {
+	CreateKey(ctx context.Context,
+		params *kms.CreateKeyInput,
+		optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error)
+}
+
+func createKMSKey(c context.Context, api KMSCreateKeyAPI, description string, policy string, tags map[string]string) (*kms.CreateKeyOutput, error) {
+	in := &kms.CreateKeyInput{
+		Description: aws.String(description),
+		Policy:      aws.String(policy),
+	}
+	for k, v := range tags {
+		in.Tags = append(in.Tags, types.Tag{TagKey: aws.String(k), TagValue: aws.String(v)})
+	}
+	return api.CreateKey(c, in)
+}
+
+type KMSEnableKeyRotationAPI interface {
+	EnableKeyRotation(ctx context.Context,
+		params *kms.EnableKeyRotationInput,
+		optFns ...func(*kms.Options)) (*kms.EnableKeyRotationOutput, error)
+}
+
+func enableKMSKeyRotation(c context.Context, api KMSEnableKeyRotationAPI, keyID string) (*kms.EnableKeyRotationOutput, error) {
+	in := &kms.EnableKeyRotationInput{
+		KeyId: aws.String(keyID),
+	}
+	return api.EnableKeyRotation(c, in)
+}
+
+type KMSCreateAliasAPI interface {
+	CreateAlias(ctx context.Context,
+		params *kms.CreateAliasInput,
+		optFns ...func(*kms.Options)) (*kms.CreateAliasOutput, error)
+}
+
+func createKMSAlias(c context.Context, api KMSCreateAliasAPI, aliasName string, keyID string) (*kms.CreateAliasOutput, error) {
+	in := &kms.CreateAliasInput{
+		AliasName:   aws.String(aliasName),
+		TargetKeyId: aws.String(keyID),
+	}
+	return api.CreateAlias(c, in)
+}
+
+type KMSDescribeKeyAPI interface {
+	DescribeKey(ctx context.Context,
+		params *kms.DescribeKeyInput,
+		optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error)
+}
+
+func describeKMSKey(c context.Context, api KMSDescribeKeyAPI, keyID string) (*kms.DescribeKeyOutput, error) {
+	in := &kms.DescribeKeyInput{
+		KeyId: aws.String(keyID),
+	}
+	return api.DescribeKey(c, in)
+}
+
+// resolveKMSKeyArn returns ARN of the key specified by key ID, key ARN, alias name or alias ARN.
+func resolveKMSKeyArn(api KMSDescribeKeyAPI, keyID string) (string, error) {
+	desc, err := describeKMSKey(context.TODO(), api, keyID)
+	if err != nil {
+		return "", err
+	}
+	return *desc.KeyMetadata.Arn, nil
+}
+
+type KMSScheduleKeyDeletionAPI interface {
+	ScheduleKeyDeletion(ctx context.Context,
+		params *kms.ScheduleKeyDeletionInput,
+		optFns ...func(*kms.Options)) (*kms.ScheduleKeyDeletionOutput, error)
+}
+
+// scheduleKMSKeyDeletion schedules deletion of the key after the minimum waiting period (7 days).
+func scheduleKMSKeyDeletion(c context.Context, api KMSScheduleKeyDeletionAPI, keyID string) (*kms.ScheduleKeyDeletionOutput, error) {
+	in := &kms.ScheduleKeyDeletionInput{
+		KeyId:               aws.String(keyID),
+		PendingWindowInDays: aws.Int32(7),
+	}
+	return api.ScheduleKeyDeletion(c, in)
+}
+
+type KMSDeleteAliasAPI interface {
+	DeleteAlias(ctx context.Context,
+		params *kms.DeleteAliasInput,
+		optFns ...func(*kms.Options)) (*kms.DeleteAliasOutput, error)
+}
+
+func deleteKMSAlias(c context.Context, api KMSDeleteAliasAPI, aliasName string) (*kms.DeleteAliasOutput, error) {
+	in := &kms.DeleteAliasInput{
+		AliasName: aws.String(aliasName),
+	}
+	return api.DeleteAlias(c, in)
+}
+
+type kmsKeyPolicy struct {
+	Version   string                  `json:"Version"`
+	Statement []kmsKeyPolicyStatement `json:"Statement"`
+}
+
+type kmsKeyPolicyStatement struct {
+	Sid       string                       `json:"Sid"`
+	Effect    string                       `json:"Effect"`
+	Principal map[string]string            `json:"Principal"`
+	Action    []string                     `json:"Action"`
+	Resource  string                       `json:"Resource"`
+	Condition map[string]map[string]string `json:"Condition,omitempty"`
+}
+
+// buildKMSKeyPolicy returns a key policy which lets the account administer the key via IAM
+// and lets principals in the account use the key only through S3 in the given region.
+func buildKMSKeyPolicy(partition string, accountID string, region string) (string, error) {
+	p := kmsKeyPolicy{
+		Version: "2012-10-17",
+		Statement: []kmsKeyPolicyStatement{
+			{
+				Sid:       "EnableIAMUserPermissions",
+				Effect:    "Allow",
+				Principal: map[string]string{"AWS": "arn:" + partition + ":iam::" + accountID + ":root"},
+				Action:    []string{"kms:*"},
+				Resource:  "*",
+			},
+			{
+				Sid:       "AllowUseOfTheKeyThroughS3",
+				Effect:    "Allow",
+				Principal: map[string]string{"AWS": "*"},
+				Action: []string{
+					"kms:Encrypt",
+					"kms:Decrypt",
+					"kms:ReEncrypt*",
+					"kms:GenerateDataKey*",
+					"kms:DescribeKey",
+				},
+				Resource: "*",
+				Condition: map[string]map[string]string{
+					"StringEquals": {
+						"kms:CallerAccount": accountID,
+						"kms:ViaService":    "s3." + region + ".amazonaws.com",
+					},
+				},
+			},
+		},
+	}
+
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
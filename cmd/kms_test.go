@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+type mockKMSCreateKeyAPI func(ctx context.Context,
+	params *kms.CreateKeyInput,
+	optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error)
+
+func (m mockKMSCreateKeyAPI) CreateKey(ctx context.Context,
+	params *kms.CreateKeyInput,
+	optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error) {
+
+	return m(ctx, params, optFns...)
+}
+
+func Test_createKMSKey(t *testing.T) {
+	type args struct {
+		description string
+		policy      string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		api     func(t *testing.T) KMSCreateKeyAPI
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "S01: Happy path",
+			args: args{
+				description: "happy key",
+				policy:      "{}",
+			},
+			api: func(t *testing.T) KMSCreateKeyAPI {
+				return mockKMSCreateKeyAPI(func(ctx context.Context,
+					params *kms.CreateKeyInput,
+					optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error) {
+
+					if *params.Policy != "{}" {
+						t.Errorf("createKMSKey() params.Policy = %v, want {}", *params.Policy)
+					}
+					return &kms.CreateKeyOutput{
+						KeyMetadata: &types.KeyMetadata{
+							KeyId: aws.String("happy-key"),
+						},
+					}, nil
+				})
+			},
+			want:    "happy-key",
+			wantErr: false,
+		},
+		{
+			name: "F01: Some error",
+			args: args{
+				description: "error key",
+				policy:      "{}",
+			},
+			api: func(t *testing.T) KMSCreateKeyAPI {
+				return mockKMSCreateKeyAPI(func(ctx context.Context,
+					params *kms.CreateKeyInput,
+					optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error) {
+
+					return nil, errors.New("some error")
+				})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := createKMSKey(context.Background(), tt.api(t), tt.args.description, tt.args.policy, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createKMSKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && *got.KeyMetadata.KeyId != tt.want {
+				t.Errorf("createKMSKey() got = %v, want %v", *got.KeyMetadata.KeyId, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildKMSKeyPolicy(t *testing.T) {
+	type args struct {
+		partition string
+		accountID string
+		region    string
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantRoot       string
+		wantViaService string
+	}{
+		{
+			name: "S01: aws partition",
+			args: args{
+				partition: "aws",
+				accountID: "123456789012",
+				region:    "ap-northeast-1",
+			},
+			wantRoot:       "arn:aws:iam::123456789012:root",
+			wantViaService: "s3.ap-northeast-1.amazonaws.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildKMSKeyPolicy(tt.args.partition, tt.args.accountID, tt.args.region)
+			if err != nil {
+				t.Fatalf("buildKMSKeyPolicy() error = %v", err)
+			}
+
+			var p kmsKeyPolicy
+			if err := json.Unmarshal([]byte(got), &p); err != nil {
+				t.Fatalf("buildKMSKeyPolicy() returns invalid json: %v", err)
+			}
+			if p.Statement[0].Principal["AWS"] != tt.wantRoot {
+				t.Errorf("buildKMSKeyPolicy() root principal = %v, want %v", p.Statement[0].Principal["AWS"], tt.wantRoot)
+			}
+			if p.Statement[1].Condition["StringEquals"]["kms:ViaService"] != tt.wantViaService {
+				t.Errorf("buildKMSKeyPolicy() kms:ViaService = %v, want %v", p.Statement[1].Condition["StringEquals"]["kms:ViaService"], tt.wantViaService)
+			}
+			if p.Statement[1].Condition["StringEquals"]["kms:CallerAccount"] != tt.args.accountID {
+				t.Errorf("buildKMSKeyPolicy() kms:CallerAccount = %v, want %v", p.Statement[1].Condition["StringEquals"]["kms:CallerAccount"], tt.args.accountID)
+			}
+		})
+	}
+}
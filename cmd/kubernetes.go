@@ -0,0 +1,467 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// kubernetesManagedByLabel marks the namespace created by tfbackend. Only such namespaces are adopted,
+// because the role can list every secret in the namespace.
+const (
+	kubernetesManagedByLabel = "app.kubernetes.io/managed-by"
+	kubernetesManagedByValue = "tfbackend"
+)
+
+var (
+	k8sNamespace    string
+	k8sSecretSuffix string
+	k8sWorkspaces   []string
+	k8sKubeconfig   string
+	k8sContext      string
+)
+
+// initKubernetesOption holds settings of the terraform backend on Kubernetes.
+type initKubernetesOption struct {
+	SecretSuffix string
+	// Workspaces are the terraform workspaces whose secrets and leases the role can read and write.
+	Workspaces []string
+	// TokenWaitAttempts and TokenWaitInterval control how long to wait for the token controller.
+	TokenWaitAttempts int
+	TokenWaitInterval time.Duration
+}
+
+type initKubernetesResult struct {
+	Namespace      string   `json:"namespace" yaml:"namespace"`
+	ServiceAccount string   `json:"service_account" yaml:"service_account"`
+	Role           string   `json:"role" yaml:"role"`
+	RoleBinding    string   `json:"role_binding" yaml:"role_binding"`
+	Secrets        []string `json:"secrets" yaml:"secrets"`
+	Leases         []string `json:"leases" yaml:"leases"`
+	// Token is the service account token. Empty if the token controller didn't issue it in time.
+	Token string `json:"-" yaml:"-"`
+}
+
+func NewCmdKubernetes() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kubernetes",
+		Short: "Create Kubernetes namespace and service account for terraform backend.",
+		Long: `Create Kubernetes namespace and service account for terraform backend.
+
+The namespace, a service account, and a role and role binding for the service account are created.
+Terraform kubernetes backend stores the state in the secret 'tfstate-{workspace}-{suffix}'
+and locks it with the lease 'lock-tfstate-{workspace}-{suffix}'.
+The role allows the followings.
+- get, update, patch and delete the secrets and leases of the workspaces (--workspace)
+- create and list secrets, and create leases
+  (Kubernetes RBAC can't limit them by resource name)
+
+terraform lists secrets to find workspaces, so the service account can read every secret
+in the namespace, including its own token. Therefore the namespace must be dedicated to
+terraform state. An existing namespace is adopted only if it has the label
+'app.kubernetes.io/managed-by=tfbackend', which tfbackend puts on the namespace it creates.
+
+At the end, the matching backend "kubernetes" block and a kubeconfig for the service account are printed.
+
+The cluster is taken from --kubeconfig (or KUBECONFIG, ~/.kube/config) and --context.
+`,
+		SilenceUsage: true,
+		RunE:         runCmdKubernetes,
+	}
+
+	// flag
+	cmd.Flags().StringVarP(&k8sNamespace, "namespace", "", "", "Name of namespace to create.")
+	cmd.MarkFlagRequired("namespace")
+	cmd.Flags().StringVarP(&k8sSecretSuffix, "secret-suffix", "", "", "Suffix of the secret name, which is 'secret_suffix' of the backend.")
+	cmd.MarkFlagRequired("secret-suffix")
+	cmd.Flags().StringSliceVarP(&k8sWorkspaces, "workspace", "", []string{"default"}, "Terraform workspaces which the service account can use. Can be specified multiple times.")
+	cmd.Flags().StringVarP(&k8sKubeconfig, "kubeconfig", "", "", "Path of kubeconfig. Default is KUBECONFIG or ~/.kube/config.")
+	cmd.Flags().StringVarP(&k8sContext, "context", "", "", "Context of kubeconfig. Default is the current context.")
+	cmd.Flags().BoolVarP(&noRollback, "no-rollback", "", false, "Don't rollback resources created or updated before a failure. Useful for debugging.")
+
+	return cmd
+}
+
+func runCmdKubernetes(cmd *cobra.Command, args []string) error {
+	// Validation
+	if len(k8sWorkspaces) == 0 {
+		return fmt.Errorf("--workspace must be specified at least once")
+	}
+
+	// Load config
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = k8sKubeconfig
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: k8sContext}).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	opt := initKubernetesOption{
+		SecretSuffix:      k8sSecretSuffix,
+		Workspaces:        k8sWorkspaces,
+		TokenWaitAttempts: 10,
+		TokenWaitInterval: time.Second,
+	}
+
+	tx := &transaction{}
+	res, err := initKubernetes(client, k8sNamespace, opt, tx)
+	if err != nil {
+		return abortTransaction(tx, fmt.Errorf("failed to initialize kubernetes backend: %w", err))
+	}
+
+	printCyan(fmt.Sprintf("Successfully create terraform backend - kubernetes namespace: %v\n", k8sNamespace))
+	fmt.Fprintf(progressOut, "Detail ... \n\n")
+	kTable := tablewriter.NewWriter(os.Stdout)
+	h, b := res.createTableInput()
+	kTable.SetHeader(h)
+	for _, v := range b {
+		kTable.Append(v)
+	}
+	kTable.SetAlignment(tablewriter.ALIGN_LEFT)
+	kTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	kTable.SetCenterSeparator("|")
+	kTable.Render()
+
+	fmt.Fprintf(progressOut, "\nBackend ... \n\n")
+	if err := renderBackendBlock(os.Stdout, "kubernetes", kubernetesBackendAttributes(k8sSecretSuffix, k8sNamespace), false); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(progressOut, "\nKubeconfig ... \n\n")
+	if res.Token == "" {
+		printRed(fmt.Sprintf("The token of service account is not issued yet. Replace <TOKEN> with 'token' of secret %v.\n\n", serviceAccountTokenSecretName(res.ServiceAccount)))
+	}
+	kubeconfig, err := serviceAccountKubeconfig(restConfig, res)
+	if err != nil {
+		return fmt.Errorf("failed to generate kubeconfig: %w", err)
+	}
+	fmt.Fprint(os.Stdout, string(kubeconfig))
+	return nil
+}
+
+// initKubernetes setup terraform backend namespace and service account on Kubernetes with messages.
+func initKubernetes(c kubernetes.Interface, namespace string, opt initKubernetesOption, tx *transaction) (*initKubernetesResult, error) {
+	progress.section("kubernetes_namespace", "🚀 Start to create terraform backend: kubernetes namespace ...")
+
+	name := "tfbackend-" + opt.SecretSuffix
+	res := initKubernetesResult{
+		Namespace:      namespace,
+		ServiceAccount: name,
+		Role:           name,
+		RoleBinding:    name,
+	}
+	for _, w := range opt.Workspaces {
+		res.Secrets = append(res.Secrets, kubernetesStateSecretName(w, opt.SecretSuffix))
+		res.Leases = append(res.Leases, kubernetesLockLeaseName(w, opt.SecretSuffix))
+	}
+
+	// Create namespace. If the namespace already exists, adopt it only if tfbackend created it.
+	progress.begin("Creating namespace")
+	current, err := c.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of namespace: %w", err)
+	}
+	if err == nil {
+		if current.Labels[kubernetesManagedByLabel] != kubernetesManagedByValue {
+			progress.fail()
+			return nil, fmt.Errorf("namespace %v is not created by tfbackend. The role can list every secret in the namespace, so specify a new namespace, or label it with %v=%v if it is dedicated to terraform state", namespace, kubernetesManagedByLabel, kubernetesManagedByValue)
+		}
+		progress.end(stepStatusUnchanged)
+	} else {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   namespace,
+			Labels: map[string]string{kubernetesManagedByLabel: kubernetesManagedByValue},
+		}}
+		if _, err := c.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create namespace: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete namespace %v", namespace), func() error {
+			return c.CoreV1().Namespaces().Delete(context.TODO(), namespace, metav1.DeleteOptions{})
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Create service account. If the service account already exists, adopt it.
+	progress.begin("Creating service account")
+	_, err = c.CoreV1().ServiceAccounts(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of service account: %w", err)
+	}
+	if err == nil {
+		progress.end(stepStatusUnchanged)
+	} else {
+		sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		if _, err := c.CoreV1().ServiceAccounts(namespace).Create(context.TODO(), sa, metav1.CreateOptions{}); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create service account: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete service account %v", name), func() error {
+			return c.CoreV1().ServiceAccounts(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Create role. If the role already exists, its rules are converged.
+	progress.begin("Creating role")
+	rules := kubernetesBackendPolicyRules(res.Secrets, res.Leases)
+	role, err := c.RbacV1().Roles(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of role: %w", err)
+	}
+	if err == nil {
+		if reflect.DeepEqual(role.Rules, rules) {
+			progress.end(stepStatusUnchanged)
+		} else {
+			previous := role.DeepCopy()
+			role.Rules = rules
+			if _, err := c.RbacV1().Roles(namespace).Update(context.TODO(), role, metav1.UpdateOptions{}); err != nil {
+				progress.fail()
+				return nil, fmt.Errorf("failed to update role: %w", err)
+			}
+			tx.record(fmt.Sprintf("Restore rules of role %v", name), func() error {
+				current, err := c.RbacV1().Roles(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				current.Rules = previous.Rules
+				_, err = c.RbacV1().Roles(namespace).Update(context.TODO(), current, metav1.UpdateOptions{})
+				return err
+			})
+			progress.end(stepStatusUpdated)
+		}
+	} else {
+		role := &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Rules: rules}
+		if _, err := c.RbacV1().Roles(namespace).Create(context.TODO(), role, metav1.CreateOptions{}); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create role: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete role %v", name), func() error {
+			return c.RbacV1().Roles(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Create role binding. If the role binding already exists, its subjects are converged.
+	// RoleRef is immutable, so the role binding which refers to another role is an error.
+	progress.begin("Creating role binding")
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: namespace}}
+	roleRef := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name}
+	binding, err := c.RbacV1().RoleBindings(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of role binding: %w", err)
+	}
+	if err == nil {
+		if binding.RoleRef != roleRef {
+			progress.fail()
+			return nil, fmt.Errorf("existing role binding %v refers to %v %v, not role %v. Delete the role binding or use another --secret-suffix", name, binding.RoleRef.Kind, binding.RoleRef.Name, name)
+		}
+		if reflect.DeepEqual(binding.Subjects, subjects) {
+			progress.end(stepStatusUnchanged)
+		} else {
+			previous := binding.DeepCopy()
+			binding.Subjects = subjects
+			if _, err := c.RbacV1().RoleBindings(namespace).Update(context.TODO(), binding, metav1.UpdateOptions{}); err != nil {
+				progress.fail()
+				return nil, fmt.Errorf("failed to update role binding: %w", err)
+			}
+			tx.record(fmt.Sprintf("Restore subjects of role binding %v", name), func() error {
+				current, err := c.RbacV1().RoleBindings(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				current.Subjects = previous.Subjects
+				_, err = c.RbacV1().RoleBindings(namespace).Update(context.TODO(), current, metav1.UpdateOptions{})
+				return err
+			})
+			progress.end(stepStatusUpdated)
+		}
+	} else {
+		binding := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Subjects:   subjects,
+			RoleRef:    roleRef,
+		}
+		if _, err := c.RbacV1().RoleBindings(namespace).Create(context.TODO(), binding, metav1.CreateOptions{}); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create role binding: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete role binding %v", name), func() error {
+			return c.RbacV1().RoleBindings(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Create secret for long-lived token of the service account. The token is issued by the token controller.
+	progress.begin("Creating service account token")
+	tokenSecret := serviceAccountTokenSecretName(name)
+	_, err = c.CoreV1().Secrets(namespace).Get(context.TODO(), tokenSecret, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of service account token: %w", err)
+	}
+	if err == nil {
+		progress.end(stepStatusUnchanged)
+	} else {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        tokenSecret,
+				Namespace:   namespace,
+				Annotations: map[string]string{corev1.ServiceAccountNameKey: name},
+			},
+			Type: corev1.SecretTypeServiceAccountToken,
+		}
+		if _, err := c.CoreV1().Secrets(namespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create service account token: %w", err)
+		}
+		tx.record(fmt.Sprintf("Delete secret %v", tokenSecret), func() error {
+			return c.CoreV1().Secrets(namespace).Delete(context.TODO(), tokenSecret, metav1.DeleteOptions{})
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Wait for the token
+	progress.begin("Confirmation - Get service account token")
+	for i := 0; i < opt.TokenWaitAttempts; i++ {
+		secret, err := c.CoreV1().Secrets(namespace).Get(context.TODO(), tokenSecret, metav1.GetOptions{})
+		if err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("successfully created service account token, but failed to get it: %w", err)
+		}
+		if token := secret.Data[corev1.ServiceAccountTokenKey]; len(token) > 0 {
+			res.Token = string(token)
+			break
+		}
+		time.Sleep(opt.TokenWaitInterval)
+	}
+	if res.Token == "" {
+		progress.end(stepStatusFailure)
+	} else {
+		progress.end(stepStatusSuccess)
+	}
+
+	return &res, nil
+}
+
+// kubernetesStateSecretName returns the secret name which terraform kubernetes backend stores the state in.
+func kubernetesStateSecretName(workspace string, suffix string) string {
+	return strings.Join([]string{"tfstate", workspace, suffix}, "-")
+}
+
+// kubernetesLockLeaseName returns the lease name which terraform kubernetes backend locks the state with.
+func kubernetesLockLeaseName(workspace string, suffix string) string {
+	return "lock-" + kubernetesStateSecretName(workspace, suffix)
+}
+
+func serviceAccountTokenSecretName(serviceAccount string) string {
+	return serviceAccount + "-token"
+}
+
+// kubernetesBackendPolicyRules returns the rules which allow terraform to read and write only the secrets and leases.
+// create and list can't be limited by resource name, so they are allowed for all secrets and leases in the namespace.
+// list is needed because terraform lists secrets to find workspaces, which is why the namespace must be dedicated.
+func kubernetesBackendPolicyRules(secrets []string, leases []string) []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"secrets"},
+			Verbs:     []string{"create", "list"},
+		},
+		{
+			APIGroups:     []string{""},
+			Resources:     []string{"secrets"},
+			ResourceNames: secrets,
+			Verbs:         []string{"get", "update", "patch", "delete"},
+		},
+		{
+			APIGroups: []string{"coordination.k8s.io"},
+			Resources: []string{"leases"},
+			Verbs:     []string{"create"},
+		},
+		{
+			APIGroups:     []string{"coordination.k8s.io"},
+			Resources:     []string{"leases"},
+			ResourceNames: leases,
+			Verbs:         []string{"get", "update", "patch", "delete"},
+		},
+	}
+}
+
+// kubernetesBackendAttributes returns the lines of backend "kubernetes" block.
+func kubernetesBackendAttributes(suffix string, namespace string) []backendAttribute {
+	return alignBackendAttributes([]backendAttribute{
+		{Name: "secret_suffix", Value: strconv.Quote(suffix)},
+		{Name: "namespace", Value: strconv.Quote(namespace)},
+	})
+}
+
+// serviceAccountKubeconfig returns kubeconfig which connects to the cluster of restConfig as the service account.
+// If the token is not issued yet, "<TOKEN>" is written instead.
+func serviceAccountKubeconfig(restConfig *rest.Config, res *initKubernetesResult) ([]byte, error) {
+	caData := restConfig.CAData
+	if len(caData) == 0 && restConfig.CAFile != "" {
+		b, err := ioutil.ReadFile(restConfig.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate authority: %w", err)
+		}
+		caData = b
+	}
+	token := res.Token
+	if token == "" {
+		token = "<TOKEN>"
+	}
+
+	const cluster = "tfbackend"
+	user := res.ServiceAccount
+	config := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			cluster: {Server: restConfig.Host, CertificateAuthorityData: caData},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			user: {Token: token},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			user: {Cluster: cluster, AuthInfo: user, Namespace: res.Namespace},
+		},
+		CurrentContext: user,
+	}
+	return clientcmd.Write(config)
+}
+
+func (i *initKubernetesResult) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Namespace", i.Namespace},
+		{"Service account", i.ServiceAccount},
+		{"Role", i.Role},
+		{"Role binding", i.RoleBinding},
+		{"State secrets", strings.Join(i.Secrets, ", ")},
+		{"Lock leases", strings.Join(i.Leases, ", ")},
+	}
+	return h, b
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_initKubernetes(t *testing.T) {
+	opt := initKubernetesOption{SecretSuffix: "network", Workspaces: []string{"default", "prod"}, TokenWaitAttempts: 1}
+	tokenSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfbackend-network-token", Namespace: "happy-ns"},
+		Type:       corev1.SecretTypeServiceAccountToken,
+		Data:       map[string][]byte{corev1.ServiceAccountTokenKey: []byte("happy-token")},
+	}
+	want := func(token string) *initKubernetesResult {
+		return &initKubernetesResult{
+			Namespace:      "happy-ns",
+			ServiceAccount: "tfbackend-network",
+			Role:           "tfbackend-network",
+			RoleBinding:    "tfbackend-network",
+			Secrets:        []string{"tfstate-default-network", "tfstate-prod-network"},
+			Leases:         []string{"lock-tfstate-default-network", "lock-tfstate-prod-network"},
+			Token:          token,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		failOn  string
+		want    *initKubernetesResult
+		wantErr bool
+	}{
+		{
+			name:    "S01: Happy path, token is not issued yet",
+			want:    want(""),
+			wantErr: false,
+		},
+		{
+			name: "S02: Adopt existing resources and converge role",
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "happy-ns", Labels: map[string]string{"app.kubernetes.io/managed-by": "tfbackend"}}},
+				&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "tfbackend-network", Namespace: "happy-ns"}},
+				&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: "tfbackend-network", Namespace: "happy-ns"}},
+				tokenSecret,
+			},
+			want:    want("happy-token"),
+			wantErr: false,
+		},
+		{
+			name: "S03: Adopt existing role binding and converge subjects",
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "happy-ns", Labels: map[string]string{"app.kubernetes.io/managed-by": "tfbackend"}}},
+				&rbacv1.RoleBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "tfbackend-network", Namespace: "happy-ns"},
+					Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "other-sa", Namespace: "happy-ns"}},
+					RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "tfbackend-network"},
+				},
+			},
+			want:    want(""),
+			wantErr: false,
+		},
+		{
+			name: "F01: Namespace which tfbackend didn't create",
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "happy-ns"}},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "F02: Creating role binding failure",
+			failOn:  "rolebindings",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "F03: Existing role binding refers to another role",
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "happy-ns", Labels: map[string]string{"app.kubernetes.io/managed-by": "tfbackend"}}},
+				&rbacv1.RoleBinding{
+					ObjectMeta: metav1.ObjectMeta{Name: "tfbackend-network", Namespace: "happy-ns"},
+					Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "tfbackend-network", Namespace: "happy-ns"}},
+					RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.objects...)
+			if tt.failOn != "" {
+				client.PrependReactor("create", tt.failOn, func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("mock error")
+				})
+			}
+
+			got, err := initKubernetes(client, "happy-ns", opt, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("initKubernetes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("initKubernetes() = %v, want %v", got, tt.want)
+			}
+			if err != nil {
+				return
+			}
+
+			role, err := client.RbacV1().Roles("happy-ns").Get(context.TODO(), "tfbackend-network", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get role: %v", err)
+			}
+			if !reflect.DeepEqual(role.Rules, kubernetesBackendPolicyRules(tt.want.Secrets, tt.want.Leases)) {
+				t.Errorf("initKubernetes() role rules = %v", role.Rules)
+			}
+			binding, err := client.RbacV1().RoleBindings("happy-ns").Get(context.TODO(), "tfbackend-network", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get role binding: %v", err)
+			}
+			wantSubjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "tfbackend-network", Namespace: "happy-ns"}}
+			if !reflect.DeepEqual(binding.Subjects, wantSubjects) {
+				t.Errorf("initKubernetes() role binding subjects = %v, want %v", binding.Subjects, wantSubjects)
+			}
+		})
+	}
+}
+
+func Test_initKubernetes_Rollback(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "rolebindings", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("mock error")
+	})
+	tx := &transaction{}
+	opt := initKubernetesOption{SecretSuffix: "network", Workspaces: []string{"default"}, TokenWaitAttempts: 1}
+	if _, err := initKubernetes(client, "happy-ns", opt, tx); err == nil {
+		t.Fatalf("initKubernetes() error = nil, want error")
+	}
+	if err := tx.rollback(); err != nil {
+		t.Fatalf("transaction.rollback() error = %v", err)
+	}
+
+	if _, err := client.CoreV1().Namespaces().Get(context.TODO(), "happy-ns", metav1.GetOptions{}); err == nil {
+		t.Errorf("rollback didn't delete namespace")
+	}
+	if _, err := client.RbacV1().Roles("happy-ns").Get(context.TODO(), "tfbackend-network", metav1.GetOptions{}); err == nil {
+		t.Errorf("rollback didn't delete role")
+	}
+}
+
+func Test_serviceAccountKubeconfig(t *testing.T) {
+	restConfig := &rest.Config{Host: "https://kubernetes.example.com"}
+	restConfig.CAData = []byte("happy-ca")
+	res := &initKubernetesResult{Namespace: "happy-ns", ServiceAccount: "tfbackend-network"}
+
+	got, err := serviceAccountKubeconfig(restConfig, res)
+	if err != nil {
+		t.Fatalf("serviceAccountKubeconfig() error = %v", err)
+	}
+	for _, want := range []string{
+		"server: https://kubernetes.example.com",
+		"token: <TOKEN>",
+		"namespace: happy-ns",
+		"current-context: tfbackend-network",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("serviceAccountKubeconfig() = \n%v\nwant to contain %v", string(got), want)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/lib/pq"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pgConnStr    string
+	pgSchemaName string
+	pgRoleName   string
+)
+
+// pgAdvisoryLockKey is the key of advisory lock taken to check the support. ("tfbk" in ASCII)
+const pgAdvisoryLockKey = 0x7466626b
+
+// PostgresClientable is the subset of database/sql used by tfbackend.
+// All queries must run on the same session, because advisory locks are held per session.
+type PostgresClientable interface {
+	Exec(ctx context.Context, query string, args ...interface{}) error
+	// QueryRow runs the query which returns one row and scans the columns into dest.
+	QueryRow(ctx context.Context, query string, args []interface{}, dest ...interface{}) error
+}
+
+// postgresClient runs queries on a single connection.
+type postgresClient struct {
+	conn *sql.Conn
+}
+
+func (c *postgresClient) Exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := c.conn.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (c *postgresClient) QueryRow(ctx context.Context, query string, args []interface{}, dest ...interface{}) error {
+	return c.conn.QueryRowContext(ctx, query, args...).Scan(dest...)
+}
+
+// initPostgresOption holds settings of the terraform backend on PostgreSQL.
+type initPostgresOption struct {
+	Schema string
+	Role   string
+	// RolePassword is set to the role only when the role is created. If empty, the role has no password.
+	RolePassword string
+}
+
+type initPostgresResult struct {
+	Database      string `json:"database" yaml:"database"`
+	ServerVersion string `json:"server_version" yaml:"server_version"`
+	Schema        string `json:"schema" yaml:"schema"`
+	Table         string `json:"table" yaml:"table"`
+	Role          string `json:"role" yaml:"role"`
+	AdvisoryLock  string `json:"advisory_lock" yaml:"advisory_lock"`
+}
+
+func NewCmdPostgres() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "postgres",
+		Short: "Create PostgreSQL schema and role for terraform pg backend.",
+		Long: `Create PostgreSQL schema and role for terraform pg backend.
+
+The schema, the 'states' table which terraform pg backend uses,
+and a least-privilege role are created.
+- The role can login, but is not superuser and can't create databases, roles or tables.
+- The role is granted CONNECT on the database, USAGE on the schema,
+  SELECT, INSERT, UPDATE and DELETE on the table, and USAGE on its sequence.
+- search_path of the role is set to the schema.
+
+Support of advisory locks, which terraform pg backend uses for state locking, is verified.
+At the end, the matching backend "pg" block is printed. Because the role can't create tables,
+the block skips schema, table and index creation.
+
+The password of a new role is taken from TFBACKEND_PG_ROLE_PASSWORD.
+--conn-str must be a user who can create schemas and roles. Default is PG_CONN_STR.
+`,
+		SilenceUsage: true,
+		RunE:         runCmdPostgres,
+	}
+
+	// flag
+	cmd.Flags().StringVarP(&pgConnStr, "conn-str", "", os.Getenv("PG_CONN_STR"), "Connection string of the database, e.g. postgres://admin@localhost/terraform_backend. Default is PG_CONN_STR.")
+	cmd.Flags().StringVarP(&pgSchemaName, "schema", "", "terraform_remote_state", "Name of schema to create.")
+	cmd.Flags().StringVarP(&pgRoleName, "role", "", "terraform_backend", "Name of role which terraform uses.")
+	cmd.Flags().BoolVarP(&noRollback, "no-rollback", "", false, "Don't rollback resources created or updated before a failure. Useful for debugging.")
+
+	return cmd
+}
+
+func runCmdPostgres(cmd *cobra.Command, args []string) error {
+	// Validation
+	if pgConnStr == "" {
+		return fmt.Errorf("connection string is not specified. Set --conn-str or PG_CONN_STR")
+	}
+
+	db, err := sql.Open("postgres", pgConnStr)
+	if err != nil {
+		return fmt.Errorf("invalid connection string: %w", err)
+	}
+	defer db.Close()
+	conn, err := db.Conn(context.TODO())
+	if err != nil {
+		return fmt.Errorf("failed to connect database: %w", err)
+	}
+	defer conn.Close()
+
+	opt := initPostgresOption{
+		Schema:       pgSchemaName,
+		Role:         pgRoleName,
+		RolePassword: os.Getenv("TFBACKEND_PG_ROLE_PASSWORD"),
+	}
+
+	tx := &transaction{}
+	res, err := initPostgres(&postgresClient{conn: conn}, opt, tx)
+	if err != nil {
+		return abortTransaction(tx, fmt.Errorf("failed to initialize postgres backend: %w", err))
+	}
+
+	printCyan(fmt.Sprintf("Successfully create terraform backend - postgres schema: %v\n", pgSchemaName))
+	fmt.Fprintf(progressOut, "Detail ... \n\n")
+	pTable := tablewriter.NewWriter(os.Stdout)
+	h, b := res.createTableInput()
+	pTable.SetHeader(h)
+	for _, v := range b {
+		pTable.Append(v)
+	}
+	pTable.SetAlignment(tablewriter.ALIGN_LEFT)
+	pTable.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	pTable.SetCenterSeparator("|")
+	pTable.Render()
+
+	fmt.Fprintf(progressOut, "\nBackend ... \n\n")
+	return renderBackendBlock(os.Stdout, "pg", pgBackendAttributes(roleConnStr(pgConnStr, res.Role), res.Schema), false)
+}
+
+// initPostgres setup terraform backend schema and role on PostgreSQL with messages.
+func initPostgres(c PostgresClientable, opt initPostgresOption, tx *transaction) (*initPostgresResult, error) {
+	progress.section("postgres_schema", "🚀 Start to create terraform backend: postgres schema ...")
+
+	schema := pq.QuoteIdentifier(opt.Schema)
+	table := schema + ".states"
+	role := pq.QuoteIdentifier(opt.Role)
+	res := initPostgresResult{Schema: opt.Schema, Table: "states", Role: opt.Role}
+
+	// Connect
+	progress.begin("Connecting database")
+	if err := c.QueryRow(context.TODO(), "SELECT current_database(), current_setting('server_version')", nil, &res.Database, &res.ServerVersion); err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+	progress.end(stepStatusSuccess)
+
+	// Create schema. If the schema already exists, adopt it.
+	progress.begin("Creating schema")
+	var exists bool
+	if err := c.QueryRow(context.TODO(), "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", []interface{}{opt.Schema}, &exists); err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of schema: %w", err)
+	}
+	if exists {
+		progress.end(stepStatusUnchanged)
+	} else {
+		if err := c.Exec(context.TODO(), "CREATE SCHEMA "+schema); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create schema: %w", err)
+		}
+		tx.record(fmt.Sprintf("Drop schema %v", opt.Schema), func() error {
+			return c.Exec(context.TODO(), "DROP SCHEMA "+schema+" CASCADE")
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Create table with the same definition as terraform pg backend.
+	progress.begin("Creating states table")
+	if err := c.QueryRow(context.TODO(), "SELECT to_regclass($1) IS NOT NULL", []interface{}{table}, &exists); err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of states table: %w", err)
+	}
+	if exists {
+		progress.end(stepStatusUnchanged)
+	} else {
+		if err := c.Exec(context.TODO(), "CREATE TABLE "+table+" (id bigserial PRIMARY KEY, name text, data text)"); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create states table: %w", err)
+		}
+		tx.record(fmt.Sprintf("Drop table %v.states", opt.Schema), func() error {
+			return c.Exec(context.TODO(), "DROP TABLE "+table)
+		})
+		if err := c.Exec(context.TODO(), "CREATE UNIQUE INDEX IF NOT EXISTS states_by_name ON "+table+" (name)"); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create index of states table: %w", err)
+		}
+		progress.end(stepStatusCreated)
+	}
+
+	// Create role. If the role already exists, adopt it.
+	progress.begin("Creating role")
+	if err := c.QueryRow(context.TODO(), "SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)", []interface{}{opt.Role}, &exists); err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to check existence of role: %w", err)
+	}
+	if exists {
+		progress.end(stepStatusUnchanged)
+	} else {
+		query := "CREATE ROLE " + role + " LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE"
+		if opt.RolePassword != "" {
+			query += " PASSWORD " + pq.QuoteLiteral(opt.RolePassword)
+		}
+		if err := c.Exec(context.TODO(), query); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to create role: %w", err)
+		}
+		// DROP OWNED revokes the privileges granted in the later steps, which block DROP ROLE.
+		tx.record(fmt.Sprintf("Drop role %v", opt.Role), func() error {
+			if err := c.Exec(context.TODO(), "DROP OWNED BY "+role); err != nil {
+				return err
+			}
+			return c.Exec(context.TODO(), "DROP ROLE "+role)
+		})
+		progress.end(stepStatusCreated)
+	}
+
+	// Grant privileges. GRANT is idempotent, so it is always run.
+	progress.begin("Granting privileges")
+	grants := []string{
+		"GRANT CONNECT ON DATABASE " + pq.QuoteIdentifier(res.Database) + " TO " + role,
+		"GRANT USAGE ON SCHEMA " + schema + " TO " + role,
+		"GRANT SELECT, INSERT, UPDATE, DELETE ON TABLE " + table + " TO " + role,
+		"GRANT USAGE, SELECT ON SEQUENCE " + schema + ".states_id_seq TO " + role,
+	}
+	for _, g := range grants {
+		if err := c.Exec(context.TODO(), g); err != nil {
+			progress.fail()
+			return nil, fmt.Errorf("failed to grant privileges: %w", err)
+		}
+	}
+	progress.end(stepStatusSuccess)
+
+	// Apply role settings
+	progress.begin("Applying role settings")
+	if err := c.Exec(context.TODO(), "ALTER ROLE "+role+" SET search_path TO "+schema); err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to set search_path of role: %w", err)
+	}
+	progress.end(stepStatusSuccess)
+
+	// Verify advisory lock, which terraform pg backend uses for state locking.
+	progress.begin("Confirmation - Advisory lock")
+	var locked, unlocked bool
+	if err := c.QueryRow(context.TODO(), "SELECT pg_try_advisory_lock($1)", []interface{}{pgAdvisoryLockKey}, &locked); err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("advisory lock is not supported: %w", err)
+	}
+	if !locked {
+		progress.fail()
+		return nil, fmt.Errorf("failed to take advisory lock. Another session holds the lock %v", pgAdvisoryLockKey)
+	}
+	if err := c.QueryRow(context.TODO(), "SELECT pg_advisory_unlock($1)", []interface{}{pgAdvisoryLockKey}, &unlocked); err != nil {
+		progress.fail()
+		return nil, fmt.Errorf("failed to release advisory lock: %w", err)
+	}
+	if !unlocked {
+		progress.fail()
+		return nil, fmt.Errorf("failed to release advisory lock. The lock %v is not held by this session", pgAdvisoryLockKey)
+	}
+	res.AdvisoryLock = "Supported"
+	progress.end(stepStatusSuccess)
+
+	return &res, nil
+}
+
+// roleConnStr returns the connection string with the user replaced by the role and without password.
+// The user and password can also be given as query parameters, so they are removed from the query too.
+// If connStr is not URL form, it returns empty string.
+func roleConnStr(connStr string, role string) string {
+	u, err := url.Parse(connStr)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		return ""
+	}
+	u.User = url.User(role)
+	query := u.Query()
+	query.Del("user")
+	query.Del("password")
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// pgBackendAttributes returns the lines of backend "pg" block. conn_str is omitted if empty.
+// The role can't create tables, so creation of schema, table and index is skipped.
+func pgBackendAttributes(connStr string, schema string) []backendAttribute {
+	var attrs []backendAttribute
+	if connStr != "" {
+		attrs = append(attrs, backendAttribute{Name: "conn_str", Value: strconv.Quote(connStr)})
+	}
+	attrs = append(attrs,
+		backendAttribute{Name: "schema_name", Value: strconv.Quote(schema)},
+		backendAttribute{Name: "skip_schema_creation", Value: "true"},
+		backendAttribute{Name: "skip_table_creation", Value: "true"},
+		backendAttribute{Name: "skip_index_creation", Value: "true"},
+	)
+	return alignBackendAttributes(attrs)
+}
+
+func (i *initPostgresResult) createTableInput() (header []string, body [][]string) {
+	h := []string{"PARAMETER", "VALUE"}
+	b := [][]string{
+		{"Database", i.Database},
+		{"Server version", i.ServerVersion},
+		{"Schema", i.Schema},
+		{"Table", i.Table},
+		{"Role", i.Role},
+		{"Advisory lock", i.AdvisoryLock},
+	}
+	return h, b
+}
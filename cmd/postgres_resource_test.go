@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// -----------------------------------
+// For initPostgres test
+// -----------------------------------
+
+// mockPostgresClient answers the queries of initPostgres and records executed statements.
+type mockPostgresClient struct {
+	schemaExists bool
+	tableExists  bool
+	roleExists   bool
+	// lockHeld means another session holds the advisory lock.
+	lockHeld bool
+	// failOn is a part of the statement which returns error.
+	failOn string
+
+	executed []string
+}
+
+func (m *mockPostgresClient) Exec(ctx context.Context, query string, args ...interface{}) error {
+	if m.failOn != "" && strings.Contains(query, m.failOn) {
+		return errors.New("mock error")
+	}
+	m.executed = append(m.executed, query)
+	return nil
+}
+
+func (m *mockPostgresClient) QueryRow(ctx context.Context, query string, args []interface{}, dest ...interface{}) error {
+	if m.failOn != "" && strings.Contains(query, m.failOn) {
+		return errors.New("mock error")
+	}
+	switch {
+	case strings.Contains(query, "current_database()"):
+		*dest[0].(*string) = "happy_db"
+		*dest[1].(*string) = "14.5"
+	case strings.Contains(query, "pg_namespace"):
+		*dest[0].(*bool) = m.schemaExists
+	case strings.Contains(query, "to_regclass"):
+		*dest[0].(*bool) = m.tableExists
+	case strings.Contains(query, "pg_roles"):
+		*dest[0].(*bool) = m.roleExists
+	case strings.Contains(query, "pg_try_advisory_lock"):
+		*dest[0].(*bool) = !m.lockHeld
+	case strings.Contains(query, "pg_advisory_unlock"):
+		*dest[0].(*bool) = true
+	default:
+		return errors.New("unexpected query: " + query)
+	}
+	return nil
+}
@@ -13,13 +13,13 @@ func printErrorRed(err error) {
 }
 
 func printRed(str string) {
-	color.Red(str)
+	color.New(color.FgRed).Fprint(progressOut, str)
 }
 
 func printBlue(str string) {
-	color.Blue(str)
+	color.New(color.FgBlue).Fprint(progressOut, str)
 }
 
 func printCyan(str string) {
-	color.Cyan(str)
+	color.New(color.FgCyan).Fprint(progressOut, str)
 }
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// progressOut is where step progress is written.
+// It is switched to stderr when the result is written to stdout as JSON or YAML.
+var progressOut io.Writer = os.Stdout
+
+// stepReport is status and duration of a completed step.
+type stepReport struct {
+	Resource   string `json:"resource" yaml:"resource"`
+	Name       string `json:"name" yaml:"name"`
+	Status     string `json:"status" yaml:"status"`
+	DurationMs int64  `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// progressLog prints step progress like "Step1: Creating bucket ... CREATED",
+// and records status and duration of each step for machine-readable output.
+type progressLog struct {
+	resource string
+	count    int
+	name     string
+	started  time.Time
+	steps    []stepReport
+}
+
+var progress = &progressLog{}
+
+// section prints the banner of the resource and restarts step numbering.
+func (p *progressLog) section(resource string, title string) {
+	p.resource = resource
+	p.count = 0
+
+	fmt.Fprintf(progressOut, "\n")
+	fmt.Fprintf(progressOut, "---------------------------------------------------------\n")
+	fmt.Fprintf(progressOut, "%v\n", title)
+	fmt.Fprintf(progressOut, "---------------------------------------------------------\n")
+	fmt.Fprintf(progressOut, "\n")
+}
+
+// begin prints the step name and starts the timer.
+func (p *progressLog) begin(format string, a ...interface{}) {
+	p.count++
+	p.name = fmt.Sprintf(format, a...)
+	p.started = time.Now()
+	fmt.Fprintf(progressOut, "Step%v: %v ... ", p.count, p.name)
+}
+
+// end prints the status of the current step and records it.
+func (p *progressLog) end(status stepStatus) {
+	fmt.Fprintf(progressOut, "%v\n", status)
+	p.record(status)
+}
+
+// fail prints FAILURE of the current step and records it.
+func (p *progressLog) fail() {
+	printRed("FAILURE\n\n")
+	p.record(stepStatusFailure)
+}
+
+func (p *progressLog) record(status stepStatus) {
+	p.steps = append(p.steps, stepReport{
+		Resource:   p.resource,
+		Name:       p.name,
+		Status:     string(status),
+		DurationMs: time.Since(p.started).Milliseconds(),
+	})
+}
+
+// awsReport is the document written by --output json|yaml.
+type awsReport struct {
+	KMS             *initKMSResult             `json:"kms,omitempty" yaml:"kms,omitempty"`
+	LogBucket       *initS3Result              `json:"log_bucket,omitempty" yaml:"log_bucket,omitempty"`
+	ReplicationRole *initReplicationRoleResult `json:"replication_role,omitempty" yaml:"replication_role,omitempty"`
+	Replica         *initS3Result              `json:"replica_bucket,omitempty" yaml:"replica_bucket,omitempty"`
+	S3              *initS3Result              `json:"s3,omitempty" yaml:"s3,omitempty"`
+	CloudTrail      *initCloudTrailResult      `json:"cloudtrail,omitempty" yaml:"cloudtrail,omitempty"`
+	DynamoDB        *initDynamoDBResult        `json:"dynamodb,omitempty" yaml:"dynamodb,omitempty"`
+	Steps           []stepReport               `json:"steps" yaml:"steps"`
+}
+
+// writeReport serializes the report in the given format.
+func writeReport(w io.Writer, r *awsReport, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		b, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	return fmt.Errorf("unsupported output format: %v", format)
+}
+
+// validateOutputFormat validates --output flag.
+func validateOutputFormat(format string) bool {
+	return format == "table" || format == "json" || format == "yaml"
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func Test_progressLog(t *testing.T) {
+	defer func(w io.Writer) { progressOut = w }(progressOut)
+	progressOut = ioutil.Discard
+
+	p := &progressLog{}
+	p.section("s3_bucket", "title")
+	p.begin("Creating bucket")
+	p.end(stepStatusCreated)
+	p.begin("Activate bucket versioning")
+	p.fail()
+	p.section("dynamodb_table", "title")
+	p.begin("Apply billing mode (%v)", "PROVISIONED")
+	p.end(stepStatusUnchanged)
+
+	want := []stepReport{
+		{Resource: "s3_bucket", Name: "Creating bucket", Status: "CREATED"},
+		{Resource: "s3_bucket", Name: "Activate bucket versioning", Status: "FAILURE"},
+		{Resource: "dynamodb_table", Name: "Apply billing mode (PROVISIONED)", Status: "UNCHANGED"},
+	}
+	if len(p.steps) != len(want) {
+		t.Fatalf("progressLog.steps = %v, want %v", p.steps, want)
+	}
+	for i := range want {
+		got := p.steps[i]
+		got.DurationMs = 0
+		if got != want[i] {
+			t.Errorf("progressLog.steps[%v] = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func Test_writeReport(t *testing.T) {
+	report := &awsReport{
+		S3: &initS3Result{
+			BucketName: "happy-bucket",
+			BucketArn:  "arn:aws:s3:::happy-bucket",
+			Region:     "ap-northeast-1",
+		},
+		DynamoDB: &initDynamoDBResult{
+			TableName:   "happy-table",
+			BillingMode: "PAY_PER_REQUEST",
+		},
+		Steps: []stepReport{
+			{Resource: "s3_bucket", Name: "Creating bucket", Status: "CREATED", DurationMs: 10},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		format    string
+		unmarshal func([]byte, interface{}) error
+		wantErr   bool
+	}{
+		{
+			name:      "S01: JSON",
+			format:    "json",
+			unmarshal: json.Unmarshal,
+			wantErr:   false,
+		},
+		{
+			name:      "S02: YAML",
+			format:    "yaml",
+			unmarshal: yaml.Unmarshal,
+			wantErr:   false,
+		},
+		{
+			name:      "F01: Unsupported format",
+			format:    "xml",
+			unmarshal: nil,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeReport(&buf, report, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("writeReport() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got struct {
+				S3 struct {
+					Region string `json:"region" yaml:"region"`
+				} `json:"s3" yaml:"s3"`
+				DynamoDB struct {
+					TableName string `json:"table_name" yaml:"table_name"`
+				} `json:"dynamodb" yaml:"dynamodb"`
+				Steps []stepReport `json:"steps" yaml:"steps"`
+			}
+			if err := tt.unmarshal(buf.Bytes(), &got); err != nil {
+				t.Errorf("writeReport() wrote invalid %v: %v", tt.format, err)
+				return
+			}
+			if got.S3.Region != "ap-northeast-1" || got.DynamoDB.TableName != "happy-table" || len(got.Steps) != 1 {
+				t.Errorf("writeReport() = %v", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+// noRollback keeps the completed steps after a failure for debugging.
+var noRollback bool
+
+// undoAction reverts a completed step.
+type undoAction struct {
+	Description string
+	Undo        func() error
+}
+
+// transaction records undo actions of completed steps, so that partially created backend can be rolled back.
+// A nil transaction records nothing.
+type transaction struct {
+	actions []undoAction
+}
+
+// record adds undo action of the step which has just completed.
+func (t *transaction) record(description string, undo func() error) {
+	if t == nil {
+		return
+	}
+	t.actions = append(t.actions, undoAction{Description: description, Undo: undo})
+}
+
+// rollback runs recorded undo actions in reverse order with messages.
+// Even if an undo action fails, the remaining ones are still run.
+func (t *transaction) rollback() error {
+	if t == nil || len(t.actions) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(progressOut, "\n")
+	fmt.Fprintf(progressOut, "---------------------------------------------------------\n")
+	fmt.Fprintf(progressOut, "⏪ Start to rollback ... \n")
+	fmt.Fprintf(progressOut, "---------------------------------------------------------\n")
+	fmt.Fprintf(progressOut, "\n")
+
+	var failed []string
+	for i := len(t.actions) - 1; i >= 0; i-- {
+		a := t.actions[i]
+		fmt.Fprintf(progressOut, "Rollback%v: %v ... ", len(t.actions)-i, a.Description)
+		if err := a.Undo(); err != nil {
+			printRed("FAILURE\n")
+			failed = append(failed, fmt.Sprintf("%v: %v", a.Description, err))
+			continue
+		}
+		fmt.Fprintf(progressOut, "SUCCESS\n")
+	}
+	t.actions = nil
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to rollback: %v", failed)
+	}
+	return nil
+}
+
+// abortTransaction rollbacks the steps recorded in tx unless --no-rollback is specified, and returns err.
+func abortTransaction(tx *transaction, err error) error {
+	if noRollback {
+		printRed("Rollback is skipped because --no-rollback is specified.\n")
+		return err
+	}
+	if rbErr := tx.rollback(); rbErr != nil {
+		return fmt.Errorf("%w (%v)", err, rbErr)
+	}
+	return err
+}
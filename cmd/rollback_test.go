@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_transaction_rollback(t *testing.T) {
+	tests := []struct {
+		name     string
+		undoErrs []error
+		want     []int
+		wantErr  bool
+	}{
+		{
+			name:     "S01: Undo in reverse order",
+			undoErrs: []error{nil, nil, nil},
+			want:     []int{2, 1, 0},
+			wantErr:  false,
+		},
+		{
+			name:     "S02: Nothing recorded",
+			undoErrs: nil,
+			want:     nil,
+			wantErr:  false,
+		},
+		{
+			name:     "F01: Continue even if undo fails",
+			undoErrs: []error{nil, errors.New("some error"), nil},
+			want:     []int{2, 1, 0},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			tx := &transaction{}
+			for i, e := range tt.undoErrs {
+				i, e := i, e
+				tx.record("some step", func() error {
+					got = append(got, i)
+					return e
+				})
+			}
+
+			err := tx.rollback()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("transaction.rollback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("transaction.rollback() order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_transaction_nil(t *testing.T) {
+	var tx *transaction
+	tx.record("some step", func() error {
+		t.Errorf("undo of nil transaction must not be run")
+		return nil
+	})
+	if err := tx.rollback(); err != nil {
+		t.Errorf("transaction.rollback() error = %v, want nil", err)
+	}
+}
@@ -12,16 +12,27 @@ import (
 
 var cfgFile string
 
+// version is set at build time, e.g. -ldflags "-X github.com/Jimon-s/tfbackend/cmd.version=v1.0.0".
+var version = "dev"
+
 func NewCmdRoot() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:           "tfbackend",
 		Short:         "tfbackend is a CLI tool to create terraform backend to cloud.",
 		Long:          `tfbackend is a CLI tool to create terraform backend to cloud.`,
+		Version:       version,
 		SilenceErrors: true,
 	}
 	cobra.OnInitialize(initConfig)
 
+	cmd.PersistentFlags().StringVarP(&cfgFile, "config", "", "", "Config file. Default is $HOME/.tfbackend.yaml.")
+
 	cmd.AddCommand(NewCmdAws())
+	cmd.AddCommand(NewCmdGcp())
+	cmd.AddCommand(NewCmdAzure())
+	cmd.AddCommand(NewCmdPostgres())
+	cmd.AddCommand(NewCmdConsul())
+	cmd.AddCommand(NewCmdKubernetes())
 	cmd.AddCommand(NewCmdCompletion())
 
 	return cmd
@@ -33,6 +44,7 @@ func Execute() {
 	cmd := NewCmdRoot()
 	if err := cmd.Execute(); err != nil {
 		printErrorRed(err)
+		os.Exit(1)
 	}
 }
 
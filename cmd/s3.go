@@ -1,23 +1,49 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
 
+type S3HeadBucketAPI interface {
+	HeadBucket(ctx context.Context,
+		params *s3.HeadBucketInput,
+		optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+}
+
+// s3BucketExists checks if the bucket exists and is accessible.
+// If the bucket is owned by other account, error is returned.
+func s3BucketExists(c context.Context, api S3HeadBucketAPI, bucketName string) (bool, error) {
+	in := &s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	}
+	if _, err := api.HeadBucket(c, in); err != nil {
+		if isAPIErrorCode(err, "NotFound", "NoSuchBucket") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 type S3CreateBucketAPI interface {
 	CreateBucket(ctx context.Context,
 		params *s3.CreateBucketInput,
 		optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
 }
 
-func createS3Bucket(c context.Context, api S3CreateBucketAPI, bucketName string, region string) (*s3.CreateBucketOutput, error) {
+// createS3Bucket creates the bucket with ACLs disabled. Object Lock can be enabled only here, when the bucket is created.
+func createS3Bucket(c context.Context, api S3CreateBucketAPI, bucketName string, region string, objectLock bool) (*s3.CreateBucketOutput, error) {
 	in := &s3.CreateBucketInput{
-		Bucket: &bucketName,
+		Bucket:                     &bucketName,
+		ObjectLockEnabledForBucket: objectLock,
+		ObjectOwnership:            types.ObjectOwnershipBucketOwnerEnforced,
 	}
 
 	if isValidLocationConstraint(region) {
@@ -31,6 +57,21 @@ func createS3Bucket(c context.Context, api S3CreateBucketAPI, bucketName string,
 	return api.CreateBucket(c, in)
 }
 
+// createS3CompatibleBucket creates the bucket on S3-compatible object store.
+// The store has its own region names, so the region is sent as LocationConstraint without validation.
+func createS3CompatibleBucket(c context.Context, api S3CreateBucketAPI, bucketName string, region string, objectLock bool) (*s3.CreateBucketOutput, error) {
+	in := &s3.CreateBucketInput{
+		Bucket:                     &bucketName,
+		ObjectLockEnabledForBucket: objectLock,
+	}
+	if region != "" && region != "us-east-1" {
+		in.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(region),
+		}
+	}
+	return api.CreateBucket(c, in)
+}
+
 type S3PutPublicAccessBlockAPI interface {
 	PutPublicAccessBlock(ctx context.Context,
 		params *s3.PutPublicAccessBlockInput,
@@ -50,6 +91,14 @@ func enableAllPublicAccessBlock(c context.Context, api S3PutPublicAccessBlockAPI
 	return api.PutPublicAccessBlock(c, in)
 }
 
+func putPublicAccessBlock(c context.Context, api S3PutPublicAccessBlockAPI, bucketName string, cfg *types.PublicAccessBlockConfiguration) (*s3.PutPublicAccessBlockOutput, error) {
+	in := &s3.PutPublicAccessBlockInput{
+		Bucket:                         &bucketName,
+		PublicAccessBlockConfiguration: cfg,
+	}
+	return api.PutPublicAccessBlock(c, in)
+}
+
 type S3PutBucketEncryptionAPI interface {
 	PutBucketEncryption(ctx context.Context,
 		params *s3.PutBucketEncryptionInput,
@@ -72,6 +121,32 @@ func enableBucketEncryptionAES256(c context.Context, api S3PutBucketEncryptionAP
 	return api.PutBucketEncryption(c, in)
 }
 
+func enableBucketEncryptionKMS(c context.Context, api S3PutBucketEncryptionAPI, bucketName string, kmsKeyID string) (*s3.PutBucketEncryptionOutput, error) {
+	in := &s3.PutBucketEncryptionInput{
+		Bucket: &bucketName,
+		ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{
+			Rules: []types.ServerSideEncryptionRule{
+				{
+					ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+						SSEAlgorithm:   types.ServerSideEncryptionAwsKms,
+						KMSMasterKeyID: aws.String(kmsKeyID),
+					},
+					BucketKeyEnabled: true,
+				},
+			},
+		},
+	}
+	return api.PutBucketEncryption(c, in)
+}
+
+func putBucketEncryption(c context.Context, api S3PutBucketEncryptionAPI, bucketName string, cfg *types.ServerSideEncryptionConfiguration) (*s3.PutBucketEncryptionOutput, error) {
+	in := &s3.PutBucketEncryptionInput{
+		Bucket:                            &bucketName,
+		ServerSideEncryptionConfiguration: cfg,
+	}
+	return api.PutBucketEncryption(c, in)
+}
+
 type S3PutBucketVersioningAPI interface {
 	PutBucketVersioning(ctx context.Context,
 		params *s3.PutBucketVersioningInput,
@@ -88,6 +163,55 @@ func enableBucketVersioning(c context.Context, api S3PutBucketVersioningAPI, buc
 	return api.PutBucketVersioning(c, in)
 }
 
+func putBucketVersioning(c context.Context, api S3PutBucketVersioningAPI, bucketName string, status types.BucketVersioningStatus) (*s3.PutBucketVersioningOutput, error) {
+	in := &s3.PutBucketVersioningInput{
+		Bucket: &bucketName,
+		VersioningConfiguration: &types.VersioningConfiguration{
+			Status: status,
+		},
+	}
+	return api.PutBucketVersioning(c, in)
+}
+
+type S3DeleteBucketAPI interface {
+	DeleteBucket(ctx context.Context,
+		params *s3.DeleteBucketInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error)
+}
+
+func deleteS3Bucket(c context.Context, api S3DeleteBucketAPI, bucketName string) (*s3.DeleteBucketOutput, error) {
+	in := &s3.DeleteBucketInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.DeleteBucket(c, in)
+}
+
+type S3DeletePublicAccessBlockAPI interface {
+	DeletePublicAccessBlock(ctx context.Context,
+		params *s3.DeletePublicAccessBlockInput,
+		optFns ...func(*s3.Options)) (*s3.DeletePublicAccessBlockOutput, error)
+}
+
+func deletePublicAccessBlock(c context.Context, api S3DeletePublicAccessBlockAPI, bucketName string) (*s3.DeletePublicAccessBlockOutput, error) {
+	in := &s3.DeletePublicAccessBlockInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.DeletePublicAccessBlock(c, in)
+}
+
+type S3DeleteBucketEncryptionAPI interface {
+	DeleteBucketEncryption(ctx context.Context,
+		params *s3.DeleteBucketEncryptionInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketEncryptionOutput, error)
+}
+
+func deleteBucketEncryption(c context.Context, api S3DeleteBucketEncryptionAPI, bucketName string) (*s3.DeleteBucketEncryptionOutput, error) {
+	in := &s3.DeleteBucketEncryptionInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.DeleteBucketEncryption(c, in)
+}
+
 type S3GetbucketLocation interface {
 	GetBucketLocation(ctx context.Context,
 		params *s3.GetBucketLocationInput,
@@ -140,6 +264,62 @@ func getBucketVersioning(c context.Context, api S3GetBucketVersioningAPI, bucket
 	return api.GetBucketVersioning(c, in)
 }
 
+type S3ListObjectVersionsAPI interface {
+	ListObjectVersions(ctx context.Context,
+		params *s3.ListObjectVersionsInput,
+		optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
+}
+
+func listS3ObjectVersions(c context.Context, api S3ListObjectVersionsAPI, bucketName string, keyMarker *string, versionIDMarker *string) (*s3.ListObjectVersionsOutput, error) {
+	in := &s3.ListObjectVersionsInput{
+		Bucket:          aws.String(bucketName),
+		KeyMarker:       keyMarker,
+		VersionIdMarker: versionIDMarker,
+	}
+	return api.ListObjectVersions(c, in)
+}
+
+type S3ListObjectsV2API interface {
+	ListObjectsV2(ctx context.Context,
+		params *s3.ListObjectsV2Input,
+		optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+func listS3Objects(c context.Context, api S3ListObjectsV2API, bucketName string, continuationToken *string) (*s3.ListObjectsV2Output, error) {
+	in := &s3.ListObjectsV2Input{
+		Bucket:            aws.String(bucketName),
+		ContinuationToken: continuationToken,
+	}
+	return api.ListObjectsV2(c, in)
+}
+
+type S3DeleteObjectsAPI interface {
+	DeleteObjects(ctx context.Context,
+		params *s3.DeleteObjectsInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+}
+
+// deleteS3Objects deletes up to 1,000 objects (or versions) at once.
+// Unlike other APIs, DeleteObjects reports errors of each object in the output, so they are returned as error.
+func deleteS3Objects(c context.Context, api S3DeleteObjectsAPI, bucketName string, objects []types.ObjectIdentifier) (*s3.DeleteObjectsOutput, error) {
+	in := &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucketName),
+		Delete: &types.Delete{
+			Objects: objects,
+			Quiet:   true,
+		},
+	}
+	out, err := api.DeleteObjects(c, in)
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Errors) > 0 {
+		e := out.Errors[0]
+		return out, fmt.Errorf("failed to delete %v object(s), for example %v: %v", len(out.Errors), aws.ToString(e.Key), aws.ToString(e.Message))
+	}
+	return out, nil
+}
+
 func isValidLocationConstraint(location string) bool {
 	for _, region := range types.BucketLocationConstraint("").Values() {
 		if string(region) == location {
@@ -148,3 +328,345 @@ func isValidLocationConstraint(location string) bool {
 	}
 	return false
 }
+
+type S3GetBucketPolicyAPI interface {
+	GetBucketPolicy(ctx context.Context,
+		params *s3.GetBucketPolicyInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error)
+}
+
+func getBucketPolicy(c context.Context, api S3GetBucketPolicyAPI, bucketName string) (*s3.GetBucketPolicyOutput, error) {
+	in := &s3.GetBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.GetBucketPolicy(c, in)
+}
+
+type S3GetBucketTaggingAPI interface {
+	GetBucketTagging(ctx context.Context,
+		params *s3.GetBucketTaggingInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error)
+}
+
+// getBucketTags returns tags of the bucket. A bucket without tags returns an empty map.
+func getBucketTags(c context.Context, api S3GetBucketTaggingAPI, bucketName string) (map[string]string, error) {
+	in := &s3.GetBucketTaggingInput{
+		Bucket: aws.String(bucketName),
+	}
+	out, err := api.GetBucketTagging(c, in)
+	if err != nil {
+		if isAPIErrorCode(err, "NoSuchTagSet") {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	tags := map[string]string{}
+	for _, t := range out.TagSet {
+		if t.Key != nil && t.Value != nil {
+			tags[*t.Key] = *t.Value
+		}
+	}
+	return tags, nil
+}
+
+type S3PutBucketTaggingAPI interface {
+	PutBucketTagging(ctx context.Context,
+		params *s3.PutBucketTaggingInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error)
+}
+
+// putBucketTags replaces all tags of the bucket with tags.
+func putBucketTags(c context.Context, api S3PutBucketTaggingAPI, bucketName string, tags map[string]string) (*s3.PutBucketTaggingOutput, error) {
+	in := &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucketName),
+		Tagging: &types.Tagging{},
+	}
+	for k, v := range tags {
+		in.Tagging.TagSet = append(in.Tagging.TagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return api.PutBucketTagging(c, in)
+}
+
+type S3DeleteBucketTaggingAPI interface {
+	DeleteBucketTagging(ctx context.Context,
+		params *s3.DeleteBucketTaggingInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketTaggingOutput, error)
+}
+
+func deleteBucketTags(c context.Context, api S3DeleteBucketTaggingAPI, bucketName string) (*s3.DeleteBucketTaggingOutput, error) {
+	in := &s3.DeleteBucketTaggingInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.DeleteBucketTagging(c, in)
+}
+
+type S3PutBucketPolicyAPI interface {
+	PutBucketPolicy(ctx context.Context,
+		params *s3.PutBucketPolicyInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketPolicyOutput, error)
+}
+
+func putBucketPolicy(c context.Context, api S3PutBucketPolicyAPI, bucketName string, policy string) (*s3.PutBucketPolicyOutput, error) {
+	in := &s3.PutBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+		Policy: aws.String(policy),
+	}
+	return api.PutBucketPolicy(c, in)
+}
+
+type S3DeleteBucketPolicyAPI interface {
+	DeleteBucketPolicy(ctx context.Context,
+		params *s3.DeleteBucketPolicyInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketPolicyOutput, error)
+}
+
+func deleteBucketPolicy(c context.Context, api S3DeleteBucketPolicyAPI, bucketName string) (*s3.DeleteBucketPolicyOutput, error) {
+	in := &s3.DeleteBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.DeleteBucketPolicy(c, in)
+}
+
+type S3GetBucketLifecycleConfigurationAPI interface {
+	GetBucketLifecycleConfiguration(ctx context.Context,
+		params *s3.GetBucketLifecycleConfigurationInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error)
+}
+
+// getBucketLifecycleRules returns lifecycle rules of the bucket. A bucket without lifecycle configuration returns no rules.
+func getBucketLifecycleRules(c context.Context, api S3GetBucketLifecycleConfigurationAPI, bucketName string) ([]types.LifecycleRule, error) {
+	in := &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucketName),
+	}
+	out, err := api.GetBucketLifecycleConfiguration(c, in)
+	if err != nil {
+		if isAPIErrorCode(err, "NoSuchLifecycleConfiguration") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return out.Rules, nil
+}
+
+type S3PutBucketLifecycleConfigurationAPI interface {
+	PutBucketLifecycleConfiguration(ctx context.Context,
+		params *s3.PutBucketLifecycleConfigurationInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketLifecycleConfigurationOutput, error)
+}
+
+// putBucketLifecycleRules replaces the whole lifecycle configuration of the bucket with rules.
+func putBucketLifecycleRules(c context.Context, api S3PutBucketLifecycleConfigurationAPI, bucketName string, rules []types.LifecycleRule) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	in := &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucketName),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{
+			Rules: rules,
+		},
+	}
+	return api.PutBucketLifecycleConfiguration(c, in)
+}
+
+type S3DeleteBucketLifecycleAPI interface {
+	DeleteBucketLifecycle(ctx context.Context,
+		params *s3.DeleteBucketLifecycleInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketLifecycleOutput, error)
+}
+
+func deleteBucketLifecycle(c context.Context, api S3DeleteBucketLifecycleAPI, bucketName string) (*s3.DeleteBucketLifecycleOutput, error) {
+	in := &s3.DeleteBucketLifecycleInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.DeleteBucketLifecycle(c, in)
+}
+
+type S3GetBucketLoggingAPI interface {
+	GetBucketLogging(ctx context.Context,
+		params *s3.GetBucketLoggingInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketLoggingOutput, error)
+}
+
+func getBucketLogging(c context.Context, api S3GetBucketLoggingAPI, bucketName string) (*s3.GetBucketLoggingOutput, error) {
+	in := &s3.GetBucketLoggingInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.GetBucketLogging(c, in)
+}
+
+type S3PutBucketLoggingAPI interface {
+	PutBucketLogging(ctx context.Context,
+		params *s3.PutBucketLoggingInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketLoggingOutput, error)
+}
+
+// putBucketLogging enables server access logging of the bucket. nil loggingEnabled disables it.
+func putBucketLogging(c context.Context, api S3PutBucketLoggingAPI, bucketName string, loggingEnabled *types.LoggingEnabled) (*s3.PutBucketLoggingOutput, error) {
+	in := &s3.PutBucketLoggingInput{
+		Bucket: aws.String(bucketName),
+		BucketLoggingStatus: &types.BucketLoggingStatus{
+			LoggingEnabled: loggingEnabled,
+		},
+	}
+	return api.PutBucketLogging(c, in)
+}
+
+type S3GetBucketReplicationAPI interface {
+	GetBucketReplication(ctx context.Context,
+		params *s3.GetBucketReplicationInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketReplicationOutput, error)
+}
+
+// getBucketReplication returns the replication configuration of the bucket, or nil if it isn't configured.
+func getBucketReplication(c context.Context, api S3GetBucketReplicationAPI, bucketName string) (*types.ReplicationConfiguration, error) {
+	in := &s3.GetBucketReplicationInput{
+		Bucket: aws.String(bucketName),
+	}
+	out, err := api.GetBucketReplication(c, in)
+	if err != nil {
+		if isAPIErrorCode(err, "ReplicationConfigurationNotFoundError") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return out.ReplicationConfiguration, nil
+}
+
+type S3PutBucketReplicationAPI interface {
+	PutBucketReplication(ctx context.Context,
+		params *s3.PutBucketReplicationInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketReplicationOutput, error)
+}
+
+// putBucketReplication replaces the whole replication configuration of the bucket.
+func putBucketReplication(c context.Context, api S3PutBucketReplicationAPI, bucketName string, cfg *types.ReplicationConfiguration) (*s3.PutBucketReplicationOutput, error) {
+	in := &s3.PutBucketReplicationInput{
+		Bucket:                   aws.String(bucketName),
+		ReplicationConfiguration: cfg,
+	}
+	return api.PutBucketReplication(c, in)
+}
+
+type S3DeleteBucketReplicationAPI interface {
+	DeleteBucketReplication(ctx context.Context,
+		params *s3.DeleteBucketReplicationInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketReplicationOutput, error)
+}
+
+func deleteBucketReplication(c context.Context, api S3DeleteBucketReplicationAPI, bucketName string) (*s3.DeleteBucketReplicationOutput, error) {
+	in := &s3.DeleteBucketReplicationInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.DeleteBucketReplication(c, in)
+}
+
+type S3PutObjectAPI interface {
+	PutObject(ctx context.Context,
+		params *s3.PutObjectInput,
+		optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// putS3Object uploads the small object with the server-side encryption header, which the bucket policy may require.
+// If encryption is empty, the header isn't sent. kmsKeyID is used only for SSE-KMS.
+func putS3Object(c context.Context, api S3PutObjectAPI, bucketName string, key string, body []byte, encryption types.ServerSideEncryption, kmsKeyID string, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	in := &s3.PutObjectInput{
+		Bucket:               aws.String(bucketName),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(body),
+		ServerSideEncryption: encryption,
+	}
+	if encryption == types.ServerSideEncryptionAwsKms && kmsKeyID != "" {
+		in.SSEKMSKeyId = aws.String(kmsKeyID)
+	}
+	return api.PutObject(c, in, optFns...)
+}
+
+// withIfNoneMatch makes PutObject a conditional write which fails with PreconditionFailed if the key exists.
+// The header is added by middleware, because PutObjectInput of this SDK version doesn't have the field.
+func withIfNoneMatch(o *s3.Options) {
+	o.APIOptions = append(o.APIOptions, smithyhttp.AddHeaderValue("If-None-Match", "*"))
+}
+
+type S3GetObjectLockConfigurationAPI interface {
+	GetObjectLockConfiguration(ctx context.Context,
+		params *s3.GetObjectLockConfigurationInput,
+		optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error)
+}
+
+// getObjectLockConfiguration returns the Object Lock configuration of the bucket, or nil if Object Lock isn't enabled.
+func getObjectLockConfiguration(c context.Context, api S3GetObjectLockConfigurationAPI, bucketName string) (*types.ObjectLockConfiguration, error) {
+	in := &s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(bucketName),
+	}
+	out, err := api.GetObjectLockConfiguration(c, in)
+	if err != nil {
+		if isAPIErrorCode(err, "ObjectLockConfigurationNotFoundError") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return out.ObjectLockConfiguration, nil
+}
+
+type S3PutObjectLockConfigurationAPI interface {
+	PutObjectLockConfiguration(ctx context.Context,
+		params *s3.PutObjectLockConfigurationInput,
+		optFns ...func(*s3.Options)) (*s3.PutObjectLockConfigurationOutput, error)
+}
+
+// putObjectLockConfiguration replaces the default retention of the bucket. Rule can be nil to remove it.
+func putObjectLockConfiguration(c context.Context, api S3PutObjectLockConfigurationAPI, bucketName string, cfg *types.ObjectLockConfiguration) (*s3.PutObjectLockConfigurationOutput, error) {
+	in := &s3.PutObjectLockConfigurationInput{
+		Bucket:                  aws.String(bucketName),
+		ObjectLockConfiguration: cfg,
+	}
+	return api.PutObjectLockConfiguration(c, in)
+}
+
+type S3GetBucketOwnershipControlsAPI interface {
+	GetBucketOwnershipControls(ctx context.Context,
+		params *s3.GetBucketOwnershipControlsInput,
+		optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error)
+}
+
+// getBucketOwnershipControls returns the ownership controls of the bucket, or nil if they aren't configured.
+func getBucketOwnershipControls(c context.Context, api S3GetBucketOwnershipControlsAPI, bucketName string) (*types.OwnershipControls, error) {
+	in := &s3.GetBucketOwnershipControlsInput{
+		Bucket: aws.String(bucketName),
+	}
+	out, err := api.GetBucketOwnershipControls(c, in)
+	if err != nil {
+		if isAPIErrorCode(err, "OwnershipControlsNotFoundError") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return out.OwnershipControls, nil
+}
+
+type S3PutBucketOwnershipControlsAPI interface {
+	PutBucketOwnershipControls(ctx context.Context,
+		params *s3.PutBucketOwnershipControlsInput,
+		optFns ...func(*s3.Options)) (*s3.PutBucketOwnershipControlsOutput, error)
+}
+
+func putBucketOwnershipControls(c context.Context, api S3PutBucketOwnershipControlsAPI, bucketName string, ownership types.ObjectOwnership) (*s3.PutBucketOwnershipControlsOutput, error) {
+	in := &s3.PutBucketOwnershipControlsInput{
+		Bucket: aws.String(bucketName),
+		OwnershipControls: &types.OwnershipControls{
+			Rules: []types.OwnershipControlsRule{{ObjectOwnership: ownership}},
+		},
+	}
+	return api.PutBucketOwnershipControls(c, in)
+}
+
+type S3DeleteBucketOwnershipControlsAPI interface {
+	DeleteBucketOwnershipControls(ctx context.Context,
+		params *s3.DeleteBucketOwnershipControlsInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteBucketOwnershipControlsOutput, error)
+}
+
+func deleteBucketOwnershipControls(c context.Context, api S3DeleteBucketOwnershipControlsAPI, bucketName string) (*s3.DeleteBucketOwnershipControlsOutput, error) {
+	in := &s3.DeleteBucketOwnershipControlsInput{
+		Bucket: aws.String(bucketName),
+	}
+	return api.DeleteBucketOwnershipControls(c, in)
+}
@@ -11,6 +11,57 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+type mockS3HeadBucketAPI func(ctx context.Context,
+	params *s3.HeadBucketInput,
+	optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+
+func (m mockS3HeadBucketAPI) HeadBucket(ctx context.Context,
+	params *s3.HeadBucketInput,
+	optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+
+	return m(ctx, params, optFns...)
+}
+
+func Test_s3BucketExists(t *testing.T) {
+	tests := []struct {
+		name    string
+		api     S3HeadBucketAPI
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "S01: Bucket exists",
+			api:     mockS3HeadBucketAPI(mockHeadBucketOK),
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "S02: Bucket doesn't exist",
+			api:     mockS3HeadBucketAPI(mockHeadBucketNotFound),
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "F01: Bucket is owned by other account",
+			api:     mockS3HeadBucketAPI(mockHeadBucketNG),
+			want:    false,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s3BucketExists(context.Background(), tt.api, "some-bucket")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("s3BucketExists() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("s3BucketExists() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 type mockS3CreateBucketAPI func(ctx context.Context,
 	params *s3.CreateBucketInput,
 	optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
@@ -26,6 +77,7 @@ func Test_createBucket_Success(t *testing.T) {
 	type args struct {
 		bucketName string
 		region     string
+		objectLock bool
 	}
 
 	// Given
@@ -44,6 +96,9 @@ func Test_createBucket_Success(t *testing.T) {
 					params *s3.CreateBucketInput,
 					optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
 
+					if params.ObjectOwnership != types.ObjectOwnershipBucketOwnerEnforced {
+						t.Errorf("createBucket() ObjectOwnership = %v, want = %v", params.ObjectOwnership, types.ObjectOwnershipBucketOwnerEnforced)
+					}
 					output := &s3.CreateBucketOutput{
 						Location: aws.String("ap-northeast-1"),
 					}
@@ -70,12 +125,32 @@ func Test_createBucket_Success(t *testing.T) {
 			want:    "us-east-1",
 			wantErr: false,
 		},
+		{
+			name: "S03: Object Lock",
+			args: args{bucketName: "sample-bucket", region: "ap-northeast-1", objectLock: true},
+			api: func(t *testing.T) S3CreateBucketAPI {
+				return mockS3CreateBucketAPI(func(ctx context.Context,
+					params *s3.CreateBucketInput,
+					optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+
+					if !params.ObjectLockEnabledForBucket {
+						t.Errorf("createBucket() ObjectLockEnabledForBucket = false, want = true")
+					}
+					output := &s3.CreateBucketOutput{
+						Location: aws.String("ap-northeast-1"),
+					}
+					return output, nil
+				})
+			},
+			want:    "ap-northeast-1",
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// When
-			got, err := createS3Bucket(context.Background(), tt.api(t), tt.args.bucketName, tt.args.region)
+			got, err := createS3Bucket(context.Background(), tt.api(t), tt.args.bucketName, tt.args.region, tt.args.objectLock)
 
 			// Then
 			if (err != nil) != tt.wantErr {
@@ -92,6 +167,7 @@ func Test_createBucket_Failure(t *testing.T) {
 	type args struct {
 		bucketName string
 		region     string
+		objectLock bool
 	}
 
 	// Given
@@ -141,7 +217,7 @@ func Test_createBucket_Failure(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// When
-			_, err := createS3Bucket(context.Background(), tt.api(t), tt.args.bucketName, tt.args.region)
+			_, err := createS3Bucket(context.Background(), tt.api(t), tt.args.bucketName, tt.args.region, tt.args.objectLock)
 
 			// Then
 			if (err != nil) != tt.wantErr {
@@ -151,6 +227,63 @@ func Test_createBucket_Failure(t *testing.T) {
 	}
 }
 
+func Test_createS3CompatibleBucket(t *testing.T) {
+	type args struct {
+		bucketName string
+		region     string
+	}
+
+	// Given
+	tests := []struct {
+		name string
+		args args
+		// want is the LocationConstraint sent to the store. Empty means no CreateBucketConfiguration.
+		want string
+	}{
+		{
+			name: "S01: Region of the store",
+			args: args{bucketName: "sample-bucket", region: "minio-local"},
+			want: "minio-local",
+		},
+		{
+			name: "S02: us-east-1",
+			args: args{bucketName: "sample-bucket", region: "us-east-1"},
+			want: "",
+		},
+		{
+			name: "S03: No region",
+			args: args{bucketName: "sample-bucket", region: ""},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			api := mockS3CreateBucketAPI(func(ctx context.Context,
+				params *s3.CreateBucketInput,
+				optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+
+				if params.CreateBucketConfiguration != nil {
+					got = string(params.CreateBucketConfiguration.LocationConstraint)
+				}
+				return &s3.CreateBucketOutput{}, nil
+			})
+
+			// When
+			_, err := createS3CompatibleBucket(context.Background(), api, tt.args.bucketName, tt.args.region, false)
+
+			// Then
+			if err != nil {
+				t.Errorf("createS3CompatibleBucket() error = %v, want = nil", err)
+			}
+			if got != tt.want {
+				t.Errorf("createS3CompatibleBucket() LocationConstraint = %v, want = %v", got, tt.want)
+			}
+		})
+	}
+}
+
 type mockS3PutPublicAccessBlockAPI func(ctx context.Context,
 	params *s3.PutPublicAccessBlockInput,
 	optFns ...func(*s3.Options)) (*s3.PutPublicAccessBlockOutput, error)
@@ -327,6 +460,70 @@ func Test_enableBucketEncryptionAES256_Failure(t *testing.T) {
 	}
 }
 
+func Test_enableBucketEncryptionKMS(t *testing.T) {
+	type args struct {
+		bucketName string
+		kmsKeyID   string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		api     func(t *testing.T) mockS3PutBucketEncryptionAPI
+		wantErr bool
+	}{
+		{
+			name: "S01: Happy path",
+			args: args{
+				bucketName: "happy-bucket",
+				kmsKeyID:   "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+			},
+			api: func(t *testing.T) mockS3PutBucketEncryptionAPI {
+				return mockS3PutBucketEncryptionAPI(func(ctx context.Context,
+					params *s3.PutBucketEncryptionInput,
+					optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+
+					rule := params.ServerSideEncryptionConfiguration.Rules[0]
+					if rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm != types.ServerSideEncryptionAwsKms {
+						t.Errorf("enableBucketEncryptionKMS() SSEAlgorithm = %v, want aws:kms", rule.ApplyServerSideEncryptionByDefault.SSEAlgorithm)
+					}
+					if *rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID != "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key" {
+						t.Errorf("enableBucketEncryptionKMS() KMSMasterKeyID = %v", *rule.ApplyServerSideEncryptionByDefault.KMSMasterKeyID)
+					}
+					if !rule.BucketKeyEnabled {
+						t.Errorf("enableBucketEncryptionKMS() BucketKeyEnabled = false, want true")
+					}
+					return &s3.PutBucketEncryptionOutput{}, nil
+				})
+			},
+			wantErr: false,
+		},
+		{
+			name: "F01: Bucket not exists",
+			args: args{
+				bucketName: "no-exist-bucket",
+				kmsKeyID:   "arn:aws:kms:ap-northeast-1:123456789012:key/happy-key",
+			},
+			api: func(t *testing.T) mockS3PutBucketEncryptionAPI {
+				return mockS3PutBucketEncryptionAPI(func(ctx context.Context,
+					params *s3.PutBucketEncryptionInput,
+					optFns ...func(*s3.Options)) (*s3.PutBucketEncryptionOutput, error) {
+
+					return nil, errors.New("no exist error")
+				})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := enableBucketEncryptionKMS(context.Background(), tt.api(t), tt.args.bucketName, tt.args.kmsKeyID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("enableBucketEncryptionKMS() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 type mockS3PutBucketVersioningAPI func(ctx context.Context,
 	params *s3.PutBucketVersioningInput,
 	optFns ...func(*s3.Options)) (*s3.PutBucketVersioningOutput, error)
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+type STSGetCallerIdentityAPI interface {
+	GetCallerIdentity(ctx context.Context,
+		params *sts.GetCallerIdentityInput,
+		optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+func getCallerIdentity(c context.Context, api STSGetCallerIdentityAPI) (*sts.GetCallerIdentityOutput, error) {
+	in := &sts.GetCallerIdentityInput{}
+	return api.GetCallerIdentity(c, in)
+}